@@ -0,0 +1,417 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdcaptureanalyze
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const containerIDPrefix = "container_id://"
+
+var (
+	eventPrefix        = []byte("_e{")
+	serviceCheckPrefix = []byte("_sc")
+	tagsFieldPrefix    = []byte("#")
+	containerIDField   = []byte("c:")
+)
+
+// packetReader reads the packets of a capture, it's implemented by replay.TrafficCaptureReader.
+type packetReader interface {
+	ReadNext() (*pb.UnixDogstatsdMsg, error)
+}
+
+// filter selects the packets analyzed and exported, its zero value selects all of them.
+type filter struct {
+	// metric selects the packets holding a metric whose name matches, only these
+	// metrics are counted in the message, metric and tag statistics
+	metric *regexp.Regexp
+	// pid selects the packets sent by a process, 0 for all of them
+	pid int32
+	// containerID selects the packets sent by a container
+	containerID string
+}
+
+// exportedPacket is the JSON representation of an exported packet
+type exportedPacket struct {
+	Timestamp   time.Time `json:"timestamp"`
+	PID         int32     `json:"pid"`
+	ContainerID string    `json:"container_id,omitempty"`
+	Payload     string    `json:"payload"`
+}
+
+// message is a DogStatsD message read from a packet
+type message struct {
+	size        int
+	metricName  string
+	tags        []string
+	containerID string
+	isEvent     bool
+	isCheck     bool
+}
+
+type metricStats struct {
+	messages int
+	bytes    int
+	contexts map[string]struct{}
+}
+
+type tagKeyStats struct {
+	values  map[string]struct{}
+	metrics map[string]struct{}
+}
+
+type senderKey struct {
+	pid         int32
+	containerID string
+}
+
+type senderStats struct {
+	packets int
+	bytes   int
+}
+
+// analyzer aggregates the statistics of the packets of a capture.
+type analyzer struct {
+	filter filter
+	// pidMap maps the PIDs of the senders to their container, as stored in the capture state
+	pidMap map[int32]string
+	// tsResolution is the resolution of the timestamps of the packets
+	tsResolution time.Duration
+
+	packets       int
+	bytes         int
+	metrics       int
+	events        int
+	serviceChecks int
+	invalid       int
+
+	metricStats map[string]*metricStats
+	tagKeyStats map[string]*tagKeyStats
+	senderStats map[senderKey]*senderStats
+
+	// exported holds the packets selected by the filter, when they are exported
+	exported []exportedPacket
+	export   bool
+}
+
+func newAnalyzer(f filter, pidMap map[int32]string, tsResolution time.Duration, export bool) *analyzer {
+	return &analyzer{
+		filter:       f,
+		pidMap:       pidMap,
+		tsResolution: tsResolution,
+		export:       export,
+		metricStats:  make(map[string]*metricStats),
+		tagKeyStats:  make(map[string]*tagKeyStats),
+		senderStats:  make(map[senderKey]*senderStats),
+	}
+}
+
+// readAll analyzes the packets of the reader until its end.
+func (a *analyzer) readAll(r packetReader) error {
+	for {
+		msg, err := r.ReadNext()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		a.add(msg)
+	}
+}
+
+// add analyzes a packet, if it's selected by the filter.
+func (a *analyzer) add(msg *pb.UnixDogstatsdMsg) {
+	payload := msg.Payload
+	if int(msg.PayloadSize) <= len(payload) {
+		payload = payload[:msg.PayloadSize]
+	}
+	senderContainer := strings.TrimPrefix(a.pidMap[msg.Pid], containerIDPrefix)
+
+	var messages []message
+	for _, raw := range bytes.Split(payload, []byte("\n")) {
+		if len(raw) == 0 {
+			continue
+		}
+		m := parseMessage(raw)
+		m.size = len(raw)
+		messages = append(messages, m)
+	}
+	for _, m := range messages {
+		// the container ID sent by the clients is preferred, as by the origin detection
+		if m.containerID != "" {
+			senderContainer = m.containerID
+			break
+		}
+	}
+	if !a.selects(msg.Pid, senderContainer, messages) {
+		return
+	}
+
+	a.packets++
+	a.bytes += len(payload)
+	sender := a.senderStats[senderKey{msg.Pid, senderContainer}]
+	if sender == nil {
+		sender = &senderStats{}
+		a.senderStats[senderKey{msg.Pid, senderContainer}] = sender
+	}
+	sender.packets++
+	sender.bytes += len(payload)
+
+	for _, m := range messages {
+		if !a.selectsMessage(m) {
+			continue
+		}
+		switch {
+		case m.isEvent:
+			a.events++
+		case m.isCheck:
+			a.serviceChecks++
+		case m.metricName == "":
+			a.invalid++
+		default:
+			a.metrics++
+			a.addMetric(m)
+		}
+	}
+
+	if a.export {
+		a.exported = append(a.exported, exportedPacket{
+			Timestamp:   time.Unix(0, 0).Add(time.Duration(msg.Timestamp) * a.tsResolution).UTC(),
+			PID:         msg.Pid,
+			ContainerID: senderContainer,
+			Payload:     string(payload),
+		})
+	}
+}
+
+// addMetric adds the statistics of a metric message.
+func (a *analyzer) addMetric(m message) {
+	stats := a.metricStats[m.metricName]
+	if stats == nil {
+		stats = &metricStats{contexts: make(map[string]struct{})}
+		a.metricStats[m.metricName] = stats
+	}
+	stats.messages++
+	stats.bytes += m.size
+
+	tags := append([]string(nil), m.tags...)
+	sort.Strings(tags)
+	stats.contexts[strings.Join(tags, ",")] = struct{}{}
+
+	for _, tag := range tags {
+		key, value, _ := strings.Cut(tag, ":")
+		keyStats := a.tagKeyStats[key]
+		if keyStats == nil {
+			keyStats = &tagKeyStats{values: make(map[string]struct{}), metrics: make(map[string]struct{})}
+			a.tagKeyStats[key] = keyStats
+		}
+		keyStats.values[value] = struct{}{}
+		keyStats.metrics[m.metricName] = struct{}{}
+	}
+}
+
+func (a *analyzer) selects(pid int32, containerID string, messages []message) bool {
+	if a.filter.pid != 0 && pid != a.filter.pid {
+		return false
+	}
+	if a.filter.containerID != "" && containerID != a.filter.containerID {
+		return false
+	}
+	if a.filter.metric == nil {
+		return true
+	}
+	for _, m := range messages {
+		if m.metricName != "" && a.selectsMessage(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsMessage returns true if a message of a selected packet is analyzed: the metric
+// filter applies to each message, the other messages of the packet are ignored.
+func (a *analyzer) selectsMessage(m message) bool {
+	return a.filter.metric == nil || (m.metricName != "" && a.filter.metric.MatchString(m.metricName))
+}
+
+// parseMessage reads the metric name, tags and container ID of a DogStatsD message. The
+// messages aren't fully validated, the metric name is empty for the invalid messages.
+func parseMessage(raw []byte) message {
+	if bytes.HasPrefix(raw, eventPrefix) {
+		return message{isEvent: true}
+	}
+	if bytes.HasPrefix(raw, serviceCheckPrefix) {
+		return message{isCheck: true}
+	}
+
+	fields := bytes.Split(raw, []byte("|"))
+	name, _, found := bytes.Cut(fields[0], []byte(":"))
+	if !found || len(name) == 0 || len(fields) < 2 {
+		return message{}
+	}
+	m := message{metricName: string(name)}
+	for _, field := range fields[2:] {
+		switch {
+		case bytes.HasPrefix(field, tagsFieldPrefix):
+			if len(field) > 1 {
+				m.tags = strings.Split(string(field[1:]), ",")
+			}
+		case bytes.HasPrefix(field, containerIDField):
+			m.containerID = string(field[len(containerIDField):])
+		}
+	}
+	return m
+}
+
+// metricReport holds the statistics of a metric name
+type metricReport struct {
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+	Bytes    int    `json:"bytes"`
+	Contexts int    `json:"contexts"`
+}
+
+// tagKeyReport holds the statistics of a tag key
+type tagKeyReport struct {
+	Key     string `json:"key"`
+	Values  int    `json:"values"`
+	Metrics int    `json:"metrics"`
+}
+
+// senderReport holds the statistics of a sender
+type senderReport struct {
+	PID         int32  `json:"pid"`
+	ContainerID string `json:"container_id,omitempty"`
+	Packets     int    `json:"packets"`
+	Bytes       int    `json:"bytes"`
+}
+
+// report holds the result of the analysis, the lists being limited to their top entries.
+type report struct {
+	Packets          int            `json:"packets"`
+	Bytes            int            `json:"bytes"`
+	Metrics          int            `json:"metrics"`
+	Events           int            `json:"events"`
+	ServiceChecks    int            `json:"service_checks"`
+	Invalid          int            `json:"invalid"`
+	TopMetrics       []metricReport `json:"top_metrics"`
+	TopCardinalities []metricReport `json:"top_cardinalities"`
+	TopTagKeys       []tagKeyReport `json:"top_tag_keys"`
+	TopSenders       []senderReport `json:"top_senders"`
+}
+
+// report returns the top entries of the analysis, up to top entries per list.
+func (a *analyzer) report(top int) report {
+	metrics := make([]metricReport, 0, len(a.metricStats))
+	for name, stats := range a.metricStats {
+		metrics = append(metrics, metricReport{Name: name, Messages: stats.messages, Bytes: stats.bytes, Contexts: len(stats.contexts)})
+	}
+	byVolume := append([]metricReport(nil), metrics...)
+	sort.Slice(byVolume, func(i, j int) bool {
+		if byVolume[i].Messages != byVolume[j].Messages {
+			return byVolume[i].Messages > byVolume[j].Messages
+		}
+		return byVolume[i].Name < byVolume[j].Name
+	})
+	byCardinality := metrics
+	sort.Slice(byCardinality, func(i, j int) bool {
+		if byCardinality[i].Contexts != byCardinality[j].Contexts {
+			return byCardinality[i].Contexts > byCardinality[j].Contexts
+		}
+		return byCardinality[i].Name < byCardinality[j].Name
+	})
+
+	tagKeys := make([]tagKeyReport, 0, len(a.tagKeyStats))
+	for key, stats := range a.tagKeyStats {
+		tagKeys = append(tagKeys, tagKeyReport{Key: key, Values: len(stats.values), Metrics: len(stats.metrics)})
+	}
+	sort.Slice(tagKeys, func(i, j int) bool {
+		if tagKeys[i].Values != tagKeys[j].Values {
+			return tagKeys[i].Values > tagKeys[j].Values
+		}
+		return tagKeys[i].Key < tagKeys[j].Key
+	})
+
+	senders := make([]senderReport, 0, len(a.senderStats))
+	for key, stats := range a.senderStats {
+		senders = append(senders, senderReport{PID: key.pid, ContainerID: key.containerID, Packets: stats.packets, Bytes: stats.bytes})
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		if senders[i].Packets != senders[j].Packets {
+			return senders[i].Packets > senders[j].Packets
+		}
+		if senders[i].PID != senders[j].PID {
+			return senders[i].PID < senders[j].PID
+		}
+		return senders[i].ContainerID < senders[j].ContainerID
+	})
+
+	return report{
+		Packets:          a.packets,
+		Bytes:            a.bytes,
+		Metrics:          a.metrics,
+		Events:           a.events,
+		ServiceChecks:    a.serviceChecks,
+		Invalid:          a.invalid,
+		TopMetrics:       limit(byVolume, top),
+		TopCardinalities: limit(byCardinality, top),
+		TopTagKeys:       limit(tagKeys, top),
+		TopSenders:       limit(senders, top),
+	}
+}
+
+func limit[T any](entries []T, top int) []T {
+	if top > 0 && len(entries) > top {
+		return entries[:top]
+	}
+	return entries
+}
+
+// format renders the report as text.
+func (r report) format() string {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "Packets: %d (%d bytes)\n", r.Packets, r.Bytes)
+	fmt.Fprintf(buf, "Messages: %d metrics, %d events, %d service checks, %d invalid\n", r.Metrics, r.Events, r.ServiceChecks, r.Invalid)
+
+	writeTable(buf, "Top metrics by messages", fmt.Sprintf("%-50s | %-10s | %-10s | %-10s\n", "Metric", "Messages", "Bytes", "Contexts"), len(r.TopMetrics), func(i int) string {
+		m := r.TopMetrics[i]
+		return fmt.Sprintf("%-50s | %-10d | %-10d | %-10d\n", m.Name, m.Messages, m.Bytes, m.Contexts)
+	})
+	writeTable(buf, "Top metrics by contexts", fmt.Sprintf("%-50s | %-10s | %-10s\n", "Metric", "Contexts", "Messages"), len(r.TopCardinalities), func(i int) string {
+		m := r.TopCardinalities[i]
+		return fmt.Sprintf("%-50s | %-10d | %-10d\n", m.Name, m.Contexts, m.Messages)
+	})
+	writeTable(buf, "Top tag keys by values", fmt.Sprintf("%-50s | %-10s | %-10s\n", "Tag key", "Values", "Metrics"), len(r.TopTagKeys), func(i int) string {
+		k := r.TopTagKeys[i]
+		return fmt.Sprintf("%-50s | %-10d | %-10d\n", k.Key, k.Values, k.Metrics)
+	})
+	writeTable(buf, "Top senders by packets", fmt.Sprintf("%-10s | %-64s | %-10s | %-10s\n", "PID", "Container", "Packets", "Bytes"), len(r.TopSenders), func(i int) string {
+		s := r.TopSenders[i]
+		return fmt.Sprintf("%-10d | %-64s | %-10d | %-10d\n", s.PID, s.ContainerID, s.Packets, s.Bytes)
+	})
+	return buf.String()
+}
+
+func writeTable(buf *bytes.Buffer, title string, header string, rows int, row func(int) string) {
+	fmt.Fprintf(buf, "\n%s:\n\n", title)
+	buf.WriteString(header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for i := 0; i < rows; i++ {
+		buf.WriteString(row(i))
+	}
+	if rows == 0 {
+		buf.WriteString("None.\n")
+	}
+}
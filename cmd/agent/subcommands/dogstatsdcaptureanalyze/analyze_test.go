@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdcaptureanalyze
+
+import (
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+type fakeReader struct {
+	msgs []*pb.UnixDogstatsdMsg
+}
+
+func (r *fakeReader) ReadNext() (*pb.UnixDogstatsdMsg, error) {
+	if len(r.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, nil
+}
+
+func packet(ts int64, pid int32, payload string) *pb.UnixDogstatsdMsg {
+	return &pb.UnixDogstatsdMsg{Timestamp: ts, Pid: pid, Payload: []byte(payload), PayloadSize: int32(len(payload))}
+}
+
+func testCapture() *fakeReader {
+	return &fakeReader{msgs: []*pb.UnixDogstatsdMsg{
+		packet(1, 10, "app.requests:1|c|#endpoint:/a,status:200\napp.requests:1|c|#status:200,endpoint:/b"),
+		packet(2, 10, "app.requests:1|c|#endpoint:/a,status:200\napp.latency:12|d|#endpoint:/a"),
+		packet(3, 20, "app.requests:1|c|#endpoint:/c,status:500|c:container2"),
+		packet(4, 30, "_e{5,4}:title|text\n_sc|check|0\ngibberish"),
+	}}
+}
+
+func TestAnalyzer(t *testing.T) {
+	a := newAnalyzer(filter{}, map[int32]string{10: "container_id://container1"}, time.Second, false)
+	require.NoError(t, a.readAll(testCapture()))
+
+	r := a.report(0)
+	assert.Equal(t, 4, r.Packets)
+	assert.Equal(t, 5, r.Metrics)
+	assert.Equal(t, 1, r.Events)
+	assert.Equal(t, 1, r.ServiceChecks)
+	assert.Equal(t, 1, r.Invalid)
+
+	require.Len(t, r.TopMetrics, 2)
+	assert.Equal(t, "app.requests", r.TopMetrics[0].Name)
+	assert.Equal(t, 4, r.TopMetrics[0].Messages)
+	// the order of the tags doesn't matter
+	assert.Equal(t, 3, r.TopMetrics[0].Contexts)
+	assert.Equal(t, "app.latency", r.TopMetrics[1].Name)
+	assert.Equal(t, len("app.latency:12|d|#endpoint:/a"), r.TopMetrics[1].Bytes)
+	assert.Equal(t, "app.requests", r.TopCardinalities[0].Name)
+
+	assert.Equal(t, []tagKeyReport{
+		{Key: "endpoint", Values: 3, Metrics: 2},
+		{Key: "status", Values: 2, Metrics: 1},
+	}, r.TopTagKeys)
+
+	require.Len(t, r.TopSenders, 3)
+	assert.Equal(t, senderReport{PID: 10, ContainerID: "container1", Packets: 2, Bytes: len(testCapture().msgs[0].Payload) + len(testCapture().msgs[1].Payload)}, r.TopSenders[0])
+	// the container ID sent by the client is used
+	assert.Equal(t, int32(20), r.TopSenders[1].PID)
+	assert.Equal(t, "container2", r.TopSenders[1].ContainerID)
+	assert.Equal(t, int32(30), r.TopSenders[2].PID)
+	assert.Equal(t, "", r.TopSenders[2].ContainerID)
+
+	assert.Len(t, a.report(1).TopMetrics, 1)
+	assert.Contains(t, r.format(), "app.requests")
+}
+
+func TestAnalyzerFilterAndExport(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filter   filter
+		expected []int64
+	}{
+		{"metric", filter{metric: regexp.MustCompile(`latency`)}, []int64{2}},
+		{"pid", filter{pid: 10}, []int64{1, 2}},
+		{"container", filter{containerID: "container2"}, []int64{3}},
+		{"no match", filter{pid: 10, containerID: "container2"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newAnalyzer(tc.filter, map[int32]string{10: "container_id://container1"}, time.Second, true)
+			require.NoError(t, a.readAll(testCapture()))
+
+			var timestamps []int64
+			for _, p := range a.exported {
+				timestamps = append(timestamps, p.Timestamp.Unix())
+			}
+			assert.Equal(t, tc.expected, timestamps)
+			assert.Equal(t, len(tc.expected), a.report(0).Packets)
+		})
+	}
+
+	// the metric filter applies to each message of the selected packets
+	a := newAnalyzer(filter{metric: regexp.MustCompile(`latency`)}, nil, time.Second, false)
+	require.NoError(t, a.readAll(testCapture()))
+	r := a.report(0)
+	assert.Equal(t, 1, r.Metrics)
+	require.Len(t, r.TopMetrics, 1)
+	assert.Equal(t, "app.latency", r.TopMetrics[0].Name)
+	assert.Equal(t, []tagKeyReport{{Key: "endpoint", Values: 1, Metrics: 1}}, r.TopTagKeys)
+
+	a = newAnalyzer(filter{pid: 20}, nil, time.Nanosecond, true)
+	require.NoError(t, a.readAll(testCapture()))
+	assert.Equal(t, []exportedPacket{{
+		Timestamp:   time.Unix(0, 3).UTC(),
+		PID:         20,
+		ContainerID: "container2",
+		Payload:     "app.requests:1|c|#endpoint:/c,status:500|c:container2",
+	}}, a.exported)
+}
+
+func TestAnalyzeCaptureFile(t *testing.T) {
+	reader, err := replay.NewTrafficCaptureReader("../../../../comp/dogstatsd/replay/resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer reader.Close()
+	pidMap, _, err := reader.ReadState()
+	require.NoError(t, err)
+
+	a := newAnalyzer(filter{}, pidMap, reader.TimestampResolution(), false)
+	reader.Seek(0)
+	require.NoError(t, a.readAll(reader))
+
+	r := a.report(defaultTop)
+	assert.Equal(t, 21, r.Packets)
+	require.Len(t, r.TopMetrics, 1)
+	assert.Equal(t, metricReport{Name: "jaime.uds.test", Messages: 21, Bytes: 21 * len("jaime.uds.test:8|g|#shell:test"), Contexts: 1}, r.TopMetrics[0])
+	assert.Equal(t, []tagKeyReport{{Key: "shell", Values: 1, Metrics: 1}}, r.TopTagKeys)
+	// each packet is sent by a different process, some of them in a container
+	assert.Len(t, r.TopSenders, defaultTop)
+	containers := 0
+	for _, s := range a.report(0).TopSenders {
+		if s.ContainerID == "c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22" {
+			containers++
+		}
+	}
+	assert.Equal(t, 7, containers)
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package dogstatsdcaptureanalyze implements 'agent dogstatsd-capture-analyze'.
+package dogstatsdcaptureanalyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const defaultTop = 10
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	// subcommand-specific flags
+
+	dsdCaptureFilePath string
+	dsdMmapCapture     bool
+	top                int
+	metricFilter       string
+	pidFilter          int32
+	containerFilter    string
+	jsonReport         bool
+	exportFilePath     string
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	dogstatsdCaptureAnalyzeCmd := &cobra.Command{
+		Use:   "dogstatsd-capture-analyze",
+		Short: "Analyze a dogstatsd traffic capture",
+		Long: `Report the top metric names by messages and by contexts, the tag keys with the most values and
+the top senders by PID and container of a traffic capture, and optionally export its packets to JSON.
+The packets can be filtered by metric name, PID and container.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fxutil.OneShot(dogstatsdCaptureAnalyze,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	dogstatsdCaptureAnalyzeCmd.Flags().StringVarP(&cliParams.dsdCaptureFilePath, "file", "f", "", "Input file with traffic captured with dogstatsd-capture.")
+	dogstatsdCaptureAnalyzeCmd.Flags().BoolVarP(&cliParams.dsdMmapCapture, "mmap", "m", true, "Mmap file for analysis. Set to false to load the entire file into memory instead")
+	dogstatsdCaptureAnalyzeCmd.Flags().IntVarP(&cliParams.top, "top", "n", defaultTop, "Number of entries reported per list, 0 for all of them.")
+	dogstatsdCaptureAnalyzeCmd.Flags().StringVarP(&cliParams.metricFilter, "metric", "", "", "Only analyze the metrics whose name matches this regular expression, and the packets holding them.")
+	dogstatsdCaptureAnalyzeCmd.Flags().Int32VarP(&cliParams.pidFilter, "pid", "", 0, "Only analyze the packets sent by this PID.")
+	dogstatsdCaptureAnalyzeCmd.Flags().StringVarP(&cliParams.containerFilter, "container", "", "", "Only analyze the packets sent by this container ID.")
+	dogstatsdCaptureAnalyzeCmd.Flags().BoolVarP(&cliParams.jsonReport, "json", "j", false, "Print out the report as JSON.")
+	dogstatsdCaptureAnalyzeCmd.Flags().StringVarP(&cliParams.exportFilePath, "export", "o", "", "Export the analyzed packets to this JSON file.")
+
+	return []*cobra.Command{dogstatsdCaptureAnalyzeCmd}
+}
+
+//nolint:revive // TODO(AML) Fix revive linter
+func dogstatsdCaptureAnalyze(log log.Component, config config.Component, cliParams *cliParams) error {
+	if cliParams.dsdCaptureFilePath == "" {
+		return fmt.Errorf("a capture file is required, use --file")
+	}
+
+	f := filter{pid: cliParams.pidFilter, containerID: cliParams.containerFilter}
+	if cliParams.metricFilter != "" {
+		metric, err := regexp.Compile(cliParams.metricFilter)
+		if err != nil {
+			return fmt.Errorf("invalid metric filter: %w", err)
+		}
+		f.metric = metric
+	}
+
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdCaptureFilePath, 1, cliParams.dsdMmapCapture)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not open: %s\n", cliParams.dsdCaptureFilePath)
+		return err
+	}
+
+	pidMap, _, err := reader.ReadState()
+	if err != nil {
+		// the report is printed on stdout
+		fmt.Fprintf(os.Stderr, "Unable to load state from file, the senders won't be resolved to their container: %v\n", err)
+	}
+
+	a := newAnalyzer(f, pidMap, reader.TimestampResolution(), cliParams.exportFilePath != "")
+	// the offset is relative to the first packet
+	reader.Seek(0)
+	if err := a.readAll(reader); err != nil {
+		return fmt.Errorf("could not read the capture: %w", err)
+	}
+
+	r := a.report(cliParams.top)
+	if cliParams.jsonReport {
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Capture: %s (version %d)\n\n", cliParams.dsdCaptureFilePath, reader.Version)
+		fmt.Print(r.format())
+	}
+
+	if cliParams.exportFilePath == "" {
+		return nil
+	}
+	out, err := json.MarshalIndent(a.exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cliParams.exportFilePath, out, 0644); err != nil {
+		return fmt.Errorf("could not export the packets: %w", err)
+	}
+	if !cliParams.jsonReport {
+		fmt.Printf("\n%d packets exported to: %s\n", len(a.exported), cliParams.exportFilePath)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdcaptureanalyze
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-capture-analyze", "-f", "capture.dog", "--metric", "^app\\.", "--pid", "42", "-n", "5", "-o", "packets.json"},
+		dogstatsdCaptureAnalyze,
+		func(cliParams *cliParams, coreParams core.BundleParams) {
+			require.Equal(t, "capture.dog", cliParams.dsdCaptureFilePath)
+			require.Equal(t, "^app\\.", cliParams.metricFilter)
+			require.Equal(t, int32(42), cliParams.pidFilter)
+			require.Equal(t, 5, cliParams.top)
+			require.Equal(t, "packets.json", cliParams.exportFilePath)
+			require.True(t, cliParams.dsdMmapCapture)
+		})
+}
@@ -87,6 +87,7 @@ import (
 	adScheduler "github.com/DataDog/datadog-agent/pkg/logs/schedulers/ad"
 	pkgMetadata "github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/remotewrite"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -339,6 +340,7 @@ func getSharedFxOption() fx.Option {
 		fx.Provide(func(config config.Component) demultiplexerimpl.Params {
 			params := demultiplexerimpl.NewDefaultParams()
 			params.EnableNoAggregationPipeline = config.GetBool("dogstatsd_no_aggregation_pipeline")
+			params.EnableOpenMetricsExposition = config.GetBool("aggregator_openmetrics.enabled")
 			return params
 		}),
 		demultiplexerimpl.Module(),
@@ -561,6 +563,13 @@ func startAgent(
 		}
 	}
 
+	// Start the Prometheus remote write receiver
+	if remotewrite.IsEnabled(pkgconfig.Datadog) {
+		if err = remotewrite.StartServer(hostnameDetected, demultiplexer, pkgconfig.Datadog); err != nil {
+			log.Errorf("Failed to start the Prometheus remote write receiver: %s", err)
+		}
+	}
+
 	// Append version and timestamp to version history log file if this Agent is different than the last run version
 	installinfo.LogVersionHistory()
 
@@ -640,6 +649,7 @@ func stopAgent(cliParams *cliParams, server dogstatsdServer.Component, demultipl
 		common.MetadataScheduler.Stop()
 	}
 	traps.StopServer()
+	remotewrite.StopServer()
 	agentAPI.StopServer()
 	clcrunnerapi.StopCLCRunnerServer()
 	jmx.StopJmxfetch()
@@ -24,6 +24,7 @@ type ConnectionTracker struct {
 	stoppedChan       chan struct{}
 	closeDelay        time.Duration
 	activeConnections *atomic.Int32
+	started           *atomic.Bool
 	name              string
 }
 
@@ -40,23 +41,33 @@ func NewConnectionTracker(name string, closeDelay time.Duration) *ConnectionTrac
 		stoppedChan:       make(chan struct{}),
 		closeDelay:        closeDelay,
 		activeConnections: atomic.NewInt32(0),
+		started:           atomic.NewBool(false),
 		name:              name,
 	}
 }
 
 // Start starts the connection tracker.
 func (t *ConnectionTracker) Start() {
+	t.started.Store(true)
 	go t.HandleConnections()
 }
 
-// Track tracks a connection.
+// Track tracks a connection, it is closed immediately if the tracker is stopped.
 func (t *ConnectionTracker) Track(conn net.Conn) {
-	t.connToTrack <- conn
+	select {
+	case t.connToTrack <- conn:
+	case <-t.stoppedChan:
+		conn.Close()
+	}
 }
 
 // Close closes a connection.
 func (t *ConnectionTracker) Close(conn net.Conn) {
-	t.connToClose <- conn
+	select {
+	case t.connToClose <- conn:
+	case <-t.stoppedChan:
+		conn.Close()
+	}
 }
 
 // HandleConnections handles connections.
@@ -128,18 +139,23 @@ func (t *ConnectionTracker) HandleConnections() {
 			stop = true
 		}
 	}
-	t.stoppedChan <- struct{}{}
+	close(t.stoppedChan)
 }
 
 // Stop stops the connection tracker.
 // To be called one the listener is stopped, after the server socket has been close.
+// The connections tracked afterwards are closed immediately.
 func (t *ConnectionTracker) Stop() {
-	if t.activeConnections.Load() == 0 {
+	if !t.started.Load() {
 		return
 	}
 
-	// Request closing connections
-	t.stopChan <- struct{}{}
+	// Request closing connections, unless the tracker is already stopped
+	select {
+	case t.stopChan <- struct{}{}:
+	case <-t.stoppedChan:
+		return
+	}
 
 	// Wait until all connections are closed
 	<-t.stoppedChan
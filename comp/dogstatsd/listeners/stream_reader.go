@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// errFrameTooLarge is returned when a packet doesn't fit in the packet buffers.
+var errFrameTooLarge = errors.New("frame larger than dogstatsd_buffer_size")
+
+// frameReader reads packets out of a stream.
+type frameReader interface {
+	// read fills the buffer with a packet of complete messages and returns its length,
+	// the packet must be processed even when an error is returned.
+	read(buf []byte) (int, error)
+}
+
+// newlineReader reads packets of newline-terminated messages, the partial message
+// following the last newline read is kept for the next packet.
+type newlineReader struct {
+	conn    io.Reader
+	pending []byte
+}
+
+func (r *newlineReader) read(buf []byte) (int, error) {
+	n := copy(buf, r.pending)
+	r.pending = r.pending[:0]
+	for {
+		m, err := r.conn.Read(buf[n:])
+		n += m
+		if i := bytes.LastIndexByte(buf[n-m:n], '\n'); i >= 0 {
+			end := n - m + i + 1
+			r.pending = append(r.pending, buf[end:n]...)
+			return end, err
+		}
+		if err == io.EOF {
+			// the last message of the stream doesn't need to be terminated
+			return n, err
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == len(buf) {
+			return 0, errFrameTooLarge
+		}
+	}
+}
+
+// lengthPrefixReader reads packets prefixed with their length.
+type lengthPrefixReader struct {
+	conn io.Reader
+}
+
+func (r *lengthPrefixReader) read(buf []byte) (int, error) {
+	return readLengthPrefixedPacket(r.conn, buf, r.conn.Read)
+}
+
+// readLengthPrefixedPacket reads a packet prefixed with its length as a little-endian
+// uint32, the framing of the TCP streams and of the `dogstatsd_stream_socket` Unix
+// socket. The payload is read with readPayload, letting the Unix socket read the
+// credentials sent along with it, reading no bytes means that the stream ended.
+// io.EOF is returned when the stream ends, even in the middle of a packet, which is
+// then incomplete and dropped.
+func readLengthPrefixedPacket(conn io.Reader, buf []byte, readPayload func([]byte) (int, error)) (int, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[:])
+	if length > uint32(len(buf)) {
+		return 0, errFrameTooLarge
+	}
+	n := 0
+	for n < int(length) {
+		m, err := readPayload(buf[n:length])
+		n += m
+		if m == 0 && err == nil {
+			err = io.EOF
+		}
+		if err != nil && n < int(length) {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewlineReader(t *testing.T) {
+	reader := &newlineReader{conn: iotest.OneByteReader(strings.NewReader("a:1|c\nb:2|c\nc:3|c"))}
+	buf := make([]byte, 8)
+
+	// the reader returns as soon as it has a complete message
+	n, err := reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "a:1|c\n", string(buf[:n]))
+	n, err = reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "b:2|c\n", string(buf[:n]))
+	n, err = reader.read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "c:3|c", string(buf[:n]))
+
+	// the partial messages are kept for the next packet
+	reader = &newlineReader{conn: strings.NewReader("a:1|c\nb:2|c\n")}
+	n, err = reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "a:1|c\n", string(buf[:n]))
+	n, err = reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "b:2|c\n", string(buf[:n]))
+
+	reader = &newlineReader{conn: strings.NewReader("a.very.long.metric:1|c\n")}
+	_, err = reader.read(buf)
+	assert.ErrorIs(t, err, errFrameTooLarge)
+}
+
+func TestLengthPrefixReader(t *testing.T) {
+	var stream []byte
+	for _, payload := range []string{"a:1|c", "b:2|c\nc:3|c", "d:4|c"} {
+		stream = binary.LittleEndian.AppendUint32(stream, uint32(len(payload)))
+		stream = append(stream, payload...)
+	}
+	// the last packet is incomplete
+	stream = stream[:len(stream)-1]
+
+	reader := &lengthPrefixReader{conn: iotest.OneByteReader(strings.NewReader(string(stream)))}
+	buf := make([]byte, 16)
+	n, err := reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "a:1|c", string(buf[:n]))
+	n, err = reader.read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "b:2|c\nc:3|c", string(buf[:n]))
+	n, err = reader.read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.Zero(t, n)
+
+	reader = &lengthPrefixReader{conn: strings.NewReader(string(binary.LittleEndian.AppendUint32(nil, 17)))}
+	_, err = reader.read(buf)
+	assert.ErrorIs(t, err, errFrameTooLarge)
+}
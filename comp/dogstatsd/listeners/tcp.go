@@ -0,0 +1,341 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpExpvars             = expvar.NewMap("dogstatsd-tcp")
+	tcpPacketReadingErrors = expvar.Int{}
+	tcpConnectionErrors    = expvar.Int{}
+	tcpPackets             = expvar.Int{}
+	tcpBytes               = expvar.Int{}
+)
+
+func init() {
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("ConnectionErrors", &tcpConnectionErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+}
+
+// Framings of the TCP streams
+const (
+	// NewlineFraming separates the messages with newlines
+	NewlineFraming = "newline"
+	// LengthPrefixFraming prefixes each packet with its length as a little-endian uint32,
+	// as done on the `dogstatsd_stream_socket` Unix socket
+	LengthPrefixFraming = "length_prefix"
+)
+
+// TCPListener implements the StatsdListener interface for TCP streams.
+// It accepts connections on a given address, optionally over TLS, and sends
+// back packets ready to be processed. It also receives the Graphite and
+// InfluxDB line protocols, the packets being tagged with their source.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	name                     string
+	source                   packets.SourceType
+	listener                 net.Listener
+	packetOut                chan packets.Packets
+	sharedPacketPoolManager  *packets.PoolManager
+	trafficCapture           replay.Component
+	connTracker              *ConnectionTracker
+	framing                  string
+	packetBufferSize         uint
+	packetBufferFlushTimeout time.Duration
+	telemetryWithListenerID  bool
+	listenWg                 sync.WaitGroup
+	// connWg tracks the goroutines handling the connections
+	connWg sync.WaitGroup
+}
+
+// tcpListenerOptions holds the settings differing between the listeners of TCP streams
+type tcpListenerOptions struct {
+	// name is used as listener ID, in the telemetry and in the logs
+	name      string
+	portKey   string
+	source    packets.SourceType
+	framing   string
+	tlsConfig *tls.Config
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, cfg config.Reader, capture replay.Component) (*TCPListener, error) {
+	framing := cfg.GetString("dogstatsd_tcp_framing")
+	if framing != NewlineFraming && framing != LengthPrefixFraming {
+		return nil, fmt.Errorf("invalid dogstatsd_tcp_framing %q, must be either %q or %q", framing, NewlineFraming, LengthPrefixFraming)
+	}
+
+	var tlsConfig *tls.Config
+	if certFile := cfg.GetString("dogstatsd_tcp_tls.cert_file"); certFile != "" {
+		var err error
+		tlsConfig, err = buildTLSConfig(certFile, cfg.GetString("dogstatsd_tcp_tls.key_file"), cfg.GetString("dogstatsd_tcp_tls.client_ca_file"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newTCPListener(packetOut, sharedPacketPoolManager, cfg, capture, tcpListenerOptions{
+		name:      "tcp",
+		portKey:   "dogstatsd_tcp_port",
+		source:    packets.TCP,
+		framing:   framing,
+		tlsConfig: tlsConfig,
+	})
+}
+
+// NewGraphiteListener returns an idle listener for the Graphite plaintext protocol,
+// whose messages are newline-terminated.
+// The traffic capture doesn't record the Graphite messages.
+func NewGraphiteListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, cfg config.Reader) (*TCPListener, error) {
+	return newTCPListener(packetOut, sharedPacketPoolManager, cfg, nil, tcpListenerOptions{
+		name:    "graphite",
+		portKey: "dogstatsd_graphite_port",
+		source:  packets.Graphite,
+		framing: NewlineFraming,
+	})
+}
+
+// NewInfluxListener returns an idle listener for the InfluxDB line protocol, whose
+// messages are newline-terminated.
+// The traffic capture doesn't record the InfluxDB messages.
+func NewInfluxListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, cfg config.Reader) (*TCPListener, error) {
+	return newTCPListener(packetOut, sharedPacketPoolManager, cfg, nil, tcpListenerOptions{
+		name:    "influx",
+		portKey: "dogstatsd_influx_port",
+		source:  packets.Influx,
+		framing: NewlineFraming,
+	})
+}
+
+func newTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, cfg config.Reader, capture replay.Component, opts tcpListenerOptions) (*TCPListener, error) {
+	port := cfg.GetString(opts.portKey)
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	var url string
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(config.GetBindHostFromConfig(cfg), port)
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if opts.tlsConfig != nil {
+		listener = tls.NewListener(listener, opts.tlsConfig)
+	}
+
+	l := &TCPListener{
+		name:                     opts.name,
+		source:                   opts.source,
+		listener:                 listener,
+		packetOut:                packetOut,
+		sharedPacketPoolManager:  sharedPacketPoolManager,
+		trafficCapture:           capture,
+		connTracker:              NewConnectionTracker(opts.name, 1*time.Second),
+		framing:                  opts.framing,
+		packetBufferSize:         uint(cfg.GetInt("dogstatsd_packet_buffer_size")),
+		packetBufferFlushTimeout: cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout"),
+		telemetryWithListenerID:  cfg.GetBool("dogstatsd_telemetry_enabled_listener_id"),
+	}
+	log.Debugf("dogstatsd-%s: %s successfully initialized (TLS: %t)", l.name, listener.Addr(), opts.tlsConfig != nil)
+	return l, nil
+}
+
+// buildTLSConfig returns the TLS configuration of the listener, the clients must present
+// a certificate signed by the given CA if any.
+func buildTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the TLS certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the TLS client CA: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in the TLS client CA %s", clientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-%s: starting to listen on %s", l.name, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("dogstatsd-%s: error accepting connection: %v", l.name, err)
+			}
+			break
+		}
+		l.connWg.Add(1)
+		go func() {
+			defer l.connWg.Done()
+			l.connTracker.Track(conn)
+			l.handleConnection(conn)
+		}()
+	}
+}
+
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	listenerID := l.name
+	if l.telemetryWithListenerID {
+		listenerID = l.name + "-" + conn.RemoteAddr().String()
+	}
+
+	packetsBuffer := packets.NewBuffer(
+		l.packetBufferSize,
+		l.packetBufferFlushTimeout,
+		l.packetOut,
+		listenerID,
+	)
+	tlmTCPConnections.Inc(listenerID)
+	defer func() {
+		l.connTracker.Close(conn)
+		// the packets read before the connection was closed must not be lost
+		packetsBuffer.Flush()
+		packetsBuffer.Close()
+		tlmTCPConnections.Dec(listenerID)
+		if l.telemetryWithListenerID {
+			l.clearTelemetry(listenerID)
+		}
+	}()
+
+	var reader frameReader
+	if l.framing == LengthPrefixFraming {
+		reader = &lengthPrefixReader{conn: conn}
+	} else {
+		reader = &newlineReader{conn: conn}
+	}
+
+	log.Debugf("dogstatsd-%s: starting to handle %s", l.name, conn.RemoteAddr())
+	t1 := time.Now()
+	for {
+		// retrieve an available packet from the packet pool,
+		// which will be pushed back by the server when processed.
+		packet := l.sharedPacketPoolManager.Get().(*packets.Packet)
+
+		t2 := time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), listenerID, "tcp", l.name)
+
+		n, err := reader.read(packet.Buffer)
+		t1 = time.Now()
+
+		if n > 0 {
+			tcpPackets.Add(1)
+			tcpBytes.Add(int64(n))
+			tlmTCPPackets.Inc(listenerID, "ok")
+			tlmTCPPacketsBytes.Add(float64(n), listenerID)
+
+			packet.Contents = packet.Buffer[:n]
+			packet.Source = l.source
+			packet.ListenerID = listenerID
+
+			if l.trafficCapture != nil && l.trafficCapture.IsOngoing() {
+				capBuff := replay.CapPool.Get().(*replay.CaptureBuffer)
+				capBuff.Pb.Ancillary = nil
+				capBuff.Pb.AncillarySize = int32(0)
+				capBuff.Pb.Pid = 0
+				capBuff.Pid = 0
+				capBuff.Oob = nil
+				capBuff.ContainerID = ""
+				capBuff.Buff = packet
+				capBuff.Pb.Timestamp = time.Now().UnixNano()
+				capBuff.Pb.PayloadSize = int32(n)
+				capBuff.Pb.Payload = packet.Buffer[:n]
+				l.trafficCapture.Enqueue(capBuff)
+			}
+
+			// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
+			packetsBuffer.Append(packet)
+		} else {
+			l.sharedPacketPoolManager.Put(packet)
+		}
+
+		if err != nil {
+			switch {
+			case err == io.EOF, errors.Is(err, net.ErrClosed):
+				log.Debugf("dogstatsd-%s: connection %s closed", l.name, conn.RemoteAddr())
+			case errors.Is(err, errFrameTooLarge):
+				log.Infof("dogstatsd-%s: dropping connection %s: %v", l.name, conn.RemoteAddr(), err)
+				tcpConnectionErrors.Add(1)
+				tlmTCPConnectionErrors.Inc("frame_too_large")
+			default:
+				log.Warnf("dogstatsd-%s: error reading from %s: %v", l.name, conn.RemoteAddr(), err)
+				tcpPacketReadingErrors.Add(1)
+				tcpConnectionErrors.Add(1)
+				tlmTCPPackets.Inc(listenerID, "error")
+				tlmTCPConnectionErrors.Inc("read")
+			}
+			return
+		}
+	}
+}
+
+// Stop closes the TCP listener and its connections, and stops listening once the
+// packets read from the connections are flushed.
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	l.listenWg.Wait()
+	l.connTracker.Stop()
+	l.connWg.Wait()
+}
+
+func (l *TCPListener) clearTelemetry(id string) {
+	// Since the listener id is volatile we need to make sure we clear the telemetry.
+	tlmListener.Delete(id, "tcp", l.name)
+	tlmTCPConnections.Delete(id)
+	tlmTCPPackets.Delete(id, "ok")
+	tlmTCPPackets.Delete(id, "error")
+	tlmTCPPacketsBytes.Delete(id)
+}
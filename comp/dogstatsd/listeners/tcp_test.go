@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, cfg map[string]interface{}) (*TCPListener, chan packets.Packets) {
+	cfg["dogstatsd_tcp_port"] = RandomPortName
+	config := fulfillDepsWithConfig(t, cfg)
+	packetsChannel := make(chan packets.Packets, 10)
+	l, err := NewTCPListener(packetsChannel, newPacketPoolManagerUDP(config), config, nil)
+	require.NoError(t, err)
+	l.Listen()
+	t.Cleanup(l.Stop)
+	return l, packetsChannel
+}
+
+// readContents returns the contents of the packets received until the connection is closed.
+func readContents(t *testing.T, packetsChannel chan packets.Packets, expected int) string {
+	var contents strings.Builder
+	for contents.Len() < expected {
+		select {
+		case pkts := <-packetsChannel:
+			for _, packet := range pkts {
+				assert.Equal(t, packets.TCP, packet.Source)
+				contents.Write(packet.Contents)
+			}
+		case <-time.After(2 * time.Second):
+			assert.FailNow(t, "timeout waiting for packets", "received %q", contents.String())
+		}
+	}
+	return contents.String()
+}
+
+func TestNewTCPListenerInvalidFraming(t *testing.T) {
+	config := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_tcp_port": RandomPortName, "dogstatsd_tcp_framing": "xml"})
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(config), config, nil)
+	assert.Error(t, err)
+}
+
+func TestTCPReceiveNewlineFraming(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	// messages can be split across writes, the last one doesn't need a newline
+	for _, chunk := range []string{"daemon:666|g\ndae", "mon2:1|c\n", "daemon3:2|c"} {
+		_, err = conn.Write([]byte(chunk))
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, conn.Close())
+
+	expected := "daemon:666|g\ndaemon2:1|c\ndaemon3:2|c"
+	assert.Equal(t, expected, readContents(t, packetsChannel, len(expected)))
+}
+
+func TestTCPReceiveLengthPrefixFraming(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_framing": LengthPrefixFraming})
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	for _, payload := range []string{"daemon:666|g", "daemon2:1|c\ndaemon3:2|c"} {
+		header := make([]byte, 4)
+		binary.LittleEndian.PutUint32(header, uint32(len(payload)))
+		_, err = conn.Write(append(header, payload...))
+		require.NoError(t, err)
+	}
+
+	expected := "daemon:666|gdaemon2:1|c\ndaemon3:2|c"
+	assert.Equal(t, expected, readContents(t, packetsChannel, len(expected)))
+}
+
+func TestLineProtocolListeners(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		portKey     string
+		newListener func(chan packets.Packets, *packets.PoolManager, config.Component) (*TCPListener, error)
+		source      packets.SourceType
+		listenerID  string
+		message     string
+	}{
+		{
+			name:    "graphite",
+			portKey: "dogstatsd_graphite_port",
+			newListener: func(packetOut chan packets.Packets, pool *packets.PoolManager, cfg config.Component) (*TCPListener, error) {
+				return NewGraphiteListener(packetOut, pool, cfg)
+			},
+			source:     packets.Graphite,
+			listenerID: "graphite",
+			message:    "servers.web01.cpu.load 1.5 1700000000\n",
+		},
+		{
+			name:    "influx",
+			portKey: "dogstatsd_influx_port",
+			newListener: func(packetOut chan packets.Packets, pool *packets.PoolManager, cfg config.Component) (*TCPListener, error) {
+				return NewInfluxListener(packetOut, pool, cfg)
+			},
+			source:     packets.Influx,
+			listenerID: "influx",
+			message:    "cpu,host=web01 usage_idle=98.5 1700000000000000000\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the framing of the DogStatsD TCP port doesn't apply
+			cfg := fulfillDepsWithConfig(t, map[string]interface{}{tc.portKey: RandomPortName, "dogstatsd_tcp_framing": LengthPrefixFraming})
+			packetsChannel := make(chan packets.Packets, 10)
+			l, err := tc.newListener(packetsChannel, newPacketPoolManagerUDP(cfg), cfg)
+			require.NoError(t, err)
+			l.Listen()
+			t.Cleanup(l.Stop)
+
+			conn, err := net.Dial("tcp", l.LocalAddr())
+			require.NoError(t, err)
+			_, err = conn.Write([]byte(tc.message))
+			require.NoError(t, err)
+			require.NoError(t, conn.Close())
+
+			select {
+			case pkts := <-packetsChannel:
+				require.NotEmpty(t, pkts)
+				assert.Equal(t, tc.source, pkts[0].Source)
+				assert.Equal(t, tc.listenerID, pkts[0].ListenerID)
+				assert.Equal(t, tc.message, string(pkts[0].Contents))
+			case <-time.After(2 * time.Second):
+				assert.FailNow(t, "timeout waiting for packets")
+			}
+		})
+	}
+}
+
+func TestTCPDropsConnectionOnTooLargeMessage(t *testing.T) {
+	l, _ := newTestTCPListener(t, map[string]interface{}{"dogstatsd_buffer_size": 16})
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("a.very.long.metric.name:1|c"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	// the connection is closed by the listener
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+func TestTCPReceiveOverTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls.cert_file": certFile,
+		"dogstatsd_tcp_tls.key_file":  keyFile,
+	})
+
+	// plain TCP connections are rejected
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("plain:1|c\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	tlsConn, err := tls.Dial("tcp", l.LocalAddr(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	_, err = tlsConn.Write([]byte("daemon:666|g\n"))
+	require.NoError(t, err)
+	defer tlsConn.Close()
+
+	assert.Equal(t, "daemon:666|g\n", readContents(t, packetsChannel, len("daemon:666|g\n")))
+}
+
+func TestNewTCPListenerWithInvalidClientCA(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	config := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tcp_port":               RandomPortName,
+		"dogstatsd_tcp_tls.cert_file":      certFile,
+		"dogstatsd_tcp_tls.key_file":       keyFile,
+		"dogstatsd_tcp_tls.client_ca_file": keyFile,
+	})
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(config), config, nil)
+	assert.Error(t, err)
+}
+
+func TestTCPStopFlushesConnections(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{"dogstatsd_packet_buffer_flush_timeout": time.Hour})
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("daemon:666|g\n"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// the packets read from the connections are flushed once the listener is stopped
+	l.Stop()
+	require.Len(t, packetsChannel, 1)
+	assert.Equal(t, "daemon:666|g\n", string((<-packetsChannel)[0].Contents))
+}
+
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dogstatsd"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
@@ -26,6 +26,16 @@ var (
 	tlmUDSConnections = telemetry.NewGauge("dogstatsd", "uds_connections",
 		[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count")
 
+	// TCP
+	tlmTCPPackets = telemetry.NewCounter("dogstatsd", "tcp_packets",
+		[]string{"listener_id", "state"}, "Dogstatsd TCP packets count")
+	tlmTCPPacketsBytes = telemetry.NewCounter("dogstatsd", "tcp_packets_bytes",
+		[]string{"listener_id"}, "Dogstatsd TCP packets bytes")
+	tlmTCPConnections = telemetry.NewGauge("dogstatsd", "tcp_connections",
+		[]string{"listener_id"}, "Dogstatsd TCP connections count")
+	tlmTCPConnectionErrors = telemetry.NewCounter("dogstatsd", "tcp_connection_errors",
+		[]string{"reason"}, "Dogstatsd TCP connections closed because of an error")
+
 	tlmListener            = telemetry.NewHistogramNoOp()
 	defaultListenerBuckets = []float64{300, 500, 1000, 1500, 2000, 2500, 3000, 10000, 20000, 50000}
 )
@@ -6,7 +6,6 @@
 package listeners
 
 import (
-	"encoding/binary"
 	"errors"
 	"expvar"
 	"fmt"
@@ -241,50 +240,34 @@ func (l *UDSListener) handleConnection(conn *net.UnixConn, closeFunc CloseFuncti
 		t2 = time.Now()
 		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), tlmListenerID, l.transport, "uds")
 
-		var expectedPacketLength uint32
-		var maxPacketLength uint32
-		if l.transport == "unix" {
-			// Read the expected packet length (in stream mode)
-			b := []byte{0, 0, 0, 0}
-			_, err = io.ReadFull(conn, b)
-			expectedPacketLength := binary.LittleEndian.Uint32(b)
+		read := func(buf []byte) (int, error) {
+			if oob == nil {
+				m, _, err := conn.ReadFromUnix(buf)
+				return m, err
+			}
+			m, oobm, _, _, err := conn.ReadMsgUnix(buf, oobS[oobn:])
+			oobn += oobm
+			return m, err
+		}
 
+		if l.transport == "unix" {
+			// Read the packet prefixed with its length (in stream mode)
+			n, err = readLengthPrefixedPacket(conn, packet.Buffer, read)
 			switch {
-			case err == io.EOF, errors.Is(err, io.ErrUnexpectedEOF):
+			case err == io.EOF:
 				log.Debugf("dogstatsd-uds: %s connection closed", l.transport)
 				return nil
-			}
-			if expectedPacketLength > uint32(len(packet.Buffer)) {
+			case errors.Is(err, errFrameTooLarge):
 				log.Info("dogstatsd-uds: packet length too large, dropping connection")
 				return nil
 			}
-			maxPacketLength = expectedPacketLength
 		} else {
-			maxPacketLength = uint32(len(packet.Buffer))
-		}
-
-		for err == nil {
-			if oob != nil {
-				n, oobn, _, _, err = conn.ReadMsgUnix(packet.Buffer[n:maxPacketLength], oobS[oobn:])
-			} else {
-				n, _, err = conn.ReadFromUnix(packet.Buffer[n:maxPacketLength])
-			}
+			// If framing is disabled (unixgram, unixpacket), we always read the whole packet
+			n, err = read(packet.Buffer)
 			if n == 0 && oobn == 0 {
 				log.Debugf("dogstatsd-uds: %s connection closed", l.transport)
 				return nil
 			}
-			// If framing is disabled (unixgram, unixpacket), we always will have read the whole packet
-			if expectedPacketLength == 0 {
-				break
-			}
-			// Otherwise see if we need to continue to accumulate bytes or not
-			if uint32(n) == expectedPacketLength {
-				break
-			}
-			if uint32(n) > expectedPacketLength {
-				log.Info("dogstatsd-uds: read length mismatch, dropping connection")
-				return nil
-			}
 		}
 
 		t1 = time.Now()
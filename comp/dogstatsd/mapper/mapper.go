@@ -36,6 +36,7 @@ type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+	tagRules *tagRules
 }
 
 // MetricMapping represent one mapping rule
@@ -47,9 +48,10 @@ type MetricMapping struct {
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
-	matched bool
+	Name     string
+	Tags     []string
+	matched  bool
+	tagRules *tagRules
 }
 
 // NewMetricMapper creates, validates, prepares a new MetricMapper
@@ -67,6 +69,11 @@ func NewMetricMapper(configProfiles []config.MappingProfile, cacheSize int) (*Me
 			Prefix:   configProfile.Prefix,
 			Mappings: make([]*MetricMapping, 0, len(configProfile.Mappings)),
 		}
+		tagRules, err := newTagRules(profile.Name, configProfile.TagRules)
+		if err != nil {
+			return nil, err
+		}
+		profile.tagRules = tagRules
 		for i, currentMapping := range configProfile.Mappings {
 			matchType := currentMapping.MatchType
 			if matchType == "" {
@@ -146,7 +153,13 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 				tags = append(tags, tagKey+":"+tagValue)
 			}
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
+			mapResult := &MapResult{Name: name, matched: true, Tags: tags, tagRules: profile.tagRules}
+			m.cache.add(metricName, mapResult)
+			return mapResult
+		}
+		if profile.tagRules != nil {
+			// the tag rules apply to all the metrics of the profile, even when not renamed
+			mapResult := &MapResult{Name: metricName, matched: true, tagRules: profile.tagRules}
 			m.cache.add(metricName, mapResult)
 			return mapResult
 		}
@@ -156,3 +169,12 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 	}
 	return nil
 }
+
+// RewriteTags applies the tag rules of the profile to the tags of a metric, the tags
+// added by the mapping excepted. The tags are rewritten in place.
+func (r *MapResult) RewriteTags(tags []string) []string {
+	if r.tagRules == nil {
+		return tags
+	}
+	return r.tagRules.apply(tags)
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// internalTagPrefix is the prefix of the tags used by DogStatsD for origin detection,
+// which are never removed by `keep_only` rules, like the `host` tag.
+const internalTagPrefix = "dd.internal."
+
+// tagRules rewrite the tags of the metrics matching a profile
+type tagRules struct {
+	drop     map[string]struct{}
+	keepOnly map[string]struct{}
+	rename   map[string]string
+	rewrite  []*tagRewrite
+}
+
+// tagRewrite rewrites the values of a tag key
+type tagRewrite struct {
+	key     string
+	regex   *regexp.Regexp
+	replace string
+}
+
+// newTagRules validates and prepares the tag rules of a profile, it returns nil when
+// the profile has no tag rules.
+func newTagRules(profileName string, configRules config.TagRules) (*tagRules, error) {
+	if len(configRules.Drop) == 0 && len(configRules.KeepOnly) == 0 && len(configRules.Rename) == 0 && len(configRules.Rewrite) == 0 {
+		return nil, nil
+	}
+	if len(configRules.Drop) > 0 && len(configRules.KeepOnly) > 0 {
+		return nil, fmt.Errorf("profile: %s, tag_rules: `drop` and `keep_only` can't be used together", profileName)
+	}
+
+	rules := &tagRules{rename: make(map[string]string, len(configRules.Rename))}
+	if len(configRules.Drop) > 0 {
+		rules.drop = toSet(configRules.Drop)
+	}
+	if len(configRules.KeepOnly) > 0 {
+		rules.keepOnly = toSet(configRules.KeepOnly)
+	}
+	for key, newKey := range configRules.Rename {
+		if newKey == "" {
+			return nil, fmt.Errorf("profile: %s, tag_rules: the new name of tag key `%s` is empty", profileName, key)
+		}
+		rules.rename[key] = newKey
+	}
+	for i, rewrite := range configRules.Rewrite {
+		if rewrite.Key == "" {
+			return nil, fmt.Errorf("profile: %s, tag_rules: rewrite num %d: key is required", profileName, i)
+		}
+		if rewrite.Match == "" {
+			return nil, fmt.Errorf("profile: %s, tag_rules: rewrite num %d: match is required", profileName, i)
+		}
+		regex, err := regexp.Compile(rewrite.Match)
+		if err != nil {
+			return nil, fmt.Errorf("profile: %s, tag_rules: rewrite num %d: invalid match `%s`: %v", profileName, i, rewrite.Match, err)
+		}
+		rules.rewrite = append(rules.rewrite, &tagRewrite{key: rewrite.Key, regex: regex, replace: rewrite.Replace})
+	}
+	return rules, nil
+}
+
+// apply rewrites the tags in place and returns them. The tags are dropped or kept first,
+// then their values are rewritten and their keys renamed, all rules using the original
+// tag keys. A tag without value is handled as a tag key. The `host` and internal tags are
+// kept by `keep_only` rules.
+func (r *tagRules) apply(tags []string) []string {
+	kept := tags[:0]
+	for _, tag := range tags {
+		key, value, hasValue := strings.Cut(tag, ":")
+		if r.keepOnly != nil {
+			if _, ok := r.keepOnly[key]; !ok && key != "host" && !strings.HasPrefix(key, internalTagPrefix) {
+				continue
+			}
+		} else if _, ok := r.drop[key]; ok {
+			continue
+		}
+
+		newKey, newValue := key, value
+		if hasValue {
+			for _, rewrite := range r.rewrite {
+				if rewrite.key == key {
+					newValue = rewrite.regex.ReplaceAllString(newValue, rewrite.replace)
+				}
+			}
+		}
+		if renamed, ok := r.rename[key]; ok {
+			newKey = renamed
+		}
+
+		if newKey != key || newValue != value {
+			if hasValue {
+				tag = newKey + ":" + newValue
+			} else {
+				tag = newKey
+			}
+		}
+		kept = append(kept, tag)
+	}
+	return kept
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
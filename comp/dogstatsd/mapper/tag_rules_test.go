@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagRules(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		config       string
+		metricName   string
+		tags         []string
+		expectedName string
+		expectedTags []string
+	}{
+		{
+			name: "Drop",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      drop: [request_id, user]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"request_id:1234", "env:prod", "user", "user_name:foo"},
+			expectedName: "test.requests",
+			expectedTags: []string{"env:prod", "user_name:foo"},
+		},
+		{
+			name: "Keep only",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+        tags:
+          job_name: "$1"
+    tag_rules:
+      keep_only: [env]
+`,
+			metricName:   "test.job.my_job",
+			tags:         []string{"request_id:1234", "env:prod", "host:foo", "dd.internal.entity_id:bar"},
+			expectedName: "test.job",
+			expectedTags: []string{"env:prod", "host:foo", "dd.internal.entity_id:bar"},
+		},
+		{
+			name: "Rename and rewrite",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      rename:
+        http.url: url
+        legacy: new
+      rewrite:
+        - key: http.url
+          match: '\?.*$'
+          replace: ''
+        - key: http.url
+          match: '/users/([a-z]+)/[0-9]+'
+          replace: '/users/$1/?'
+`,
+			metricName:   "test.requests",
+			tags:         []string{"http.url:/users/admin/42?debug=1", "legacy", "env:prod"},
+			expectedName: "test.requests",
+			expectedTags: []string{"url:/users/admin/?", "new", "env:prod"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			mapper, err := getMapper(t, scenario.config)
+			require.NoError(t, err)
+
+			mapResult := mapper.Map(scenario.metricName)
+			require.NotNil(t, mapResult)
+			assert.Equal(t, scenario.expectedName, mapResult.Name)
+			assert.Equal(t, scenario.expectedTags, mapResult.RewriteTags(scenario.tags))
+		})
+	}
+}
+
+func TestTagRulesOtherProfiles(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      drop: [request_id]
+  - name: other
+    prefix: 'other.'
+    mappings:
+      - match: "other.*"
+        name: "other"
+`)
+	require.NoError(t, err)
+
+	assert.Nil(t, mapper.Map("foo.requests"))
+	mapResult := mapper.Map("other.requests")
+	require.NotNil(t, mapResult)
+	tags := []string{"request_id:1234"}
+	assert.Equal(t, tags, mapResult.RewriteTags(tags))
+}
+
+func TestTagRulesErrors(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "Drop and keep only",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      drop: [foo]
+      keep_only: [bar]
+`,
+			expectedError: "can't be used together",
+		},
+		{
+			name: "Empty new key",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      rename:
+        foo: ""
+`,
+			expectedError: "the new name of tag key `foo` is empty",
+		},
+		{
+			name: "Missing rewrite key",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      rewrite:
+        - match: 'foo'
+`,
+			expectedError: "key is required",
+		},
+		{
+			name: "Invalid rewrite regex",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      rewrite:
+        - key: foo
+          match: '(foo'
+`,
+			expectedError: "invalid match `(foo`",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := getMapper(t, scenario.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), scenario.expectedError)
+		})
+	}
+}
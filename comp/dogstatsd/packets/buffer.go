@@ -68,6 +68,13 @@ func (pb *Buffer) Append(packet *Packet) {
 	}
 }
 
+// Flush sends the buffered packets to the output channel.
+func (pb *Buffer) Flush() {
+	pb.m.Lock()
+	defer pb.m.Unlock()
+	pb.flush()
+}
+
 func (pb *Buffer) flush() {
 	if len(pb.packets) > 0 {
 		t1 := time.Now()
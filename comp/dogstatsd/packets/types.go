@@ -21,6 +21,12 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener
+	TCP
+	// Graphite plaintext protocol listener
+	Graphite
+	// Influx InfluxDB line protocol listener
+	Influx
 )
 
 // Packet represents a statsd packet ready to process,
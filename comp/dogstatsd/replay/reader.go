@@ -92,12 +92,7 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	// skip header
 	tc.offset = uint32(len(datadogHeader))
 
-	var tsResolution time.Duration
-	if tc.Version < minNanoVersion {
-		tsResolution = time.Second
-	} else {
-		tsResolution = time.Nanosecond
-	}
+	tsResolution := tc.TimestampResolution()
 	tc.Unlock()
 
 	last := int64(0)
@@ -136,6 +131,15 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	}
 }
 
+// TimestampResolution returns the resolution of the timestamps of the packets, which
+// depends on the version of the capture file.
+func (tc *TrafficCaptureReader) TimestampResolution() time.Duration {
+	if tc.Version < minNanoVersion {
+		return time.Second
+	}
+	return time.Nanosecond
+}
+
 // Close cleans up any resources used by the TrafficCaptureReader, should not normally
 // be called directly.
 func (tc *TrafficCaptureReader) Close() error {
@@ -225,11 +229,14 @@ func (tc *TrafficCaptureReader) ReadState() (map[int32]string, map[string]*pb.En
 		return nil, nil, nil
 	}
 
+	if int(sz) > length-4 {
+		return nil, nil, fmt.Errorf("the tagger state size %v exceeds the file size", sz)
+	}
+
 	// pb state
 	pbState := &pb.TaggerState{}
 	err := proto.Unmarshal(tc.Contents[length-int(sz)-4:length-4], pbState)
 	if err != nil {
-		tc.Unlock()
 		return nil, nil, err
 	}
 
@@ -61,6 +61,17 @@ func TestReaderZstdNoMmap(t *testing.T) {
 	readerTest(t, "resources/test/datadog-capture.dog.zstd", false)
 }
 
+func TestReadStateCorrupt(t *testing.T) {
+	for _, contents := range [][]byte{
+		{0xff, 0xff, 0xff, 0xff, 4, 0, 0, 0},
+		{0xff, 0xff, 0xff, 0xff, 16, 0, 0, 0},
+	} {
+		tc := &TrafficCaptureReader{Contents: contents, Version: minStateVersion}
+		_, _, err := tc.ReadState()
+		assert.Error(t, err)
+	}
+}
+
 func TestSeek(t *testing.T) {
 	// well-formed input file
 	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog.zstd", 1, true)
@@ -173,7 +173,7 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 	}
 
 	if conf.metricBlocklist.test(metricName) {
-		return []metrics.MetricSample{}
+		return dest
 	}
 
 	if conf.serverlessMode { // we don't want to set the host while running in serverless mode
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	graphiteTagSeparator      = []byte(";")
+	graphiteTagValueSeparator = []byte("=")
+)
+
+// parseGraphiteMetricSample parses a message of the Graphite plaintext protocol:
+// `<path>[;<tag>=<value>...] <value> [<timestamp>]`. The path is used as metric name,
+// to be mapped by the mapper profiles, and the sample is a gauge.
+// As for the DogStatsD messages, the timestamp is only read when the no-aggregation
+// pipeline is enabled, a negative timestamp meaning that the sample is on time.
+func (p *parser) parseGraphiteMetricSample(message []byte) (dogstatsdMetricSample, error) {
+	fields := bytes.Fields(message)
+	if len(fields) < 2 || len(fields) > 3 {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite message format")
+	}
+
+	path, rawTags, _ := bytes.Cut(fields[0], graphiteTagSeparator)
+	if len(path) == 0 {
+		return dogstatsdMetricSample{}, fmt.Errorf("empty graphite metric path")
+	}
+	tags, err := p.parseGraphiteTags(rawTags)
+	if err != nil {
+		return dogstatsdMetricSample{}, err
+	}
+
+	value, err := parseFloat64(fields[1])
+	if err != nil {
+		return dogstatsdMetricSample{}, fmt.Errorf("could not parse graphite value %q: %v", fields[1], err)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite value %q", fields[1])
+	}
+
+	var timestamp time.Time
+	if len(fields) == 3 {
+		// carbon accepts fractional timestamps
+		ts, err := parseFloat64(fields[2])
+		if err != nil {
+			return dogstatsdMetricSample{}, fmt.Errorf("could not parse graphite timestamp %q: %v", fields[2], err)
+		}
+		if p.readTimestamps && ts > 0 {
+			timestamp = time.Unix(int64(ts), 0)
+		}
+	}
+
+	return dogstatsdMetricSample{
+		name:       p.interner.LoadOrStore(path),
+		value:      value,
+		metricType: gaugeType,
+		sampleRate: 1,
+		tags:       tags,
+		ts:         timestamp,
+	}, nil
+}
+
+// parseGraphiteTags parses the `;`-separated tags of a Graphite path, their `=`
+// separator is replaced by `:` in place.
+func (p *parser) parseGraphiteTags(rawTags []byte) ([]string, error) {
+	if len(rawTags) == 0 {
+		return nil, nil
+	}
+	tags := make([]string, 0, bytes.Count(rawTags, graphiteTagSeparator)+1)
+	for len(rawTags) > 0 {
+		var tag []byte
+		tag, rawTags, _ = bytes.Cut(rawTags, graphiteTagSeparator)
+		sepIndex := bytes.Index(tag, graphiteTagValueSeparator)
+		if sepIndex <= 0 || sepIndex == len(tag)-1 {
+			return nil, fmt.Errorf("invalid graphite tag %q", tag)
+		}
+		tag[sepIndex] = colonSeparator[0]
+		tags = append(tags, p.interner.LoadOrStore(tag))
+	}
+	return tags, nil
+}
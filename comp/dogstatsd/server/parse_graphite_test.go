@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func newTestParser(t *testing.T, overrides map[string]any) *parser {
+	cfg := fxutil.Test[config.Component](t, fx.Options(
+		config.MockModule(),
+		fx.Replace(config.MockParams{Overrides: overrides}),
+	))
+	return newParser(cfg, newFloat64ListPool(), 1)
+}
+
+func TestParseGraphite(t *testing.T) {
+	p := newTestParser(t, map[string]any{"dogstatsd_no_aggregation_pipeline": false})
+
+	sample, err := p.parseGraphiteMetricSample([]byte("servers.web01.cpu.load 1.5 1700000000"))
+	require.NoError(t, err)
+	assert.Equal(t, "servers.web01.cpu.load", sample.name)
+	assert.Equal(t, 1.5, sample.value)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, 1.0, sample.sampleRate)
+	assert.Empty(t, sample.tags)
+	// the timestamps are ignored without the no-aggregation pipeline
+	assert.Zero(t, sample.ts)
+
+	sample, err = p.parseGraphiteMetricSample([]byte("servers.cpu.load;host=web01;dc=paris -2\t1700000000.5"))
+	require.NoError(t, err)
+	assert.Equal(t, "servers.cpu.load", sample.name)
+	assert.Equal(t, -2.0, sample.value)
+	assert.Equal(t, []string{"host:web01", "dc:paris"}, sample.tags)
+
+	// the timestamp is optional
+	sample, err = p.parseGraphiteMetricSample([]byte("servers.cpu.load 3"))
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, sample.value)
+}
+
+func TestParseGraphiteTimestamp(t *testing.T) {
+	p := newTestParser(t, map[string]any{"dogstatsd_no_aggregation_pipeline": true})
+
+	sample, err := p.parseGraphiteMetricSample([]byte("servers.cpu.load 1 1700000000"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0), sample.ts)
+
+	// carbon-c-relay uses -1 for the samples sent without timestamp
+	sample, err = p.parseGraphiteMetricSample([]byte("servers.cpu.load 1 -1"))
+	require.NoError(t, err)
+	assert.Zero(t, sample.ts)
+}
+
+func TestParseGraphiteErrors(t *testing.T) {
+	p := newTestParser(t, map[string]any{})
+
+	for _, message := range []string{
+		"servers.cpu.load",
+		"servers.cpu.load 1 1700000000 extra",
+		"servers.cpu.load abc 1700000000",
+		"servers.cpu.load NaN 1700000000",
+		"servers.cpu.load 1 abc",
+		";host=web01 1 1700000000",
+		"servers.cpu.load;host 1 1700000000",
+		"servers.cpu.load;=web01 1 1700000000",
+		"servers.cpu.load;host= 1 1700000000",
+	} {
+		_, err := p.parseGraphiteMetricSample([]byte(message))
+		assert.Error(t, err, message)
+	}
+}
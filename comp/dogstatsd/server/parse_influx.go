@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseInfluxLine parses a line of the InfluxDB line protocol:
+// `<measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]`.
+// A gauge sample named `<measurement>.<field>` is returned for each numeric or boolean
+// field, the string fields are ignored. The comment lines don't return any sample.
+// As for the DogStatsD messages, the timestamp, in nanoseconds, is only read when the
+// no-aggregation pipeline is enabled.
+func (p *parser) parseInfluxLine(line []byte) ([]dogstatsdMetricSample, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] == '#' {
+		return nil, nil
+	}
+
+	measurement, sep, rest := influxSplit(line, ", ", false)
+	if len(measurement) == 0 {
+		return nil, fmt.Errorf("empty influx measurement")
+	}
+	measurement = influxUnescape(measurement)
+
+	var tags []string
+	for sep == ',' {
+		var rawTag []byte
+		rawTag, sep, rest = influxSplit(rest, ", ", false)
+		key, _, value := influxSplit(rawTag, "=", false)
+		if len(key) == 0 || len(value) == 0 {
+			return nil, fmt.Errorf("invalid influx tag %q", rawTag)
+		}
+		tag := make([]byte, 0, len(rawTag))
+		tag = append(tag, influxUnescape(key)...)
+		tag = append(tag, colonSeparator...)
+		tag = append(tag, influxUnescape(value)...)
+		tags = append(tags, p.interner.LoadOrStore(tag))
+	}
+	if sep != ' ' {
+		return nil, fmt.Errorf("no influx field found")
+	}
+
+	fieldSet, _, rawTimestamp := influxSplit(rest, " ", true)
+	var timestamp time.Time
+	if rawTimestamp = bytes.TrimSpace(rawTimestamp); len(rawTimestamp) > 0 {
+		ts, err := parseInt64(rawTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse influx timestamp %q: %v", rawTimestamp, err)
+		}
+		if p.readTimestamps && ts > 0 {
+			timestamp = time.Unix(0, ts)
+		}
+	}
+
+	var samples []dogstatsdMetricSample
+	var name []byte
+	for len(fieldSet) > 0 {
+		var field []byte
+		field, _, fieldSet = influxSplit(fieldSet, ",", true)
+		key, _, rawValue := influxSplit(field, "=", true)
+		if len(key) == 0 || len(rawValue) == 0 {
+			return nil, fmt.Errorf("invalid influx field %q", field)
+		}
+		value, ok, err := parseInfluxFieldValue(rawValue)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		sampleTags := tags
+		if len(samples) > 0 && len(tags) > 0 {
+			// the tags of the samples are rewritten in place by the mapper and the enrichment
+			sampleTags = append(make([]string, 0, len(tags)), tags...)
+		}
+		name = append(name[:0], measurement...)
+		name = append(name, '.')
+		name = append(name, influxUnescape(key)...)
+		samples = append(samples, dogstatsdMetricSample{
+			name:       p.interner.LoadOrStore(name),
+			value:      value,
+			metricType: gaugeType,
+			sampleRate: 1,
+			tags:       sampleTags,
+			ts:         timestamp,
+		})
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no numeric influx field found")
+	}
+	return samples, nil
+}
+
+// parseInfluxFieldValue parses the value of a field. The booleans are read as 1 and 0,
+// ok is false for the string values.
+func parseInfluxFieldValue(rawValue []byte) (value float64, ok bool, err error) {
+	switch last := rawValue[len(rawValue)-1]; {
+	case rawValue[0] == '"':
+		return 0, false, nil
+	case last == 'i':
+		var v int64
+		v, err = parseInt64(rawValue[:len(rawValue)-1])
+		value = float64(v)
+	case last == 'u':
+		var v uint64
+		v, err = strconv.ParseUint(string(rawValue[:len(rawValue)-1]), 10, 64)
+		value = float64(v)
+	default:
+		switch string(rawValue) {
+		case "t", "T", "true", "True", "TRUE":
+			return 1, true, nil
+		case "f", "F", "false", "False", "FALSE":
+			return 0, true, nil
+		}
+		value, err = parseFloat64(rawValue)
+		if err == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+			err = fmt.Errorf("invalid value")
+		}
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not parse influx field value %q: %v", rawValue, err)
+	}
+	return value, true, nil
+}
+
+// influxSplit returns the data found before the first unescaped separator, the separator
+// and the remainder. The separators found in double-quoted strings are ignored when quoted
+// is true. If no separator is found, the separator is 0 and the remainder is nil.
+func influxSplit(b []byte, separators string, quoted bool) ([]byte, byte, []byte) {
+	inString := false
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '\\':
+			i++
+		case quoted && c == '"':
+			inString = !inString
+		case !inString && strings.IndexByte(separators, c) >= 0:
+			return b[:i], c, b[i+1:]
+		}
+	}
+	return b, 0, nil
+}
+
+// influxUnescape removes the backslashes escaping the commas, spaces, equal signs and
+// backslashes of the measurements and the keys and values of the tags and fields.
+func influxUnescape(b []byte) []byte {
+	if bytes.IndexByte(b, '\\') < 0 {
+		return b
+	}
+	unescaped := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) {
+			switch b[i+1] {
+			case ',', ' ', '=', '\\':
+				i++
+			}
+		}
+		unescaped = append(unescaped, b[i])
+	}
+	return unescaped
+}
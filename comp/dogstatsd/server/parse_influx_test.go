@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInflux(t *testing.T) {
+	p := newTestParser(t, map[string]any{"dogstatsd_no_aggregation_pipeline": false})
+
+	samples, err := p.parseInfluxLine([]byte(`cpu,host=web01,region=eu-west usage_idle=98.5,usage_user=1i,active=true,state="ok",count=3u 1700000000000000000`))
+	require.NoError(t, err)
+	require.Len(t, samples, 4)
+
+	expected := []struct {
+		name  string
+		value float64
+	}{
+		{"cpu.usage_idle", 98.5},
+		{"cpu.usage_user", 1},
+		{"cpu.active", 1},
+		{"cpu.count", 3},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.name, samples[i].name)
+		assert.Equal(t, e.value, samples[i].value)
+		assert.Equal(t, gaugeType, samples[i].metricType)
+		assert.Equal(t, 1.0, samples[i].sampleRate)
+		assert.Equal(t, []string{"host:web01", "region:eu-west"}, samples[i].tags)
+		// the timestamps are ignored without the no-aggregation pipeline
+		assert.Zero(t, samples[i].ts)
+	}
+
+	// the samples don't share their tags, which are rewritten in place
+	samples[0].tags[0] = "host:other"
+	assert.Equal(t, "host:web01", samples[1].tags[0])
+}
+
+func TestParseInfluxEscaping(t *testing.T) {
+	p := newTestParser(t, map[string]any{})
+
+	samples, err := p.parseInfluxLine([]byte(`disk\ io,path=/var\,log,label\=x=a\ b used\ pct=12.5,comment="a, b=c d"`))
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "disk io.used pct", samples[0].name)
+	assert.Equal(t, 12.5, samples[0].value)
+	assert.Equal(t, []string{"path:/var,log", "label=x:a b"}, samples[0].tags)
+}
+
+func TestParseInfluxTimestamp(t *testing.T) {
+	p := newTestParser(t, map[string]any{"dogstatsd_no_aggregation_pipeline": true})
+
+	samples, err := p.parseInfluxLine([]byte("mem used=1 1700000000000000000\r"))
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "mem.used", samples[0].name)
+	assert.Equal(t, time.Unix(1700000000, 0), samples[0].ts)
+	assert.Nil(t, samples[0].tags)
+}
+
+func TestParseInfluxComments(t *testing.T) {
+	p := newTestParser(t, map[string]any{})
+
+	for _, line := range []string{"# a comment", "  "} {
+		samples, err := p.parseInfluxLine([]byte(line))
+		assert.NoError(t, err)
+		assert.Empty(t, samples)
+	}
+}
+
+func TestParseInfluxErrors(t *testing.T) {
+	p := newTestParser(t, map[string]any{})
+
+	for _, line := range []string{
+		"cpu",
+		"cpu,host=web01",
+		",host=web01 value=1",
+		"cpu,host value=1",
+		"cpu,host= value=1",
+		"cpu value",
+		"cpu =1",
+		"cpu value=abc",
+		"cpu value=1x",
+		"cpu value=NaN",
+		"cpu value=1 abc",
+		`cpu state="ok"`,
+	} {
+		_, err := p.parseInfluxLine([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
@@ -373,6 +373,33 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture)
+		if err != nil {
+			s.log.Errorf("Can't init TCP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_graphite_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_graphite_port") > 0 {
+		graphiteListener, err := listeners.NewGraphiteListener(packetsChannel, sharedPacketPoolManager, s.config)
+		if err != nil {
+			s.log.Errorf("Can't init Graphite listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, graphiteListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_influx_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_influx_port") > 0 {
+		influxListener, err := listeners.NewInfluxListener(packetsChannel, sharedPacketPoolManager, s.config)
+		if err != nil {
+			s.log.Errorf("Can't init InfluxDB listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, influxListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture)
@@ -384,7 +411,7 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 	}
 
 	if len(tmpListeners) == 0 {
-		return fmt.Errorf("listening on neither udp nor socket, please check your configuration")
+		return fmt.Errorf("listening on neither udp, tcp nor socket, please check your configuration")
 	}
 
 	s.udsListenerRunning = udsListenerRunning
@@ -618,7 +645,10 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 			if s.Statistics != nil {
 				s.Statistics.StatEvent(1)
 			}
-			messageType := findMessageType(message)
+			messageType := metricSampleType
+			if !isLineProtocol(packet.Source) {
+				messageType = findMessageType(message)
+			}
 
 			switch messageType {
 			case serviceCheckType:
@@ -640,7 +670,7 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 
 				samples = samples[0:0]
 
-				samples, err = s.parseMetricMessage(samples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+				samples, err = s.parseSourceMetricMessage(samples, parser, message, packet)
 				if err != nil {
 					s.errLog("Dogstatsd: error parsing metric message '%q': %s", message, err)
 					continue
@@ -670,6 +700,25 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 	return samples
 }
 
+// isLineProtocol returns true for the listeners only receiving the metrics of a line
+// protocol, without events nor service checks.
+func isLineProtocol(source packets.SourceType) bool {
+	return source == packets.Graphite || source == packets.Influx
+}
+
+// parseSourceMetricMessage parses a metric message with the protocol of the listener
+// the packet was received by.
+func (s *server) parseSourceMetricMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, packet *packets.Packet) ([]metrics.MetricSample, error) {
+	switch packet.Source {
+	case packets.Graphite:
+		return s.parseGraphiteMessage(metricSamples, parser, message, packet.ListenerID)
+	case packets.Influx:
+		return s.parseInfluxMessage(metricSamples, parser, message, packet.ListenerID)
+	default:
+		return s.parseMetricMessage(metricSamples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+	}
+}
+
 // getOriginCounter returns a telemetry counter for processed metrics using the given origin as a tag.
 // They are stored in cache to avoid heap escape.
 // Only `maxOriginCounters` are stored to avoid an infinite expansion.
@@ -728,34 +777,65 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		errorCnt.Inc()
 		return metricSamples, err
 	}
+	return s.mapAndEnrichMetricSample(metricSamples, sample, origin, listenerID, okCnt), nil
+}
+
+// parseGraphiteMessage parses a message received by the Graphite listener.
+func (s *server) parseGraphiteMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, listenerID string) ([]metrics.MetricSample, error) {
+	sample, err := parser.parseGraphiteMetricSample(message)
+	if err != nil {
+		dogstatsdMetricParseErrors.Add(1)
+		tlmProcessedError.Inc()
+		return metricSamples, err
+	}
+	return s.mapAndEnrichMetricSample(metricSamples, sample, "", listenerID, tlmProcessedOk), nil
+}
+
+// parseInfluxMessage parses a line received by the InfluxDB listener.
+func (s *server) parseInfluxMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, listenerID string) ([]metrics.MetricSample, error) {
+	samples, err := parser.parseInfluxLine(message)
+	if err != nil {
+		dogstatsdMetricParseErrors.Add(1)
+		tlmProcessedError.Inc()
+		return metricSamples, err
+	}
+	for _, sample := range samples {
+		metricSamples = s.mapAndEnrichMetricSample(metricSamples, sample, "", listenerID, tlmProcessedOk)
+	}
+	return metricSamples, nil
+}
 
+// mapAndEnrichMetricSample applies the mapper profiles to a parsed sample, and appends
+// the metric samples it holds to metricSamples.
+func (s *server) mapAndEnrichMetricSample(metricSamples []metrics.MetricSample, sample dogstatsdMetricSample, origin string, listenerID string, okCnt telemetry.SimpleCounter) []metrics.MetricSample {
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
 			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
 			sample.name = mapResult.Name
-			sample.tags = append(sample.tags, mapResult.Tags...)
+			sample.tags = append(mapResult.RewriteTags(sample.tags), mapResult.Tags...)
 		}
 	}
 
+	first := len(metricSamples)
 	metricSamples = enrichMetricSample(metricSamples, sample, origin, listenerID, s.enrichConfig)
 
 	if len(sample.values) > 0 {
 		s.sharedFloat64List.put(sample.values)
 	}
 
-	for idx := range metricSamples {
+	for idx := first; idx < len(metricSamples); idx++ {
 		// All metricSamples already share the same Tags slice. We can
 		// extends the first one and reuse it for the rest.
-		if idx == 0 {
+		if idx == first {
 			metricSamples[idx].Tags = append(metricSamples[idx].Tags, s.extraTags...)
 		} else {
-			metricSamples[idx].Tags = metricSamples[0].Tags
+			metricSamples[idx].Tags = metricSamples[first].Tags
 		}
 		dogstatsdMetricPackets.Add(1)
 		okCnt.Inc()
 	}
-	return metricSamples, nil
+	return metricSamples
 }
 
 func (s *server) parseEventMessage(parser *parser, message []byte, origin string) (*event.Event, error) {
@@ -746,6 +746,36 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Tag rules",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        tags:
+          job_name: "$1"
+    tag_rules:
+      drop: [request_id]
+      rename:
+        http.path: path
+      rewrite:
+        - key: http.path
+          match: '/[0-9]+'
+          replace: '/?'
+`,
+			packets: []string{
+				"test.job.duration.my_job_name:666|g|#request_id:1234,http.path:/users/42",
+				"test.job.not_mapped:666|g|#request_id:1234,some:tag",
+			},
+			expectedSamples: []MetricSample{
+				{Name: "test.job.duration", Tags: []string{"path:/users/?", "job_name:my_job_name"}, Mtype: metrics.GaugeType, Value: 666.0},
+				{Name: "test.job.not_mapped", Tags: []string{"some:tag"}, Mtype: metrics.GaugeType, Value: 666.0},
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
@@ -803,6 +833,60 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestLineProtocolMapping(t *testing.T) {
+	deps := fulfillDepsWithConfigYaml(t, `
+dogstatsd_port: 0
+dogstatsd_graphite_port: __random__
+dogstatsd_influx_port: __random__
+dogstatsd_no_aggregation_pipeline: false
+dogstatsd_tags: ["env:prod"]
+dogstatsd_mapper_profiles:
+  - name: servers
+    prefix: 'servers.'
+    mappings:
+      - match: "servers.*.cpu.*"
+        name: "servers.cpu.$2"
+        tags:
+          server: "$1"
+  - name: cpu
+    prefix: 'cpu.'
+    mappings:
+      - match: "cpu.usage_*"
+        name: "cpu.usage"
+        tags:
+          mode: "$1"
+`)
+	s := deps.Server.(*server)
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+	parser := newParser(deps.Config, newFloat64ListPool(), 1)
+
+	samples, err := s.parseGraphiteMessage(nil, parser, []byte("servers.web01.cpu.load;dc=paris 1.5 1700000000"), "graphite")
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "servers.cpu.load", samples[0].Name)
+	assert.Equal(t, 1.5, samples[0].Value)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, "graphite", samples[0].ListenerID)
+	assert.ElementsMatch(t, []string{"dc:paris", "server:web01", "env:prod"}, samples[0].Tags)
+
+	samples, err = s.parseInfluxMessage(nil, parser, []byte("cpu,host=web01 usage_idle=98.5,usage_user=1.5 1700000000000000000"), "influx")
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, "cpu.usage", samples[0].Name)
+	assert.Equal(t, 98.5, samples[0].Value)
+	assert.ElementsMatch(t, []string{"mode:idle", "env:prod"}, samples[0].Tags)
+	assert.Equal(t, "web01", samples[0].Host)
+	assert.Equal(t, "cpu.usage", samples[1].Name)
+	assert.Equal(t, 1.5, samples[1].Value)
+	assert.ElementsMatch(t, []string{"mode:user", "env:prod"}, samples[1].Tags)
+
+	_, err = s.parseInfluxMessage(nil, parser, []byte("cpu usage_idle=abc"), "influx")
+	assert.Error(t, err)
+}
+
 func TestNewServerExtraTags(t *testing.T) {
 	cfg := make(map[string]interface{})
 
@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+)
+
+// payloadTypeEndpoints lists the endpoints of each payload type that can be
+// given a budget or a high priority in `forwarder_budgets`. The v1 intake
+// endpoint is mostly used for events.
+var payloadTypeEndpoints = map[string][]transaction.Endpoint{
+	"series":       {endpoints.V1SeriesEndpoint, endpoints.SeriesEndpoint},
+	"sketches":     {endpoints.V1SketchSeriesEndpoint, endpoints.SketchSeriesEndpoint},
+	"check_runs":   {endpoints.V1CheckRunsEndpoint, endpoints.ServiceChecksEndpoint},
+	"events":       {endpoints.V1IntakeEndpoint, endpoints.EventsEndpoint},
+	"metadata":     {endpoints.V1MetadataEndpoint, endpoints.HostMetadataEndpoint},
+	"process":      {endpoints.ProcessesEndpoint, endpoints.ProcessDiscoveryEndpoint, endpoints.ProcessLifecycleEndpoint, endpoints.RtProcessesEndpoint, endpoints.ContainerEndpoint, endpoints.RtContainerEndpoint, endpoints.ConnectionsEndpoint},
+	"orchestrator": {endpoints.OrchestratorEndpoint, endpoints.OrchestratorManifestEndpoint},
+}
+
+// tokenBucket allows a rate of tokens per second, with bursts of up to one
+// second worth of tokens.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		tokens: rate,
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// delay returns how long to wait until n tokens can be taken, 0 if they can be
+// taken now. A request larger than the burst is allowed once the bucket is full,
+// the bucket then going into debt so that the rate is respected on average. The
+// debt only delays the transactions of the same payload type, which wait in their
+// own queue of throttledTransactions.
+func (b *tokenBucket) delay(n float64) time.Duration {
+	if n > b.rate {
+		n = b.rate
+	}
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// payloadBudget limits the bandwidth and the number of requests per second of a
+// payload type. A zero limit means unlimited.
+type payloadBudget struct {
+	payloadType  string
+	highPriority bool
+
+	m        sync.Mutex
+	bytes    *tokenBucket
+	requests *tokenBucket
+}
+
+// reserve consumes the budget and returns 0 if a transaction of the given size
+// can be sent now. Otherwise it returns how long to wait before trying again.
+func (b *payloadBudget) reserve(size int, now time.Time) time.Duration {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	var delay time.Duration
+	if b.bytes != nil {
+		b.bytes.refill(now)
+		delay = b.bytes.delay(float64(size))
+	}
+	if b.requests != nil {
+		b.requests.refill(now)
+		if d := b.requests.delay(1); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if b.bytes != nil {
+		b.bytes.tokens -= float64(size)
+		tlmBudgetAvailableTokens.Set(b.bytes.tokens, b.payloadType, "bytes")
+	}
+	if b.requests != nil {
+		b.requests.tokens--
+		tlmBudgetAvailableTokens.Set(b.requests.tokens, b.payloadType, "requests")
+	}
+	return 0
+}
+
+// payloadBudgets holds the budgets configured in `forwarder_budgets`, shared by
+// all the domains of a forwarder since they usually share the same link.
+type payloadBudgets struct {
+	byEndpoint map[string]*payloadBudget
+}
+
+// newPayloadBudgets returns the configured budgets, or nil when no budget is
+// configured.
+func newPayloadBudgets(config config.Component) *payloadBudgets {
+	now := time.Now()
+	byEndpoint := map[string]*payloadBudget{}
+	for payloadType, typeEndpoints := range payloadTypeEndpoints {
+		prefix := "forwarder_budgets." + payloadType + "."
+		budget := &payloadBudget{payloadType: payloadType}
+
+		if bytesPerSecond := config.GetFloat64(prefix + "bytes_per_second"); bytesPerSecond > 0 {
+			budget.bytes = newTokenBucket(bytesPerSecond, now)
+		}
+		if requestsPerSecond := config.GetFloat64(prefix + "requests_per_second"); requestsPerSecond > 0 {
+			budget.requests = newTokenBucket(requestsPerSecond, now)
+		}
+
+		budget.highPriority = config.GetBool(prefix + "high_priority")
+
+		if budget.bytes == nil && budget.requests == nil && !budget.highPriority {
+			continue
+		}
+		for _, endpoint := range typeEndpoints {
+			byEndpoint[endpoint.Name] = budget
+		}
+	}
+
+	if len(byEndpoint) == 0 {
+		return nil
+	}
+	return &payloadBudgets{byEndpoint: byEndpoint}
+}
+
+// budget returns the budget of the transaction, nil if its payload type has no budget.
+func (b *payloadBudgets) budget(t transaction.Transaction) *payloadBudget {
+	if b == nil {
+		return nil
+	}
+	return b.byEndpoint[t.GetEndpointName()]
+}
+
+// priority returns the priority of the transactions of the endpoint: high if
+// its payload type is configured as high priority, defaultPriority otherwise.
+func (b *payloadBudgets) priority(endpoint transaction.Endpoint, defaultPriority transaction.Priority) transaction.Priority {
+	if b == nil {
+		return defaultPriority
+	}
+
+	if budget, ok := b.byEndpoint[endpoint.Name]; ok && budget.highPriority {
+		return transaction.TransactionPriorityHigh
+	}
+	return defaultPriority
+}
+
+// throttledTransactions holds the transactions of a domain delayed by their budget
+// until it allows them, in a queue per payload type. The throttled transactions
+// don't hold the workers, which keep sending the transactions of the other payload
+// types in the meantime, and they keep their order within their payload type.
+type throttledTransactions struct {
+	budgets *payloadBudgets
+	// send sends the transactions allowed by their budget back to the workers
+	send func(transaction.Transaction)
+
+	m      sync.Mutex
+	queues map[*payloadBudget][]transaction.Transaction
+	timers map[*payloadBudget]*time.Timer
+	// admitted holds the transactions sent back to the workers, whose budget is
+	// already consumed
+	admitted map[transaction.Transaction]struct{}
+	stopped  bool
+}
+
+// newThrottledTransactions returns the queues of throttled transactions of a
+// domain, or nil when no budget is configured.
+func newThrottledTransactions(budgets *payloadBudgets, send func(transaction.Transaction)) *throttledTransactions {
+	if budgets == nil {
+		return nil
+	}
+	return &throttledTransactions{
+		budgets:  budgets,
+		send:     send,
+		queues:   make(map[*payloadBudget][]transaction.Transaction),
+		timers:   make(map[*payloadBudget]*time.Timer),
+		admitted: make(map[transaction.Transaction]struct{}),
+	}
+}
+
+// admit consumes the budget of the transaction and returns true if it can be sent
+// now. Otherwise the transaction is queued until its budget allows it, and sent
+// back to the workers then. The transactions without a budget are always admitted.
+func (q *throttledTransactions) admit(t transaction.Transaction) bool {
+	if q == nil {
+		return true
+	}
+	budget := q.budgets.budget(t)
+	if budget == nil {
+		return true
+	}
+
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if _, ok := q.admitted[t]; ok {
+		delete(q.admitted, t)
+		return true
+	}
+	if q.stopped {
+		return true
+	}
+	// the transactions already waiting go first
+	if len(q.queues[budget]) == 0 {
+		delay := budget.reserve(t.GetPayloadSize(), time.Now())
+		if delay == 0 {
+			return true
+		}
+		q.schedule(budget, delay)
+	}
+	q.queues[budget] = append(q.queues[budget], t)
+	transactionsThrottled.Add(1)
+	tlmTxThrottled.Inc(budget.payloadType, t.GetEndpointName())
+	return false
+}
+
+// schedule releases the queue of the budget after the delay. The lock must be held
+// by the caller.
+func (q *throttledTransactions) schedule(budget *payloadBudget, delay time.Duration) {
+	q.timers[budget] = time.AfterFunc(delay, func() { q.release(budget) })
+}
+
+// release sends the queued transactions allowed by the budget back to the workers.
+func (q *throttledTransactions) release(budget *payloadBudget) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	delete(q.timers, budget)
+	if q.stopped {
+		return
+	}
+	queue := q.queues[budget]
+	for len(queue) > 0 {
+		t := queue[0]
+		// the budget is shared by the domains, it may be used by another one in the meantime
+		if delay := budget.reserve(t.GetPayloadSize(), time.Now()); delay > 0 {
+			q.schedule(budget, delay)
+			break
+		}
+		queue[0] = nil
+		queue = queue[1:]
+		q.admitted[t] = struct{}{}
+		// send doesn't block
+		q.send(t)
+	}
+	if len(queue) == 0 {
+		delete(q.queues, budget)
+	} else {
+		q.queues[budget] = queue
+	}
+}
+
+// stop stops the timers and returns the transactions still waiting for their budget.
+func (q *throttledTransactions) stop() []transaction.Transaction {
+	if q == nil {
+		return nil
+	}
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.stopped = true
+	for _, timer := range q.timers {
+		timer.Stop()
+	}
+	var pending []transaction.Transaction
+	for _, queue := range q.queues {
+		pending = append(pending, queue...)
+	}
+	q.queues = nil
+	q.timers = nil
+	q.admitted = nil
+	return pending
+}
@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package defaultforwarder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/core/log/logimpl"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestPayloadBudgetBytes(t *testing.T) {
+	now := time.Now()
+	b := &payloadBudget{payloadType: "series", bytes: newTokenBucket(100, now)}
+
+	assert.Zero(t, b.reserve(60, now))
+	assert.Zero(t, b.reserve(40, now))
+	assert.Equal(t, 10*time.Millisecond, b.reserve(1, now))
+
+	// refilled at 100 bytes per second
+	assert.Zero(t, b.reserve(50, now.Add(500*time.Millisecond)))
+	assert.Equal(t, 10*time.Millisecond, b.reserve(1, now.Add(500*time.Millisecond)))
+
+	// the bucket never holds more than one second worth of tokens
+	now = now.Add(time.Hour)
+	assert.Zero(t, b.reserve(100, now))
+	assert.NotZero(t, b.reserve(1, now))
+}
+
+func TestPayloadBudgetLargePayload(t *testing.T) {
+	now := time.Now()
+	b := &payloadBudget{payloadType: "process", bytes: newTokenBucket(100, now)}
+
+	// payloads larger than the burst go through once the bucket is full...
+	assert.Zero(t, b.reserve(300, now))
+	// ...and the bucket has to pay the debt back before anything else is sent
+	assert.Equal(t, 10*time.Millisecond, b.reserve(1, now.Add(2*time.Second)))
+	assert.Zero(t, b.reserve(1, now.Add(2*time.Second+10*time.Millisecond)))
+	// waiting for a payload larger than the burst means waiting for a full bucket
+	assert.Equal(t, time.Second, b.reserve(300, now.Add(2*time.Second+10*time.Millisecond)))
+}
+
+func TestPayloadBudgetRequests(t *testing.T) {
+	now := time.Now()
+	b := &payloadBudget{payloadType: "events", requests: newTokenBucket(2, now), bytes: newTokenBucket(1000, now)}
+
+	assert.Zero(t, b.reserve(10, now))
+	assert.Zero(t, b.reserve(10, now))
+	assert.Equal(t, 500*time.Millisecond, b.reserve(10, now))
+	// a delayed transaction doesn't consume the bytes budget
+	assert.InDelta(t, 980, b.bytes.tokens, 0.01)
+
+	assert.Zero(t, b.reserve(10, now.Add(500*time.Millisecond)))
+}
+
+func TestNewPayloadBudgets(t *testing.T) {
+	mockConfig := pkgconfig.Mock(t)
+	assert.Nil(t, newPayloadBudgets(mockConfig))
+
+	mockConfig.SetWithoutSource("forwarder_budgets.process.bytes_per_second", 1024)
+	mockConfig.SetWithoutSource("forwarder_budgets.series.high_priority", true)
+	budgets := newPayloadBudgets(mockConfig)
+	require.NotNil(t, budgets)
+
+	for _, endpoint := range payloadTypeEndpoints["process"] {
+		require.Contains(t, budgets.byEndpoint, endpoint.Name)
+		assert.Equal(t, "process", budgets.byEndpoint[endpoint.Name].payloadType)
+		assert.NotNil(t, budgets.byEndpoint[endpoint.Name].bytes)
+		assert.Nil(t, budgets.byEndpoint[endpoint.Name].requests)
+	}
+	assert.NotContains(t, budgets.byEndpoint, endpoints.SketchSeriesEndpoint.Name)
+
+	assert.Equal(t, transaction.TransactionPriorityHigh, budgets.priority(endpoints.SeriesEndpoint, transaction.TransactionPriorityNormal))
+	assert.Equal(t, transaction.TransactionPriorityNormal, budgets.priority(endpoints.ProcessesEndpoint, transaction.TransactionPriorityNormal))
+	assert.Equal(t, transaction.TransactionPriorityHigh, budgets.priority(endpoints.ProcessesEndpoint, transaction.TransactionPriorityHigh))
+}
+
+func TestCreateHTTPTransactionsWithHighPriorityPayloadType(t *testing.T) {
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_budgets.sketches.high_priority", true)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(monoKeysDomains)))
+
+	p1 := []byte("A payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1})
+
+	transactions := forwarder.createHTTPTransactions(endpoints.SketchSeriesEndpoint, payloads, nil)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, transaction.TransactionPriorityHigh, transactions[0].Priority)
+
+	transactions = forwarder.createHTTPTransactions(endpoints.SeriesEndpoint, payloads, nil)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, transaction.TransactionPriorityNormal, transactions[0].Priority)
+}
+
+func newBudgetTransaction(domain string, endpoint transaction.Endpoint) *transaction.HTTPTransaction {
+	tr := transaction.NewHTTPTransaction()
+	tr.Domain = domain
+	tr.Endpoint = endpoint
+	tr.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte("payload"))
+	tr.Headers = http.Header{}
+	return tr
+}
+
+func TestThrottledTransactions(t *testing.T) {
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_budgets.process.requests_per_second", 20)
+	budgets := newPayloadBudgets(mockConfig)
+	require.NotNil(t, budgets)
+	// use the whole budget, the next request is allowed in 50ms
+	budgets.byEndpoint[endpoints.ProcessesEndpoint.Name].requests.tokens = 0
+
+	sent := make(chan transaction.Transaction, 2)
+	q := newThrottledTransactions(budgets, func(t transaction.Transaction) { sent <- t })
+	throttled := transactionsThrottled.Value()
+
+	// the transactions without a budget are never delayed
+	assert.True(t, q.admit(budgetTransaction(endpoints.SeriesEndpoint)))
+
+	first, second := budgetTransaction(endpoints.ProcessesEndpoint), budgetTransaction(endpoints.ProcessesEndpoint)
+	assert.False(t, q.admit(first))
+	assert.False(t, q.admit(second))
+	assert.Equal(t, throttled+2, transactionsThrottled.Value())
+
+	// the throttled transactions are sent back in order once the budget allows them
+	for _, expected := range []transaction.Transaction{first, second} {
+		select {
+		case tr := <-sent:
+			assert.Equal(t, expected, tr)
+			// their budget is already consumed
+			assert.True(t, q.admit(tr))
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "the throttled transaction wasn't sent back")
+		}
+	}
+	assert.Equal(t, throttled+2, transactionsThrottled.Value())
+	assert.Empty(t, q.stop())
+}
+
+func budgetTransaction(endpoint transaction.Endpoint) *transaction.HTTPTransaction {
+	return newBudgetTransaction("http://localhost:1", endpoint)
+}
+
+func TestThrottledTransactionDoesNotDelayOtherPayloadTypes(t *testing.T) {
+	received := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_budgets.orchestrator.requests_per_second", 0.01)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	retryQueue := retry.NewTransactionRetryQueue(
+		transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true},
+		nil,
+		1024,
+		0,
+		retry.NewTransactionRetryQueueTelemetry("domain"),
+		retry.NewPointCountTelemetryMock())
+	forwarder := newDomainForwarder(mockConfig, log, "test", retryQueue, 1, 0, transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}, retry.NewPointCountTelemetry("domain"))
+	forwarder.budgets = newPayloadBudgets(mockConfig)
+	require.NotNil(t, forwarder.budgets)
+	// use the whole budget, the next orchestrator request is allowed in 100 seconds
+	forwarder.budgets.byEndpoint[endpoints.OrchestratorEndpoint.Name].requests.tokens = 0
+	require.NoError(t, forwarder.Start())
+
+	// the only worker of the domain isn't held by the throttled transaction
+	orchestrator := newBudgetTransaction(ts.URL, endpoints.OrchestratorEndpoint)
+	forwarder.sendHTTPTransactions(orchestrator)
+	series := newBudgetTransaction(ts.URL, endpoints.SeriesEndpoint)
+	sent := make(chan struct{})
+	series.CompletionHandler = func(_ *transaction.HTTPTransaction, _ int, _ []byte, _ error) { close(sent) }
+	forwarder.sendHTTPTransactions(series)
+	select {
+	case <-sent:
+		assert.Equal(t, endpoints.SeriesEndpoint.Route, <-received)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the series transaction wasn't sent")
+	}
+
+	// the throttled transaction isn't retried, and is kept in the retry queue on stop
+	requireLenForwarderRetryQueue(t, forwarder, 0)
+	forwarder.Stop(false)
+	assert.Empty(t, received)
+	trs, err := forwarder.retryQueue.ExtractTransactions()
+	require.NoError(t, err)
+	assert.Equal(t, []transaction.Transaction{orchestrator}, trs)
+}
@@ -202,6 +202,8 @@ type DefaultForwarder struct {
 	domainForwarders map[string]*domainForwarder
 	domainResolvers  map[string]resolver.DomainResolver
 	healthChecker    *forwarderHealth
+	failover         *failoverGroup
+	budgets          *payloadBudgets
 	internalState    *atomic.Uint32
 	m                sync.Mutex // To control Start/Stop races
 
@@ -230,6 +232,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 			disableAPIKeyChecking: options.DisableAPIKeyChecking,
 			validationInterval:    options.APIKeyValidationInterval,
 		},
+		budgets:           newPayloadBudgets(config),
 		completionHandler: options.CompletionHandler,
 		agentName:         agentName,
 	}
@@ -306,6 +309,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				options.ConnectionResetInterval,
 				domainForwarderSort,
 				pointCountTelemetry)
+			fwd.budgets = f.budgets
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
@@ -314,6 +318,10 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 		}
 	}
 
+	if config.GetBool("forwarder_failover.enabled") {
+		f.setupFailover()
+	}
+
 	timeInterval := config.GetInt("forwarder_retry_queue_capacity_time_interval_sec")
 	if f.agentName != "" {
 		f.queueDurationCapacity = retry.NewQueueDurationCapacity(
@@ -334,6 +342,72 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	return f
 }
 
+// setupFailover creates the failover group from the domains listed in
+// `forwarder_failover.domains`, ordered by priority. The domains must be
+// configured endpoints with API keys.
+func (f *DefaultForwarder) setupFailover() {
+	var domains []string
+	apiKeys := map[string]string{}
+	for _, configuredDomain := range f.config.GetStringSlice("forwarder_failover.domains") {
+		domain, _ := utils.AddAgentVersionToDomain(configuredDomain, "app")
+		dr, ok := f.domainResolvers[domain]
+		if !ok {
+			f.log.Errorf("Failover domain '%s' is not a configured endpoint with API keys, ignoring it", configuredDomain)
+			continue
+		}
+		if _, ok := apiKeys[domain]; ok {
+			continue
+		}
+		domains = append(domains, domain)
+		apiKeys[domain] = dr.GetAPIKeys()[0]
+	}
+
+	if len(domains) < 2 {
+		f.log.Errorf("Forwarder failover requires at least two configured domains in forwarder_failover.domains, found %d: failover is disabled", len(domains))
+		return
+	}
+
+	f.failover = newFailoverGroup(f.config, f.log, domains, apiKeys)
+	f.failover.handOver = f.handOverTransactions
+	for _, domain := range domains {
+		f.domainForwarders[domain].failover = f.failover.reporter(domain)
+	}
+	f.log.Infof("Forwarder failover enabled for domains: %s", strings.Join(domains, ", "))
+}
+
+// handOverTransactions sends the transactions retried by a domain of the failover
+// group which is no longer active to the active domain, once for each of its API keys.
+// The transactions of a domain are created for each of its API keys, only those of
+// the API key used to probe the domain are handed over so that each payload is sent
+// once to each API key of the active domain.
+func (f *DefaultForwarder) handOverTransactions(from string, transactions []transaction.Transaction) {
+	active := f.failover.activeDomain()
+	dr, ok := f.domainResolvers[active]
+	if !ok || active == from {
+		return
+	}
+
+	var handedOver []*transaction.HTTPTransaction
+	for _, t := range transactions {
+		httpTransaction, ok := t.(*transaction.HTTPTransaction)
+		if !ok || httpTransaction.Headers.Get(apiHTTPHeaderKey) != f.failover.apiKeys[from] {
+			continue
+		}
+		for _, apiKey := range dr.GetAPIKeys() {
+			moved := *httpTransaction
+			moved.Domain, _ = dr.Resolve(httpTransaction.Endpoint)
+			moved.Headers = httpTransaction.Headers.Clone()
+			moved.Headers.Set(apiHTTPHeaderKey, apiKey)
+			handedOver = append(handedOver, &moved)
+		}
+	}
+
+	f.log.Infof("Handing over %d transactions of '%s' to '%s'", len(handedOver), from, active)
+	if err := f.sendHTTPTransactions(handedOver); err != nil {
+		f.log.Errorf("Could not hand over the transactions of '%s' to '%s': %v", from, active, err)
+	}
+}
+
 func getAgentName(options *Options) string {
 	if HasFeature(options.EnabledFeatures, CoreFeatures) {
 		return "core"
@@ -368,6 +442,9 @@ func (f *DefaultForwarder) Start() error {
 		len(endpointLogs), f.NumberOfWorkers, strings.Join(endpointLogs, " ; "))
 
 	f.healthChecker.Start()
+	if f.failover != nil {
+		f.failover.start()
+	}
 	f.internalState.Store(Started)
 	return nil
 }
@@ -416,6 +493,9 @@ func (f *DefaultForwarder) Stop() {
 	}
 
 	f.healthChecker.Stop()
+	if f.failover != nil {
+		f.failover.stopProbing()
+	}
 
 	f.healthChecker = nil
 	f.domainForwarders = map[string]*domainForwarder{}
@@ -437,9 +517,14 @@ func (f *DefaultForwarder) createHTTPTransactions(endpoint transaction.Endpoint,
 func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.Endpoint, payloads transaction.BytesPayloads, extra http.Header, priority transaction.Priority, storableOnDisk bool) []*transaction.HTTPTransaction {
 	transactions := make([]*transaction.HTTPTransaction, 0, len(payloads)*len(f.domainForwarders))
 	allowArbitraryTags := f.config.GetBool("allow_arbitrary_tags")
+	priority = f.budgets.priority(endpoint, priority)
 
 	for _, payload := range payloads {
 		for domain, dr := range f.domainResolvers {
+			// Only the active domain of the failover group receives new transactions
+			if f.failover != nil && !f.failover.isActive(domain) {
+				continue
+			}
 			for _, apiKey := range dr.GetAPIKeys() {
 				t := transaction.NewHTTPTransaction()
 				t.Domain, _ = dr.Resolve(endpoint)
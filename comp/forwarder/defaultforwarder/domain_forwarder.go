@@ -44,6 +44,9 @@ type domainForwarder struct {
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
 	pointCountTelemetry       *retry.PointCountTelemetry
+	failover                  *failoverReporter // nil when the domain isn't part of a failover group
+	budgets                   *payloadBudgets   // nil when no budget is configured
+	throttled                 *throttledTransactions
 }
 
 func newDomainForwarder(
@@ -90,6 +93,15 @@ func (f *domainForwarder) retryTransactions(_ time.Time) {
 		f.log.Errorf("Error when getting transactions from the retry queue: %v", err)
 	}
 
+	// the domain failed over, its transactions are retried by the active domain
+	if !f.failover.isActive() {
+		f.failover.handOver(transactions)
+		transactionCount := f.retryQueue.GetTransactionCount()
+		transactionsRetryQueueSize.Set(int64(transactionCount))
+		tlmTxRetryQueueSize.Set(float64(transactionCount), f.domain)
+		return
+	}
+
 	f.transactionPrioritySorter.Sort(transactions)
 
 	for _, t := range transactions {
@@ -191,6 +203,7 @@ func (f *domainForwarder) init() {
 	f.stopRetry = make(chan bool)
 	f.stopConnectionReset = make(chan bool)
 	f.workers = []*Worker{}
+	f.throttled = newThrottledTransactions(f.budgets, f.sendHTTPTransactions)
 }
 
 // Start starts a domainForwarder.
@@ -208,6 +221,8 @@ func (f *domainForwarder) Start() error {
 
 	for i := 0; i < f.numberOfWorkers; i++ {
 		w := NewWorker(f.config, f.log, f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList, f.pointCountTelemetry)
+		w.failover = f.failover
+		w.throttled = f.throttled
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
@@ -235,10 +250,15 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 		f.stopConnectionReset <- true
 	}
 	f.stopRetry <- true
+	// the transactions waiting for their budget are kept in the retry queue
+	throttled := f.throttled.stop()
 	for _, w := range f.workers {
 		w.Stop(purgeHighPrio)
 	}
 	f.workers = []*Worker{}
+	for _, t := range throttled {
+		f.addToTransactionRetryQueue(t)
+	}
 	close(f.highPrio)
 	close(f.lowPrio)
 	close(f.requeuedTransaction)
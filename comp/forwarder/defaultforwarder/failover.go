@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	defaultFailoverErrorBudget   = 10
+	defaultFailoverProbeInterval = 30 * time.Second
+)
+
+// failoverGroup routes the traffic of an ordered list of domains to a single
+// one of them: the first domain receives the transactions until it exceeds its
+// error budget, at which point the traffic moves to the next domain. Domains
+// with a higher priority than the active one are probed on a regular basis and
+// the traffic fails back to them as soon as they are reachable again.
+//
+// The transactions waiting in the retry queue of a domain that is no longer
+// active are handed over to the active domain rather than kept buffered until
+// the domain recovers.
+//
+// Domains that are not part of the group are not affected and keep receiving
+// every transaction.
+type failoverGroup struct {
+	log           log.Component
+	domains       []string
+	apiKeys       map[string]string
+	errorBudget   int
+	probeInterval time.Duration
+	client        *http.Client
+	// handOver sends the transactions of an inactive domain to the active one
+	handOver func(from string, transactions []transaction.Transaction)
+
+	m                 sync.RWMutex
+	active            int
+	consecutiveErrors int
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newFailoverGroup returns a failoverGroup for the given domains, ordered by
+// priority. apiKeys contains the API key used to probe each domain.
+func newFailoverGroup(config config.Component, log log.Component, domains []string, apiKeys map[string]string) *failoverGroup {
+	errorBudget := config.GetInt("forwarder_failover.error_budget")
+	if errorBudget <= 0 {
+		log.Warnf("Configured forwarder_failover.error_budget (%v) is not positive; %v will be used", errorBudget, defaultFailoverErrorBudget)
+		errorBudget = defaultFailoverErrorBudget
+	}
+
+	probeInterval := config.GetDuration("forwarder_failover.probe_interval") * time.Second
+	if probeInterval <= 0 {
+		log.Warnf("Configured forwarder_failover.probe_interval (%v) is not positive; %v will be used", probeInterval, defaultFailoverProbeInterval)
+		probeInterval = defaultFailoverProbeInterval
+	}
+
+	g := &failoverGroup{
+		log:           log,
+		domains:       domains,
+		apiKeys:       apiKeys,
+		errorBudget:   errorBudget,
+		probeInterval: probeInterval,
+		client: &http.Client{
+			Transport: httputils.CreateHTTPTransport(config),
+			Timeout:   validateAPIKeyTimeout,
+		},
+	}
+	setFailoverStatus(g.domains, g.domains[0])
+	return g
+}
+
+func (g *failoverGroup) indexOf(domain string) int {
+	for i, d := range g.domains {
+		if d == domain {
+			return i
+		}
+	}
+	return -1
+}
+
+// isActive returns whether new transactions should be sent to the domain.
+func (g *failoverGroup) isActive(domain string) bool {
+	i := g.indexOf(domain)
+	if i == -1 {
+		return true
+	}
+
+	g.m.RLock()
+	defer g.m.RUnlock()
+	return i == g.active
+}
+
+// activeDomain returns the domain currently receiving the traffic of the group.
+func (g *failoverGroup) activeDomain() string {
+	g.m.RLock()
+	defer g.m.RUnlock()
+	return g.domains[g.active]
+}
+
+// onError records a failed transaction for the domain. Once the active domain
+// has failed errorBudget times in a row, the traffic moves to the next domain.
+// The last domain of the group stays active whatever its errors.
+func (g *failoverGroup) onError(domain string) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	if g.indexOf(domain) != g.active {
+		return
+	}
+
+	g.consecutiveErrors++
+	if g.consecutiveErrors < g.errorBudget || g.active == len(g.domains)-1 {
+		return
+	}
+
+	g.log.Warnf("Domain '%s' failed %d times in a row, failing over to '%s'", domain, g.consecutiveErrors, g.domains[g.active+1])
+	g.switchTo(g.active + 1)
+	failoverCount.Add(1)
+}
+
+// onSuccess records a successful transaction for the domain.
+func (g *failoverGroup) onSuccess(domain string) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	if g.indexOf(domain) == g.active {
+		g.consecutiveErrors = 0
+	}
+}
+
+// switchTo makes the domain at the given index the active one. The lock must
+// be held by the caller.
+func (g *failoverGroup) switchTo(i int) {
+	tlmFailoverSwitches.Inc(g.domains[g.active], g.domains[i])
+	g.active = i
+	g.consecutiveErrors = 0
+	setFailoverStatus(g.domains, g.domains[i])
+}
+
+func (g *failoverGroup) start() {
+	g.stop = make(chan struct{})
+	g.stopped = make(chan struct{})
+	go g.probeLoop()
+}
+
+func (g *failoverGroup) stopProbing() {
+	close(g.stop)
+	<-g.stopped
+}
+
+func (g *failoverGroup) probeLoop() {
+	ticker := time.NewTicker(g.probeInterval)
+	defer ticker.Stop()
+	defer close(g.stopped)
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			g.probeHigherPriorityDomains()
+		}
+	}
+}
+
+// probeHigherPriorityDomains fails back to the domain with the highest priority
+// that is reachable, if it has a higher priority than the active one.
+func (g *failoverGroup) probeHigherPriorityDomains() {
+	g.m.RLock()
+	active := g.active
+	g.m.RUnlock()
+
+	for i := 0; i < active; i++ {
+		domain := g.domains[i]
+		if err := g.probe(domain); err != nil {
+			g.log.Debugf("Domain '%s' is still unreachable: %v", domain, err)
+			continue
+		}
+
+		g.m.Lock()
+		// the active domain may have changed while probing
+		if i < g.active {
+			g.log.Infof("Domain '%s' is reachable again, failing back from '%s'", domain, g.domains[g.active])
+			g.switchTo(i)
+			failbackCount.Add(1)
+		}
+		g.m.Unlock()
+		return
+	}
+}
+
+// probe checks whether the domain is able to handle requests. Any response
+// that isn't a server error means the domain is reachable.
+func (g *failoverGroup) probe(domain string) error {
+	req, err := http.NewRequest("GET", domain+endpoints.V1ValidateEndpoint.Route, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(apiHTTPHeaderKey, g.apiKeys[domain])
+	req.Header.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reporter returns the failoverReporter of a domain of the group.
+func (g *failoverGroup) reporter(domain string) *failoverReporter {
+	return &failoverReporter{group: g, domain: domain}
+}
+
+// failoverReporter reports the outcome of the transactions of a domain to its
+// failover group. A nil failoverReporter ignores the reports, so that domains
+// outside of a failover group don't need any special handling.
+type failoverReporter struct {
+	group  *failoverGroup
+	domain string
+}
+
+// isActive returns whether the domain receives the traffic of its group, domains
+// outside of a failover group are always active.
+func (r *failoverReporter) isActive() bool {
+	return r == nil || r.group.isActive(r.domain)
+}
+
+// handOver sends the transactions of the domain to the active domain of its group.
+func (r *failoverReporter) handOver(transactions []transaction.Transaction) {
+	if r != nil && r.group.handOver != nil {
+		r.group.handOver(r.domain, transactions)
+	}
+}
+
+func (r *failoverReporter) onError() {
+	if r != nil {
+		r.group.onError(r.domain)
+	}
+}
+
+func (r *failoverReporter) onSuccess() {
+	if r != nil {
+		r.group.onSuccess(r.domain)
+	}
+}
@@ -0,0 +1,222 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package defaultforwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/core/log/logimpl"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestFailoverErrorBudget(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 3)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	g := newFailoverGroup(mockConfig, log, []string{"primary", "secondary", "tertiary"}, nil)
+
+	assert.True(t, g.isActive("primary"))
+	assert.False(t, g.isActive("secondary"))
+	assert.True(t, g.isActive("outside"))
+
+	// a success resets the budget
+	g.onError("primary")
+	g.onError("primary")
+	g.onSuccess("primary")
+	g.onError("primary")
+	g.onError("primary")
+	assert.Equal(t, "primary", g.activeDomain())
+
+	// errors of inactive domains are ignored
+	g.onError("secondary")
+	g.onError("primary")
+	assert.Equal(t, "secondary", g.activeDomain())
+	assert.False(t, g.isActive("primary"))
+	assert.True(t, g.isActive("secondary"))
+	assert.Equal(t, "secondary", failoverActiveDomain.Value())
+
+	// the last domain stays active
+	for i := 0; i < 10; i++ {
+		g.onError("secondary")
+		g.onError("tertiary")
+	}
+	assert.Equal(t, "tertiary", g.activeDomain())
+}
+
+func TestFailoverInvalidSettings(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 0)
+	mockConfig.SetWithoutSource("forwarder_failover.probe_interval", -1)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	g := newFailoverGroup(mockConfig, log, []string{"primary", "secondary"}, nil)
+
+	assert.Equal(t, defaultFailoverErrorBudget, g.errorBudget)
+	assert.Equal(t, defaultFailoverProbeInterval, g.probeInterval)
+}
+
+func TestFailoverProbeFailsBack(t *testing.T) {
+	primaryUp := atomic.NewBool(false)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, endpoints.V1ValidateEndpoint.Route, r.URL.Path)
+		assert.Equal(t, "primary_key", r.Header.Get(apiHTTPHeaderKey))
+		if primaryUp.Load() {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer primary.Close()
+
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 1)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	domains := []string{primary.URL, "http://secondary.invalid"}
+	g := newFailoverGroup(mockConfig, log, domains, map[string]string{primary.URL: "primary_key"})
+
+	g.onError(primary.URL)
+	require.Equal(t, "http://secondary.invalid", g.activeDomain())
+
+	g.probeHigherPriorityDomains()
+	assert.Equal(t, "http://secondary.invalid", g.activeDomain())
+
+	// any response but a server error means the domain is back
+	primaryUp.Store(true)
+	g.probeHigherPriorityDomains()
+	assert.Equal(t, primary.URL, g.activeDomain())
+	assert.Equal(t, primary.URL, failoverActiveDomain.Value())
+}
+
+func TestFailoverCreateHTTPTransactions(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.enabled", true)
+	mockConfig.SetWithoutSource("forwarder_failover.domains", []string{"https://proxy-us.example.com", "unknown", "https://proxy-eu.example.com"})
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 1)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	keysPerDomains := map[string][]string{
+		"https://proxy-us.example.com": {"api-key-1"},
+		"https://proxy-eu.example.com": {"api-key-2"},
+		"https://other.example.com":    {"api-key-3"},
+	}
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysPerDomains)))
+	require.NotNil(t, forwarder.failover)
+	assert.Equal(t, []string{"https://proxy-us.example.com", "https://proxy-eu.example.com"}, forwarder.failover.domains)
+	assert.Nil(t, forwarder.domainForwarders["https://other.example.com"].failover)
+
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p1 := []byte("A payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1})
+	transactionDomains := func() []string {
+		var domains []string
+		for _, t := range forwarder.createHTTPTransactions(endpoint, payloads, nil) {
+			domains = append(domains, t.Domain)
+		}
+		return domains
+	}
+
+	assert.ElementsMatch(t, []string{"https://proxy-us.example.com", "https://other.example.com"}, transactionDomains())
+
+	forwarder.domainForwarders["https://proxy-us.example.com"].failover.onError()
+	assert.ElementsMatch(t, []string{"https://proxy-eu.example.com", "https://other.example.com"}, transactionDomains())
+}
+
+func TestFailoverHandsOverRetriedTransactions(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.enabled", true)
+	mockConfig.SetWithoutSource("forwarder_failover.domains", []string{"https://proxy-us.example.com", "https://proxy-eu.example.com"})
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 1)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	keysPerDomains := map[string][]string{
+		"https://proxy-us.example.com": {"api-key-1", "api-key-2"},
+		"https://proxy-eu.example.com": {"api-key-3", "api-key-4"},
+	}
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysPerDomains)))
+	require.NotNil(t, forwarder.failover)
+	forwarder.internalState.Store(Started)
+	primary := forwarder.domainForwarders["https://proxy-us.example.com"]
+	secondary := forwarder.domainForwarders["https://proxy-eu.example.com"]
+
+	p1 := []byte("A payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1})
+	for _, tr := range forwarder.createHTTPTransactions(transaction.Endpoint{Route: "/api/foo", Name: "foo"}, payloads, nil) {
+		primary.addToTransactionRetryQueue(tr)
+	}
+	require.Equal(t, 2, primary.retryQueue.GetTransactionCount())
+
+	// the retry queue of the active domain is retried as usual
+	primary.retryTransactions(time.Now())
+	require.Equal(t, 2, primary.retryQueue.GetTransactionCount())
+
+	primary.failover.onError()
+	primary.retryTransactions(time.Now())
+	assert.Zero(t, primary.retryQueue.GetTransactionCount())
+
+	// the payload is sent once to each API key of the active domain
+	handedOver, err := secondary.retryQueue.ExtractTransactions()
+	require.NoError(t, err)
+	var apiKeys []string
+	for _, tr := range handedOver {
+		httpTransaction := tr.(*transaction.HTTPTransaction)
+		assert.Equal(t, "https://proxy-eu.example.com", httpTransaction.Domain)
+		assert.Equal(t, p1, httpTransaction.Payload.GetContent())
+		apiKeys = append(apiKeys, httpTransaction.Headers.Get(apiHTTPHeaderKey))
+	}
+	assert.ElementsMatch(t, []string{"api-key-3", "api-key-4"}, apiKeys)
+}
+
+func TestFailoverCountsBlockedTransactions(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.error_budget", 2)
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	g := newFailoverGroup(mockConfig, log, []string{"primary", "secondary"}, nil)
+
+	requeue := make(chan transaction.Transaction, 2)
+	w := NewWorker(mockConfig, log, nil, nil, requeue, newBlockedEndpoints(mockConfig, log), &PointSuccessfullySentMock{})
+	w.failover = g.reporter("primary")
+	w.blockedList.close("error_url")
+
+	// the transactions refused while the endpoint is blocked use the error budget
+	for i := 0; i < 2; i++ {
+		mock := newTestTransaction()
+		mock.On("GetTarget").Return("error_url")
+		w.process(context.Background(), mock)
+		mock.AssertNumberOfCalls(t, "Process", 0)
+	}
+	assert.Equal(t, "secondary", g.activeDomain())
+	assert.Len(t, requeue, 2)
+}
+
+func TestFailoverRequiresTwoDomains(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetWithoutSource("forwarder_failover.enabled", true)
+	mockConfig.SetWithoutSource("forwarder_failover.domains", []string{"https://proxy-us.example.com", "https://proxy-us.example.com"})
+	log := fxutil.Test[log.Component](t, logimpl.MockModule())
+	keysPerDomains := map[string][]string{
+		"https://proxy-us.example.com": {"api-key-1"},
+		"https://proxy-eu.example.com": {"api-key-2"},
+	}
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysPerDomains)))
+	assert.Nil(t, forwarder.failover)
+
+	// every domain keeps receiving the transactions
+	p1 := []byte("A payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1})
+	assert.Len(t, forwarder.createHTTPTransactions(transaction.Endpoint{Route: "/api/foo", Name: "foo"}, payloads, nil), 2)
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
@@ -38,6 +39,13 @@ var (
 	apiKeyStatus  = expvar.Map{}
 	apiKeyFailure = expvar.Map{}
 
+	failoverStatus        = expvar.Map{}
+	failoverDomains       = expvar.String{}
+	failoverPrimaryDomain = expvar.String{}
+	failoverActiveDomain  = expvar.String{}
+	failoverCount         = expvar.Int{}
+	failbackCount         = expvar.Int{}
+
 	// domainURLRegexp determines if an URL belongs to Datadog or not. If the URL belongs to Datadog it's prefixed
 	// with 'api.' (see computeDomainsURL).
 	domainURLRegexp = regexp.MustCompile(`([a-z]{2}\d\.)?(datadoghq\.[a-z]+|ddog-gov\.com)$`)
@@ -56,6 +64,22 @@ func initForwarderHealthExpvars() {
 	apiKeyFailure.Init()
 	transaction.ForwarderExpvars.Set("APIKeyStatus", &apiKeyStatus)
 	transaction.ForwarderExpvars.Set("APIKeyFailure", &apiKeyFailure)
+
+	failoverStatus.Init()
+	transaction.ForwarderExpvars.Set("Failover", &failoverStatus)
+}
+
+// setFailoverStatus reports the domains of the failover group, by priority, and
+// the one currently receiving its traffic.
+func setFailoverStatus(domains []string, activeDomain string) {
+	failoverDomains.Set(strings.Join(domains, ", "))
+	failoverPrimaryDomain.Set(domains[0])
+	failoverActiveDomain.Set(activeDomain)
+	failoverStatus.Set("Domains", &failoverDomains)
+	failoverStatus.Set("PrimaryDomain", &failoverPrimaryDomain)
+	failoverStatus.Set("ActiveDomain", &failoverActiveDomain)
+	failoverStatus.Set("Failovers", &failoverCount)
+	failoverStatus.Set("Failbacks", &failbackCount)
 }
 
 // forwarderHealth report the health status of the Forwarder. A Forwarder is
@@ -26,6 +26,7 @@ var (
 	transactionsRetried              = expvar.Int{}
 	transactionsRetriedByEndpoint    = expvar.Map{}
 	transactionsRetryQueueSize       = expvar.Int{}
+	transactionsThrottled            = expvar.Int{}
 	transactionsOrchestratorManifest = expvar.Int{}
 
 	tlmTxInputBytes = telemetry.NewCounter("transactions", "input_bytes",
@@ -40,6 +41,12 @@ var (
 		[]string{"domain", "endpoint"}, "Transaction retry count")
 	tlmTxRetryQueueSize = telemetry.NewGauge("transactions", "retry_queue_size",
 		[]string{"domain"}, "Retry queue size")
+	tlmFailoverSwitches = telemetry.NewCounter("failover", "switches",
+		[]string{"from", "to"}, "Count of traffic switches between the domains of the failover group")
+	tlmTxThrottled = telemetry.NewCounter("transactions", "throttled",
+		[]string{"payload_type", "endpoint"}, "Count of transactions delayed because their payload type exceeded its budget")
+	tlmBudgetAvailableTokens = telemetry.NewGauge("budget", "available_tokens",
+		[]string{"payload_type", "unit"}, "Tokens left in the budget of a payload type, in bytes or requests")
 )
 
 func init() {
@@ -104,4 +111,5 @@ func initTransactionsExpvars() {
 	transaction.TransactionsExpvars.Set("Retried", &transactionsRetried)
 	transaction.TransactionsExpvars.Set("RetriedByEndpoint", &transactionsRetriedByEndpoint)
 	transaction.TransactionsExpvars.Set("RetryQueueSize", &transactionsRetryQueueSize)
+	transaction.TransactionsExpvars.Set("Throttled", &transactionsThrottled)
 }
@@ -39,6 +39,8 @@ type Worker struct {
 	stopped               chan struct{}
 	blockedList           *blockedEndpoints
 	pointSuccessfullySent PointSuccessfullySent
+	failover              *failoverReporter
+	throttled             *throttledTransactions
 }
 
 // PointSuccessfullySent is called when sending successfully a point to the intake.
@@ -183,15 +185,22 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 	// Run the endpoint through our blockedEndpoints circuit breaker
 	target := t.GetTarget()
 	if w.blockedList.isBlock(target) {
+		// the transactions refused while the endpoint is blocked count as failures, so
+		// that the failover isn't delayed by the backoff of the endpoint
+		w.failover.onError()
 		w.requeue(t)
 		w.log.Errorf("Too many errors for endpoint '%s': retrying later", target)
+	} else if !w.throttled.admit(t) {
+		w.log.Debugf("Transaction for endpoint '%s' exceeds its budget: sending it later", t.GetEndpointName())
 	} else if err := t.Process(ctx, w.config, w.log, w.Client); err != nil {
 		w.blockedList.close(target)
+		w.failover.onError()
 		w.requeue(t)
 		w.log.Errorf("Error while processing transaction: %v", err)
 	} else {
 		w.pointSuccessfullySent.OnPointSuccessfullySent(t.GetPointCount())
 		w.blockedList.recover(target)
+		w.failover.onSuccess()
 	}
 }
 
@@ -8,6 +8,7 @@
 package agent
 
 import (
+	"path/filepath"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -17,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
+	"github.com/DataDog/datadog-agent/pkg/logs/launchers/command"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/container"
 	filelauncher "github.com/DataDog/datadog-agent/pkg/logs/launchers/file"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/journald"
@@ -24,6 +26,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 )
 
@@ -41,8 +44,32 @@ func (a *agent) SetupPipeline(
 	destinationsCtx := client.NewDestinationsContext()
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver(nil)
 
+	// the metrics generated from logs are sent through the aggregator
+	var metricSender pipeline.MetricSender
+	if a.demultiplexer != nil {
+		if defaultSender, err := a.demultiplexer.GetDefaultSender(); err == nil {
+			metricSender = defaultSender
+		} else {
+			a.log.Warnf("Could not get the default sender, the metrics generated from logs won't be sent: %v", err)
+		}
+	}
+
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx)
+	var pipelineProvider pipeline.Provider
+	if a.config.GetBool("logs_config.disk_buffer_enabled") {
+		diskBufferPath := a.config.GetString("logs_config.disk_buffer_path")
+		if diskBufferPath == "" {
+			diskBufferPath = filepath.Join(a.config.GetString("logs_config.run_path"), "buffer")
+		}
+		pipelineProvider = pipeline.NewProviderWithDiskBuffer(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx, sender.DiskBufferConfig{
+			Path:               diskBufferPath,
+			MaxSizeBytes:       a.config.GetInt64("logs_config.disk_buffer_max_size_bytes"),
+			MaxDiskRatio:       a.config.GetFloat64("logs_config.disk_buffer_max_disk_ratio"),
+			OutdatedFileInDays: a.config.GetInt("logs_config.disk_buffer_outdated_file_in_days"),
+		}, metricSender)
+	} else {
+		pipelineProvider = pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx, metricSender)
+	}
 
 	// setup the launchers
 	lnchrs := launchers.NewLaunchers(a.sources, pipelineProvider, auditor, a.tracker)
@@ -52,10 +79,11 @@ func (a *agent) SetupPipeline(
 		a.config.GetBool("logs_config.validate_pod_container_id"),
 		time.Duration(a.config.GetFloat64("logs_config.file_scan_period")*float64(time.Second)),
 		a.config.GetString("logs_config.file_wildcard_selection_mode")))
-	lnchrs.AddLauncher(listener.NewLauncher(a.config.GetInt("logs_config.frame_size")))
+	lnchrs.AddLauncher(listener.NewLauncher(a.config.GetInt("logs_config.frame_size"), a.config.GetInt("logs_config.fluent_forward_max_entry_size")))
 	lnchrs.AddLauncher(journald.NewLauncher())
 	lnchrs.AddLauncher(windowsevent.NewLauncher())
 	lnchrs.AddLauncher(container.NewLauncher(a.sources))
+	lnchrs.AddLauncher(command.NewLauncher(a.config.GetBool("logs_config.command_sources_enabled")))
 
 	a.schedulers = schedulers.NewSchedulers(a.sources, a.services)
 	a.auditor = auditor
@@ -175,15 +175,33 @@ func buildTCPEndpoints(coreConfig pkgconfigmodel.Reader, logsConfig *LogsConfigK
 		*main.UseSSL = !logsConfig.devModeNoSSL()
 	}
 
+	// additional and routes endpoints inherit the settings of the main one
+	setDefaults := func(endpoint *Endpoint) {
+		if endpoint.UseSSL == nil {
+			endpoint.UseSSL = main.UseSSL
+		}
+		endpoint.ProxyAddress = proxyAddress
+		endpoint.APIKey = pkgconfigutils.SanitizeAPIKey(endpoint.APIKey)
+	}
+
 	additionals := logsConfig.getAdditionalEndpoints()
 	for i := 0; i < len(additionals); i++ {
-		if additionals[i].UseSSL == nil {
-			additionals[i].UseSSL = main.UseSSL
+		setDefaults(&additionals[i])
+	}
+
+	routes, err := logsConfig.getRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		for i := range route.Endpoints {
+			setDefaults(&route.Endpoints[i])
 		}
-		additionals[i].ProxyAddress = proxyAddress
-		additionals[i].APIKey = pkgconfigutils.SanitizeAPIKey(additionals[i].APIKey)
 	}
-	return NewEndpoints(main, additionals, useProto, false), nil
+
+	endpoints := NewEndpoints(main, additionals, useProto, false)
+	endpoints.Routes = routes
+	return endpoints, nil
 }
 
 // BuildHTTPEndpoints returns the HTTP endpoints to send logs to.
@@ -251,27 +269,42 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 		*main.UseSSL = useSSL
 	}
 
-	additionals := logsConfig.getAdditionalEndpoints()
-	for i := 0; i < len(additionals); i++ {
-		if additionals[i].UseSSL == nil {
-			additionals[i].UseSSL = main.UseSSL
+	// additional and routes endpoints inherit the settings of the main one
+	setDefaults := func(endpoint *Endpoint) {
+		if endpoint.UseSSL == nil {
+			endpoint.UseSSL = main.UseSSL
 		}
-		additionals[i].APIKey = pkgconfigutils.SanitizeAPIKey(additionals[i].APIKey)
-		additionals[i].UseCompression = main.UseCompression
-		additionals[i].CompressionLevel = main.CompressionLevel
-		additionals[i].BackoffBase = main.BackoffBase
-		additionals[i].BackoffMax = main.BackoffMax
-		additionals[i].BackoffFactor = main.BackoffFactor
-		additionals[i].RecoveryInterval = main.RecoveryInterval
-		additionals[i].RecoveryReset = main.RecoveryReset
-
-		if additionals[i].Version == 0 {
-			additionals[i].Version = main.Version
+		endpoint.APIKey = pkgconfigutils.SanitizeAPIKey(endpoint.APIKey)
+		endpoint.UseCompression = main.UseCompression
+		endpoint.CompressionLevel = main.CompressionLevel
+		endpoint.BackoffBase = main.BackoffBase
+		endpoint.BackoffMax = main.BackoffMax
+		endpoint.BackoffFactor = main.BackoffFactor
+		endpoint.RecoveryInterval = main.RecoveryInterval
+		endpoint.RecoveryReset = main.RecoveryReset
+
+		if endpoint.Version == 0 {
+			endpoint.Version = main.Version
 		}
-		if additionals[i].Version == EPIntakeVersion2 {
-			additionals[i].TrackType = intakeTrackType
-			additionals[i].Protocol = intakeProtocol
-			additionals[i].Origin = intakeOrigin
+		if endpoint.Version == EPIntakeVersion2 {
+			endpoint.TrackType = intakeTrackType
+			endpoint.Protocol = intakeProtocol
+			endpoint.Origin = intakeOrigin
+		}
+	}
+
+	additionals := logsConfig.getAdditionalEndpoints()
+	for i := 0; i < len(additionals); i++ {
+		setDefaults(&additionals[i])
+	}
+
+	routes, err := logsConfig.getRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		for i := range route.Endpoints {
+			setDefaults(&route.Endpoints[i])
 		}
 	}
 
@@ -281,7 +314,9 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+	endpoints.Routes = routes
+	return endpoints, nil
 }
 
 type defaultParseAddressFunc func(string) (host string, port int, err error)
@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
@@ -153,6 +154,29 @@ func (l *LogsConfigKeys) getAdditionalEndpoints() []Endpoint {
 	return endpoints
 }
 
+// getRoutes returns the validated routes, an error is returned if they are invalid.
+func (l *LogsConfigKeys) getRoutes() ([]*Route, error) {
+	var routes []*Route
+	var err error
+	configKey := l.getConfigKey("routes")
+	raw := l.getConfig().Get(configKey)
+	if raw == nil {
+		return routes, nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &routes)
+	} else {
+		err = l.getConfig().UnmarshalKey(configKey, &routes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", configKey, err)
+	}
+	if err := ValidateRoutes(routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
 func (l *LogsConfigKeys) expectedTagsDuration() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("expected_tags_duration"))
 }
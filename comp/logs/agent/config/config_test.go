@@ -245,6 +245,39 @@ func (suite *ConfigTestSuite) TestMultipleTCPEndpointsEnvVar() {
 	suite.Equal(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestRoutesInheritMainEndpointSettings() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
+	suite.config.SetWithoutSource("logs_config.use_compression", true)
+	suite.config.SetWithoutSource("logs_config.compression_level", 6)
+	suite.config.SetWithoutSource("logs_config.routes", `[
+		{"name": "security", "tags": ["team:security"], "endpoints": [{"api_key": "456  \n", "host": "security.endpoint", "port": 443}]}]`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Len(endpoints.Routes, 1)
+
+	route := endpoints.Routes[0]
+	suite.Equal("security", route.Name)
+	suite.Equal([]string{"team:security"}, route.Tags)
+	suite.Len(route.Endpoints, 1)
+	suite.Equal("456", route.Endpoints[0].APIKey)
+	suite.Equal("security.endpoint", route.Endpoints[0].Host)
+	suite.True(route.Endpoints[0].UseCompression)
+	suite.Equal(6, route.Endpoints[0].CompressionLevel)
+	suite.Equal(endpoints.Main.UseSSL, route.Endpoints[0].UseSSL)
+}
+
+func (suite *ConfigTestSuite) TestInvalidRoutes() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.routes", `[{"name": "security", "endpoints": [{"host": "security.endpoint"}]}]`)
+
+	_, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Error(err)
+	_, err = buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Error(err)
+}
+
 func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 	suite.config.SetWithoutSource("api_key", "123")
 	suite.config.SetWithoutSource("logs_config.batch_wait", 1)
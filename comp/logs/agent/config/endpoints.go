@@ -114,6 +114,8 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+	// Routes send the logs matching them to additional endpoints
+	Routes []*Route
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -24,6 +24,9 @@ const (
 	JournaldType      = "journald"
 	WindowsEventType  = "windows_event"
 	StringChannelType = "string_channel"
+	SyslogType        = "syslog"
+	CommandType       = "command"
+	FluentForwardType = "fluent_forward"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -31,6 +34,13 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// GzipCompression for gzip compressed files
+	GzipCompression string = "gzip"
+	// ZstdCompression for zstd compressed files
+	ZstdCompression string = "zstd"
+	// AutoCompression to detect the compression from the file extension
+	AutoCompression string = "auto"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -40,11 +50,13 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
-	Path        string // File, Journald
+	Protocol    string `mapstructure:"protocol" json:"protocol"`         // Syslog
+	Path        string // File, Journald, Fluent Forward
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	Compression  string   `mapstructure:"compression" json:"compression"`       // File
 
 	//nolint:revive // TODO(AML) Fix revive linter
 	ConfigId           string   `mapstructure:"config_id" json:"config_id"`                   // Journald
@@ -67,6 +79,9 @@ type LogsConfig struct {
 	ChannelPath string `mapstructure:"channel_path" json:"channel_path"` // Windows Event
 	Query       string // Windows Event
 
+	Command string   `mapstructure:"command" json:"command"` // Command
+	Args    []string `mapstructure:"args" json:"args"`       // Command
+
 	// used as input only by the Channel tailer.
 	// could have been unidirectional but the tailer could not close it in this case.
 	Channel chan *ChannelMessage
@@ -114,12 +129,17 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
 		fmt.Fprintf(&b, ws("Identifier: %#v,"), c.Identifier)
 		fmt.Fprintf(&b, ws("ExcludePaths: %#v,"), c.ExcludePaths)
 		fmt.Fprintf(&b, ws("TailingMode: %#v,"), c.TailingMode)
+		fmt.Fprintf(&b, ws("Compression: %#v,"), c.Compression)
 	case DockerType, ContainerdType:
 		fmt.Fprintf(&b, ws("Image: %#v,"), c.Image)
 		fmt.Fprintf(&b, ws("Label: %#v,"), c.Label)
@@ -135,6 +155,13 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case WindowsEventType:
 		fmt.Fprintf(&b, ws("ChannelPath: %#v,"), c.ChannelPath)
 		fmt.Fprintf(&b, ws("Query: %#v,"), c.Query)
+	case FluentForwardType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case CommandType:
+		fmt.Fprintf(&b, ws("Command: %#v,"), c.Command)
+		fmt.Fprintf(&b, ws("Args: %#v,"), c.Args)
 	case StringChannelType:
 		fmt.Fprintf(&b, ws("Channel: %p,"), c.Channel)
 		c.ChannelTagsMutex.Lock()
@@ -169,11 +196,14 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
 		Port            int               `json:"port,omitempty"`           // Network
-		Path            string            `json:"path,omitempty"`           // File, Journald
+		Protocol        string            `json:"protocol,omitempty"`       // Syslog
+		Path            string            `json:"path,omitempty"`           // File, Journald, Fluent Forward
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
 		TailingMode     string            `json:"start_position,omitempty"` // File
+		Compression     string            `json:"compression,omitempty"`    // File
 		ChannelPath     string            `json:"channel_path,omitempty"`   // Windows Event
+		Command         string            `json:"command,omitempty"`        // Command
 		Service         string            `json:"service,omitempty"`
 		Source          string            `json:"source,omitempty"`
 		Tags            []string          `json:"tags,omitempty"`
@@ -182,11 +212,14 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	}{
 		Type:            c.Type,
 		Port:            c.Port,
+		Protocol:        c.Protocol,
 		Path:            c.Path,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
 		TailingMode:     c.TailingMode,
+		Compression:     c.Compression,
 		ChannelPath:     c.ChannelPath,
+		Command:         c.Command,
 		Service:         c.Service,
 		Source:          c.Source,
 		Tags:            c.Tags,
@@ -252,10 +285,28 @@ func (c *LogsConfig) Validate() error {
 		if err != nil {
 			return err
 		}
+		switch c.Compression {
+		case "", GzipCompression, ZstdCompression, AutoCompression:
+		default:
+			return fmt.Errorf("invalid compression '%v' for %v", c.Compression, c.Path)
+		}
 	case c.Type == TCPType && c.Port == 0:
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		if c.Port == 0 {
+			return fmt.Errorf("syslog source must have a port")
+		}
+		switch c.Protocol {
+		case "", TCPType, UDPType:
+		default:
+			return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+		}
+	case c.Type == FluentForwardType && c.Port == 0 && c.Path == "":
+		return fmt.Errorf("fluent_forward source must have a port or a socket path")
+	case c.Type == CommandType && c.Command == "":
+		return fmt.Errorf("command source must have a command")
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -12,29 +12,85 @@ import (
 
 // Processing rule types
 const (
-	ExcludeAtMatch = "exclude_at_match"
-	IncludeAtMatch = "include_at_match"
-	MaskSequences  = "mask_sequences"
-	MultiLine      = "multi_line"
+	ExcludeAtMatch  = "exclude_at_match"
+	IncludeAtMatch  = "include_at_match"
+	MaskSequences   = "mask_sequences"
+	MultiLine       = "multi_line"
+	ParseAsJSON     = "parse_json"
+	ParseAsKeyValue = "parse_key_value"
+	ParseWithRegex  = "parse_regex"
+	GenerateMetric  = "generate_metric"
+	Sample          = "sample"
+	RateLimit       = "rate_limit"
+	Dedup           = "dedup"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
-// be applied on log lines
+// DefaultDedupWindow is the default duration, in seconds, during which a `dedup` rule
+// collapses identical logs.
+const DefaultDedupWindow = 10
+
+// Types of the metrics generated by the `generate_metric` rules
+const (
+	CountMetric        = "count"
+	DistributionMetric = "distribution"
+)
+
+// ProcessingRule defines an exclusion, a masking, a parsing or a metric generation
+// rule to be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Metric is the metric generated by a `generate_metric` rule
+	Metric *LogMetric `mapstructure:"metric" json:"metric,omitempty"`
+	// SampleRate is the ratio of logs kept by a `sample` rule, between 0 and 1
+	SampleRate float64 `mapstructure:"sample_rate" json:"sample_rate,omitempty"`
+	// SampleAttribute is the attribute whose hash decides if a log is kept by a `sample` rule,
+	// the content of the log is hashed when it is empty
+	SampleAttribute string `mapstructure:"sample_attribute" json:"sample_attribute,omitempty"`
+	// LogsPerSecond is the rate of logs allowed per source and service by a `rate_limit` rule
+	LogsPerSecond float64 `mapstructure:"logs_per_second" json:"logs_per_second,omitempty"`
+	// Burst is the number of logs a `rate_limit` rule allows above its rate, it defaults to the rate
+	Burst int `mapstructure:"burst" json:"burst,omitempty"`
+	// Window is the duration in seconds during which a `dedup` rule collapses the identical
+	// consecutive logs of a source, it defaults to DefaultDedupWindow
+	Window float64 `mapstructure:"window" json:"window,omitempty"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
 }
 
+// LogMetric defines the metric generated from the logs matching a `generate_metric` rule.
+// A log matches when the rule pattern matches its content, or the value of Attribute if set.
+// Without any pattern, all the logs having Attribute match.
+type LogMetric struct {
+	Name string
+	// Type is either `count` or `distribution`
+	Type string
+	// Attribute is the parsed attribute the rule matches on, nested attributes are
+	// separated with dots.
+	Attribute string
+	// ValueAttribute is the attribute, or the named capture group of the pattern,
+	// holding the value of the distributions.
+	ValueAttribute string `mapstructure:"value_attribute" json:"value_attribute"`
+	Tags           []string
+	// TagAttributes are the attributes, or named capture groups, added as `<attribute>:<value>` tags.
+	TagAttributes []string `mapstructure:"tag_attributes" json:"tag_attributes"`
+}
+
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, except for the `parse_json` and `parse_key_value`
+// rules which do not need one
+// - at least one named capture group for `parse_regex` rules
+// - a metric with a name and a valid type for `generate_metric` rules, and either
+// a pattern or an attribute to match on
+// - a sample rate in ]0, 1] for `sample` rules, a positive rate for `rate_limit`
+// rules and a non-negative window for `dedup` rules; their pattern is optional and
+// restricts the logs they apply to
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +98,27 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, ParseWithRegex:
 			break
+		case ParseAsJSON, ParseAsKeyValue:
+			// these rules parse the whole content and don't need any pattern
+			continue
+		case GenerateMetric:
+			if err := validateLogMetric(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				// the rule matches on the presence of the attribute
+				continue
+			}
+		case Sample, RateLimit, Dedup:
+			if err := validateDropRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				// the rule applies to all the logs
+				continue
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,23 +128,73 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+		if rule.Type == ParseWithRegex && !hasNamedGroup(re) {
+			return fmt.Errorf("pattern %s for processing rule: %s must contain at least one named capture group", rule.Pattern, rule.Name)
+		}
+	}
+	return nil
+}
+
+func validateLogMetric(rule *ProcessingRule) error {
+	metric := rule.Metric
+	if metric == nil || metric.Name == "" {
+		return fmt.Errorf("a metric name must be set for processing rule `%s`", rule.Name)
+	}
+	switch metric.Type {
+	case CountMetric:
+	case DistributionMetric:
+		if metric.ValueAttribute == "" {
+			return fmt.Errorf("a value attribute must be set for the distribution of processing rule `%s`", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric type %s is not supported for processing rule `%s`", metric.Type, rule.Name)
+	}
+	if rule.Pattern == "" && metric.Attribute == "" {
+		return fmt.Errorf("a pattern or an attribute must be set for processing rule `%s`", rule.Name)
 	}
 	return nil
 }
 
+func validateDropRule(rule *ProcessingRule) error {
+	switch {
+	case rule.Type == Sample && (rule.SampleRate <= 0 || rule.SampleRate > 1):
+		return fmt.Errorf("sample rate must be greater than 0 and at most 1 for processing rule `%s`", rule.Name)
+	case rule.Type == RateLimit && rule.LogsPerSecond <= 0:
+		return fmt.Errorf("logs per second must be positive for processing rule `%s`", rule.Name)
+	case rule.Type == RateLimit && rule.Burst < 0:
+		return fmt.Errorf("burst can't be negative for processing rule `%s`", rule.Name)
+	case rule.Type == Dedup && rule.Window < 0:
+		return fmt.Errorf("window can't be negative for processing rule `%s`", rule.Name)
+	}
+	return nil
+}
+
+// hasNamedGroup returns true if the regular expression contains at least one named capture group.
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ParseAsJSON || rule.Type == ParseAsKeyValue || (rule.Pattern == "" && (rule.Type == GenerateMetric || rule.Type == Sample || rule.Type == RateLimit || rule.Type == Dedup)) {
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, ParseWithRegex, GenerateMetric, Sample, RateLimit, Dedup:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -33,3 +33,94 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParsingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: ParseAsJSON},
+		{Name: "kv", Type: ParseAsKeyValue},
+		{Name: "regex", Type: ParseWithRegex, Pattern: `^(?P<level>\w+) (?P<message>.*)$`},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.NotNil(t, validRules[2].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "regex", Type: ParseWithRegex},
+		{Name: "regex", Type: ParseWithRegex, Pattern: `^(\w+) (.*)$`},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, Pattern: "GET", Metric: &LogMetric{Name: "requests", Type: CountMetric}},
+		{Name: "attribute", Type: GenerateMetric, Metric: &LogMetric{Name: "requests", Type: CountMetric, Attribute: "http.method"}},
+		{Name: "distribution", Type: GenerateMetric, Pattern: `took (?P<duration>\d+)ms`, Metric: &LogMetric{Name: "duration", Type: DistributionMetric, ValueAttribute: "duration"}},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].Regex)
+	assert.Nil(t, validRules[1].Regex)
+	assert.NotNil(t, validRules[2].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no metric", Type: GenerateMetric, Pattern: "GET"},
+		{Name: "no metric name", Type: GenerateMetric, Pattern: "GET", Metric: &LogMetric{Type: CountMetric}},
+		{Name: "invalid type", Type: GenerateMetric, Pattern: "GET", Metric: &LogMetric{Name: "requests", Type: "gauge"}},
+		{Name: "no value", Type: GenerateMetric, Pattern: "GET", Metric: &LogMetric{Name: "duration", Type: DistributionMetric}},
+		{Name: "no match", Type: GenerateMetric, Metric: &LogMetric{Name: "requests", Type: CountMetric}},
+		{Name: "invalid pattern", Type: GenerateMetric, Pattern: "(", Metric: &LogMetric{Name: "requests", Type: CountMetric}},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateSampleAndRateLimitRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "sample", Type: Sample, SampleRate: 0.1},
+		{Name: "sample debug", Type: Sample, SampleRate: 0.01, Pattern: "DEBUG", SampleAttribute: "trace_id"},
+		{Name: "rate limit", Type: RateLimit, LogsPerSecond: 100},
+		{Name: "rate limit errors", Type: RateLimit, LogsPerSecond: 0.5, Burst: 10, Pattern: "ERROR"},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.NotNil(t, validRules[1].Regex)
+	assert.Nil(t, validRules[2].Regex)
+	assert.NotNil(t, validRules[3].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no sample rate", Type: Sample},
+		{Name: "negative sample rate", Type: Sample, SampleRate: -0.1},
+		{Name: "sample rate above 1", Type: Sample, SampleRate: 10},
+		{Name: "no rate", Type: RateLimit},
+		{Name: "negative burst", Type: RateLimit, LogsPerSecond: 1, Burst: -1},
+		{Name: "invalid pattern", Type: Sample, SampleRate: 0.5, Pattern: "("},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateDedupRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "dedup", Type: Dedup},
+		{Name: "dedup errors", Type: Dedup, Window: 30, Pattern: "ERROR"},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.NotNil(t, validRules[1].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "negative window", Type: Dedup, Window: -1},
+		{Name: "invalid pattern", Type: Dedup, Pattern: "("},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Route sends the logs matching its conditions to its endpoints, on top of the main
+// and additional endpoints unless the route is exclusive. A log matches when it
+// satisfies all the conditions set, a condition listing several values being satisfied
+// by any of them.
+type Route struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Sources are the sources of the logs matching the route
+	Sources []string `mapstructure:"sources" json:"sources,omitempty"`
+	// Services are the services of the logs matching the route
+	Services []string `mapstructure:"services" json:"services,omitempty"`
+	// Tags are the tags of the logs matching the route, e.g. `team:security`
+	Tags []string `mapstructure:"tags" json:"tags,omitempty"`
+	// Pattern is a regular expression matching the content of the logs
+	Pattern string `mapstructure:"pattern" json:"pattern,omitempty"`
+	// Endpoints are the endpoints receiving the logs matching the route
+	Endpoints []Endpoint `mapstructure:"endpoints" json:"endpoints"`
+	// Exclusive routes are the only destinations of the logs they match, these logs
+	// are not sent to the main and additional endpoints
+	Exclusive bool `mapstructure:"exclusive" json:"exclusive,omitempty"`
+
+	Regex *regexp.Regexp `json:"-"`
+}
+
+// ValidateRoutes validates the routes, and compiles their patterns.
+func ValidateRoutes(routes []*Route) error {
+	names := make(map[string]bool)
+	for _, route := range routes {
+		if route.Name == "" {
+			return fmt.Errorf("all logs routes must have a name")
+		}
+		if names[route.Name] {
+			return fmt.Errorf("logs route names must be unique, found duplicate `%s`", route.Name)
+		}
+		names[route.Name] = true
+
+		if len(route.Sources) == 0 && len(route.Services) == 0 && len(route.Tags) == 0 && route.Pattern == "" {
+			return fmt.Errorf("logs route `%s` must have at least one condition among sources, services, tags and pattern", route.Name)
+		}
+		if len(route.Endpoints) == 0 {
+			return fmt.Errorf("logs route `%s` must have at least one endpoint", route.Name)
+		}
+		for _, endpoint := range route.Endpoints {
+			if endpoint.Host == "" {
+				return fmt.Errorf("all the endpoints of logs route `%s` must have a host", route.Name)
+			}
+		}
+
+		if route.Pattern != "" {
+			regex, err := regexp.Compile(route.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern for logs route `%s`: %v", route.Name, err)
+			}
+			route.Regex = regex
+		}
+	}
+	return nil
+}
+
+// ForRoute returns the endpoints composite of a route, with the batching settings
+// of the main endpoints. The first endpoint of the route is its main one.
+func (e *Endpoints) ForRoute(route *Route) *Endpoints {
+	return NewEndpointsWithBatchSettings(route.Endpoints[0], route.Endpoints[1:], e.UseProto, e.UseHTTP,
+		e.BatchWait, e.BatchMaxConcurrentSend, e.BatchMaxSize, e.BatchMaxContentSize, e.InputChanSize)
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRoutes(t *testing.T) {
+	endpoints := []Endpoint{{Host: "security.example.com"}}
+
+	route := &Route{Name: "security", Tags: []string{"team:security"}, Pattern: "auth(entication)? failed", Endpoints: endpoints}
+	assert.NoError(t, ValidateRoutes([]*Route{route}))
+	assert.True(t, route.Regex.MatchString("authentication failed for user"))
+
+	invalidRoutes := map[string][]*Route{
+		"no name":        {{Sources: []string{"nginx"}, Endpoints: endpoints}},
+		"duplicate name": {{Name: "a", Sources: []string{"nginx"}, Endpoints: endpoints}, {Name: "a", Services: []string{"web"}, Endpoints: endpoints}},
+		"no condition":   {{Name: "a", Endpoints: endpoints}},
+		"no endpoint":    {{Name: "a", Sources: []string{"nginx"}}},
+		"no host":        {{Name: "a", Sources: []string{"nginx"}, Endpoints: []Endpoint{{Port: 443}}}},
+		"invalid regex":  {{Name: "a", Pattern: "(", Endpoints: endpoints}},
+	}
+	for name, routes := range invalidRoutes {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, ValidateRoutes(routes))
+		})
+	}
+}
+
+func TestEndpointsForRoute(t *testing.T) {
+	main := Endpoint{Host: "main.example.com"}
+	endpoints := NewEndpointsWithBatchSettings(main, nil, false, true, 0, 1, 2, 3, 4)
+	route := &Route{Name: "a", Endpoints: []Endpoint{{Host: "first.example.com"}, {Host: "second.example.com"}}}
+
+	routeEndpoints := endpoints.ForRoute(route)
+	assert.Equal(t, "first.example.com", routeEndpoints.Main.Host)
+	assert.Len(t, routeEndpoints.Endpoints, 2)
+	assert.True(t, routeEndpoints.UseHTTP)
+	assert.Equal(t, endpoints.BatchMaxSize, routeEndpoints.BatchMaxSize)
+	assert.Nil(t, routeEndpoints.Routes)
+}
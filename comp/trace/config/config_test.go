@@ -1220,6 +1220,102 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_ZIPKIN_RECEIVER_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+		t.Setenv("DD_APM_JAEGER_RECEIVER_ENABLED", "true")
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.ZipkinReceiverEnabled)
+		assert.True(t, cfg.JaegerReceiverEnabled)
+	})
+
+	env = "DD_APM_STATS_CUSTOM_TAGS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `["tenant", "http.route"]`)
+		t.Setenv("DD_APM_STATS_CUSTOM_TAGS_MAX_CARDINALITY", "25")
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []string{"tenant", "http.route"}, cfg.StatsCustomTags)
+		assert.Equal(t, 25, cfg.StatsCustomTagsMaxCardinality)
+	})
+
+	env = "DD_APM_SPAN_FILTERS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name": "cache", "expr": "name == \"cache.get\""}, {"expr": "resource =~ \"^GET /health\"", "action": "drop_trace", "stage": "after_stats"}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*config.SpanFilterRule{
+			{Name: "cache", Expr: `name == "cache.get"`, Action: config.SpanFilterDropSpan, Stage: config.SpanFilterBeforeStats},
+			{Name: "span_filter_1", Expr: `resource =~ "^GET /health"`, Action: config.SpanFilterDropTrace, Stage: config.SpanFilterAfterStats},
+		}, cfg.SpanFilters)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT", "10")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_CACHE_TTL", "60")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_CACHE_SIZE", "1000")
+		t.Setenv(env, `[{"name":"slow", "type":"latency", "threshold_ms":5000}, {"type":"attribute", "key":"http.route", "pattern":"^/checkout"}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSampling.Enabled)
+		assert.Equal(t, 10*time.Second, cfg.TailSampling.DecisionWait)
+		assert.Equal(t, time.Minute, cfg.TailSampling.DecisionCacheTTL)
+		assert.Equal(t, 1000, cfg.TailSampling.DecisionCacheSize)
+		require.Len(t, cfg.TailSampling.Policies, 2)
+		assert.Equal(t, &config.TailSamplingPolicy{Name: "slow", Type: config.TailSamplingLatency, ThresholdMs: 5000}, cfg.TailSampling.Policies[0])
+		assert.Equal(t, "attribute_1", cfg.TailSampling.Policies[1].Name)
+		assert.Equal(t, "http.route", cfg.TailSampling.Policies[1].Key)
+		require.NotNil(t, cfg.TailSampling.Policies[1].Re)
+		assert.True(t, cfg.TailSampling.Policies[1].Re.MatchString("/checkout/cart"))
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -1860,6 +1956,45 @@ func TestLoadEnv(t *testing.T) {
 		assert.True(t, cfg.Obfuscation.Memcached.KeepCommand)
 	})
 
+	env = "DD_APM_OBFUSCATION_GRAPHQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "false")
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.False(t, coreconfig.Datadog.GetBool("apm_config.obfuscation.graphql.enabled"))
+		assert.False(t, cfg.Obfuscation.GraphQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_GRAPHQL_REMOVE_ALIASES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params:      corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+				SetupConfig: true,
+			}),
+			MockModule(),
+		))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.Obfuscation.GraphQL.Enabled)
+		assert.True(t, coreconfig.Datadog.GetBool("apm_config.obfuscation.graphql.remove_aliases"))
+		assert.True(t, cfg.Obfuscation.GraphQL.RemoveAliases)
+	})
+
 	env = "DD_APM_OBFUSCATION_MONGODB_ENABLED"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, "true")
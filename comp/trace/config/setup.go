@@ -237,6 +237,8 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.connection_limit") {
 		c.ConnectionLimit = core.GetInt("apm_config.connection_limit")
 	}
+	c.ZipkinReceiverEnabled = core.GetBool("apm_config.zipkin_receiver.enabled")
+	c.JaegerReceiverEnabled = core.GetBool("apm_config.jaeger_receiver.enabled")
 	c.PeerServiceAggregation = core.GetBool("apm_config.peer_service_aggregation")
 	if c.PeerServiceAggregation {
 		log.Warn("`apm_config.peer_service_aggregation` is deprecated, please use `apm_config.peer_tags_aggregation` instead")
@@ -246,6 +248,12 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.peer_tags") {
 		c.PeerTags = core.GetStringSlice("apm_config.peer_tags")
 	}
+	if core.IsSet("apm_config.stats_custom_tags") {
+		c.StatsCustomTags = core.GetStringSlice("apm_config.stats_custom_tags")
+	}
+	if core.IsSet("apm_config.stats_custom_tags_max_cardinality") {
+		c.StatsCustomTagsMaxCardinality = core.GetInt("apm_config.stats_custom_tags_max_cardinality")
+	}
 	if core.IsSet("apm_config.extra_sample_rate") {
 		c.ExtraSampleRate = core.GetFloat64("apm_config.extra_sample_rate")
 	}
@@ -271,6 +279,10 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.RareSamplerCardinality = core.GetInt("apm_config.rare_sampler.cardinality")
 	}
 
+	if err := applyTailSamplingConfig(c, core); err != nil {
+		return err
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -408,6 +420,7 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.Obfuscation.Mongo.Enabled = true
 		c.Obfuscation.Memcached.Enabled = true
 		c.Obfuscation.Redis.Enabled = true
+		c.Obfuscation.GraphQL.Enabled = true
 
 		// TODO(x): There is an issue with coreconfig.Datadog.IsSet("apm_config.obfuscation"), probably coming from Viper,
 		// where it returns false even is "apm_config.obfuscation.credit_cards.enabled" is set via an environment
@@ -427,6 +440,12 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		if coreconfig.Datadog.IsSet("apm_config.obfuscation.elasticsearch.obfuscate_sql_values") {
 			c.Obfuscation.ES.ObfuscateSQLValues = coreconfig.Datadog.GetStringSlice("apm_config.obfuscation.elasticsearch.obfuscate_sql_values")
 		}
+		if coreconfig.Datadog.IsSet("apm_config.obfuscation.graphql.enabled") {
+			c.Obfuscation.GraphQL.Enabled = coreconfig.Datadog.GetBool("apm_config.obfuscation.graphql.enabled")
+		}
+		if coreconfig.Datadog.IsSet("apm_config.obfuscation.graphql.remove_aliases") {
+			c.Obfuscation.GraphQL.RemoveAliases = coreconfig.Datadog.GetBool("apm_config.obfuscation.graphql.remove_aliases")
+		}
 		if coreconfig.Datadog.IsSet("apm_config.obfuscation.http.remove_query_string") {
 			c.Obfuscation.HTTP.RemoveQueryString = coreconfig.Datadog.GetBool("apm_config.obfuscation.http.remove_query_string")
 		}
@@ -513,6 +532,18 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.span_filters"; core.IsSet(k) {
+		filters := make([]*config.SpanFilterRule, 0)
+		if err := coreconfig.Datadog.UnmarshalKey(k, &filters); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"short-spans\", \"expr\": \"duration < 1000\", \"action\": \"drop_span\"}]', error: %v", k, err)
+		} else {
+			if err := compileSpanFilters(filters); err != nil {
+				return fmt.Errorf("span_filters: %s", err)
+			}
+			c.SpanFilters = filters
+		}
+	}
+
 	// undocumented writers
 	for key, cfg := range map[string]*config.WriterConfig{
 		"apm_config.trace_writer": c.TraceWriter,
@@ -705,6 +736,97 @@ func compileReplaceRules(rules []*config.ReplaceRule) error {
 	return nil
 }
 
+// applyTailSamplingConfig reads the `apm_config.tail_sampling` settings.
+func applyTailSamplingConfig(c *config.AgentConfig, core corecompcfg.Component) error {
+	c.TailSampling.Enabled = core.GetBool("apm_config.tail_sampling.enabled")
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = getDuration(core.GetInt("apm_config.tail_sampling.decision_wait"))
+	}
+	if core.IsSet("apm_config.tail_sampling.max_memory_bytes") {
+		c.TailSampling.MaxMemoryBytes = core.GetInt64("apm_config.tail_sampling.max_memory_bytes")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_cache_ttl") {
+		c.TailSampling.DecisionCacheTTL = getDuration(core.GetInt("apm_config.tail_sampling.decision_cache_ttl"))
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_cache_size") {
+		c.TailSampling.DecisionCacheSize = core.GetInt("apm_config.tail_sampling.decision_cache_size")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		policies := make([]*config.TailSamplingPolicy, 0)
+		if err := coreconfig.Datadog.UnmarshalKey(k, &policies); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"slow\", \"type\": \"latency\", \"threshold_ms\": 5000}]', error: %v", k, err)
+		} else {
+			if err := compileTailSamplingPolicies(policies); err != nil {
+				return fmt.Errorf("tail_sampling.policies: %s", err)
+			}
+			c.TailSampling.Policies = policies
+		}
+	}
+	if c.TailSampling.Enabled && len(c.TailSampling.Policies) == 0 {
+		log.Warn("Tail sampling is enabled without any policy in apm_config.tail_sampling.policies: only the traces kept by the tracers will be sent")
+	}
+	return nil
+}
+
+func compileTailSamplingPolicies(policies []*config.TailSamplingPolicy) error {
+	for i, p := range policies {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("%s_%d", p.Type, i)
+		}
+		switch p.Type {
+		case config.TailSamplingLatency:
+			if p.ThresholdMs <= 0 {
+				return fmt.Errorf("policy %q: \"threshold_ms\" must be positive", p.Name)
+			}
+		case config.TailSamplingError:
+		case config.TailSamplingAttribute:
+			if p.Key == "" {
+				return fmt.Errorf("policy %q: attribute policies must have a \"key\"", p.Name)
+			}
+			if p.Pattern != "" {
+				re, err := regexp.Compile(p.Pattern)
+				if err != nil {
+					return fmt.Errorf("policy %q: %s", p.Name, err)
+				}
+				p.Re = re
+			}
+		case config.TailSamplingRate:
+			if p.Rate < 0 || p.Rate > 1 {
+				return fmt.Errorf("policy %q: \"rate\" must be between 0 and 1", p.Name)
+			}
+		default:
+			return fmt.Errorf("policy %q: unknown type %q", p.Name, p.Type)
+		}
+	}
+	return nil
+}
+
+func compileSpanFilters(filters []*config.SpanFilterRule) error {
+	for i, f := range filters {
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("span_filter_%d", i)
+		}
+		if f.Expr == "" {
+			return fmt.Errorf("filter %q: \"expr\" is required", f.Name)
+		}
+		switch f.Action {
+		case "":
+			f.Action = config.SpanFilterDropSpan
+		case config.SpanFilterDropSpan, config.SpanFilterDropTrace:
+		default:
+			return fmt.Errorf("filter %q: unknown action %q", f.Name, f.Action)
+		}
+		switch f.Stage {
+		case "":
+			f.Stage = config.SpanFilterBeforeStats
+		case config.SpanFilterBeforeStats, config.SpanFilterAfterStats:
+		default:
+			return fmt.Errorf("filter %q: unknown stage %q", f.Name, f.Stage)
+		}
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -125,6 +125,7 @@ var (
 	aggregatorOrchestratorManifestsErrors      = expvar.Int{}
 	aggregatorDogstatsdContexts                = expvar.Int{}
 	aggregatorDogstatsdContextsByMtype         = []expvar.Int{}
+	aggregatorDogstatsdCardinalityOverflow     = expvar.Map{}
 	aggregatorEventPlatformEvents              = expvar.Map{}
 	aggregatorEventPlatformEventsErrors        = expvar.Map{}
 
@@ -143,6 +144,8 @@ var (
 		[]string{"shard", "metric_type"}, "Count the number of dogstatsd contexts in the aggregator, by metric type")
 	tlmDogstatsdContextsBytesByMtype = telemetry.NewGauge("aggregator", "dogstatsd_contexts_bytes_by_mtype",
 		[]string{"shard", "metric_type", util.BytesKindTelemetryKey}, "Estimated count of bytes taken by contexts in the aggregator, by metric type")
+	tlmDogstatsdCardinalityOverflow = telemetry.NewCounter("aggregator", "dogstatsd_cardinality_overflow",
+		[]string{"shard", "limit"}, "Count the number of dogstatsd samples aggregated in an overflow context, by cardinality limit")
 	tlmChecksContexts = telemetry.NewGauge("aggregator", "checks_contexts",
 		[]string{"shard"}, "Count the number of checks contexts in the check aggregator")
 	tlmChecksContextsByMtype = telemetry.NewGauge("aggregator", "checks_contexts_by_mtype",
@@ -191,6 +194,7 @@ func init() {
 	aggregatorExpvars.Set("OrchestratorManifests", &aggregatorOrchestratorManifests)
 	aggregatorExpvars.Set("OrchestratorManifestsErrors", &aggregatorOrchestratorManifestsErrors)
 	aggregatorExpvars.Set("DogstatsdContexts", &aggregatorDogstatsdContexts)
+	aggregatorExpvars.Set("DogstatsdCardinalityOverflow", &aggregatorDogstatsdCardinalityOverflow)
 	aggregatorExpvars.Set("EventPlatformEvents", &aggregatorEventPlatformEvents)
 	aggregatorExpvars.Set("EventPlatformEventsErrors", &aggregatorEventPlatformEventsErrors)
 
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+)
+
+const (
+	// cardinalityOverflowTag replaces the metric tags of the contexts over a cardinality limit,
+	// their samples are aggregated in a single context per metric name and origin.
+	cardinalityOverflowTag = "cardinality_overflow:true"
+
+	// the limits are named after their settings, they are reported in the telemetry
+	// and in the agent status
+	cardinalityLimitMetric = "per_metric"
+	cardinalityLimitOrigin = "per_origin"
+)
+
+// cardinalityLimiter limits the number of contexts a metric name, and an origin, can use
+// during a flush window. It's not thread safe: it's used by a single time sampler, whose
+// samples and flushes happen in the same goroutine.
+type cardinalityLimiter struct {
+	perMetric int
+	perOrigin int
+
+	// allowed holds the contexts counted in the current window
+	allowed        map[ckey.ContextKey]struct{}
+	countsByName   map[string]int
+	countsByOrigin map[ckey.TagsKey]int
+}
+
+// newCardinalityLimiter returns a limiter, or nil when no limit is set.
+func newCardinalityLimiter(perMetric, perOrigin int) *cardinalityLimiter {
+	if perMetric <= 0 && perOrigin <= 0 {
+		return nil
+	}
+	return &cardinalityLimiter{
+		perMetric:      perMetric,
+		perOrigin:      perOrigin,
+		allowed:        make(map[ckey.ContextKey]struct{}),
+		countsByName:   make(map[string]int),
+		countsByOrigin: make(map[ckey.TagsKey]int),
+	}
+}
+
+// track counts a context in the current window. It returns the limit the context is over,
+// or an empty string if the context can be tracked. The origin limit only applies to the
+// samples with an origin, i.e. with tags added by the tagger.
+func (l *cardinalityLimiter) track(contextKey ckey.ContextKey, name string, originKey ckey.TagsKey, hasOrigin bool) string {
+	if _, ok := l.allowed[contextKey]; ok {
+		return ""
+	}
+	// the counts only grow during a window: a context over a limit stays over it until
+	// the next window, there is no need to remember it.
+	if l.perMetric > 0 && l.countsByName[name] >= l.perMetric {
+		return cardinalityLimitMetric
+	}
+	if l.perOrigin > 0 && hasOrigin && l.countsByOrigin[originKey] >= l.perOrigin {
+		return cardinalityLimitOrigin
+	}
+
+	l.allowed[contextKey] = struct{}{}
+	l.countsByName[name]++
+	if hasOrigin {
+		l.countsByOrigin[originKey]++
+	}
+	return ""
+}
+
+// reset starts a new window.
+func (l *cardinalityLimiter) reset() {
+	l.allowed = make(map[ckey.ContextKey]struct{})
+	l.countsByName = make(map[string]int)
+	l.countsByOrigin = make(map[ckey.TagsKey]int)
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestNewCardinalityLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newCardinalityLimiter(0, 0))
+	assert.NotNil(t, newCardinalityLimiter(0, 10))
+}
+
+func TestCardinalityLimiterPerMetric(t *testing.T) {
+	l := newCardinalityLimiter(2, 0)
+
+	assert.Equal(t, "", l.track(ckey.ContextKey(1), "foo", 0, false))
+	assert.Equal(t, "", l.track(ckey.ContextKey(2), "foo", 0, false))
+	assert.Equal(t, cardinalityLimitMetric, l.track(ckey.ContextKey(3), "foo", 0, false))
+	// the contexts already counted in the window are still allowed
+	assert.Equal(t, "", l.track(ckey.ContextKey(1), "foo", 0, false))
+	assert.Equal(t, "", l.track(ckey.ContextKey(4), "bar", 0, false))
+
+	l.reset()
+	assert.Equal(t, "", l.track(ckey.ContextKey(3), "foo", 0, false))
+}
+
+func TestCardinalityLimiterPerOrigin(t *testing.T) {
+	l := newCardinalityLimiter(0, 2)
+
+	assert.Equal(t, "", l.track(ckey.ContextKey(1), "foo", 10, true))
+	assert.Equal(t, "", l.track(ckey.ContextKey(2), "bar", 10, true))
+	assert.Equal(t, cardinalityLimitOrigin, l.track(ckey.ContextKey(3), "baz", 10, true))
+	assert.Equal(t, "", l.track(ckey.ContextKey(4), "baz", 20, true))
+	// the samples without origin aren't limited
+	for i := 5; i < 10; i++ {
+		assert.Equal(t, "", l.track(ckey.ContextKey(i), "baz", 0, false))
+	}
+}
+
+func TestContextResolverCardinalityOverflow(t *testing.T) {
+	r := newContextResolver(tags.NewStore(true, "test"), "test")
+	r.limiter = newCardinalityLimiter(0, 2)
+	overflowed := func() int64 {
+		if v, ok := aggregatorDogstatsdCardinalityOverflow.Get(cardinalityLimitOrigin).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	initial := overflowed()
+
+	key1 := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:1"}})
+	key2 := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:2"}})
+	overflow := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:3"}})
+	assert.Equal(t, overflow, r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:4"}}))
+	assert.Equal(t, key1, r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:1"}}))
+	assert.NotEqual(t, key2, overflow)
+	r.trackContext(&mockSample{"foo", []string{"pod:b"}, []string{"id:3"}})
+
+	require.Equal(t, 4, r.length())
+	context, ok := r.get(overflow)
+	require.True(t, ok)
+	assert.Equal(t, "foo", context.Name)
+	metrics.AssertCompositeTagsEqual(t, tagset.CompositeTagsFromSlice([]string{"pod:a", cardinalityOverflowTag}), context.Tags())
+
+	// the overflowing samples are counted by limit, not by metric name
+	assert.Equal(t, int64(2), overflowed()-initial)
+	assert.Nil(t, aggregatorDogstatsdCardinalityOverflow.Get("foo"))
+}
+
+func TestTimeSamplerCardinalityLimit(t *testing.T) {
+	pkgconfig.Datadog.SetWithoutSource("dogstatsd_cardinality_limit.per_metric", 1)
+	defer pkgconfig.Datadog.SetWithoutSource("dogstatsd_cardinality_limit.per_metric", 0)
+	sampler := testTimeSampler()
+
+	sample := func(tag string, timestamp float64) {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "my.count",
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{tag},
+			SampleRate: 1,
+		}, timestamp)
+	}
+	sample("id:1", 12345)
+	sample("id:2", 12345)
+	sample("id:3", 12346)
+
+	series, _ := flushSerie(sampler, 12350)
+	require.Len(t, series, 2)
+	values := map[string]float64{}
+	for _, serie := range series {
+		values[serie.Tags.Join(",")] = serie.Points[0].Value
+	}
+	assert.Equal(t, map[string]float64{"id:1": 1, cardinalityOverflowTag: 2}, values)
+
+	// the limit applies to each window
+	sample("id:2", 12355)
+	series, _ = flushSerie(sampler, 12360)
+	values = map[string]float64{}
+	for _, serie := range series {
+		values[serie.Tags.Join(",")] = serie.Points[0].Value
+	}
+	assert.Equal(t, 1.0, values["id:2"])
+}
@@ -71,6 +71,8 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	// limiter is nil when the cardinality isn't limited
+	limiter *cardinalityLimiter
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -101,6 +103,17 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
+	if cr.limiter != nil {
+		if limit := cr.limiter.track(contextKey, metricSampleContext.GetName(), taggerKey, len(cr.taggerBuffer.Get()) > 0); limit != "" {
+			// the sample is aggregated with the other overflowing samples of the metric and origin
+			tlmDogstatsdCardinalityOverflow.Inc(cr.id, limit)
+			aggregatorDogstatsdCardinalityOverflow.Add(limit, 1)
+			cr.metricBuffer.Reset()
+			cr.metricBuffer.Append(cardinalityOverflowTag)
+			contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+		}
+	}
+
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
 		mtype := metricSampleContext.GetMetricType()
 		context := &Context{
@@ -203,9 +216,11 @@ type timestampContextResolver struct {
 	lastSeenByKey map[ckey.ContextKey]float64
 }
 
-func newTimestampContextResolver(cache *tags.Store, id string) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, id string, limiter *cardinalityLimiter) *timestampContextResolver {
+	resolver := newContextResolver(cache, id)
+	resolver.limiter = limiter
 	return &timestampContextResolver{
-		resolver:      newContextResolver(cache, id),
+		resolver:      resolver,
 		lastSeenByKey: make(map[ckey.ContextKey]float64),
 	}
 }
@@ -228,6 +243,13 @@ func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.Met
 	return contextKey
 }
 
+// resetCardinalityWindow starts a new window for the cardinality limits.
+func (cr *timestampContextResolver) resetCardinalityWindow() {
+	if cr.resolver.limiter != nil {
+		cr.resolver.limiter.reset()
+	}
+}
+
 func (cr *timestampContextResolver) length() int {
 	return cr.resolver.length()
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	agentruntime "github.com/DataDog/datadog-agent/pkg/runtime"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
@@ -93,6 +94,7 @@ func createIterableMetrics(
 	serializer serializer.MetricSerializer,
 	logPayloads bool,
 	isServerless bool,
+	exposition *openmetrics.Exposition,
 ) (*metrics.IterableSeries, *metrics.IterableSketches) {
 	var series *metrics.IterableSeries
 	var sketches *metrics.IterableSketches
@@ -103,6 +105,9 @@ func createIterableMetrics(
 				log.Debugf("Flushing serie: %s", se)
 			}
 			tagsetTlm.updateHugeSerieTelemetry(se)
+			if exposition != nil {
+				exposition.AddSerie(se)
+			}
 		}, flushAndSerializeInParallel.BufferSize, flushAndSerializeInParallel.ChannelSize)
 	}
 
@@ -115,6 +120,9 @@ func createIterableMetrics(
 				log.DebugfServerless("Sending sketches payload : %s", sketch.String())
 			}
 			tagsetTlm.updateHugeSketchesTelemetry(sketch)
+			if exposition != nil {
+				exposition.AddSketch(sketch)
+			}
 		}, flushAndSerializeInParallel.BufferSize, flushAndSerializeInParallel.ChannelSize)
 	}
 	return series, sketches
@@ -9,12 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/log"
 	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
 	orchestratorforwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
@@ -78,6 +80,10 @@ type AgentDemultiplexerOptions struct {
 
 	UseDogstatsdContextLimiter bool
 	DogstatsdMaxMetricsTags    int
+
+	// EnableOpenMetricsExposition serves the flushed metrics locally, only the running
+	// Agent enables it so that the one-shot commands don't compete for its port.
+	EnableOpenMetricsExposition bool
 }
 
 // DefaultAgentDemultiplexerOptions returns the default options to initialize an AgentDemultiplexer.
@@ -117,6 +123,10 @@ type dataOutputs struct {
 	forwarders       forwarders
 	sharedSerializer serializer.MetricSerializer
 	noAggSerializer  serializer.MetricSerializer
+
+	// exposition serves the flushed series and sketches locally in the OpenMetrics
+	// format, nil when disabled.
+	exposition *openmetrics.Exposition
 }
 
 // InitAndStartAgentDemultiplexer creates a new Demultiplexer and runs what's necessary
@@ -193,6 +203,16 @@ func initAgentDemultiplexer(log log.Component, sharedForwarder forwarder.Forward
 		)
 	}
 
+	var exposition *openmetrics.Exposition
+	if options.EnableOpenMetricsExposition {
+		exposition = openmetrics.NewExposition()
+		addr := net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("aggregator_openmetrics.port"))
+		if err := exposition.Start(addr); err != nil {
+			log.Errorf("Could not start the OpenMetrics exposition of the flushed metrics: %v", err)
+			exposition = nil
+		}
+	}
+
 	// --
 
 	demux := &AgentDemultiplexer{
@@ -214,6 +234,7 @@ func initAgentDemultiplexer(log log.Component, sharedForwarder forwarder.Forward
 
 			sharedSerializer: sharedSerializer,
 			noAggSerializer:  noAggSerializer,
+			exposition:       exposition,
 		},
 
 		senders: newSenders(agg),
@@ -380,6 +401,11 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 	}
 	d.aggregator = nil
 
+	if d.dataOutputs.exposition != nil {
+		d.dataOutputs.exposition.Stop()
+		d.dataOutputs.exposition = nil
+	}
+
 	// forwarders
 
 	if !d.options.DontStartForwarders {
@@ -443,7 +469,7 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 	}
 
 	logPayloads := config.Datadog.GetBool("log_payloads")
-	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false)
+	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false, d.dataOutputs.exposition)
 
 	metrics.Serialize(
 		series,
@@ -498,6 +524,11 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 			}
 		})
 
+	if d.dataOutputs.exposition != nil {
+		// the series and sketches are all appended to the sinks once Serialize returns
+		d.dataOutputs.exposition.Commit()
+	}
+
 	addFlushTime("MainFlushTime", int64(time.Since(start)))
 	aggregatorNumberOfFlush.Add(1)
 }
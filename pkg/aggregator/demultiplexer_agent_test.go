@@ -9,6 +9,8 @@ package aggregator
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"testing"
 	"time"
 
@@ -19,7 +21,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 	"github.com/DataDog/datadog-agent/pkg/util/optional"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
 )
 
 //nolint:revive // TODO(AML) Fix revive linter
@@ -142,3 +146,38 @@ func TestMetricSampleTypeConversion(t *testing.T) {
 		require.Equal(test.apiMetricType, rv, fmt.Sprintf("Wrong conversion for %s", test.metricType.String()))
 	}
 }
+
+func TestDemuxOpenMetricsExposition(t *testing.T) {
+	require := require.New(t)
+
+	deps := fxutil.Test[TestDeps](t, defaultforwarder.MockModule(), config.MockModule(), logimpl.MockModule(),
+		fx.Replace(config.MockParams{Overrides: map[string]interface{}{
+			"aggregator_openmetrics.port": 0,
+			"dogstatsd_pipeline_count":    1,
+		}}))
+	opts := demuxTestOptions()
+	opts.EnableOpenMetricsExposition = true
+	demux := InitAndStartAgentDemultiplexerForTest(deps, opts, "")
+	defer demux.Stop(false)
+	require.NotNil(demux.dataOutputs.exposition)
+
+	s := &MockSerializerIterableSerie{}
+	s.On("SendServiceChecks", mock.Anything).Return(nil)
+	demux.aggregator.serializer = s
+	demux.sharedSerializer = s
+
+	demux.AggregateSample(metrics.MetricSample{Name: "my.metric", Value: 2, Mtype: metrics.GaugeType, Tags: []string{"env:prod"}, Timestamp: 10})
+	// AggregateSample is async, wait for the sample to be processed by the sampler
+	time.Sleep(1 * time.Second)
+	demux.ForceFlushToSerializer(time.Unix(30, 0), true)
+
+	resp, err := http.Get("http://" + demux.dataOutputs.exposition.Addr() + "/metrics")
+	require.NoError(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	require.Contains(string(body), "# TYPE my_metric gauge\n")
+	require.Contains(string(body), `my_metric{env="prod"} 2 10`+"\n")
+	// the series are still sent to the serializer
+	require.NotEmpty(s.series)
+}
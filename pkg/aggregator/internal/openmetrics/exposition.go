@@ -0,0 +1,342 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics exposes the series and sketches flushed by the aggregator in
+// the OpenMetrics text format, on a local HTTP endpoint.
+package openmetrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MetricsPath is the path of the endpoint serving the last flush
+const MetricsPath = "/metrics"
+
+// ContentType is the content type of the OpenMetrics text format
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+const (
+	familyGauge   = "gauge"
+	familySummary = "summary"
+)
+
+// sketchQuantiles are the quantiles of the sketches exposed in the summaries, 0 and 1
+// being their minimum and maximum.
+var sketchQuantiles = []float64{0, 0.5, 0.75, 0.9, 0.95, 0.99, 1}
+
+// family is a metric family, with the rendered samples of each of its label sets
+type family struct {
+	typ     string
+	help    string
+	samples map[string]string
+}
+
+// Exposition records the series and sketches of a flush, and serves those of the last
+// complete flush. Safe for concurrent use.
+type Exposition struct {
+	mu      sync.Mutex
+	current map[string]*family
+	// samples maps the names of the samples of the current flush to their family
+	samples  map[string]string
+	dropped  int
+	rendered []byte
+
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewExposition returns an exposition serving an empty flush until the first flush is
+// committed.
+func NewExposition() *Exposition {
+	e := &Exposition{
+		current:  make(map[string]*family),
+		samples:  make(map[string]string),
+		rendered: []byte("# EOF\n"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(MetricsPath, e.ServeHTTP)
+	e.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return e
+}
+
+// Start starts serving the exposition on the given address.
+func (e *Exposition) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("can't listen on %s: %w", addr, err)
+	}
+	e.listener = listener
+	log.Infof("Serving the flushed metrics in the OpenMetrics format on http://%s%s", listener.Addr(), MetricsPath)
+
+	go func() {
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("OpenMetrics exposition server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the exposition is served on.
+func (e *Exposition) Addr() string {
+	return e.listener.Addr().String()
+}
+
+// Stop stops serving the exposition.
+func (e *Exposition) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.server.Shutdown(ctx); err != nil {
+		log.Warnf("Error while stopping the OpenMetrics exposition server: %v", err)
+	}
+}
+
+// ServeHTTP serves the last committed flush.
+func (e *Exposition) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	e.mu.Lock()
+	rendered := e.rendered
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(rendered) //nolint:errcheck
+}
+
+// AddSerie records a flushed serie. The counts are exposed with the sum of their points,
+// the gauges and rates with their last point.
+func (e *Exposition) AddSerie(serie *metrics.Serie) {
+	if len(serie.Points) == 0 {
+		return
+	}
+	value, ts := serie.Points[0].Value, serie.Points[0].Ts
+	for _, p := range serie.Points[1:] {
+		if serie.MType == metrics.APICountType {
+			value += p.Value
+		} else if p.Ts >= ts {
+			value = p.Value
+		}
+		if p.Ts > ts {
+			ts = p.Ts
+		}
+	}
+
+	name := sanitizeName(serie.Name)
+	labels := renderLabels(serie.Tags, serie.Host, "")
+	sample := name + labels + " " + formatFloat(value) + " " + formatFloat(ts) + "\n"
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if f := e.family(name, familyGauge, "Datadog "+serie.MType.String()); f != nil {
+		f.samples[labels] = sample
+	}
+}
+
+// AddSketch records a flushed sketch, exposed as a summary of the merge of its points.
+func (e *Exposition) AddSketch(sketch *metrics.SketchSeries) {
+	if len(sketch.Points) == 0 {
+		return
+	}
+	merged := &quantile.Sketch{}
+	var ts int64
+	for _, p := range sketch.Points {
+		if p.Sketch != nil {
+			merged.Merge(quantile.Default(), p.Sketch)
+		}
+		if p.Ts > ts {
+			ts = p.Ts
+		}
+	}
+
+	name := sanitizeName(sketch.Name)
+	timestamp := strconv.FormatInt(ts, 10)
+	var b strings.Builder
+	for _, q := range sketchQuantiles {
+		value := merged.Quantile(quantile.Default(), q)
+		b.WriteString(name + renderLabels(sketch.Tags, sketch.Host, formatFloat(q)) + " " + formatFloat(value) + " " + timestamp + "\n")
+	}
+	labels := renderLabels(sketch.Tags, sketch.Host, "")
+	b.WriteString(name + "_count" + labels + " " + strconv.FormatInt(merged.Basic.Cnt, 10) + " " + timestamp + "\n")
+	b.WriteString(name + "_sum" + labels + " " + formatFloat(merged.Basic.Sum) + " " + timestamp + "\n")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if f := e.family(name, familySummary, "Datadog distribution"); f != nil {
+		f.samples[labels] = b.String()
+	}
+}
+
+// family returns the family of the current flush with the given name, it returns nil
+// if the name, or the name of one of its samples, is already used by a family of another
+// type: a gauge named `foo_count` conflicts with a summary named `foo`.
+func (e *Exposition) family(name string, typ string, help string) *family {
+	f, ok := e.current[name]
+	if ok {
+		if f.typ != typ {
+			e.dropped++
+			return nil
+		}
+		return f
+	}
+	sampleNames := familySampleNames(name, typ)
+	for _, n := range sampleNames {
+		if _, ok := e.samples[n]; ok {
+			e.dropped++
+			return nil
+		}
+	}
+	for _, n := range sampleNames {
+		e.samples[n] = name
+	}
+	f = &family{typ: typ, help: help, samples: make(map[string]string)}
+	e.current[name] = f
+	return f
+}
+
+// familySampleNames returns the names of the samples of a family.
+func familySampleNames(name string, typ string) []string {
+	if typ == familySummary {
+		return []string{name, name + "_count", name + "_sum"}
+	}
+	return []string{name}
+}
+
+// Commit renders the series and sketches recorded since the previous commit, which are
+// then served until the next commit.
+func (e *Exposition) Commit() {
+	e.mu.Lock()
+	current, dropped := e.current, e.dropped
+	e.current, e.dropped = make(map[string]*family, len(current)), 0
+	e.samples = make(map[string]string, len(e.samples))
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		log.Debugf("%d series and sketches not exposed in the OpenMetrics format because of a metric name conflict", dropped)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		f := current[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n# HELP %s %s\n", name, f.typ, name, f.help)
+		labels := make([]string, 0, len(f.samples))
+		for l := range f.samples {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			b.WriteString(f.samples[l])
+		}
+	}
+	b.WriteString("# EOF\n")
+
+	e.mu.Lock()
+	e.rendered = b.Bytes()
+	e.mu.Unlock()
+}
+
+// renderLabels renders the tags and host as labels, with the given quantile label if
+// not empty. The values of the tags sharing a key are joined with commas, and the tags
+// without value are exposed with the value `true`.
+func renderLabels(tags tagset.CompositeTags, host string, quantileLabel string) string {
+	values := make(map[string][]string, tags.Len()+1)
+	tags.ForEach(func(tag string) {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			value = "true"
+		}
+		key = sanitizeLabelName(key)
+		values[key] = append(values[key], value)
+	})
+	if _, ok := values["host"]; !ok && host != "" {
+		values["host"] = []string{host}
+	}
+	if quantileLabel != "" {
+		values["quantile"] = []string{quantileLabel}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(strings.Join(values[key], ",")))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sanitizeName replaces the characters not allowed in the metric names, such as the
+// dots, with underscores.
+func sanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in the label names with underscores.
+func sanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+func sanitize(name string, allowColons bool) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (allowColons && c == ':') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
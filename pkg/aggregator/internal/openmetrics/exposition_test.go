@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func scrape(t *testing.T, e *Exposition) string {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+func TestSeries(t *testing.T) {
+	e := NewExposition()
+	assert.Equal(t, "# EOF\n", scrape(t, e))
+
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:a", "role:b", "canary"}),
+		Host:   "my-host",
+		MType:  metrics.APIGaugeType,
+	})
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.count",
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{`path:C:\dir "quoted"`, "1st-key:v"}),
+		MType:  metrics.APICountType,
+	})
+	e.AddSerie(&metrics.Serie{
+		Name:   "no.points",
+		Points: nil,
+		MType:  metrics.APIGaugeType,
+	})
+
+	// nothing is served before the end of the flush
+	assert.Equal(t, "# EOF\n", scrape(t, e))
+
+	e.Commit()
+	expected := `# TYPE my_count gauge
+# HELP my_count Datadog count
+my_count{_st_key="v",path="C:\\dir \"quoted\""} 3 20
+# TYPE my_gauge gauge
+# HELP my_gauge Datadog gauge
+my_gauge{canary="true",env="prod",host="my-host",role="a,b"} 2 20
+# EOF
+`
+	assert.Equal(t, expected, scrape(t, e))
+
+	// the next flush replaces the previous one
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.rate",
+		Points: []metrics.Point{{Ts: 30, Value: 0.5}},
+		MType:  metrics.APIRateType,
+	})
+	assert.Equal(t, expected, scrape(t, e))
+	e.Commit()
+	assert.Equal(t, `# TYPE my_rate gauge
+# HELP my_rate Datadog rate
+my_rate 0.5 30
+# EOF
+`, scrape(t, e))
+}
+
+func TestSketches(t *testing.T) {
+	e := NewExposition()
+
+	s1, s2 := &quantile.Sketch{}, &quantile.Sketch{}
+	s1.Insert(quantile.Default(), 1, 2, 3)
+	s2.Insert(quantile.Default(), 4)
+	e.AddSketch(&metrics.SketchSeries{
+		Name:   "my.distribution",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:   "my-host",
+		Points: []metrics.SketchPoint{{Ts: 10, Sketch: s1}, {Ts: 20, Sketch: s2}},
+	})
+	// a gauge can't share the name of a summary
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.distribution",
+		Points: []metrics.Point{{Ts: 10, Value: 1}},
+		MType:  metrics.APIGaugeType,
+	})
+	e.Commit()
+
+	merged := s1.Copy()
+	merged.Merge(quantile.Default(), s2)
+	labels := `env="prod",host="my-host"`
+	expected := "# TYPE my_distribution summary\n# HELP my_distribution Datadog distribution\n"
+	for _, q := range sketchQuantiles {
+		expected += "my_distribution{" + labels + `,quantile="` + formatFloat(q) + `"} ` + formatFloat(merged.Quantile(quantile.Default(), q)) + " 20\n"
+	}
+	expected += "my_distribution_count{" + labels + "} 4 20\n"
+	expected += "my_distribution_sum{" + labels + "} 10 20\n"
+	expected += "# EOF\n"
+	assert.Equal(t, expected, scrape(t, e))
+}
+
+func TestSketchSampleNameConflicts(t *testing.T) {
+	e := NewExposition()
+
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1)
+	gauge := func(name string) *metrics.Serie {
+		return &metrics.Serie{Name: name, Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType}
+	}
+	// the summaries can't use the names of the samples of the existing families
+	e.AddSerie(gauge("a.count"))
+	e.AddSketch(&metrics.SketchSeries{Name: "a", Points: []metrics.SketchPoint{{Ts: 10, Sketch: sketch}}})
+	// and the gauges can't use the names of the samples of the existing summaries
+	e.AddSketch(&metrics.SketchSeries{Name: "b", Points: []metrics.SketchPoint{{Ts: 10, Sketch: sketch}}})
+	e.AddSerie(gauge("b.sum"))
+	e.Commit()
+
+	body := scrape(t, e)
+	assert.Contains(t, body, "# TYPE a_count gauge\n")
+	assert.NotContains(t, body, "# TYPE a summary\n")
+	assert.Contains(t, body, "# TYPE b summary\n")
+	assert.NotContains(t, body, "# TYPE b_sum gauge\n")
+	assert.Equal(t, 1, strings.Count(body, "b_sum "))
+
+	// the names are released at each flush
+	e.AddSketch(&metrics.SketchSeries{Name: "a", Points: []metrics.SketchPoint{{Ts: 20, Sketch: sketch}}})
+	e.Commit()
+	assert.Contains(t, scrape(t, e), "# TYPE a summary\n")
+}
+
+func TestServer(t *testing.T) {
+	e := NewExposition()
+	require.NoError(t, e.Start("127.0.0.1:0"))
+	defer e.Stop()
+
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 10, Value: 1}},
+		MType:  metrics.APIGaugeType,
+	})
+	e.Commit()
+
+	resp, err := http.Get("http://" + e.Addr() + MetricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "my_gauge 1 10\n")
+
+	resp, err = http.Post("http://"+e.Addr()+MetricsPath, "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -55,7 +55,7 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostnam
 
 	s := &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newTimestampContextResolver(cache, idString),
+		contextResolver:             newTimestampContextResolver(cache, idString, newCardinalityLimiter(config.Datadog.GetInt("dogstatsd_cardinality_limit.per_metric"), config.Datadog.GetInt("dogstatsd_cardinality_limit.per_origin"))),
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
@@ -241,6 +241,7 @@ func (s *TimeSampler) flush(timestamp float64, series metrics.SerieSink, sketche
 			return ok
 		})
 	s.lastCutOffTime = cutoffTime
+	s.contextResolver.resetCardinalityWindow()
 
 	s.updateMetrics()
 	s.sendTelemetry(timestamp, series)
@@ -45,7 +45,7 @@ func NewLogReporter(hostname string, sourceName, sourceType, runPath string, end
 	auditor.Start()
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, dstcontext)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, dstcontext, nil)
 	pipelineProvider.Start()
 
 	logSource := sources.NewLogSource(
@@ -188,6 +188,8 @@ type (
 	Listeners = pkgconfigsetup.Listeners
 	// MappingProfile Alias
 	MappingProfile = pkgconfigsetup.MappingProfile
+	// TagRules Alias
+	TagRules = pkgconfigsetup.TagRules
 	// Endpoint Alias
 	Endpoint = pkgconfigsetup.Endpoint
 )
@@ -164,6 +164,22 @@ type MappingProfile struct {
 	Name     string          `mapstructure:"name" json:"name" yaml:"name"`
 	Prefix   string          `mapstructure:"prefix" json:"prefix" yaml:"prefix"`
 	Mappings []MetricMapping `mapstructure:"mappings" json:"mappings" yaml:"mappings"`
+	TagRules TagRules        `mapstructure:"tag_rules" json:"tag_rules" yaml:"tag_rules"`
+}
+
+// TagRules represent the rules rewriting the tags of the metrics matching a mapping profile
+type TagRules struct {
+	Drop     []string          `mapstructure:"drop" json:"drop" yaml:"drop"`
+	KeepOnly []string          `mapstructure:"keep_only" json:"keep_only" yaml:"keep_only"`
+	Rename   map[string]string `mapstructure:"rename" json:"rename" yaml:"rename"`
+	Rewrite  []TagRewrite      `mapstructure:"rewrite" json:"rewrite" yaml:"rewrite"`
+}
+
+// TagRewrite represent one rule rewriting the value of a tag
+type TagRewrite struct {
+	Key     string `mapstructure:"key" json:"key" yaml:"key"`
+	Match   string `mapstructure:"match" json:"match" yaml:"match"`
+	Replace string `mapstructure:"replace" json:"replace" yaml:"replace"`
 }
 
 // MetricMapping represent one mapping rule
@@ -431,6 +447,8 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+	config.BindEnvAndSetDefault("aggregator_openmetrics.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_openmetrics.port", 9199)
 
 	// Serializer
 	config.BindEnvAndSetDefault("enable_stream_payload_serialization", true)
@@ -445,6 +463,8 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("serializer_max_series_points_per_payload", 10000)
 	config.BindEnvAndSetDefault("serializer_max_series_payload_size", 512000)
 	config.BindEnvAndSetDefault("serializer_max_series_uncompressed_payload_size", 5242880)
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "zlib")
+	config.BindEnvAndSetDefault("serializer_zstd_compressor_level", 1)
 
 	config.BindEnvAndSetDefault("use_v2_api.series", true)
 	// Serializer: allow user to blacklist any kind of payload to be sent
@@ -469,6 +489,33 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("forwarder_backoff_max", 64)
 	config.BindEnvAndSetDefault("forwarder_recovery_interval", DefaultForwarderRecoveryInterval)
 	config.BindEnvAndSetDefault("forwarder_recovery_reset", false)
+	// Forwarder failover settings
+	config.BindEnvAndSetDefault("forwarder_failover.enabled", false)
+	config.BindEnvAndSetDefault("forwarder_failover.domains", []string{})
+	config.BindEnvAndSetDefault("forwarder_failover.error_budget", 10)
+	config.BindEnvAndSetDefault("forwarder_failover.probe_interval", 30) // in seconds
+	// Forwarder budgets per payload type, 0 means unlimited
+	config.BindEnvAndSetDefault("forwarder_budgets.series.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.series.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.series.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.sketches.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.sketches.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.sketches.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.check_runs.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.check_runs.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.check_runs.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.events.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.events.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.events.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.metadata.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.metadata.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.metadata.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.process.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.process.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.process.high_priority", false)
+	config.BindEnvAndSetDefault("forwarder_budgets.orchestrator.bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.orchestrator.requests_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_budgets.orchestrator.high_priority", false)
 
 	// Forwarder storage on disk
 	config.BindEnvAndSetDefault("forwarder_storage_path", "")
@@ -504,6 +551,13 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", "")        // Notice: empty means feature disabled
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "") // Experimental || Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)       // Notice: 0 means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "newline")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.client_ca_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_graphite_port", 0) // Notice: 0 means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_influx_port", 0)   // Notice: 0 means feature disabled
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -519,6 +573,9 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Limit the number of contexts per metric name and per origin in each flush window.
+	config.BindEnvAndSetDefault("dogstatsd_cardinality_limit.per_metric", 0)
+	config.BindEnvAndSetDefault("dogstatsd_cardinality_limit.per_origin", 0)
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -541,6 +598,16 @@ func InitConfig(config pkgconfigmodel.Config) {
 	// How many metrics maximum in payloads sent by the no-aggregation pipeline to the intake.
 	config.BindEnvAndSetDefault("dogstatsd_no_aggregation_pipeline_batch_size", 2048)
 
+	// Prometheus remote write receiver
+	config.BindEnvAndSetDefault("prometheus_remote_write.enabled", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.port", 9201)
+	config.BindEnvAndSetDefault("prometheus_remote_write.namespace", "")
+	config.BindEnvAndSetDefault("prometheus_remote_write.counter_suffixes", []string{"_total", "_count", "_sum", "_bucket"})
+	config.BindEnvAndSetDefault("prometheus_remote_write.labels_as_tags", map[string]string{})
+	config.BindEnvAndSetDefault("prometheus_remote_write.exclude_labels", []string{})
+	config.BindEnvAndSetDefault("prometheus_remote_write.max_request_size", 32*1024*1024)
+	config.BindEnvAndSetDefault("prometheus_remote_write.counter_expiry_seconds", 600)
+
 	// To enable the following feature, GODEBUG must contain `madvdontneed=1`
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.enabled", false)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.low_soft_limit", 0.7)
@@ -864,8 +931,12 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("logs_config.use_port_443", false)
 	// increase the read buffer size of the UDP sockets:
 	config.BindEnvAndSetDefault("logs_config.frame_size", 9000)
+	// maximum size in bytes of the entries sent by the Fluent Forward clients:
+	config.BindEnvAndSetDefault("logs_config.fluent_forward_max_entry_size", 16*1024*1024)
 	// maximum log message size in bytes
 	config.BindEnvAndSetDefault("logs_config.max_message_size_bytes", DefaultMaxMessageSizeBytes)
+	// allow the `command` logs sources to run their command:
+	config.BindEnvAndSetDefault("logs_config.command_sources_enabled", false)
 
 	// increase the number of files that can be tailed in parallel:
 	if runtime.GOOS == "darwin" {
@@ -879,6 +950,8 @@ func InitConfig(config pkgconfigmodel.Config) {
 	}
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules")
+	// send the logs matching routing rules to additional endpoints
+	config.BindEnv("logs_config.routes")
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// Enable the agent to use files to collect container logs on standalone docker environment, containers
@@ -942,6 +1015,13 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("logs_config.docker_path_override", "")
 
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// On-disk buffer used to store logs payloads while all the reliable endpoints are unreachable.
+	// The payloads are replayed once an endpoint recovers. The default path is `<run_path>/buffer`.
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_enabled", false)
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size_bytes", 2*1024*1024*1024) // 2GB
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_disk_ratio", 0.80)
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_outdated_file_in_days", 10)
 	// Timeout in milliseonds used when performing agreggation operations,
 	// including multi-line log processing rules and chunked line reaggregation.
 	// It may be useful to increase it when logs writing is slowed down, that
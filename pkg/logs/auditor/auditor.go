@@ -33,6 +33,8 @@ const registryAPIVersion = 2
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	// KeepAlive prevents the entry of the given identifier from expiring.
+	KeepAlive(identifier string)
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -152,6 +154,16 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// KeepAlive refreshes the entry of the given identifier if it exists, so that
+// it is not removed from the registry once the TTL is reached.
+func (a *RegistryAuditor) KeepAlive(identifier string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.LastUpdated = time.Now().UTC()
+	}
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
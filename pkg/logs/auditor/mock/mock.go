@@ -10,11 +10,13 @@ package mock
 type Registry struct {
 	offset      string
 	tailingMode string
+	// KeptAlive holds the identifiers passed to KeepAlive.
+	KeptAlive map[string]int
 }
 
 // NewRegistry returns a new registry.
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{KeptAlive: make(map[string]int)}
 }
 
 // GetOffset returns the offset.
@@ -36,3 +38,8 @@ func (r *Registry) GetTailingMode(identifier string) string { //nolint:revive //
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// KeepAlive records the identifier.
+func (r *Registry) KeepAlive(identifier string) {
+	r.KeptAlive[identifier]++
+}
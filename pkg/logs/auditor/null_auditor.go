@@ -36,6 +36,11 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// KeepAlive does nothing.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (a *NullAuditor) KeepAlive(identifier string) {}
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
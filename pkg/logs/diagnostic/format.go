@@ -8,7 +8,6 @@ package diagnostic
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/util/hostname"
@@ -30,12 +29,7 @@ func (l *logFormatter) Format(m *message.Message, eventType string, redactedMsg
 		hname = "unknown"
 	}
 
-	ts := time.Now().UTC()
-	// TODO(remy): should we consider renaming the "Timestamp: %s" to mention
-	// it's only concerning the serverless agent?
-	if !m.ServerlessExtra.Timestamp.IsZero() {
-		ts = m.ServerlessExtra.Timestamp
-	}
+	ts := m.GetTimestamp()
 
 	return fmt.Sprintf("Integration Name: %s | Type: %s | Status: %s | Timestamp: %s | Hostname: %s | Service: %s | Source: %s | Tags: %s | Message: %s\n",
 		m.Origin.LogSource.Name,
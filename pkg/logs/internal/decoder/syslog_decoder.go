@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// NewSyslogDecoder returns a decoder breaking a stream of syslog messages into structured messages.
+// As each syslog frame holds a complete message, the frames are never aggregated by the
+// multi-line handlers.
+func NewSyslogDecoder() *Decoder {
+	inputChan := make(chan *message.Message)
+	outputChan := make(chan *message.Message)
+	lineLimit := config.MaxMessageSizeBytes(pkgConfig.Datadog)
+
+	outputFn := func(m *message.Message) { outputChan <- m }
+	lineHandler := NewSingleLineHandler(outputFn, lineLimit)
+	lineParser := NewSingleLineParser(lineHandler.process, syslog.New())
+	framer := framer.NewFramer(lineParser.process, framer.SyslogStream, lineLimit)
+
+	return New(inputChan, outputChan, framer, lineParser, lineHandler, &DetectedPattern{})
+}
@@ -42,6 +42,10 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog messages framed with either octet-counting or a newline trailer,
+	// as described in RFC6587.  The result does not include the framing data.
+	SyslogStream
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -86,6 +90,7 @@ func NewFramer(
 	contentLenLimit int,
 ) *Framer {
 	var matcher FrameMatcher
+	frameLenLimit := contentLenLimit
 	switch framing {
 	case UTF8Newline:
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
@@ -99,6 +104,11 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case SyslogStream:
+		matcher = &syslogMatcher{contentLenLimit: contentLenLimit}
+		// leave room for the header of octet-counted frames, the matcher
+		// limits the content length itself
+		frameLenLimit += maxSyslogHeaderLen
 	case NoFraming:
 		matcher = &noFramingMatcher{}
 	default:
@@ -111,7 +121,7 @@ func NewFramer(
 		matcher:         matcher,
 		buffer:          bytes.Buffer{},
 		bytesFramed:     0,
-		contentLenLimit: contentLenLimit,
+		contentLenLimit: frameLenLimit,
 	}
 }
 
@@ -158,6 +168,12 @@ func (fr *Framer) Process(input *message.Message) {
 		buf := fr.buffer.Bytes()[framed:]
 
 		content, rawDataLen := fr.matcher.FindFrame(buf, seen-framed)
+		if content == nil && rawDataLen > 0 {
+			// the matcher discarded bytes which are not part of any frame
+			framed += rawDataLen
+			seen = framed
+			continue
+		}
 		if content == nil {
 			// if the matcher was asked to match more than contentLenLimit,
 			// chop off contentLenLimit raw bytes and output them
@@ -182,6 +198,7 @@ func (fr *Framer) Process(input *message.Message) {
 			Origin:             input.Origin,
 			Status:             input.Status,
 			IngestionTimestamp: input.IngestionTimestamp,
+			EventTimestamp:     input.EventTimestamp,
 			ParsingExtra:       input.ParsingExtra,
 			ServerlessExtra:    input.ServerlessExtra,
 		}
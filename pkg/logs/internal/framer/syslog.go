@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import "bytes"
+
+// maxSyslogMsgLenDigits is the maximum number of digits accepted for the
+// MSG-LEN of an octet-counted frame, longer lengths are not considered
+// as octet-counting.
+const maxSyslogMsgLenDigits = 9
+
+// maxSyslogHeaderLen is the maximum length of the `MSG-LEN SP` header of an
+// octet-counted frame.
+const maxSyslogHeaderLen = maxSyslogMsgLenDigits + 1
+
+// syslogMatcher implements EndLineMatcher for syslog messages transported over
+// a stream, as described in RFC6587.  Each frame is either:
+//
+//   - octet-counted: `MSG-LEN SP SYSLOG-MSG`, where MSG-LEN is the length in
+//     bytes of SYSLOG-MSG, or
+//   - non-transparent: `SYSLOG-MSG TRAILER`, where TRAILER is a newline or a
+//     NUL byte.
+//
+// The method is detected for each frame, as senders are allowed to mix them: a
+// frame starting with a digit is octet-counted, any other frame is
+// non-transparent.  The returned content does not contain the framing data.
+//
+// Octet-counted frames longer than contentLenLimit are truncated, the remaining
+// bytes of the frame are discarded as they arrive so that the next frame is found
+// where the sender put it.
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Non-transparent lines longer than this value will be split into multiple
+	// frames, octet-counted frames longer than this value are truncated.
+	contentLenLimit int
+
+	// discard is the number of bytes of a truncated octet-counted frame that
+	// have not been received yet.
+	discard int
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (s *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if s.discard > 0 {
+		skipped := s.discard
+		if skipped > len(buf) {
+			skipped = len(buf)
+		}
+		s.discard -= skipped
+		return nil, skipped
+	}
+
+	if len(buf) > 0 && isDigit(buf[0]) {
+		msgLen, headerLen, valid := parseOctetCount(buf)
+		if valid {
+			end := headerLen + msgLen
+			switch {
+			case headerLen == 0:
+				// the header is not complete yet
+				return nil, 0
+			case msgLen > s.contentLenLimit:
+				if len(buf) < headerLen+s.contentLenLimit {
+					return nil, 0
+				}
+				consumed := end
+				if consumed > len(buf) {
+					consumed = len(buf)
+				}
+				s.discard = end - consumed
+				return buf[headerLen : headerLen+s.contentLenLimit], consumed
+			case end > len(buf):
+				// the message is not complete yet
+				return nil, 0
+			}
+			return buf[headerLen:end], end
+		}
+	}
+	return s.findNonTransparentFrame(buf, seen)
+}
+
+// parseOctetCount parses the `MSG-LEN SP` header of an octet-counted frame.  headerLen
+// is 0 if the header is not complete yet, valid is false if buf does not start
+// with such a header.
+func parseOctetCount(buf []byte) (msgLen int, headerLen int, valid bool) {
+	for i, c := range buf {
+		switch {
+		case c == ' ':
+			return msgLen, i + 1, true
+		case !isDigit(c) || i >= maxSyslogMsgLenDigits:
+			return 0, 0, false
+		}
+		msgLen = msgLen*10 + int(c-'0')
+	}
+	return 0, 0, true
+}
+
+// findNonTransparentFrame returns the frame terminated by a newline or a NUL byte.
+func (s *syslogMatcher) findNonTransparentFrame(buf []byte, seen int) ([]byte, int) {
+	end := bytes.IndexAny(buf[seen:], "\n\x00")
+	if end == -1 {
+		if len(buf) >= s.contentLenLimit {
+			// the framer lets octet-counted headers exceed contentLenLimit, the
+			// line has to be split here
+			return buf[:s.contentLenLimit], s.contentLenLimit
+		}
+		return nil, 0
+	}
+
+	// limit the returned line to contentLenLimit bytes
+	eol := end + seen
+	if eol > s.contentLenLimit {
+		return buf[:s.contentLenLimit], s.contentLenLimit
+	}
+	return buf[:eol], eol + 1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func syslogFrames(t *testing.T, input []byte, chunkSize int, limit int) ([]string, []int) {
+	t.Helper()
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(msg *message.Message, rawDataLen int) {
+		gotContent = append(gotContent, string(msg.GetContent()))
+		gotLens = append(gotLens, rawDataLen)
+	}
+	fr := NewFramer(outputFn, SyslogStream, limit)
+	for len(input) > 0 {
+		n := chunkSize
+		if n > len(input) {
+			n = len(input)
+		}
+		fr.Process(message.NewMessage(input[:n], nil, "", 0))
+		input = input[n:]
+	}
+	return gotContent, gotLens
+}
+
+func TestSyslogFraming(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		lines []string
+		lens  []int
+	}{
+		{
+			name:  "octet-counting",
+			input: "11 <34>1 - - a12 <34>1 - - bc",
+			lines: []string{"<34>1 - - a", "<34>1 - - bc"},
+			lens:  []int{14, 15},
+		},
+		{
+			name:  "octet-counting with newlines in the message",
+			input: "13 <34>1 - - a\nb",
+			lines: []string{"<34>1 - - a\nb"},
+			lens:  []int{16},
+		},
+		{
+			name:  "non-transparent",
+			input: "<34>1 - - a\n<34>1 - - bc\x00",
+			lines: []string{"<34>1 - - a", "<34>1 - - bc"},
+			lens:  []int{12, 13},
+		},
+		{
+			name:  "mixed",
+			input: "11 <34>1 - - a<34>1 - - bc\n",
+			lines: []string{"<34>1 - - a", "<34>1 - - bc"},
+			lens:  []int{14, 13},
+		},
+		{
+			name:  "digits not followed by a space",
+			input: "123abc\n",
+			lines: []string{"123abc"},
+			lens:  []int{7},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, chunkSize := range []int{len(test.input), 5, 1} {
+				lines, lens := syslogFrames(t, []byte(test.input), chunkSize, contentLenLimit)
+				assert.Equal(t, test.lines, lines, "chunk size %d", chunkSize)
+				assert.Equal(t, test.lens, lens, "chunk size %d", chunkSize)
+			}
+		})
+	}
+}
+
+func TestSyslogFramingIncompleteFrame(t *testing.T) {
+	lines, _ := syslogFrames(t, []byte("20 <34>1 - - a"), 100, contentLenLimit)
+	assert.Empty(t, lines)
+
+	lines, _ = syslogFrames(t, []byte("<34>1 - - a"), 100, contentLenLimit)
+	assert.Empty(t, lines)
+}
+
+func TestSyslogFramingContentLenLimit(t *testing.T) {
+	lines, lens := syslogFrames(t, []byte("10 0123456789"), 100, 4)
+	assert.Equal(t, []string{"0123"}, lines)
+	assert.Equal(t, []int{13}, lens)
+}
+
+func TestSyslogFramingOversizedFrameInChunks(t *testing.T) {
+	input := []byte("20 01234567890123456789<34>1 - - a\n5 abcde")
+	for _, chunkSize := range []int{len(input), 7, 3, 1} {
+		lines, lens := syslogFrames(t, input, chunkSize, 12)
+		// the remaining bytes of the oversized frame are discarded, the next frames are found
+		assert.Equal(t, []string{"012345678901", "<34>1 - - a", "abcde"}, lines, "chunk size %d", chunkSize)
+		assert.Equal(t, []int{12, 7}, lens[1:], "chunk size %d", chunkSize)
+	}
+}
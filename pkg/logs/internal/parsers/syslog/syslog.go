@@ -0,0 +1,288 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a Parser for syslog messages, in both the RFC5424
+// and the RFC3164 (BSD) formats.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue is the RFC5424 NILVALUE, used for unknown header fields.
+const nilValue = "-"
+
+// rfc3164TimestampLen is the length of an RFC3164 timestamp, e.g. `Oct 11 22:14:15`.
+const rfc3164TimestampLen = len(time.Stamp)
+
+// maxTagLen is the maximum length of an RFC3164 TAG, longer tags are left in the message.
+const maxTagLen = 48
+
+// bom is the UTF-8 byte order mark which can prefix the MSG part of RFC5424 messages.
+var bom = []byte{0xef, 0xbb, 0xbf}
+
+// severityStatuses maps the syslog severities to message statuses.
+var severityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+var errNoPriority = errors.New("cannot parse syslog message, no valid priority found")
+
+// New returns a new parser which parses syslog messages into structured messages.
+//
+// For example:
+//
+//	`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3"] An application event`
+//
+// returns a structured message with the status `notice` and the content:
+//
+//	{
+//	    "message": "An application event",
+//	    "syslog": {
+//	        "facility": 20,
+//	        "severity": 5,
+//	        "version": 1,
+//	        "timestamp": "2003-10-11T22:14:15.003Z",
+//	        "hostname": "mymachine.example.com",
+//	        "appname": "evntslog",
+//	        "procid": "1234",
+//	        "msgid": "ID47",
+//	        "structured_data": {"exampleSDID@32473": {"iut": "3"}}
+//	    }
+//	}
+//
+// Messages without any priority are left untouched.
+func New() parsers.Parser {
+	return &syslogFormat{}
+}
+
+type syslogFormat struct{}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	content := msg.GetContent()
+	pri, rest, ok := parsePriority(content)
+	if !ok {
+		msg.Status = message.StatusInfo
+		return msg, errNoPriority
+	}
+
+	attrs := map[string]interface{}{
+		"facility": pri / 8,
+		"severity": pri % 8,
+	}
+	var body []byte
+	if version, afterVersion, ok := parseVersion(rest); ok {
+		attrs["version"] = version
+		body = parseRFC5424(afterVersion, attrs)
+	} else {
+		body = parseRFC3164(rest, attrs)
+	}
+
+	structured := &message.BasicStructuredContent{
+		Data: map[string]interface{}{
+			"syslog": attrs,
+		},
+	}
+	msg.SetStructuredContent(structured)
+	msg.SetContent(body)
+	msg.Status = severityStatuses[pri%8]
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// parsePriority parses the `<PRI>` prefix of a message.
+func parsePriority(content []byte) (int, []byte, bool) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(content[:min(len(content), 5)], '>')
+	if end < 2 {
+		return 0, nil, false
+	}
+	pri, err := strconv.Atoi(string(content[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, false
+	}
+	return pri, content[end+1:], true
+}
+
+// parseVersion parses the RFC5424 `VERSION SP` which follows the priority,
+// RFC3164 messages don't have any.
+func parseVersion(content []byte) (int, []byte, bool) {
+	end := bytes.IndexByte(content, ' ')
+	if end < 1 || end > 2 {
+		return 0, nil, false
+	}
+	version, err := strconv.Atoi(string(content[:end]))
+	if err != nil || version < 1 {
+		return 0, nil, false
+	}
+	return version, content[end+1:], true
+}
+
+// parseRFC5424 parses `TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]`
+// into attrs and returns MSG.
+func parseRFC5424(content []byte, attrs map[string]interface{}) []byte {
+	for _, field := range []string{"timestamp", "hostname", "appname", "procid", "msgid"} {
+		var token []byte
+		token, content = nextToken(content)
+		if len(token) > 0 && string(token) != nilValue {
+			attrs[field] = string(token)
+		}
+	}
+
+	if len(content) > 0 && content[0] == '[' {
+		var sd map[string]map[string]string
+		sd, content = parseStructuredData(content)
+		if len(sd) > 0 {
+			attrs["structured_data"] = sd
+		}
+	} else {
+		content = bytes.TrimPrefix(content, []byte(nilValue))
+	}
+
+	if len(content) > 0 && content[0] == ' ' {
+		content = content[1:]
+	}
+	return bytes.TrimPrefix(content, bom)
+}
+
+// parseStructuredData parses the RFC5424 STRUCTURED-DATA, a list of `[SD-ID *(SP PARAM-NAME="PARAM-VALUE")]`
+// elements, and returns the remaining content.
+func parseStructuredData(content []byte) (map[string]map[string]string, []byte) {
+	sd := make(map[string]map[string]string)
+	i := 0
+	for i < len(content) && content[i] == '[' {
+		i++
+		start := i
+		for i < len(content) && content[i] != ' ' && content[i] != ']' {
+			i++
+		}
+		params := make(map[string]string)
+		sd[string(content[start:i])] = params
+
+		for i < len(content) && content[i] == ' ' {
+			i++
+			start = i
+			for i < len(content) && content[i] != '=' && content[i] != ']' {
+				i++
+			}
+			name := string(content[start:i])
+			if i+1 >= len(content) || content[i] != '=' || content[i+1] != '"' {
+				break
+			}
+			i += 2
+			var value []byte
+			for i < len(content) && content[i] != '"' {
+				// only '"', '\' and ']' are escaped, other backslashes are kept as is
+				if content[i] == '\\' && i+1 < len(content) && (content[i+1] == '"' || content[i+1] == '\\' || content[i+1] == ']') {
+					i++
+				}
+				value = append(value, content[i])
+				i++
+			}
+			i++ // skip the closing quote
+			params[name] = string(value)
+		}
+
+		// skip to the end of the element
+		for i < len(content) && content[i] != ']' {
+			i++
+		}
+		i++
+	}
+	if i > len(content) {
+		i = len(content)
+	}
+	return sd, content[i:]
+}
+
+// parseRFC3164 parses `[TIMESTAMP SP HOSTNAME SP] TAG[[PID]]: MSG` into attrs and returns MSG.
+// As RFC3164 only describes observed formats, the parsing is lenient and the content
+// that can't be parsed is left in the message.
+func parseRFC3164(content []byte, attrs map[string]interface{}) []byte {
+	if timestamp, rest, ok := parseRFC3164Timestamp(content); ok {
+		attrs["timestamp"] = timestamp
+		content = rest
+
+		// the hostname is only present after a timestamp, it can't be confused with the
+		// tag as the latter is followed by a colon or a pid.
+		if hostname, rest := nextToken(content); len(hostname) > 0 && len(rest) > 0 && bytes.IndexAny(hostname, ":[") == -1 {
+			attrs["hostname"] = string(hostname)
+			content = rest
+		}
+	}
+
+	// TAG is alphanumeric, and usually followed by `[PID]` and a colon
+	i := 0
+	for i < len(content) && i <= maxTagLen && content[i] != ':' && content[i] != '[' && content[i] != ' ' {
+		i++
+	}
+	if i == 0 || i >= len(content) {
+		return content
+	}
+	appname := string(content[:i])
+	var procid string
+	rest := content[i:]
+	if rest[0] == '[' {
+		end := bytes.IndexByte(rest, ']')
+		if end == -1 {
+			return content
+		}
+		procid = string(rest[1:end])
+		rest = rest[end+1:]
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return content
+	}
+
+	attrs["appname"] = appname
+	if procid != "" {
+		attrs["procid"] = procid
+	}
+	return bytes.TrimPrefix(rest[1:], []byte(" "))
+}
+
+// parseRFC3164Timestamp parses either a `Mmm dd hh:mm:ss` timestamp, or an RFC3339 timestamp
+// which is sent by some of the current implementations.
+func parseRFC3164Timestamp(content []byte) (string, []byte, bool) {
+	if len(content) > rfc3164TimestampLen && content[rfc3164TimestampLen] == ' ' {
+		if _, err := time.Parse(time.Stamp, string(content[:rfc3164TimestampLen])); err == nil {
+			return string(content[:rfc3164TimestampLen]), content[rfc3164TimestampLen+1:], true
+		}
+	}
+	token, rest := nextToken(content)
+	if _, err := time.Parse(time.RFC3339Nano, string(token)); err == nil && len(rest) > 0 {
+		return string(token), rest, true
+	}
+	return "", content, false
+}
+
+// nextToken returns the content until the next space and the content following this space.
+func nextToken(content []byte) ([]byte, []byte) {
+	end := bytes.IndexByte(content, ' ')
+	if end == -1 {
+		return content, nil
+	}
+	return content[:end], content[end+1:]
+}
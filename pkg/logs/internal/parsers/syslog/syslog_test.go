@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func parse(t *testing.T, content string) (*message.Message, map[string]interface{}) {
+	t.Helper()
+	msg, err := New().Parse(message.NewMessage([]byte(content), nil, "", 0))
+	require.NoError(t, err)
+	require.Equal(t, message.StateStructured, msg.State)
+	structured, ok := msg.GetStructuredContent().(*message.BasicStructuredContent)
+	require.True(t, ok)
+	return msg, structured.Data["syslog"].(map[string]interface{})
+}
+
+func TestParseRFC5424(t *testing.T) {
+	msg, attrs := parse(t, `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Appli\"cation"][examplePriority@32473 class="high"] An application event`)
+	assert.Equal(t, "An application event", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, map[string]interface{}{
+		"facility":  20,
+		"severity":  5,
+		"version":   1,
+		"timestamp": "2003-10-11T22:14:15.003Z",
+		"hostname":  "mymachine.example.com",
+		"appname":   "evntslog",
+		"procid":    "1234",
+		"msgid":     "ID47",
+		"structured_data": map[string]map[string]string{
+			"exampleSDID@32473":     {"iut": "3", "eventSource": `Appli"cation`},
+			"examplePriority@32473": {"class": "high"},
+		},
+	}, attrs)
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), `"message":"An application event"`)
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg, attrs := parse(t, "<34>1 - - - - - - \xef\xbb\xbf'su root' failed")
+	assert.Equal(t, "'su root' failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, map[string]interface{}{"facility": 4, "severity": 2, "version": 1}, attrs)
+
+	msg, _ = parse(t, "<34>1 - host app - - -")
+	assert.Equal(t, "", string(msg.GetContent()))
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg, attrs := parse(t, "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8")
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, map[string]interface{}{
+		"facility":  4,
+		"severity":  2,
+		"timestamp": "Oct 11 22:14:15",
+		"hostname":  "mymachine",
+		"appname":   "su",
+		"procid":    "123",
+	}, attrs)
+
+	// no hostname
+	msg, attrs = parse(t, "<13>Feb  5 17:32:18 sshd: connection closed")
+	assert.Equal(t, "connection closed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "Feb  5 17:32:18", attrs["timestamp"])
+	assert.Equal(t, "sshd", attrs["appname"])
+	assert.NotContains(t, attrs, "hostname")
+
+	// RFC3339 timestamp
+	_, attrs = parse(t, "<14>2023-01-02T03:04:05Z router kernel: link up")
+	assert.Equal(t, "2023-01-02T03:04:05Z", attrs["timestamp"])
+	assert.Equal(t, "router", attrs["hostname"])
+	assert.Equal(t, "kernel", attrs["appname"])
+
+	// no header at all
+	msg, attrs = parse(t, "<15>just a message")
+	assert.Equal(t, "just a message", string(msg.GetContent()))
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, map[string]interface{}{"facility": 1, "severity": 7}, attrs)
+}
+
+func TestParseInvalidMessage(t *testing.T) {
+	for _, content := range []string{"no priority", "<>1 - - - - - -", "<192>1 - - - - - -", "<abc>msg"} {
+		msg, err := New().Parse(message.NewMessage([]byte(content), nil, "", 0))
+		assert.Error(t, err, content)
+		assert.Equal(t, message.StateUnstructured, msg.State)
+		assert.Equal(t, content, string(msg.GetContent()))
+		assert.Equal(t, message.StatusInfo, msg.Status)
+	}
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// repeatCountAttribute holds the number of identical logs a message stands for.
+const repeatCountAttribute = "repeat_count"
+
+// dedupExpirationInterval is the interval at which the windows of the `dedup` rules
+// are checked for expiration.
+const dedupExpirationInterval = time.Second
+
+// dedupKey identifies a sequence of identical logs: each `dedup` rule collapses the
+// logs of every tailer separately.
+type dedupKey struct {
+	rule       *config.ProcessingRule
+	source     *sources.LogSource
+	identifier string
+}
+
+// repetition is the sequence of identical logs following the last log sent for a key.
+type repetition struct {
+	content []byte
+	expires time.Time
+	// last is the last repeated log, and count the number of repeated logs
+	last  *message.Message
+	count int
+}
+
+// deduplicator collapses the consecutive identical logs of a tailer matching a `dedup`
+// rule. The first log of a sequence is sent as is, the following ones are collapsed
+// into the last of them, sent with the number of logs it stands for once the sequence
+// ends: when a different log is received or when the window of the rule expires.
+type deduplicator struct {
+	mu          sync.Mutex
+	repetitions map[dedupKey]*repetition
+}
+
+func newDeduplicator() *deduplicator {
+	return &deduplicator{repetitions: make(map[dedupKey]*repetition)}
+}
+
+// add returns true if the message repeats the previous one and must not be sent, and
+// the message collapsing the sequence it ends if any.
+func (d *deduplicator) add(rule *config.ProcessingRule, msg *message.Message, now time.Time) (bool, *message.Message) {
+	key := dedupKey{rule: rule, source: msg.Origin.LogSource, identifier: msg.Origin.Identifier}
+	content := msg.GetContent()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r, found := d.repetitions[key]
+	if found && now.Before(r.expires) && bytes.Equal(r.content, content) {
+		r.last = msg
+		r.count++
+		return true, nil
+	}
+
+	var collapsed *message.Message
+	if found {
+		collapsed = r.collapse()
+	}
+	if rule.Regex != nil && !rule.Regex.Match(content) {
+		// the message ends the sequence without starting a new one
+		delete(d.repetitions, key)
+		return false, collapsed
+	}
+
+	window := rule.Window
+	if window == 0 {
+		window = config.DefaultDedupWindow
+	}
+	d.repetitions[key] = &repetition{
+		content: append([]byte(nil), content...),
+		expires: now.Add(time.Duration(window * float64(time.Second))),
+	}
+	return false, collapsed
+}
+
+// expire removes the sequences whose window expired before the given time, and returns
+// the messages collapsing them. All the sequences are removed when the time is zero.
+func (d *deduplicator) expire(now time.Time) []*message.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var collapsed []*message.Message
+	for key, r := range d.repetitions {
+		if now.IsZero() || !now.Before(r.expires) {
+			if msg := r.collapse(); msg != nil {
+				collapsed = append(collapsed, msg)
+			}
+			delete(d.repetitions, key)
+		}
+	}
+	return collapsed
+}
+
+// collapse returns the last repeated message holding the number of repeated messages,
+// or nil if there was no repetition.
+func (r *repetition) collapse() *message.Message {
+	if r.count == 0 {
+		return nil
+	}
+	// messages with a tailer-specific structured content are sent without the count
+	applyParsedAttributes(r.last, r.last.GetContent(), map[string]interface{}{repeatCountAttribute: r.count})
+	return r.last
+}
+
+// dedupRule returns the first `dedup` rule, the global rules coming first.
+func dedupRule(rules ...[]*config.ProcessingRule) *config.ProcessingRule {
+	for _, r := range rules {
+		for _, rule := range r {
+			if rule.Type == config.Dedup {
+				return rule
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func repeatCount(msg *message.Message) interface{} {
+	structured, ok := msg.GetStructuredContent().(*message.BasicStructuredContent)
+	if !ok {
+		return nil
+	}
+	return structured.Data[repeatCountAttribute]
+}
+
+func TestDeduplicatorCollapsesConsecutiveIdenticalLogs(t *testing.T) {
+	d := newDeduplicator()
+	rule := &config.ProcessingRule{Type: config.Dedup, Name: "dedup", Window: 10}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	now := time.Now()
+
+	repeated, collapsed := d.add(rule, newMessage([]byte("connection refused"), source, ""), now)
+	assert.False(t, repeated)
+	assert.Nil(t, collapsed)
+	for i := 0; i < 3; i++ {
+		repeated, collapsed = d.add(rule, newMessage([]byte("connection refused"), source, ""), now.Add(time.Second))
+		assert.True(t, repeated)
+		assert.Nil(t, collapsed)
+	}
+
+	// a different log ends the sequence
+	repeated, collapsed = d.add(rule, newMessage([]byte("connected"), source, ""), now.Add(2*time.Second))
+	assert.False(t, repeated)
+	require.NotNil(t, collapsed)
+	assert.Equal(t, "connection refused", string(collapsed.GetContent()))
+	assert.Equal(t, 3, repeatCount(collapsed))
+
+	// a single log isn't collapsed
+	repeated, collapsed = d.add(rule, newMessage([]byte("connection refused"), source, ""), now.Add(3*time.Second))
+	assert.False(t, repeated)
+	assert.Nil(t, collapsed)
+}
+
+func TestDeduplicatorSeparatesTailers(t *testing.T) {
+	d := newDeduplicator()
+	rule := &config.ProcessingRule{Type: config.Dedup, Name: "dedup"}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	now := time.Now()
+
+	first := newMessage([]byte("retrying"), source, "")
+	first.Origin.Identifier = "file:/var/log/a.log"
+	second := newMessage([]byte("retrying"), source, "")
+	second.Origin.Identifier = "file:/var/log/b.log"
+
+	repeated, _ := d.add(rule, first, now)
+	assert.False(t, repeated)
+	repeated, _ = d.add(rule, second, now)
+	assert.False(t, repeated)
+}
+
+func TestDeduplicatorExpiresWindows(t *testing.T) {
+	d := newDeduplicator()
+	rule := &config.ProcessingRule{Type: config.Dedup, Name: "dedup", Window: 5}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	now := time.Now()
+
+	d.add(rule, newMessage([]byte("timeout"), source, ""), now)
+	d.add(rule, newMessage([]byte("timeout"), source, ""), now.Add(time.Second))
+	d.add(rule, newMessage([]byte("timeout"), source, ""), now.Add(2*time.Second))
+
+	assert.Empty(t, d.expire(now.Add(4*time.Second)))
+	collapsed := d.expire(now.Add(5 * time.Second))
+	require.Len(t, collapsed, 1)
+	assert.Equal(t, 2, repeatCount(collapsed[0]))
+	assert.Empty(t, d.repetitions)
+
+	// once the window expired, the log starts a new sequence
+	repeated, _ := d.add(rule, newMessage([]byte("timeout"), source, ""), now.Add(6*time.Second))
+	assert.False(t, repeated)
+}
+
+func TestDeduplicatorWithPattern(t *testing.T) {
+	d := newDeduplicator()
+	rule := &config.ProcessingRule{Type: config.Dedup, Name: "dedup errors", Regex: regexp.MustCompile("ERROR")}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		repeated, _ := d.add(rule, newMessage([]byte("INFO ok"), source, ""), now)
+		assert.False(t, repeated)
+	}
+	d.add(rule, newMessage([]byte("ERROR failed"), source, ""), now)
+	repeated, _ := d.add(rule, newMessage([]byte("ERROR failed"), source, ""), now)
+	assert.True(t, repeated)
+	_, collapsed := d.add(rule, newMessage([]byte("INFO ok"), source, ""), now)
+	require.NotNil(t, collapsed)
+	assert.Equal(t, 1, repeatCount(collapsed))
+}
+
+func TestProcessorCollapsesMaskedRepeatedLogs(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.Dedup, Name: "dedup"},
+		{Type: config.MaskSequences, Name: "mask ids", Regex: regexp.MustCompile(`id=\d+`), Placeholder: []byte("id=*")},
+	}})
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, RawEncoder, diagnostic.NewBufferedMessageReceiver(nil))
+	p.Start()
+
+	// the logs are compared once masked, whatever the position of the rule
+	inputChan <- newMessage([]byte("request id=1 failed"), source, "")
+	inputChan <- newMessage([]byte("request id=2 failed"), source, "")
+	inputChan <- newMessage([]byte("request id=3 failed"), source, "")
+	assert.Contains(t, string((<-outputChan).GetContent()), "request id=* failed")
+
+	// the collapsed logs are sent when the processor stops
+	p.Stop()
+	require.Len(t, outputChan, 1)
+	msg := <-outputChan
+	assert.Contains(t, string(msg.GetContent()), `"message":"request id=* failed"`)
+	assert.Contains(t, string(msg.GetContent()), `"repeat_count":2`)
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MetricSender is the part of the aggregator sender used to send the metrics
+// generated from logs.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// generateMetric sends the metric of a `generate_metric` rule if the message matches it.
+// The values of the metric and its tags are looked up in the named capture groups of the
+// rule pattern first, and then in the attributes of the structured message.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, msg *message.Message, content []byte) {
+	if p.metricSender == nil {
+		return
+	}
+	metric := rule.Metric
+	attrs := structuredAttributes(msg)
+
+	var groups map[string]string
+	if rule.Regex != nil {
+		target := content
+		if metric.Attribute != "" {
+			value, found := lookupAttribute(attrs, metric.Attribute)
+			if !found {
+				return
+			}
+			target = []byte(value)
+		}
+		match := rule.Regex.FindSubmatch(target)
+		if match == nil {
+			return
+		}
+		groups = make(map[string]string)
+		for i, name := range rule.Regex.SubexpNames() {
+			if name != "" && match[i] != nil {
+				groups[name] = string(match[i])
+			}
+		}
+	} else if _, found := lookupAttribute(attrs, metric.Attribute); !found {
+		return
+	}
+
+	lookup := func(name string) (string, bool) {
+		if value, found := groups[name]; found {
+			return value, true
+		}
+		return lookupAttribute(attrs, name)
+	}
+
+	tags := make([]string, 0, len(metric.Tags)+len(metric.TagAttributes)+2)
+	tags = append(tags, metric.Tags...)
+	for _, attr := range metric.TagAttributes {
+		if value, found := lookup(attr); found && value != "" {
+			tags = append(tags, attr+":"+value)
+		}
+	}
+	if msg.Origin != nil {
+		if source := msg.Origin.Source(); source != "" {
+			tags = append(tags, "source:"+source)
+		}
+		if service := msg.Origin.Service(); service != "" {
+			tags = append(tags, "service:"+service)
+		}
+	}
+
+	switch metric.Type {
+	case config.CountMetric:
+		p.metricSender.Count(metric.Name, 1, "", tags)
+	case config.DistributionMetric:
+		raw, found := lookup(metric.ValueAttribute)
+		if !found {
+			return
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			log.Debugf("Can't generate metric %s from processing rule %s, invalid value %q: %v", metric.Name, rule.Name, raw, err)
+			metrics.TlmLogMetricsInvalidValues.Inc()
+			return
+		}
+		p.metricSender.Distribution(metric.Name, value, "", tags)
+	}
+	metrics.TlmLogMetricsGenerated.Inc()
+}
+
+// structuredAttributes returns the attributes of a message parsed into a basic structured
+// content, nil otherwise.
+func structuredAttributes(msg *message.Message) map[string]interface{} {
+	if structured, ok := msg.GetStructuredContent().(*message.BasicStructuredContent); ok {
+		return structured.Data
+	}
+	return nil
+}
+
+// lookupAttribute returns the value of a scalar attribute, nested attributes are
+// looked up with a dotted path, e.g. `http.status_code`.
+func lookupAttribute(attrs map[string]interface{}, path string) (string, bool) {
+	if attrs == nil || path == "" {
+		return "", false
+	}
+	var value interface{} = attrs
+	for _, key := range strings.Split(path, ".") {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = nested[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case map[string]interface{}, []interface{}, nil:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+type sample struct {
+	metricType string
+	name       string
+	value      float64
+	tags       []string
+}
+
+type fakeMetricSender struct {
+	samples []sample
+	commits int
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.samples = append(s.samples, sample{config.CountMetric, metric, value, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.samples = append(s.samples, sample{config.DistributionMetric, metric, value, tags})
+}
+
+func (s *fakeMetricSender) Commit() {
+	s.commits++
+}
+
+func TestGenerateMetricFromPattern(t *testing.T) {
+	metricSender := &fakeMetricSender{}
+	p := &Processor{metricSender: metricSender}
+	source := sources.NewLogSource("", &config.LogsConfig{Source: "nginx", ProcessingRules: []*config.ProcessingRule{
+		{
+			Type:  config.GenerateMetric,
+			Name:  "requests",
+			Regex: regexp.MustCompile(`status=(?P<status>\d+)`),
+			Metric: &config.LogMetric{
+				Name:          "nginx.requests",
+				Type:          config.CountMetric,
+				Tags:          []string{"team:web"},
+				TagAttributes: []string{"status"},
+			},
+		},
+		{
+			Type:  config.GenerateMetric,
+			Name:  "duration",
+			Regex: regexp.MustCompile(`duration_ms=(?P<duration_ms>[\d.]+)`),
+			Metric: &config.LogMetric{
+				Name:           "nginx.request.duration",
+				Type:           config.DistributionMetric,
+				ValueAttribute: "duration_ms",
+			},
+		},
+		// the metrics are generated before the logs get excluded
+		newProcessingRule(config.ExcludeAtMatch, "", "status=200"),
+	}})
+
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("GET / status=200 duration_ms=12.5"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET / status=500"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("not an access log"), source, "")))
+
+	assert.Equal(t, []sample{
+		{config.CountMetric, "nginx.requests", 1, []string{"team:web", "status:200", "source:nginx"}},
+		{config.DistributionMetric, "nginx.request.duration", 12.5, []string{"source:nginx"}},
+		{config.CountMetric, "nginx.requests", 1, []string{"team:web", "status:500", "source:nginx"}},
+	}, metricSender.samples)
+}
+
+func TestGenerateMetricFromParsedAttributes(t *testing.T) {
+	metricSender := &fakeMetricSender{}
+	p := &Processor{metricSender: metricSender}
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.ParseAsJSON},
+		{
+			Type: config.GenerateMetric,
+			Name: "errors",
+			// the pattern is applied on the attribute rather than on the content
+			Regex: regexp.MustCompile(`^5\d\d$`),
+			Metric: &config.LogMetric{
+				Name:      "api.errors",
+				Type:      config.CountMetric,
+				Attribute: "http.status_code",
+			},
+		},
+		{
+			Type: config.GenerateMetric,
+			Name: "latency",
+			Metric: &config.LogMetric{
+				Name:           "api.latency",
+				Type:           config.DistributionMetric,
+				Attribute:      "latency",
+				ValueAttribute: "latency",
+				TagAttributes:  []string{"http.method"},
+			},
+		},
+	}})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"message":"ok","http":{"status_code":200,"method":"GET"},"latency":0.25}`), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"message":"ko","http":{"status_code":503,"method":"POST"}}`), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"message":"invalid","latency":"slow"}`), source, "")))
+
+	assert.Equal(t, []sample{
+		{config.DistributionMetric, "api.latency", 0.25, []string{"http.method:GET"}},
+		{config.CountMetric, "api.errors", 1, []string{}},
+	}, metricSender.samples)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	p := &Processor{}
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.GenerateMetric, Name: "all", Regex: regexp.MustCompile(`.`), Metric: &config.LogMetric{Name: "logs", Type: config.CountMetric}},
+	}})
+	msg := newMessage([]byte("hello"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+}
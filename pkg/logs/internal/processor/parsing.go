@@ -0,0 +1,308 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Reserved attributes extracted by the parsing rules and mapped onto the message itself.
+var (
+	messageAttributes   = []string{"message", "msg"}
+	statusAttributes    = []string{"status", "level", "severity"}
+	timestampAttributes = []string{"timestamp", "@timestamp", "date", "time", "ts"}
+	serviceAttribute    = "service"
+)
+
+// timestampLayouts are the layouts tried, in order, to parse a string timestamp attribute.
+// Layouts without any time zone are interpreted as UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// statusAliases maps the usual level names found in logs to message statuses.
+var statusAliases = map[string]string{
+	"emerg":     message.StatusEmergency,
+	"emergency": message.StatusEmergency,
+	"fatal":     message.StatusEmergency,
+	"panic":     message.StatusEmergency,
+	"alert":     message.StatusAlert,
+	"crit":      message.StatusCritical,
+	"critical":  message.StatusCritical,
+	"err":       message.StatusError,
+	"error":     message.StatusError,
+	"warn":      message.StatusWarning,
+	"warning":   message.StatusWarning,
+	"notice":    message.StatusNotice,
+	"info":      message.StatusInfo,
+	"debug":     message.StatusDebug,
+	"trace":     message.StatusDebug,
+}
+
+// parse extracts attributes out of the content using the given parsing rule,
+// it returns false if the content could not be parsed.
+func parse(rule *config.ProcessingRule, content []byte) (map[string]interface{}, bool) {
+	switch rule.Type {
+	case config.ParseAsJSON:
+		return parseJSON(content)
+	case config.ParseAsKeyValue:
+		return parseKeyValue(content)
+	case config.ParseWithRegex:
+		return parseRegex(rule.Regex, content)
+	}
+	return nil, false
+}
+
+// parseJSON parses a JSON object, keeping the native types of the values.
+func parseJSON(content []byte) (map[string]interface{}, bool) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	attrs := make(map[string]interface{})
+	if err := json.Unmarshal(trimmed, &attrs); err != nil {
+		return nil, false
+	}
+	return attrs, true
+}
+
+// parseKeyValue parses logfmt-like `key=value` pairs, values can be double-quoted
+// and contain escaped quotes. Tokens without any `=` are ignored.
+func parseKeyValue(content []byte) (map[string]interface{}, bool) {
+	attrs := make(map[string]interface{})
+	s := string(content)
+	i := 0
+	for i < len(s) {
+		// skip leading whitespaces
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' {
+			i++
+		}
+		key := s[start:i]
+		if i >= len(s) || s[i] != '=' {
+			// not a key=value pair, skip the token
+			continue
+		}
+		i++ // skip '='
+
+		var value string
+		if i < len(s) && s[i] == '"' {
+			var b strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			i++ // skip the closing quote
+			value = b.String()
+		} else {
+			start = i
+			for i < len(s) && !isSpace(s[i]) {
+				i++
+			}
+			value = s[start:i]
+		}
+		if key != "" {
+			attrs[key] = value
+		}
+	}
+	return attrs, len(attrs) > 0
+}
+
+// parseRegex extracts the named capture groups of the regex, unnamed and
+// non-participating groups are ignored.
+func parseRegex(re *regexp.Regexp, content []byte) (map[string]interface{}, bool) {
+	if re == nil {
+		return nil, false
+	}
+	match := re.FindSubmatchIndex(content)
+	if match == nil {
+		return nil, false
+	}
+	attrs := make(map[string]interface{})
+	for i, name := range re.SubexpNames() {
+		if name == "" || match[2*i] < 0 {
+			continue
+		}
+		attrs[name] = string(content[match[2*i]:match[2*i+1]])
+	}
+	return attrs, len(attrs) > 0
+}
+
+// applyParsedAttributes stores the parsed attributes as the structured content of the message
+// and maps the reserved attributes (status, service, timestamp) onto the message.
+// If the message is already structured, the attributes are merged into its content when possible.
+func applyParsedAttributes(msg *message.Message, content []byte, attrs map[string]interface{}) bool {
+	var structured *message.BasicStructuredContent
+	switch msg.State {
+	case message.StateUnstructured:
+		structured = &message.BasicStructuredContent{Data: make(map[string]interface{}, len(attrs)+1)}
+	case message.StateStructured:
+		basic, ok := msg.GetStructuredContent().(*message.BasicStructuredContent)
+		if !ok {
+			// we can't merge attributes into a tailer-specific structured content
+			return false
+		}
+		structured = basic
+	default:
+		return false
+	}
+
+	for k, v := range attrs {
+		structured.Data[k] = v
+	}
+
+	// the "message" key must hold the actual message of the log
+	content = extractMessage(attrs, content)
+	for _, attr := range messageAttributes {
+		delete(structured.Data, attr)
+	}
+	if status, found := extractStatus(attrs); found {
+		msg.Status = status
+	}
+	if service, ok := attrs[serviceAttribute].(string); ok && service != "" {
+		msg.Origin.SetService(service)
+	}
+	if ts, found := extractTimestamp(attrs); found {
+		msg.EventTimestamp = ts
+	}
+
+	msg.SetStructuredContent(structured)
+	msg.SetContent(content)
+	return true
+}
+
+// maskAttributes applies a `mask_sequences` rule on the string values of the attributes
+// of a structured message, so that extracted attributes can't leak masked sequences.
+// The message attribute is masked along with the content by the caller.
+func maskAttributes(rule *config.ProcessingRule, msg *message.Message) {
+	structured, ok := msg.GetStructuredContent().(*message.BasicStructuredContent)
+	if !ok {
+		return
+	}
+	for k, v := range structured.Data {
+		if k != "message" {
+			structured.Data[k] = maskValue(rule, v)
+		}
+	}
+}
+
+// maskValue masks the strings of a parsed value, walking through nested objects and arrays.
+func maskValue(rule *config.ProcessingRule, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return string(rule.Regex.ReplaceAll([]byte(v), rule.Placeholder))
+	case map[string]interface{}:
+		for k, nested := range v {
+			v[k] = maskValue(rule, nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = maskValue(rule, nested)
+		}
+	}
+	return value
+}
+
+// extractMessage returns the message attribute if any, otherwise the original content.
+// A null message attribute is considered absent.
+func extractMessage(attrs map[string]interface{}, content []byte) []byte {
+	for _, attr := range messageAttributes {
+		if v, ok := attrs[attr]; ok && v != nil {
+			if s, ok := v.(string); ok {
+				return []byte(s)
+			}
+			return []byte(fmt.Sprint(v))
+		}
+	}
+	return content
+}
+
+// extractStatus returns the message status from the first status attribute
+// found that maps to a known status.
+func extractStatus(attrs map[string]interface{}) (string, bool) {
+	for _, attr := range statusAttributes {
+		v, ok := attrs[attr].(string)
+		if !ok {
+			continue
+		}
+		if status, ok := statusAliases[strings.ToLower(v)]; ok {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// extractTimestamp returns the time of the first timestamp attribute found that can be parsed,
+// either as a date string or as a number of seconds, milliseconds, microseconds or nanoseconds
+// since the epoch.
+func extractTimestamp(attrs map[string]interface{}) (time.Time, bool) {
+	for _, attr := range timestampAttributes {
+		switch v := attrs[attr].(type) {
+		case float64:
+			return fromEpoch(v), true
+		case string:
+			if ts, ok := parseTimestamp(v); ok {
+				return ts, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTimestamp parses a string timestamp holding either a date or a number since the epoch.
+func parseTimestamp(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		return fromEpoch(epoch), true
+	}
+	for _, layout := range timestampLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// fromEpoch converts a number since the epoch to a time, guessing its unit from its magnitude.
+func fromEpoch(epoch float64) time.Time {
+	switch {
+	case epoch >= 1e17:
+		return time.Unix(0, int64(epoch)).UTC()
+	case epoch >= 1e14:
+		return time.UnixMicro(int64(epoch)).UTC()
+	case epoch >= 1e11:
+		return time.UnixMilli(int64(epoch)).UTC()
+	}
+	sec, frac := int64(epoch), epoch-float64(int64(epoch))
+	return time.Unix(sec, int64(frac*1e9)).UTC()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
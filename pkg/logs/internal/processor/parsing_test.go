@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestParseKeyValue(t *testing.T) {
+	attrs, ok := parseKeyValue([]byte(`2023-09-12 level=warn msg="user \"bob\" logged in" duration_ms=12 empty=`))
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"level":       "warn",
+		"msg":         `user "bob" logged in`,
+		"duration_ms": "12",
+		"empty":       "",
+	}, attrs)
+
+	_, ok = parseKeyValue([]byte("no pairs in here"))
+	assert.False(t, ok)
+}
+
+func TestParseJSON(t *testing.T) {
+	attrs, ok := parseJSON([]byte(` {"message":"hello","count":2}`))
+	require.True(t, ok)
+	assert.Equal(t, "hello", attrs["message"])
+	assert.Equal(t, float64(2), attrs["count"])
+
+	_, ok = parseJSON([]byte(`[1, 2]`))
+	assert.False(t, ok)
+	_, ok = parseJSON([]byte(`{"truncated":`))
+	assert.False(t, ok)
+}
+
+func TestParsingRules(t *testing.T) {
+	p := &Processor{}
+	assert := assert.New(t)
+
+	tests := []struct {
+		rule            *config.ProcessingRule
+		input           string
+		expectedContent string
+		expectedStatus  string
+		expectedService string
+		expectedAttrs   map[string]interface{}
+	}{
+		{
+			rule:            &config.ProcessingRule{Type: config.ParseAsJSON},
+			input:           `{"level":"ERROR","service":"api","message":"boom","code":500}`,
+			expectedContent: "boom",
+			expectedStatus:  message.StatusError,
+			expectedService: "api",
+			expectedAttrs:   map[string]interface{}{"level": "ERROR", "service": "api", "code": float64(500)},
+		},
+		{
+			rule:            &config.ProcessingRule{Type: config.ParseAsKeyValue},
+			input:           `user=bob action=login`,
+			expectedContent: `user=bob action=login`,
+			expectedStatus:  message.StatusInfo,
+			expectedAttrs:   map[string]interface{}{"user": "bob", "action": "login"},
+		},
+		{
+			rule:            newProcessingRule(config.ParseWithRegex, "", `^(?P<severity>\w+) \[(?P<service>[^\]]+)\] (?P<message>.*)$`),
+			input:           `warning [billing] invoice not found`,
+			expectedContent: "invoice not found",
+			expectedStatus:  message.StatusWarning,
+			expectedService: "billing",
+			expectedAttrs:   map[string]interface{}{"severity": "warning", "service": "billing"},
+		},
+		{
+			// content which can't be parsed is left untouched
+			rule:            &config.ProcessingRule{Type: config.ParseAsJSON},
+			input:           `not json`,
+			expectedContent: `not json`,
+			expectedStatus:  message.StatusInfo,
+		},
+	}
+
+	for _, test := range tests {
+		source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{test.rule}}}
+		msg := newMessage([]byte(test.input), &source, message.StatusInfo)
+		assert.True(p.applyRedactingRules(msg))
+		assert.Equal(test.expectedContent, string(msg.GetContent()))
+		assert.Equal(test.expectedStatus, msg.GetStatus())
+		assert.Equal(test.expectedService, msg.Origin.Service())
+
+		if test.expectedAttrs == nil {
+			assert.Equal(message.StateUnstructured, msg.State)
+			continue
+		}
+		structured, ok := msg.GetStructuredContent().(*message.BasicStructuredContent)
+		assert.True(ok)
+		test.expectedAttrs["message"] = test.expectedContent
+		assert.Equal(test.expectedAttrs, structured.Data)
+	}
+}
+
+func TestParsingRuleFollowedByMask(t *testing.T) {
+	p := &Processor{}
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.ParseAsJSON},
+		newProcessingRule(config.MaskSequences, "[masked]", `\d{4}-\d{4}`),
+	}}}
+	msg := newMessage([]byte(`{"message":"card 1234-5678 used","user":"bob"}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "card [masked] used", string(msg.GetContent()))
+
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"card [masked] used","user":"bob"}`, string(rendered))
+}
+
+func TestParsingRuleMasksAttributes(t *testing.T) {
+	p := &Processor{}
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.ParseAsKeyValue},
+		newProcessingRule(config.MaskSequences, "[masked]", `password=\S+|hunter2`),
+	}}}
+	msg := newMessage([]byte(`user=bob password=hunter2 msg="login password=hunter2"`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "login [masked]", string(msg.GetContent()))
+
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"login [masked]","user":"bob","password":"[masked]"}`, string(rendered))
+
+	source.Config.ProcessingRules[0] = &config.ProcessingRule{Type: config.ParseAsJSON}
+	msg = newMessage([]byte(`{"message":"login","auth":{"secret":"hunter2","methods":["hunter2"]}}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	rendered, err = msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"login","auth":{"secret":"[masked]","methods":["[masked]"]}}`, string(rendered))
+}
+
+func TestParsingRuleExtractsTimestamp(t *testing.T) {
+	p := &Processor{}
+	expected := time.Date(2023, 9, 12, 8, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		rule  *config.ProcessingRule
+		input string
+	}{
+		{&config.ProcessingRule{Type: config.ParseAsJSON}, `{"message":"hello","timestamp":"2023-09-12T10:30:15+02:00"}`},
+		{&config.ProcessingRule{Type: config.ParseAsJSON}, `{"message":"hello","@timestamp":1694507415000}`},
+		{&config.ProcessingRule{Type: config.ParseAsJSON}, `{"message":"hello","ts":1694507415}`},
+		{&config.ProcessingRule{Type: config.ParseAsKeyValue}, `time="2023-09-12 08:30:15" msg=hello`},
+		{newProcessingRule(config.ParseWithRegex, "", `^(?P<date>\S+) (?P<message>.*)$`), `1694507415000000000 hello`},
+	}
+
+	for _, test := range tests {
+		source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{test.rule}}}
+		msg := newMessage([]byte(test.input), &source, "")
+		assert.True(t, p.applyRedactingRules(msg))
+		assert.Equal(t, "hello", string(msg.GetContent()), test.input)
+		assert.True(t, expected.Equal(msg.EventTimestamp), "%s: %s", test.input, msg.EventTimestamp)
+		assert.True(t, expected.Equal(msg.GetTimestamp()), test.input)
+	}
+
+	// unparsable timestamps are kept as attributes only
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{{Type: config.ParseAsJSON}}}}
+	msg := newMessage([]byte(`{"message":"hello","timestamp":"yesterday"}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.True(t, msg.EventTimestamp.IsZero())
+}
+
+func TestParsingRuleNullMessage(t *testing.T) {
+	p := &Processor{}
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{{Type: config.ParseAsJSON}}}}
+
+	msg := newMessage([]byte(`{"message":null,"msg":"hello"}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "hello", string(msg.GetContent()))
+
+	input := `{"message":null,"user":"bob"}`
+	msg = newMessage([]byte(input), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, input, string(msg.GetContent()))
+}
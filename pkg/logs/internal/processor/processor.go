@@ -8,6 +8,7 @@ package processor
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -30,6 +31,9 @@ type Processor struct {
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
+	metricSender              MetricSender
+	routes                    []*Route
+	deduplicator              *deduplicator
 	mu                        sync.Mutex
 }
 
@@ -42,9 +46,26 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		deduplicator:              newDeduplicator(),
 	}
 }
 
+// NewWithMetricSender returns an initialized Processor sending the metrics generated
+// by the `generate_metric` rules with the given sender.
+func NewWithMetricSender(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSender MetricSender) *Processor {
+	p := New(inputChan, outputChan, processingRules, encoder, diagnosticMessageReceiver)
+	p.metricSender = metricSender
+	return p
+}
+
+// NewWithRoutes returns an initialized Processor also sending the messages matching
+// the given routes to their output channels.
+func NewWithRoutes(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSender MetricSender, routes []*Route) *Processor {
+	p := NewWithMetricSender(inputChan, outputChan, processingRules, encoder, diagnosticMessageReceiver, metricSender)
+	p.routes = routes
+	return p
+}
+
 // Start starts the Processor.
 func (p *Processor) Start() {
 	go p.run()
@@ -67,6 +88,8 @@ func (p *Processor) Flush(ctx context.Context) {
 			return
 		default:
 			if len(p.inputChan) == 0 {
+				// send the logs collapsed so far by the `dedup` rules
+				p.sendCollapsed(time.Time{})
 				return
 			}
 			msg := <-p.inputChan
@@ -80,11 +103,24 @@ func (p *Processor) run() {
 	defer func() {
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		p.processMessage(msg)
-		p.mu.Lock() // block here if we're trying to flush synchronously
-		//nolint:staticcheck
-		p.mu.Unlock()
+	ticker := time.NewTicker(dedupExpirationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-p.inputChan:
+			if !ok {
+				p.sendCollapsed(time.Time{})
+				return
+			}
+			p.processMessage(msg)
+			p.mu.Lock() // block here if we're trying to flush synchronously
+			//nolint:staticcheck
+			p.mu.Unlock()
+		case now := <-ticker.C:
+			p.mu.Lock()
+			p.sendCollapsed(now)
+			p.mu.Unlock()
+		}
 	}
 }
 
@@ -95,29 +131,69 @@ func (p *Processor) processMessage(msg *message.Message) {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
+		if p.isRepeated(msg) {
+			metrics.TlmLogsDeduplicated.Inc(msg.Origin.Source())
 			return
 		}
-		msg.SetRendered(rendered)
+		p.send(msg)
+	}
+}
 
-		// report this message to diagnostic receivers (e.g. `stream-logs` command)
-		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+// send renders, encodes and sends a processed message.
+func (p *Processor) send(msg *message.Message) {
+	// the routes are matched on the content, before it is rendered and encoded
+	routes := p.matchRoutes(msg)
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
 
+	// report this message to diagnostic receivers (e.g. `stream-logs` command)
+	p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
+	}
+
+	// the routes are sent copies of the message before it is handed to the main pipeline
+	sendToRoutes(msg, routes)
+	if !isExclusive(routes) {
 		p.outputChan <- msg
 	}
 }
 
+// isRepeated returns true if the message is collapsed by a `dedup` rule, which applies
+// once all the other rules are. The message collapsing the sequence of logs it ends, if
+// any, is sent first.
+func (p *Processor) isRepeated(msg *message.Message) bool {
+	rule := dedupRule(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules)
+	if rule == nil {
+		return false
+	}
+	repeated, collapsed := p.deduplicator.add(rule, msg, time.Now())
+	if collapsed != nil {
+		p.send(collapsed)
+	}
+	return repeated
+}
+
+// sendCollapsed sends the messages collapsing the sequences of logs whose window expired
+// before the given time, or all of them when the time is zero.
+func (p *Processor) sendCollapsed(now time.Time) {
+	for _, msg := range p.deduplicator.expire(now) {
+		p.send(msg)
+	}
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
 // it applies the change directly on the Message content.
+// Parsing rules turn the message into a structured one holding the extracted attributes.
 func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	var content []byte = msg.GetContent()
 
@@ -136,6 +212,25 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+			maskAttributes(rule, msg)
+		case config.ParseAsJSON, config.ParseAsKeyValue, config.ParseWithRegex:
+			// on success, the message becomes structured and the next rules
+			// are applied on its message attribute
+			if attrs, ok := parse(rule, content); ok && applyParsedAttributes(msg, content, attrs) {
+				content = msg.GetContent()
+			}
+		case config.GenerateMetric:
+			p.generateMetric(rule, msg, content)
+		case config.Sample:
+			if (rule.Regex == nil || rule.Regex.Match(content)) && !keepSampled(rule, msg, content) {
+				metrics.TlmLogsSampledOut.Inc(rule.Type, msg.Origin.Source())
+				return false
+			}
+		case config.RateLimit:
+			if (rule.Regex == nil || rule.Regex.Match(content)) && !rateLimiters.allow(rule, msg.Origin, time.Now()) {
+				metrics.TlmLogsSampledOut.Inc(rule.Type, msg.Origin.Source())
+				return false
+			}
 		}
 	}
 
@@ -8,7 +8,6 @@ package processor
 import (
 	"fmt"
 	"regexp"
-	"time"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -40,7 +39,7 @@ func (r *rawEncoder) Encode(msg *message.Message) error {
 		extraContent = append(extraContent, ' ')
 
 		// Timestamp
-		extraContent = time.Now().UTC().AppendFormat(extraContent, config.DateFormat)
+		extraContent = msg.GetTimestamp().AppendFormat(extraContent, config.DateFormat)
 		extraContent = append(extraContent, ' ')
 
 		extraContent = append(extraContent, []byte(msg.GetHostname())...)
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// Route sends the messages matching its rule to its output channel, on top of the
+// main output channel of the processor unless the route is exclusive.
+type Route struct {
+	Rule       *config.Route
+	OutputChan chan *message.Message
+}
+
+// matchRoutes returns the routes matching a processed message, its content must not
+// be encoded yet.
+func (p *Processor) matchRoutes(msg *message.Message) []*Route {
+	var matched []*Route
+	for _, route := range p.routes {
+		if matchRoute(route.Rule, msg) {
+			matched = append(matched, route)
+		}
+	}
+	return matched
+}
+
+// sendToRoutes sends a copy of an encoded message to each matched route, as the
+// pipelines of the routes run concurrently with the main one. The non-exclusive routes
+// never block the processor, messages are dropped when such a route is falling behind.
+// The exclusive routes are the only destinations of their messages, they are never dropped.
+func sendToRoutes(msg *message.Message, routes []*Route) {
+	for _, route := range routes {
+		routed := *msg
+		if route.Rule.Exclusive {
+			route.OutputChan <- &routed
+			metrics.TlmLogsRouted.Inc(route.Rule.Name)
+			continue
+		}
+		select {
+		case route.OutputChan <- &routed:
+			metrics.TlmLogsRouted.Inc(route.Rule.Name)
+		default:
+			metrics.TlmLogsRoutedDropped.Inc(route.Rule.Name)
+		}
+	}
+}
+
+// isExclusive returns true if one of the matched routes is exclusive, the message must
+// then not be sent to the main output channel.
+func isExclusive(routes []*Route) bool {
+	for _, route := range routes {
+		if route.Rule.Exclusive {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute returns true if the message satisfies all the conditions of the route.
+func matchRoute(route *config.Route, msg *message.Message) bool {
+	if len(route.Sources) > 0 && !contains(route.Sources, msg.Origin.Source()) {
+		return false
+	}
+	if len(route.Services) > 0 && !contains(route.Services, msg.Origin.Service()) {
+		return false
+	}
+	if len(route.Tags) > 0 {
+		found := false
+		for _, tag := range msg.Origin.Tags() {
+			if contains(route.Tags, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if route.Regex != nil && !route.Regex.Match(msg.GetContent()) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newRoutedMessage(content, source, service string, tags ...string) *message.Message {
+	msg := newMessage([]byte(content), sources.NewLogSource("", &config.LogsConfig{Source: source, Service: service}), message.StatusInfo)
+	msg.Origin.SetTags(tags)
+	return msg
+}
+
+func TestMatchRoute(t *testing.T) {
+	route := &config.Route{
+		Sources:  []string{"nginx", "apache"},
+		Services: []string{"web"},
+		Tags:     []string{"team:security", "env:prod"},
+		Regex:    regexp.MustCompile("denied"),
+	}
+
+	assert.True(t, matchRoute(route, newRoutedMessage("access denied", "nginx", "web", "env:prod")))
+	assert.True(t, matchRoute(route, newRoutedMessage("access denied", "apache", "web", "team:security", "region:eu")))
+	assert.False(t, matchRoute(route, newRoutedMessage("access denied", "redis", "web", "env:prod")))
+	assert.False(t, matchRoute(route, newRoutedMessage("access denied", "nginx", "db", "env:prod")))
+	assert.False(t, matchRoute(route, newRoutedMessage("access denied", "nginx", "web", "env:staging")))
+	assert.False(t, matchRoute(route, newRoutedMessage("access granted", "nginx", "web", "env:prod")))
+
+	// unset conditions match any message
+	assert.True(t, matchRoute(&config.Route{Services: []string{"web"}}, newRoutedMessage("hello", "", "web")))
+}
+
+func TestProcessorSendsMatchingMessagesToRoutes(t *testing.T) {
+	inputChan := make(chan *message.Message, 4)
+	outputChan := make(chan *message.Message, 4)
+	routeChan := make(chan *message.Message, 1)
+	routes := []*Route{{Rule: &config.Route{Name: "security", Tags: []string{"team:security"}}, OutputChan: routeChan}}
+	p := NewWithRoutes(inputChan, outputChan, nil, RawEncoder, diagnostic.NewBufferedMessageReceiver(nil), nil, routes)
+	p.Start()
+	defer p.Stop()
+
+	inputChan <- newRoutedMessage("first", "", "", "team:security")
+	inputChan <- newRoutedMessage("second", "", "", "team:web")
+	inputChan <- newRoutedMessage("third", "", "", "team:security")
+
+	// all the messages are sent to the main output
+	for _, content := range []string{"first", "second", "third"} {
+		assert.Contains(t, string((<-outputChan).GetContent()), content)
+	}
+
+	// the third message is dropped as the route is full
+	routed := <-routeChan
+	assert.Contains(t, string(routed.GetContent()), "first")
+	assert.Len(t, routeChan, 0)
+}
+
+func TestProcessorSendsExclusiveRouteMessagesOnlyToTheRoute(t *testing.T) {
+	inputChan := make(chan *message.Message, 4)
+	outputChan := make(chan *message.Message, 4)
+	routeChan := make(chan *message.Message, 4)
+	sharedChan := make(chan *message.Message, 4)
+	routes := []*Route{
+		{Rule: &config.Route{Name: "security", Tags: []string{"team:security"}, Exclusive: true}, OutputChan: routeChan},
+		{Rule: &config.Route{Name: "all", Tags: []string{"team:security", "team:web"}}, OutputChan: sharedChan},
+	}
+	p := NewWithRoutes(inputChan, outputChan, nil, RawEncoder, diagnostic.NewBufferedMessageReceiver(nil), nil, routes)
+	p.Start()
+	defer p.Stop()
+
+	inputChan <- newRoutedMessage("first", "", "", "team:security")
+	inputChan <- newRoutedMessage("second", "", "", "team:web")
+
+	// the message matching the exclusive route is not sent to the main output
+	assert.Contains(t, string((<-outputChan).GetContent()), "second")
+	routed := <-routeChan
+	assert.Contains(t, string(routed.GetContent()), "first")
+	assert.Len(t, outputChan, 0)
+
+	// each route gets its own copy of the message
+	shared := <-sharedChan
+	assert.Contains(t, string(shared.GetContent()), "first")
+	assert.NotSame(t, routed, shared)
+	assert.Contains(t, string((<-sharedChan).GetContent()), "second")
+}
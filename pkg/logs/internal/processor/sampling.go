@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// keepSampled returns true if the message is kept by a `sample` rule. The decision is
+// deterministic: it only depends on the hash of the sample attribute, or of the content
+// if the rule doesn't have any or the message doesn't hold it, so that all the logs
+// sharing a value (e.g. a trace id) are either kept or dropped together.
+func keepSampled(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	if rule.SampleRate >= 1 {
+		return true
+	}
+	if rule.SampleRate <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	if value, found := lookupAttribute(structuredAttributes(msg), rule.SampleAttribute); found {
+		_, _ = h.Write([]byte(value))
+	} else {
+		_, _ = h.Write(content)
+	}
+	return float64(mix(h.Sum64())) < rule.SampleRate*math.MaxUint64
+}
+
+// mix is the murmur3 finalizer, FNV alone doesn't spread similar inputs (e.g. sequential
+// ids) evenly enough over the whole range.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// rateLimitKey identifies a token bucket: each `rate_limit` rule limits every
+// source and service separately.
+type rateLimitKey struct {
+	rule    *config.ProcessingRule
+	source  string
+	service string
+}
+
+// rateLimiter holds the token buckets of the `rate_limit` rules. It is shared by
+// all the processors as the logs of a source can go through different pipelines.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*tokenBucket
+	// sweepSize is the number of buckets above which full buckets are removed
+	sweepSize int
+}
+
+// minSweepSize is the minimum number of buckets before full buckets are removed.
+const minSweepSize = 64
+
+var rateLimiters = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets:   make(map[rateLimitKey]*tokenBucket),
+		sweepSize: minSweepSize,
+	}
+}
+
+// allow returns true if the message is allowed by the rule at the given time.
+func (r *rateLimiter) allow(rule *config.ProcessingRule, origin *message.Origin, now time.Time) bool {
+	key := rateLimitKey{rule: rule}
+	if origin != nil {
+		key.source = origin.Source()
+		key.service = origin.Service()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, found := r.buckets[key]
+	if !found {
+		r.sweep(now)
+		burst := float64(rule.Burst)
+		if burst == 0 {
+			burst = math.Max(1, math.Ceil(rule.LogsPerSecond))
+		}
+		bucket = &tokenBucket{rate: rule.LogsPerSecond, burst: burst, tokens: burst, last: now}
+		r.buckets[key] = bucket
+	}
+	return bucket.take(now)
+}
+
+// sweep removes the full buckets when there are too many of them, e.g. because sources
+// were removed. A full bucket behaves as a new one, so removing it doesn't change the
+// rate limiting.
+func (r *rateLimiter) sweep(now time.Time) {
+	if len(r.buckets) < r.sweepSize {
+		return
+	}
+	for key, bucket := range r.buckets {
+		if bucket.isFull(now) {
+			delete(r.buckets, key)
+		}
+	}
+	r.sweepSize = int(math.Max(minSweepSize, float64(2*len(r.buckets))))
+}
+
+// tokenBucket allows `rate` events per second on average, and bursts of up to `burst` events.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// take consumes a token, it returns false if there is none left.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) isFull(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestSampleRule(t *testing.T) {
+	p := &Processor{}
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.Sample, Name: "debug", SampleRate: 0.1, Regex: regexp.MustCompile("DEBUG")},
+	}})
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		if p.applyRedactingRules(newMessage([]byte(fmt.Sprintf("DEBUG request %d", i)), source, "")) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 150)
+
+	// the logs which don't match the pattern are always kept
+	for i := 0; i < 100; i++ {
+		assert.True(t, p.applyRedactingRules(newMessage([]byte(fmt.Sprintf("INFO request %d", i)), source, "")))
+	}
+}
+
+func TestSampleRuleIsDeterministic(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.Sample, Name: "traces", SampleRate: 0.5, SampleAttribute: "trace_id"}
+
+	keptByTrace := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		traceID := fmt.Sprintf("%d", i%20)
+		msg := message.NewStructuredMessage(&message.BasicStructuredContent{Data: map[string]interface{}{
+			"message":  fmt.Sprintf("span %d", i),
+			"trace_id": traceID,
+		}}, nil, "", 0)
+		kept := keepSampled(rule, msg, msg.GetContent())
+		if previous, found := keptByTrace[traceID]; found {
+			assert.Equal(t, previous, kept, "all the logs of a trace must be either kept or dropped")
+		}
+		keptByTrace[traceID] = kept
+	}
+
+	// the content is hashed when the attribute is missing
+	msg := newMessage([]byte("no trace"), nil, "")
+	assert.Equal(t, keepSampled(rule, msg, msg.GetContent()), keepSampled(rule, msg, msg.GetContent()))
+
+	assert.True(t, keepSampled(&config.ProcessingRule{SampleRate: 1}, msg, msg.GetContent()))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter()
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "limit", LogsPerSecond: 2, Burst: 4}
+	web := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Source: "nginx", Service: "web"}))
+	api := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Source: "nginx", Service: "api"}))
+	now := time.Now()
+
+	// the burst is allowed at once
+	for i := 0; i < 4; i++ {
+		assert.True(t, limiter.allow(rule, web, now))
+	}
+	assert.False(t, limiter.allow(rule, web, now))
+
+	// each service has its own bucket
+	assert.True(t, limiter.allow(rule, api, now))
+
+	// the bucket is refilled at the rate of the rule
+	assert.False(t, limiter.allow(rule, web, now.Add(400*time.Millisecond)))
+	assert.True(t, limiter.allow(rule, web, now.Add(500*time.Millisecond)))
+	assert.False(t, limiter.allow(rule, web, now.Add(500*time.Millisecond)))
+
+	// and never holds more tokens than the burst
+	later := now.Add(time.Hour)
+	for i := 0; i < 4; i++ {
+		assert.True(t, limiter.allow(rule, web, later))
+	}
+	assert.False(t, limiter.allow(rule, web, later))
+}
+
+func TestRateLimiterDefaultBurst(t *testing.T) {
+	limiter := newRateLimiter()
+	now := time.Now()
+
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "limit", LogsPerSecond: 2.5}
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.allow(rule, nil, now))
+	}
+	assert.False(t, limiter.allow(rule, nil, now))
+
+	slow := &config.ProcessingRule{Type: config.RateLimit, Name: "slow", LogsPerSecond: 0.1}
+	assert.True(t, limiter.allow(slow, nil, now))
+	assert.False(t, limiter.allow(slow, nil, now.Add(9*time.Second)))
+	assert.True(t, limiter.allow(slow, nil, now.Add(10*time.Second)))
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	limiter := newRateLimiter()
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "limit", LogsPerSecond: 1}
+	now := time.Now()
+
+	for i := 0; i < minSweepSize; i++ {
+		origin := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Service: fmt.Sprintf("service-%d", i)}))
+		assert.True(t, limiter.allow(rule, origin, now))
+	}
+	assert.Len(t, limiter.buckets, minSweepSize)
+
+	// the buckets are full again after a second, so they are removed when a new one is needed
+	origin := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Service: "new"}))
+	assert.True(t, limiter.allow(rule, origin, now.Add(time.Second)))
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestRateLimitRule(t *testing.T) {
+	p := &Processor{}
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.RateLimit, Name: "errors", LogsPerSecond: 1, Burst: 2, Regex: regexp.MustCompile("ERROR")},
+	}})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR 1"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR 2"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("ERROR 3"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO 1"), source, "")))
+}
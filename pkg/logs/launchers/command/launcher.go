@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package command implements a launcher running commands and collecting their outputs.
+package command
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/tailers"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/command"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// Launcher reacts to sources with Config.Type = command by running their command,
+// the command is killed when the source is removed.
+//
+// As the commands run with the permissions of the agent, they are only run when
+// enabled in the agent configuration, and only for sources defined in configuration
+// files: the autodiscovery scheduler drops the command sources of the other providers,
+// and the sources attached to a container or a pod are rejected here.
+type Launcher struct {
+	enabled          bool
+	pipelineProvider pipeline.Provider
+	addedSources     chan *sources.LogSource
+	removedSources   chan *sources.LogSource
+	tailers          map[*sources.LogSource]*tailer.Tailer
+	stop             chan struct{}
+}
+
+// NewLauncher returns a new Launcher.
+func NewLauncher(enabled bool) *Launcher {
+	return &Launcher{
+		enabled: enabled,
+		tailers: make(map[*sources.LogSource]*tailer.Tailer),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvider pipeline.Provider, registry auditor.Registry, tracker *tailers.TailerTracker) { //nolint:revive // TODO fix revive unused-parameter
+	l.pipelineProvider = pipelineProvider
+	l.addedSources, l.removedSources = sourceProvider.SubscribeForType(config.CommandType)
+	go l.run()
+}
+
+// run starts and stops the tailers as sources are added and removed.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.addedSources:
+			if !l.enabled {
+				log.Warnf("Not running command %s, command sources are disabled, set logs_config.command_sources_enabled to true to enable them", tailer.Identifier(source))
+				source.Status.Error(errors.New("command sources are disabled"))
+				continue
+			}
+			if source.Config.Identifier != "" {
+				log.Warnf("Not running command %s, command sources can't be attached to a container or a pod", tailer.Identifier(source))
+				source.Status.Error(errors.New("command sources can only be defined in configuration files"))
+				continue
+			}
+			if _, exists := l.tailers[source]; exists {
+				continue
+			}
+			log.Infof("Running command %s", tailer.Identifier(source))
+			t := tailer.NewTailer(source, l.pipelineProvider.NextPipelineChan())
+			t.Start()
+			l.tailers[source] = t
+		case source := <-l.removedSources:
+			if t, exists := l.tailers[source]; exists {
+				t.Stop()
+				delete(l.tailers, source)
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop kills all the commands and waits for their outputs to be flushed.
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := startstop.NewParallelStopper()
+	for source, t := range l.tailers {
+		stopper.Add(t)
+		delete(l.tailers, source)
+	}
+	stopper.Stop()
+}
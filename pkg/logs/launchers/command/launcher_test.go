@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/tailers"
+)
+
+func TestLauncherRunsAndStopsCommands(t *testing.T) {
+	logSources := sources.NewLogSources()
+	pipelineProvider := mock.NewMockProvider()
+	launcher := NewLauncher(true)
+	launcher.Start(logSources, pipelineProvider, nil, tailers.NewTailerTracker())
+
+	source := sources.NewLogSource("events", &config.LogsConfig{Type: config.CommandType, Command: "sh", Args: []string{"-c", "echo hello; sleep 60"}})
+	logSources.AddSource(source)
+
+	select {
+	case msg := <-pipelineProvider.NextPipelineChan():
+		assert.Equal(t, "hello", string(msg.GetContent()))
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "no message received")
+	}
+
+	logSources.RemoveSource(source)
+	// the run loop handles the removal before receiving the stop signal
+	launcher.stop <- struct{}{}
+	assert.Empty(t, launcher.tailers)
+}
+
+func TestLauncherDisabled(t *testing.T) {
+	logSources := sources.NewLogSources()
+	launcher := NewLauncher(false)
+	launcher.Start(logSources, mock.NewMockProvider(), nil, tailers.NewTailerTracker())
+
+	source := sources.NewLogSource("events", &config.LogsConfig{Type: config.CommandType, Command: "true"})
+	logSources.AddSource(source)
+	launcher.stop <- struct{}{}
+
+	assert.True(t, source.Status.IsError())
+	assert.Empty(t, launcher.tailers)
+}
+
+func TestLauncherRejectsContainerSources(t *testing.T) {
+	logSources := sources.NewLogSources()
+	launcher := NewLauncher(true)
+	launcher.Start(logSources, mock.NewMockProvider(), nil, tailers.NewTailerTracker())
+
+	source := sources.NewLogSource("events", &config.LogsConfig{Type: config.CommandType, Command: "true", Identifier: "a1887023ed72"})
+	logSources.AddSource(source)
+	launcher.stop <- struct{}{}
+
+	assert.True(t, source.Status.IsError())
+	assert.Empty(t, launcher.tailers)
+}
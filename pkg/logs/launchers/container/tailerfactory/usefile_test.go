@@ -42,6 +42,13 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// KeepAlive implements auditor.Registry#KeepAlive.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) KeepAlive(identifier string) {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
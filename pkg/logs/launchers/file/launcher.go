@@ -7,6 +7,7 @@
 package file
 
 import (
+	"os"
 	"regexp"
 	"time"
 
@@ -48,6 +49,20 @@ type Launcher struct {
 	// Feature flag defaulting to false, use `logs_config.validate_pod_container_id`.
 	validatePodContainerID bool
 	scanPeriod             time.Duration
+	// consumedArchives holds the identifiers of the compressed files which have been
+	// read until their end, they are not tailed again and their registry entries are
+	// kept alive as long as the files exist.
+	consumedArchives map[string]struct{}
+	// archiveIdentifiers caches the identifiers of the compressed files by path.
+	archiveIdentifiers map[string]archiveFingerprint
+}
+
+// archiveFingerprint is the identifier of a compressed file, valid as long as the
+// size and modification time of the file don't change.
+type archiveFingerprint struct {
+	size       int64
+	modTime    time.Time
+	identifier string
 }
 
 // NewLauncher returns a new launcher.
@@ -72,6 +87,8 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		stop:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		consumedArchives:       make(map[string]struct{}),
+		archiveIdentifiers:     make(map[string]archiveFingerprint),
 	}
 }
 
@@ -147,6 +164,11 @@ func (s *Launcher) scan() {
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsArchiveConsumed() {
+				s.consumedArchives[tailer.Identifier()] = struct{}{}
+				// track the path of the archive to forget it once the file is removed
+				_, _ = s.archiveIdentifier(file.Path)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -188,7 +210,7 @@ func (s *Launcher) scan() {
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
-		if !isTailed && tailersLen < s.tailingLimit {
+		if !isTailed && tailersLen < s.tailingLimit && !s.isArchiveConsumed(file) {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
 			succeeded := s.startNewTailer(file, config.Beginning)
 			if !succeeded {
@@ -202,6 +224,8 @@ func (s *Launcher) scan() {
 	}
 	log.Debugf("After starting new tailers, there are %d tailers running. Limit is %d.\n", tailersLen, s.tailingLimit)
 
+	s.refreshConsumedArchives()
+
 	// Check how many file handles the Agent process has open and log a warning if the process is coming close to the OS file limit
 	fileStats, err := util.GetProcessFileStats()
 	if err == nil {
@@ -249,12 +273,17 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			continue
 		}
 
+		if s.isArchiveConsumed(file) {
+			continue
+		}
+
 		mode, _ := config.TailingModeFromString(source.Config.TailingMode)
 
-		if source.Config.Identifier != "" {
+		if source.Config.Identifier != "" || file.Compression() != "" {
 			// only sources generated from a service discovery will contain a config identifier,
 			// in which case we want to collect all logs.
 			// FIXME: better detect a source that has been generated from a service discovery.
+			// Compressed files are archives which are always collected from the beginning.
 			mode = config.Beginning
 		}
 
@@ -262,6 +291,58 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 	}
 }
 
+// isArchiveConsumed returns true if the file is a compressed file which has already been read until its end.
+func (s *Launcher) isArchiveConsumed(file *tailer.File) bool {
+	if file.Compression() == "" || len(s.consumedArchives) == 0 {
+		return false
+	}
+	identifier, err := s.archiveIdentifier(file.Path)
+	if err != nil {
+		return false
+	}
+	_, consumed := s.consumedArchives[identifier]
+	return consumed
+}
+
+// archiveIdentifier returns the identifier of a compressed file, it is only computed
+// again when the size or the modification time of the file changed.
+func (s *Launcher) archiveIdentifier(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if cached, ok := s.archiveIdentifiers[path]; ok && cached.size == fi.Size() && cached.modTime.Equal(fi.ModTime()) {
+		return cached.identifier, nil
+	}
+	identifier, err := tailer.ArchiveIdentifier(path)
+	if err != nil {
+		return "", err
+	}
+	s.archiveIdentifiers[path] = archiveFingerprint{size: fi.Size(), modTime: fi.ModTime(), identifier: identifier}
+	return identifier, nil
+}
+
+// refreshConsumedArchives forgets the compressed files which don't exist anymore and
+// keeps alive the registry entries of the consumed ones, so that they are not collected
+// again after a restart, even once the registry TTL is reached.
+func (s *Launcher) refreshConsumedArchives() {
+	present := make(map[string]struct{}, len(s.archiveIdentifiers))
+	for path, cached := range s.archiveIdentifiers {
+		if _, err := os.Stat(path); err != nil {
+			delete(s.archiveIdentifiers, path)
+			continue
+		}
+		present[cached.identifier] = struct{}{}
+	}
+	for identifier := range s.consumedArchives {
+		if _, ok := present[identifier]; !ok {
+			delete(s.consumedArchives, identifier)
+			continue
+		}
+		s.registry.KeepAlive(identifier)
+	}
+}
+
 // startNewTailer creates a new tailer, making it tail from the last committed offset, the beginning or the end of the file,
 // returns true if the operation succeeded, false otherwise.
 func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool {
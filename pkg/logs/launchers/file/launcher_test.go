@@ -8,6 +8,7 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -666,3 +667,76 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
+
+func TestLauncherDoesNotTailConsumedArchivesAgain(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/archive.log.gz", testDir)
+
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte("archived line\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Nil(t, f.Close())
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name")
+	launcher.pipelineProvider = mock.NewMockProvider()
+	registry := auditor.NewRegistry()
+	launcher.registry = registry
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.gz", testDir), Compression: config.AutoCompression})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(pkgConfig.Datadog, util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	msg := <-outputChan
+	assert.Equal(t, "archived line", string(msg.GetContent()))
+
+	tailer, _ := launcher.tailers.Get(path)
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+
+	// the consumed archive is neither tailed nor read again
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Equal(t, 0, len(outputChan))
+
+	// the registry entry of the consumed archive is kept alive while the file exists
+	identifier := tailer.Identifier()
+	assert.Equal(t, 2, registry.KeptAlive[identifier])
+	assert.Len(t, launcher.archiveIdentifiers, 1)
+
+	assert.Nil(t, os.Remove(path))
+	launcher.scan()
+	assert.Empty(t, launcher.consumedArchives)
+	assert.Empty(t, launcher.archiveIdentifiers)
+	assert.Equal(t, 2, registry.KeptAlive[identifier])
+}
+
+func TestLauncherCachesArchiveIdentifiers(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/archive.log.gz", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("first"), 0644))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name")
+	first, err := launcher.archiveIdentifier(path)
+	assert.Nil(t, err)
+
+	// the identifier is not computed again while the file is unchanged
+	cached := launcher.archiveIdentifiers[path]
+	cached.identifier = "cached"
+	launcher.archiveIdentifiers[path] = cached
+	identifier, err := launcher.archiveIdentifier(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "cached", identifier)
+
+	assert.Nil(t, os.WriteFile(path, []byte("second content"), 0644))
+	identifier, err = launcher.archiveIdentifier(path)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "cached", identifier)
+	assert.NotEqual(t, first, identifier)
+}
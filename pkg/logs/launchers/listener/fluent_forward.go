@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/fluentforward"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// A FluentForwardListener accepts the connections of the Fluentd and Fluent Bit clients,
+// over TCP or a unix socket, and delegates the read operations to a tailer.
+type FluentForwardListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	idleTimeout      time.Duration
+	maxEntrySize     int
+	listener         net.Listener
+	tailers          map[*tailer.Tailer]struct{}
+	mu               sync.Mutex
+	done             chan struct{}
+}
+
+// NewFluentForwardListener returns an initialized FluentForwardListener, the clients
+// sending entries larger than maxEntrySize bytes are disconnected.
+func NewFluentForwardListener(pipelineProvider pipeline.Provider, source *sources.LogSource, maxEntrySize int) *FluentForwardListener {
+	return &FluentForwardListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		idleTimeout:      parseIdleTimeout(source),
+		maxEntrySize:     maxEntrySize,
+		tailers:          make(map[*tailer.Tailer]struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// address returns the network and the address to listen on, the unix socket
+// path taking precedence over the port.
+func (l *FluentForwardListener) address() (string, string) {
+	if l.source.Config.Path != "" {
+		return "unix", l.source.Config.Path
+	}
+	return "tcp", fmt.Sprintf(":%d", l.source.Config.Port)
+}
+
+// Start starts the listener to accept new incoming connections.
+func (l *FluentForwardListener) Start() {
+	network, address := l.address()
+	log.Infof("Starting Fluent Forward listener on %s %s", network, address)
+	if network == "unix" {
+		// remove the socket left by a previous run
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Can't remove existing socket %s: %v", address, err)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Errorf("Can't start Fluent Forward listener on %s %s: %v", network, address, err)
+		l.source.Status.Error(err)
+		close(l.done)
+		return
+	}
+	l.listener = listener
+	l.source.Status.Success()
+	go l.run()
+}
+
+// Stop stops the listener from accepting new connections and all the active tailers.
+func (l *FluentForwardListener) Stop() {
+	network, address := l.address()
+	log.Infof("Stopping Fluent Forward listener on %s %s", network, address)
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stopper := startstop.NewParallelStopper()
+	for t := range l.tailers {
+		stopper.Add(t)
+	}
+	stopper.Stop()
+	l.tailers = make(map[*tailer.Tailer]struct{})
+}
+
+// run accepts new connections and creates a dedicated tailer for each.
+func (l *FluentForwardListener) run() {
+	defer close(l.done)
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !isClosedConnError(err) {
+				log.Warnf("Can't accept Fluent Forward connections: %v", err)
+				l.source.Status.Error(err)
+			}
+			return
+		}
+		l.startTailer(conn)
+	}
+}
+
+// startTailer creates and starts a new tailer, which is removed once its connection is closed.
+func (l *FluentForwardListener) startTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.idleTimeout, l.maxEntrySize)
+	l.tailers[t] = struct{}{}
+	t.Start()
+	go func() {
+		t.Wait()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.tailers, t)
+	}()
+}
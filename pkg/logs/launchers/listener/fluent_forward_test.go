@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package listener
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func fluentMessageEntry(tag, content string) []byte {
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, tag)
+	b = msgp.AppendInt64(b, 1700000000)
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"log": content})
+	return b
+}
+
+func TestFluentForwardShouldReceiveMessagesOverTCP(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewFluentForwardListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.FluentForwardType, Port: tcpTestPort}), 1024*1024)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(fluentMessageEntry("app", "hello world"))
+	require.NoError(t, err)
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+}
+
+func TestFluentForwardShouldReceiveMessagesOverUnixSocket(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	path := filepath.Join(t.TempDir(), "fluent.sock")
+	listener := NewFluentForwardListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.FluentForwardType, Path: path}), 1024*1024)
+	listener.Start()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+
+	_, err = conn.Write(fluentMessageEntry("app", "hello world"))
+	require.NoError(t, err)
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+
+	// stopping the listener closes the active connections
+	listener.Stop()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
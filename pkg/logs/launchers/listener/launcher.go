@@ -17,19 +17,23 @@ import (
 
 // Launcher summons different protocol specific listeners based on configuration
 type Launcher struct {
-	pipelineProvider pipeline.Provider
-	frameSize        int
-	tcpSources       chan *sources.LogSource
-	udpSources       chan *sources.LogSource
-	listeners        []startstop.StartStoppable
-	stop             chan struct{}
+	pipelineProvider   pipeline.Provider
+	frameSize          int
+	fluentMaxEntrySize int
+	tcpSources         chan *sources.LogSource
+	udpSources         chan *sources.LogSource
+	syslogSources      chan *sources.LogSource
+	fluentSources      chan *sources.LogSource
+	listeners          []startstop.StartStoppable
+	stop               chan struct{}
 }
 
 // NewLauncher returns an initialized Launcher
-func NewLauncher(frameSize int) *Launcher {
+func NewLauncher(frameSize int, fluentMaxEntrySize int) *Launcher {
 	return &Launcher{
-		frameSize: frameSize,
-		stop:      make(chan struct{}),
+		frameSize:          frameSize,
+		fluentMaxEntrySize: fluentMaxEntrySize,
+		stop:               make(chan struct{}),
 	}
 }
 
@@ -40,6 +44,8 @@ func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvid
 	l.pipelineProvider = pipelineProvider
 	l.tcpSources = sourceProvider.GetAddedForType(config.TCPType)
 	l.udpSources = sourceProvider.GetAddedForType(config.UDPType)
+	l.syslogSources = sourceProvider.GetAddedForType(config.SyslogType)
+	l.fluentSources = sourceProvider.GetAddedForType(config.FluentForwardType)
 	go l.run()
 }
 
@@ -55,6 +61,20 @@ func (l *Launcher) run() {
 			listener := NewUDPListener(l.pipelineProvider, source, l.frameSize)
 			listener.Start()
 			l.listeners = append(l.listeners, listener)
+		case source := <-l.syslogSources:
+			var listener startstop.StartStoppable
+			// syslog messages are received over TCP unless configured otherwise
+			if source.Config.Protocol == config.UDPType {
+				listener = NewUDPListener(l.pipelineProvider, source, l.frameSize)
+			} else {
+				listener = NewTCPListener(l.pipelineProvider, source, l.frameSize)
+			}
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
+		case source := <-l.fluentSources:
+			listener := NewFluentForwardListener(l.pipelineProvider, source, l.fluentMaxEntrySize)
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
 		case <-l.stop:
 			return
 		}
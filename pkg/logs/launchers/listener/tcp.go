@@ -33,26 +33,29 @@ type TCPListener struct {
 
 // NewTCPListener returns an initialized TCPListener
 func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *TCPListener {
-	var idleTimeout time.Duration
-	if source.Config.IdleTimeout != "" {
-		var err error
-		idleTimeout, err = time.ParseDuration(source.Config.IdleTimeout)
-		if err != nil {
-			log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
-			idleTimeout = 0
-		}
-	}
-
 	return &TCPListener{
 		pipelineProvider: pipelineProvider,
 		source:           source,
-		idleTimeout:      idleTimeout,
+		idleTimeout:      parseIdleTimeout(source),
 		frameSize:        frameSize,
 		tailers:          []*tailer.Tailer{},
 		stop:             make(chan struct{}, 1),
 	}
 }
 
+// parseIdleTimeout returns the idle timeout of the connections of a source, 0 if none.
+func parseIdleTimeout(source *sources.LogSource) time.Duration {
+	if source.Config.IdleTimeout == "" {
+		return 0
+	}
+	idleTimeout, err := time.ParseDuration(source.Config.IdleTimeout)
+	if err != nil {
+		log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
+		return 0
+	}
+	return idleTimeout
+}
+
 // Start starts the listener to accepts new incoming connections.
 func (l *TCPListener) Start() {
 	log.Infof("Starting TCP forwarder on port %d, with read buffer size: %d", l.source.Config.Port, l.frameSize)
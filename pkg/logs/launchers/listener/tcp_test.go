@@ -42,6 +42,26 @@ func TestTCPShouldReceivesMessages(t *testing.T) {
 	listener.Stop()
 }
 
+func TestTCPShouldReceiveSyslogMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	var msg *message.Message
+
+	fmt.Fprintf(conn, "38 <12>1 - myhost myapp - - - hello world")
+	msg = <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+
+	listener.Stop()
+}
+
 func TestTCPDoesNotTruncateMessagesThatAreBiggerThanTheReadBufferSize(t *testing.T) {
 	pp := mock.NewMockProvider()
 	msgChan := pp.NextPipelineChan()
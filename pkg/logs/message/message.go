@@ -36,7 +36,10 @@ type Message struct {
 	Origin             *Origin
 	Status             string
 	IngestionTimestamp int64
-	RawDataLen         int
+	// Optional. Must be UTC. Time at which the log was emitted when it is known,
+	// e.g. provided by the source or extracted by a parsing rule.
+	EventTimestamp time.Time
+	RawDataLen     int
 	// Extra information from the parsers
 	ParsingExtra
 	// Extra information for Serverless Logs messages
@@ -136,6 +139,24 @@ func (m *MessageContent) SetContent(content []byte) {
 	}
 }
 
+// GetStructuredContent returns the structured content of the message,
+// it is nil unless the MessageContent is in `StateStructured`.
+func (m *MessageContent) GetStructuredContent() StructuredContent {
+	if m.State != StateStructured {
+		return nil
+	}
+	return m.structuredContent
+}
+
+// SetStructuredContent stores the given structured content and sets the MessageContent
+// state to structured. It is used by the processor when a parsing rule extracted
+// attributes from an unstructured content.
+func (m *MessageContent) SetStructuredContent(content StructuredContent) {
+	m.structuredContent = content
+	m.content = nil
+	m.State = StateStructured
+}
+
 // SetRendered sets the content for the MessageContent and sets MessageContent state to rendered.
 func (m *MessageContent) SetRendered(content []byte) {
 	m.content = content
@@ -302,6 +323,18 @@ func (m *Message) GetStatus() string {
 	return m.Status
 }
 
+// GetTimestamp returns the time at which the log was emitted when it is known,
+// otherwise the current time.
+func (m *Message) GetTimestamp() time.Time {
+	if !m.EventTimestamp.IsZero() {
+		return m.EventTimestamp
+	}
+	if !m.ServerlessExtra.Timestamp.IsZero() {
+		return m.ServerlessExtra.Timestamp
+	}
+	return time.Now().UTC()
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
@@ -57,6 +57,24 @@ var (
 	// TlmSenderLatency a histogram of http sender latency (ms)
 	TlmSenderLatency = telemetry.NewHistogram("logs", "sender_latency",
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
+	// TlmLogsSampledOut is the total number of logs dropped by the sampling and rate limiting rules
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		[]string{"rule_type", "source"}, "Total number of logs dropped by the sampling and rate limiting rules")
+	// TlmLogsDeduplicated is the total number of logs collapsed by the dedup rules
+	TlmLogsDeduplicated = telemetry.NewCounter("logs", "deduplicated",
+		[]string{"source"}, "Total number of logs collapsed by the dedup rules")
+	// TlmLogsRouted is the total number of logs sent to the routes
+	TlmLogsRouted = telemetry.NewCounter("logs", "routed",
+		[]string{"route"}, "Total number of logs sent to the routes")
+	// TlmLogsRoutedDropped is the total number of logs dropped because their route was falling behind
+	TlmLogsRoutedDropped = telemetry.NewCounter("logs", "routed_dropped",
+		[]string{"route"}, "Total number of logs dropped because their route was falling behind")
+	// TlmLogMetricsGenerated is the total number of metric samples generated from logs
+	TlmLogMetricsGenerated = telemetry.NewCounter("logs", "generated_metrics",
+		nil, "Total number of metric samples generated from logs")
+	// TlmLogMetricsInvalidValues is the total number of logs whose metric value could not be parsed
+	TlmLogMetricsInvalidValues = telemetry.NewCounter("logs", "generated_metrics_invalid_values",
+		nil, "Total number of logs whose metric value could not be parsed")
 	// DestinationExpVars a map of sender utilization metrics for each http destination
 	DestinationExpVars = expvar.Map{}
 	// TODO: Add LogsCollected for the total number of collected logs.
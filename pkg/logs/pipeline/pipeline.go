@@ -27,6 +27,7 @@ type Pipeline struct {
 	processor *processor.Processor
 	strategy  sender.Strategy
 	sender    *sender.Sender
+	routes    []*routePipeline
 }
 
 // NewPipeline returns a new Pipeline
@@ -36,9 +37,11 @@ func NewPipeline(outputChan chan *message.Payload,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	serverless bool,
+	diskBufferConfig *sender.DiskBufferConfig,
+	metricSender processor.MetricSender,
 	pipelineID int) *Pipeline {
 
-	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineID, serverless)
+	mainDestinations := getDestinations(endpoints, destinationsContext, fmt.Sprintf("logs_%d", pipelineID), serverless)
 
 	strategyInput := make(chan *message.Message, config.ChanSize)
 	senderInput := make(chan *message.Payload, 1) // Only buffer 1 message since payloads can be large
@@ -58,10 +61,23 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, pipelineID)
-	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	if diskBufferConfig != nil {
+		logsSender = sender.NewSenderWithDiskBuffer(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, *diskBufferConfig)
+	} else {
+		logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
+
+	var routes []*processor.Route
+	var routePipelines []*routePipeline
+	for _, route := range endpoints.Routes {
+		routePipeline := newRoutePipeline(route, outputChan, endpoints, destinationsContext, serverless, pipelineID)
+		routes = append(routes, &processor.Route{Rule: route, OutputChan: routePipeline.inputChan})
+		routePipelines = append(routePipelines, routePipeline)
+	}
+
+	processor := processor.NewWithRoutes(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver, metricSender, routes)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -69,6 +85,7 @@ func NewPipeline(outputChan chan *message.Payload,
 		processor: processor,
 		strategy:  strategy,
 		sender:    logsSender,
+		routes:    routePipelines,
 	}
 }
 
@@ -76,6 +93,9 @@ func NewPipeline(outputChan chan *message.Payload,
 func (p *Pipeline) Start() {
 	p.sender.Start()
 	p.strategy.Start()
+	for _, route := range p.routes {
+		route.start()
+	}
 	p.processor.Start()
 }
 
@@ -84,25 +104,31 @@ func (p *Pipeline) Stop() {
 	p.processor.Stop()
 	p.strategy.Stop()
 	p.sender.Stop()
+	for _, route := range p.routes {
+		route.stop()
+	}
 }
 
 // Flush flushes synchronously the processor and sender managed by this pipeline.
 func (p *Pipeline) Flush(ctx context.Context) {
 	p.flushChan <- struct{}{}
+	for _, route := range p.routes {
+		route.flush()
+	}
 	p.processor.Flush(ctx) // flush messages in the processor into the sender
 }
 
-func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineID int, serverless bool) *client.Destinations {
+func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, telemetryPrefix string, serverless bool) *client.Destinations {
 	reliable := []client.Destination{}
 	additionals := []client.Destination{}
 
 	if endpoints.UseHTTP {
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
-			telemetryName := fmt.Sprintf("logs_%d_reliable_%d", pipelineID, i)
+			telemetryName := fmt.Sprintf("%s_reliable_%d", telemetryPrefix, i)
 			reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, !serverless, telemetryName))
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
-			telemetryName := fmt.Sprintf("logs_%d_unreliable_%d", pipelineID, i)
+			telemetryName := fmt.Sprintf("%s_unreliable_%d", telemetryPrefix, i)
 			additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, telemetryName))
 		}
 		return client.NewDestinations(reliable, additionals)
@@ -7,6 +7,9 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"time"
 
 	"go.uber.org/atomic"
 
@@ -15,10 +18,20 @@ import (
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
 )
 
+// metricsCommitInterval is the interval at which the metrics generated from logs are
+// committed to the aggregator.
+const metricsCommitInterval = 10 * time.Second
+
+// MetricSender is the aggregator sender used to send the metrics generated
+// by the `generate_metric` processing rules.
+type MetricSender = processor.MetricSender
+
 // Provider provides message channels
 type Provider interface {
 	Start()
@@ -40,18 +53,29 @@ type provider struct {
 	pipelines            []*Pipeline
 	currentPipelineIndex *atomic.Uint32
 	destinationsContext  *client.DestinationsContext
+	diskBufferConfig     *sender.DiskBufferConfig
+	metricSender         MetricSender
+	stopCommit           chan struct{}
 
 	serverless bool
 }
 
-// NewProvider returns a new Provider
-func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false)
+// NewProvider returns a new Provider.
+// The metrics generated by the `generate_metric` processing rules are sent with metricSender,
+// they are dropped if it is nil.
+func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, metricSender MetricSender) Provider {
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, nil, metricSender, false)
+}
+
+// NewProviderWithDiskBuffer returns a new Provider whose pipelines store payloads on disk
+// while the reliable endpoints are unreachable. The disk buffer is shared evenly between the pipelines.
+func NewProviderWithDiskBuffer(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diskBufferConfig sender.DiskBufferConfig, metricSender MetricSender) Provider {
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, &diskBufferConfig, metricSender, false)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
 func NewServerlessProvider(numberOfPipelines int, auditor auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, true)
+	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, nil, nil, true)
 }
 
 // NewMockProvider creates a new provider that will not provide any pipelines.
@@ -59,7 +83,7 @@ func NewMockProvider() Provider {
 	return &provider{}
 }
 
-func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool) Provider {
+func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diskBufferConfig *sender.DiskBufferConfig, metricSender MetricSender, serverless bool) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
 		auditor:                   auditor,
@@ -69,6 +93,8 @@ func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessa
 		pipelines:                 []*Pipeline{},
 		currentPipelineIndex:      atomic.NewUint32(0),
 		destinationsContext:       destinationsContext,
+		diskBufferConfig:          diskBufferConfig,
+		metricSender:              metricSender,
 		serverless:                serverless,
 	}
 }
@@ -79,10 +105,43 @@ func (p *provider) Start() {
 	p.outputChan = p.auditor.Channel()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, p.pipelineDiskBufferConfig(i), p.metricSender, i)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
+
+	if p.metricSender != nil {
+		p.stopCommit = make(chan struct{})
+		go p.commitMetrics(p.stopCommit)
+	}
+}
+
+// commitMetrics periodically commits the metrics generated from logs, so that the
+// aggregator flushes them.
+func (p *provider) commitMetrics(stop chan struct{}) {
+	ticker := time.NewTicker(metricsCommitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.metricSender.Commit()
+		case <-stop:
+			p.metricSender.Commit()
+			return
+		}
+	}
+}
+
+// pipelineDiskBufferConfig returns the disk buffer settings of a pipeline, each pipeline
+// uses its own folder and an even share of the maximum size.
+func (p *provider) pipelineDiskBufferConfig(pipelineID int) *sender.DiskBufferConfig {
+	if p.diskBufferConfig == nil {
+		return nil
+	}
+	bufferConfig := *p.diskBufferConfig
+	bufferConfig.Path = filepath.Join(bufferConfig.Path, fmt.Sprintf("pipeline_%d", pipelineID))
+	bufferConfig.MaxSizeBytes /= int64(p.numberOfPipelines)
+	return &bufferConfig
 }
 
 // Stop stops all pipelines in parallel,
@@ -93,6 +152,10 @@ func (p *provider) Stop() {
 		stopper.Add(pipeline)
 	}
 	stopper.Stop()
+	if p.stopCommit != nil {
+		close(p.stopCommit)
+		p.stopCommit = nil
+	}
 	p.pipelines = p.pipelines[:0]
 	p.outputChan = nil
 }
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+)
+
+// routePipeline batches and sends the messages of a route to the endpoints of the route.
+// The payloads sent are not audited, the main pipeline being responsible for it, unless
+// the route is exclusive: its messages are then not sent to the main pipeline.
+type routePipeline struct {
+	inputChan chan *message.Message
+	flushChan chan struct{}
+	sinkChan  chan *message.Payload
+	strategy  sender.Strategy
+	sender    *sender.Sender
+	done      chan struct{}
+}
+
+func newRoutePipeline(route *config.Route, outputChan chan *message.Payload, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool, pipelineID int) *routePipeline {
+	routeEndpoints := endpoints.ForRoute(route)
+	destinations := getDestinations(routeEndpoints, destinationsContext, fmt.Sprintf("logs_%d_route_%s", pipelineID, route.Name), serverless)
+
+	inputChan := make(chan *message.Message, config.ChanSize)
+	senderInput := make(chan *message.Payload, 1)
+	flushChan := make(chan struct{})
+
+	// the payloads of the exclusive routes are audited, the others are drained
+	var sinkChan chan *message.Payload
+	if !route.Exclusive {
+		sinkChan = make(chan *message.Payload, config.ChanSize)
+		outputChan = sinkChan
+	}
+
+	return &routePipeline{
+		inputChan: inputChan,
+		flushChan: flushChan,
+		sinkChan:  sinkChan,
+		strategy:  getStrategy(inputChan, senderInput, flushChan, routeEndpoints, serverless, pipelineID),
+		sender:    sender.NewSender(senderInput, outputChan, destinations, config.DestinationPayloadChanSize),
+		done:      make(chan struct{}),
+	}
+}
+
+func (r *routePipeline) start() {
+	if r.sinkChan != nil {
+		go func() {
+			for range r.sinkChan { //nolint:revive // the payloads are only drained
+			}
+			close(r.done)
+		}()
+	}
+	r.sender.Start()
+	r.strategy.Start()
+}
+
+// stop must be called once the processor feeding the route is stopped.
+func (r *routePipeline) stop() {
+	r.strategy.Stop()
+	r.sender.Stop()
+	if r.sinkChan != nil {
+		close(r.sinkChan)
+		<-r.done
+	}
+}
+
+func (r *routePipeline) flush() {
+	r.flushChan <- struct{}{}
+}
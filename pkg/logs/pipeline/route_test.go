@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestPipelineSendsMatchingMessagesToRoutes(t *testing.T) {
+	mainRequests := make(chan int, 10)
+	mainServer := http.NewTestServerWithOptions(200, 0, true, mainRequests)
+	defer mainServer.Stop()
+	routeRequests := make(chan int, 10)
+	routeServer := http.NewTestServerWithOptions(200, 0, true, routeRequests)
+	defer routeServer.Stop()
+
+	endpoints := config.NewEndpointsWithBatchSettings(mainServer.Endpoint, nil, false, true, 10*time.Millisecond, 1, 1, 1000, 10)
+	endpoints.Routes = []*config.Route{{Name: "security", Sources: []string{"auth"}, Endpoints: []config.Endpoint{routeServer.Endpoint}}}
+
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	defer destinationsContext.Stop()
+
+	outputChan := make(chan *message.Payload, 10)
+	pipeline := NewPipeline(outputChan, nil, endpoints, destinationsContext, diagnostic.NewBufferedMessageReceiver(nil), false, nil, nil, 0)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	authSource := sources.NewLogSource("", &config.LogsConfig{Source: "auth"})
+	webSource := sources.NewLogSource("", &config.LogsConfig{Source: "web"})
+	pipeline.InputChan <- message.NewMessageWithSource([]byte("authentication failed"), message.StatusInfo, authSource, 0)
+	pipeline.InputChan <- message.NewMessageWithSource([]byte("GET /"), message.StatusInfo, webSource, 0)
+
+	// all the messages are sent to the main endpoint and audited
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, 200, <-mainRequests)
+		<-outputChan
+	}
+
+	// only the matching message is sent to the route endpoint, and is not audited again
+	assert.Equal(t, 200, <-routeRequests)
+	assert.Never(t, func() bool { return len(routeRequests) > 0 || len(outputChan) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestPipelineAuditsExclusiveRoutes(t *testing.T) {
+	mainRequests := make(chan int, 10)
+	mainServer := http.NewTestServerWithOptions(200, 0, true, mainRequests)
+	defer mainServer.Stop()
+	routeRequests := make(chan int, 10)
+	routeServer := http.NewTestServerWithOptions(200, 0, true, routeRequests)
+	defer routeServer.Stop()
+
+	endpoints := config.NewEndpointsWithBatchSettings(mainServer.Endpoint, nil, false, true, 10*time.Millisecond, 1, 1, 1000, 10)
+	endpoints.Routes = []*config.Route{{Name: "security", Sources: []string{"auth"}, Endpoints: []config.Endpoint{routeServer.Endpoint}, Exclusive: true}}
+
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	defer destinationsContext.Stop()
+
+	outputChan := make(chan *message.Payload, 10)
+	pipeline := NewPipeline(outputChan, nil, endpoints, destinationsContext, diagnostic.NewBufferedMessageReceiver(nil), false, nil, nil, 0)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	authSource := sources.NewLogSource("", &config.LogsConfig{Source: "auth"})
+	pipeline.InputChan <- message.NewMessageWithSource([]byte("authentication failed"), message.StatusInfo, authSource, 0)
+
+	// the message is only sent to the route endpoint, which audits it
+	assert.Equal(t, 200, <-routeRequests)
+	payload := <-outputChan
+	assert.Len(t, payload.Messages, 1)
+	assert.Never(t, func() bool { return len(mainRequests) > 0 || len(outputChan) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
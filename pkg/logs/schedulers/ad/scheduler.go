@@ -183,6 +183,13 @@ func (s *Scheduler) toSources(config integration.Config) ([]*sourcesPkg.LogSourc
 	configName := s.configName(config)
 	var sources []*sourcesPkg.LogSource
 	for _, cfg := range configs {
+		if cfg.Type == logsConfig.CommandType && config.Provider != names.File {
+			// commands run with the permissions of the agent, they must not be defined by
+			// anyone able to label a container or to annotate a pod
+			log.Warnf("Ignoring the command logs config of %s from %s, command sources can only be defined in configuration files", configName, config.Provider)
+			continue
+		}
+
 		// if no service is set fall back to the global one
 		if cfg.Service == "" && globalServiceDefined {
 			cfg.Service = commonGlobalOptions.Service
@@ -145,3 +145,22 @@ func TestIgnoreConfigIfLogsExcluded(t *testing.T) {
 	scheduler.Unschedule([]integration.Config{configService})
 	require.Equal(t, 0, len(spy.Events)) // no events
 }
+
+func TestScheduleConfigIgnoresCommandSourcesNotFromFiles(t *testing.T) {
+	scheduler, spy := setup()
+	scheduler.Schedule([]integration.Config{{
+		Name:       "events",
+		LogsConfig: []byte(`[{"type":"command","command":"cat","args":["/etc/shadow"]}]`),
+		Provider:   names.Kubernetes,
+	}})
+	assert.Empty(t, spy.Events)
+
+	scheduler.Schedule([]integration.Config{{
+		Name:       "events",
+		LogsConfig: []byte("logs:\n  - type: command\n    command: kubectl\n    args: [get, events, -w]\n"),
+		Provider:   names.File,
+	}})
+	require.Equal(t, 1, len(spy.Events))
+	assert.Equal(t, config.CommandType, spy.Events[0].Source.Config.Type)
+	assert.Equal(t, "kubectl", spy.Events[0].Source.Config.Command)
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	diskBufferExtension  = ".logs"
+	diskBufferFileFormat = "2006_01_02__15_04_05.000000000_"
+	diskBufferVersion    = byte(1)
+)
+
+var (
+	tlmDiskBufferStored   = telemetry.NewCounter("logs_sender", "disk_buffer_payloads_stored", []string{}, "Payloads stored in the on-disk buffer")
+	tlmDiskBufferReplayed = telemetry.NewCounter("logs_sender", "disk_buffer_payloads_replayed", []string{}, "Payloads replayed from the on-disk buffer")
+	tlmDiskBufferDropped  = telemetry.NewCounter("logs_sender", "disk_buffer_payloads_dropped", []string{}, "Payloads removed from the on-disk buffer because it was full, outdated or unreadable")
+	tlmDiskBufferSize     = telemetry.NewGauge("logs_sender", "disk_buffer_size_bytes", []string{}, "Size of the payloads stored in the on-disk buffer")
+)
+
+// DiskBufferConfig holds the settings of the on-disk buffer used by the sender to spill
+// payloads when no reliable destination can accept them.
+type DiskBufferConfig struct {
+	// Path is the folder where the payloads are stored.
+	Path string
+	// MaxSizeBytes is the maximum size of the stored payloads.
+	MaxSizeBytes int64
+	// MaxDiskRatio is the maximum ratio of the disk that can be used, including other files.
+	MaxDiskRatio float64
+	// OutdatedFileInDays is the age after which a stored payload is removed without being replayed.
+	OutdatedFileInDays int
+}
+
+type diskUsageRetriever interface {
+	GetUsage(path string) (*filesystem.DiskUsage, error)
+}
+
+// diskBuffer stores payloads on disk, one file per payload, and returns them in FIFO order.
+// When the buffer is full, the oldest payloads are removed to make room for the new ones.
+// It is not thread-safe and is only used from the sender loop.
+type diskBuffer struct {
+	path               string
+	maxSizeBytes       int64
+	maxDiskRatio       float64
+	disk               diskUsageRetriever
+	filenames          []string
+	currentSizeInBytes int64
+}
+
+// newDiskBuffer creates the storage folder if needed and reloads the payloads stored
+// by a previous run, removing the outdated ones.
+func newDiskBuffer(config DiskBufferConfig, disk diskUsageRetriever) (*diskBuffer, error) {
+	if config.Path == "" {
+		return nil, errors.New("no path configured for the logs disk buffer")
+	}
+	if err := os.MkdirAll(config.Path, 0700); err != nil {
+		return nil, err
+	}
+
+	b := &diskBuffer{
+		path:         config.Path,
+		maxSizeBytes: config.MaxSizeBytes,
+		maxDiskRatio: config.MaxDiskRatio,
+		disk:         disk,
+	}
+	if err := b.reloadExistingFiles(time.Now().Add(-time.Duration(config.OutdatedFileInDays) * 24 * time.Hour)); err != nil {
+		return nil, err
+	}
+
+	// Check if there is an error when computing the available space
+	// to warn the user sooner (and not when there is an outage)
+	_, err := b.computeAvailableSpace()
+	return b, err
+}
+
+// Store writes the payload to disk, removing the oldest payloads if there is not enough room.
+func (b *diskBuffer) Store(payload *message.Payload) error {
+	data := encodePayload(payload)
+	size := int64(len(data))
+
+	if err := b.makeRoomFor(size); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(b.path, time.Now().UTC().Format(diskBufferFileFormat)+"*"+diskBufferExtension)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	b.filenames = append(b.filenames, file.Name())
+	b.currentSizeInBytes += size
+	tlmDiskBufferStored.Inc()
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	return nil
+}
+
+// Peek returns the oldest stored payload without removing it, nil if the buffer is empty.
+// Files which can't be read are removed.
+func (b *diskBuffer) Peek() *message.Payload {
+	for len(b.filenames) > 0 {
+		filename := b.filenames[0]
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			var payload *message.Payload
+			if payload, err = decodePayload(data); err == nil {
+				return payload
+			}
+		}
+		log.Warnf("Cannot read the logs payload stored in %s, removing it: %v", filename, err)
+		b.removeFirst()
+		tlmDiskBufferDropped.Inc()
+	}
+	return nil
+}
+
+// Pop removes the oldest stored payload.
+func (b *diskBuffer) Pop() {
+	if len(b.filenames) > 0 {
+		b.removeFirst()
+		tlmDiskBufferReplayed.Inc()
+	}
+}
+
+// Len returns the number of stored payloads.
+func (b *diskBuffer) Len() int {
+	return len(b.filenames)
+}
+
+func (b *diskBuffer) makeRoomFor(size int64) error {
+	if size > b.maxSizeBytes {
+		return fmt.Errorf("the payload is too big. Current:%v Maximum:%v", size, b.maxSizeBytes)
+	}
+
+	maxStorageInBytes, err := b.computeAvailableSpace()
+	if err != nil {
+		return err
+	}
+	for len(b.filenames) > 0 && b.currentSizeInBytes+size > maxStorageInBytes {
+		log.Errorf("Maximum disk space for logs payloads is reached. Removing %s", b.filenames[0])
+		b.removeFirst()
+		tlmDiskBufferDropped.Inc()
+	}
+	if b.currentSizeInBytes+size > maxStorageInBytes {
+		return fmt.Errorf("not enough disk space to store the payload")
+	}
+	return nil
+}
+
+// computeAvailableSpace returns the amount of bytes the buffer can use, taking into
+// account both the configured maximum size and the maximum disk ratio.
+func (b *diskBuffer) computeAvailableSpace() (int64, error) {
+	usage, err := b.disk.GetUsage(b.path)
+	if err != nil {
+		return 0, err
+	}
+	diskReserved := float64(usage.Total) * (1 - b.maxDiskRatio)
+	available := b.currentSizeInBytes + int64(usage.Available) - int64(math.Ceil(diskReserved))
+	if available < b.maxSizeBytes {
+		return available, nil
+	}
+	return b.maxSizeBytes, nil
+}
+
+func (b *diskBuffer) removeFirst() {
+	filename := b.filenames[0]
+	b.filenames = b.filenames[1:]
+
+	if info, err := os.Stat(filename); err == nil {
+		b.currentSizeInBytes -= info.Size()
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Cannot remove the logs payload file %s: %v", filename, err)
+	}
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+}
+
+func (b *diskBuffer) reloadExistingFiles(outdatedFileTime time.Time) error {
+	entries, err := os.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+
+	var files []os.FileInfo
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || filepath.Ext(entry.Name()) != diskBufferExtension {
+			continue
+		}
+		if info.ModTime().Before(outdatedFileTime) {
+			log.Infof("Removing outdated logs payload file %s", entry.Name())
+			_ = os.Remove(filepath.Join(b.path, entry.Name()))
+			tlmDiskBufferDropped.Inc()
+			continue
+		}
+		files = append(files, info)
+	}
+
+	// file names start with their creation time
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	for _, file := range files {
+		b.filenames = append(b.filenames, filepath.Join(b.path, file.Name()))
+		b.currentSizeInBytes += file.Size()
+	}
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	return nil
+}
+
+// encodePayload serializes the parts of the payload needed to send it again:
+// version (1 byte) | unencoded size (uint32) | encoding length (uint16) | encoding | encoded payload.
+// The messages are not stored as their offsets are already committed by the auditor.
+func encodePayload(payload *message.Payload) []byte {
+	var buf bytes.Buffer
+	buf.Grow(1 + 4 + 2 + len(payload.Encoding) + len(payload.Encoded))
+	buf.WriteByte(diskBufferVersion)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(payload.UnencodedSize))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(payload.Encoding)))
+	buf.WriteString(payload.Encoding)
+	buf.Write(payload.Encoded)
+	return buf.Bytes()
+}
+
+func decodePayload(data []byte) (*message.Payload, error) {
+	if len(data) < 7 {
+		return nil, errors.New("truncated payload")
+	}
+	if data[0] != diskBufferVersion {
+		return nil, fmt.Errorf("unsupported payload version %d", data[0])
+	}
+	unencodedSize := binary.LittleEndian.Uint32(data[1:5])
+	encodingLen := int(binary.LittleEndian.Uint16(data[5:7]))
+	if len(data) < 7+encodingLen {
+		return nil, errors.New("truncated payload")
+	}
+	return &message.Payload{
+		Encoding:      string(data[7 : 7+encodingLen]),
+		Encoded:       data[7+encodingLen:],
+		UnencodedSize: int(unencodedSize),
+	}, nil
+}
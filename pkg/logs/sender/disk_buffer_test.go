@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+type diskUsageRetrieverMock struct {
+	diskUsage *filesystem.DiskUsage
+}
+
+func (m diskUsageRetrieverMock) GetUsage(_ string) (*filesystem.DiskUsage, error) {
+	return m.diskUsage, nil
+}
+
+func newTestDiskBuffer(t *testing.T, path string, maxSizeBytes int64) *diskBuffer {
+	disk := diskUsageRetrieverMock{diskUsage: &filesystem.DiskUsage{Total: 10000, Available: 10000}}
+	buffer, err := newDiskBuffer(DiskBufferConfig{
+		Path:               path,
+		MaxSizeBytes:       maxSizeBytes,
+		MaxDiskRatio:       1,
+		OutdatedFileInDays: 1,
+	}, disk)
+	require.NoError(t, err)
+	return buffer
+}
+
+func newTestPayload(content string) *message.Payload {
+	return &message.Payload{
+		Messages:      []*message.Message{{}},
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content) * 2,
+	}
+}
+
+func TestDiskBufferStoreAndPeek(t *testing.T) {
+	buffer := newTestDiskBuffer(t, t.TempDir(), 1000)
+	assert.Nil(t, buffer.Peek())
+
+	require.NoError(t, buffer.Store(newTestPayload("first")))
+	require.NoError(t, buffer.Store(newTestPayload("second")))
+	assert.Equal(t, 2, buffer.Len())
+
+	payload := buffer.Peek()
+	require.NotNil(t, payload)
+	assert.Equal(t, []byte("first"), payload.Encoded)
+	assert.Equal(t, "gzip", payload.Encoding)
+	assert.Equal(t, 10, payload.UnencodedSize)
+	// the offsets of the messages are committed when the payload is stored
+	assert.Nil(t, payload.Messages)
+
+	buffer.Pop()
+	assert.Equal(t, []byte("second"), buffer.Peek().Encoded)
+	buffer.Pop()
+	assert.Nil(t, buffer.Peek())
+	assert.Equal(t, int64(0), buffer.currentSizeInBytes)
+}
+
+func TestDiskBufferRemovesOldestWhenFull(t *testing.T) {
+	// each payload takes 7 bytes of header, 4 bytes for the encoding and 10 bytes of content
+	buffer := newTestDiskBuffer(t, t.TempDir(), 50)
+
+	require.NoError(t, buffer.Store(newTestPayload("payload--1")))
+	require.NoError(t, buffer.Store(newTestPayload("payload--2")))
+	require.NoError(t, buffer.Store(newTestPayload("payload--3")))
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, []byte("payload--2"), buffer.Peek().Encoded)
+
+	assert.Error(t, buffer.Store(newTestPayload(string(make([]byte, 100)))))
+}
+
+func TestDiskBufferReloadsExistingFiles(t *testing.T) {
+	path := t.TempDir()
+	buffer := newTestDiskBuffer(t, path, 1000)
+	require.NoError(t, buffer.Store(newTestPayload("first")))
+	require.NoError(t, buffer.Store(newTestPayload("second")))
+	require.NoError(t, buffer.Store(newTestPayload("outdated")))
+
+	outdated := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(buffer.filenames[2], outdated, outdated))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "unrelated.txt"), []byte("foo"), 0600))
+
+	buffer = newTestDiskBuffer(t, path, 1000)
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, []byte("first"), buffer.Peek().Encoded)
+}
+
+func TestDiskBufferRemovesCorruptedFiles(t *testing.T) {
+	buffer := newTestDiskBuffer(t, t.TempDir(), 1000)
+	require.NoError(t, buffer.Store(newTestPayload("first")))
+	require.NoError(t, buffer.Store(newTestPayload("second")))
+	require.NoError(t, os.WriteFile(buffer.filenames[0], []byte{0xff}, 0600))
+
+	assert.Equal(t, []byte("second"), buffer.Peek().Encoded)
+	assert.Equal(t, 1, buffer.Len())
+}
@@ -12,6 +12,16 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// diskBufferReplayInterval is the interval at which the sender tries to replay stored payloads.
+	diskBufferReplayInterval = 100 * time.Millisecond
+	// diskBufferReplayBatchSize is the maximum number of stored payloads replayed at once
+	// so that the replay doesn't starve the incoming payloads.
+	diskBufferReplayBatchSize = 10
 )
 
 var (
@@ -27,12 +37,16 @@ var (
 // one reliable destination is also sending logs. However they do not update
 // the auditor or block the pipeline if they fail. There will always be at
 // least 1 reliable destination (the main destination).
+// When a disk buffer is configured, payloads that can't be sent to any reliable
+// destination are stored on disk instead of blocking the pipeline, and they are
+// replayed once a reliable destination recovers.
 type Sender struct {
 	inputChan    chan *message.Payload
 	outputChan   chan *message.Payload
 	destinations *client.Destinations
 	done         chan struct{}
 	bufferSize   int
+	diskBuffer   *diskBuffer
 }
 
 // NewSender returns a new sender.
@@ -46,6 +60,22 @@ func NewSender(inputChan chan *message.Payload, outputChan chan *message.Payload
 	}
 }
 
+// NewSenderWithDiskBuffer returns a new sender spilling payloads to disk when all reliable
+// destinations are failing. It falls back to a sender without disk buffer if the buffer can't be created.
+func NewSenderWithDiskBuffer(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, diskBufferConfig DiskBufferConfig) *Sender {
+	s := NewSender(inputChan, outputChan, destinations, bufferSize)
+	buffer, err := newDiskBuffer(diskBufferConfig, filesystem.NewDisk())
+	if buffer == nil {
+		log.Errorf("Cannot create the logs disk buffer in %s, payloads won't be stored on disk: %v", diskBufferConfig.Path, err)
+		return s
+	}
+	if err != nil {
+		log.Warnf("Cannot compute the available disk space for the logs disk buffer in %s: %v", diskBufferConfig.Path, err)
+	}
+	s.diskBuffer = buffer
+	return s
+}
+
 // Start starts the sender.
 func (s *Sender) Start() {
 	go s.run()
@@ -64,48 +94,112 @@ func (s *Sender) run() {
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		var startInUse = time.Now()
+	var replayTick <-chan time.Time
+	if s.diskBuffer != nil {
+		ticker := time.NewTicker(diskBufferReplayInterval)
+		defer ticker.Stop()
+		replayTick = ticker.C
+	}
 
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					sent = true
-				}
+	for {
+		select {
+		case payload, ok := <-s.inputChan:
+			if !ok {
+				s.cleanup(reliableDestinations, unreliableDestinations, sink)
+				return
 			}
+			s.send(payload, reliableDestinations, unreliableDestinations)
+		case <-replayTick:
+			s.replay(reliableDestinations)
+		}
+	}
+}
+
+func (s *Sender) send(payload *message.Payload, reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) {
+	var startInUse = time.Now()
 
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
+	sent := false
+	stored := false
+	for !sent {
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
 			}
 		}
 
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
+		if !sent && s.diskBuffer != nil {
+			// All reliable destinations are failing, spill the payload to disk rather than
+			// blocking the pipeline. It is durably queued so the auditor can commit its offsets.
+			if err := s.diskBuffer.Store(payload); err != nil {
+				log.Warnf("Cannot store the logs payload on disk: %v", err)
+			} else {
+				s.outputChan <- payload
+				sent = true
+				stored = true
 			}
 		}
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
+		if !sent {
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if stored {
+		// Payloads stored on disk are replayed to the reliable destinations later on,
+		// unreliable destinations don't get them as no reliable destination is sending.
+		tlmSendWaitTime.Add(float64(time.Since(startInUse) / time.Millisecond))
+		return
+	}
+
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
 			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
 			}
 		}
+	}
+
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+		}
+	}
+
+	inUse := float64(time.Since(startInUse) / time.Millisecond)
+	tlmSendWaitTime.Add(inUse)
+}
 
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
+// replay sends the payloads stored on disk to the reliable destinations, oldest first,
+// and stops as soon as none of them accepts a payload.
+// Stored payloads don't carry any message as their offsets were committed when they were stored.
+func (s *Sender) replay(reliableDestinations []*DestinationSender) {
+	for i := 0; i < diskBufferReplayBatchSize; i++ {
+		payload := s.diskBuffer.Peek()
+		if payload == nil {
+			return
+		}
+		sent := false
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
+			}
+		}
+		if !sent {
+			return
+		}
+		s.diskBuffer.Pop()
 	}
+}
 
+func (s *Sender) cleanup(reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender, sink chan *message.Payload) {
 	// Cleanup the destinations
 	for _, destSender := range reliableDestinations {
 		destSender.Stop()
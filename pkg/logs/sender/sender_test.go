@@ -306,3 +306,63 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	sender.Stop()
 }
+
+func TestSenderStoresOnDiskWhenMainFails(t *testing.T) {
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+
+	reliableRespond := make(chan int)
+	reliableServer := http.NewTestServerWithOptions(200, 0, true, reliableRespond)
+
+	destinations := client.NewDestinations([]client.Destination{reliableServer.Destination}, nil)
+
+	sender := NewSenderWithDiskBuffer(input, output, destinations, 10, DiskBufferConfig{
+		Path:               t.TempDir(),
+		MaxSizeBytes:       1024 * 1024,
+		MaxDiskRatio:       1,
+		OutdatedFileInDays: 1,
+	})
+	assert.NotNil(t, sender.diskBuffer)
+	sender.Start()
+
+	input <- &message.Payload{Encoded: []byte("1")}
+	<-reliableRespond
+	<-output
+
+	reliableServer.ChangeStatus(500)
+
+	input <- &message.Payload{Encoded: []byte("2")}
+	<-reliableRespond // let it respond 500 once
+	<-reliableRespond // its in a loop now, the sender has marked the endpoint as retrying
+
+	// the payload is stored on disk and reaches the auditor without waiting for the endpoint
+	stored := &message.Payload{Encoded: []byte("3")}
+	input <- stored
+	for {
+		select {
+		case payload := <-output:
+			assert.Equal(t, stored, payload)
+		case <-reliableRespond:
+			continue
+		}
+		break
+	}
+
+	// Recover the server
+	reliableServer.ChangeStatus(200)
+	for {
+		if (<-reliableRespond) == 200 {
+			break
+		}
+	}
+	assert.Equal(t, []byte("2"), (<-output).Encoded) // the payload stuck in the destination
+
+	// the stored payload is replayed
+	<-reliableRespond
+	replayed := <-output
+	assert.Equal(t, []byte("3"), replayed.Encoded)
+	assert.Nil(t, replayed.Messages)
+
+	reliableServer.Stop()
+	sender.Stop()
+}
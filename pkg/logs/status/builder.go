@@ -166,6 +166,17 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.Protocol
+	case config.FluentForwardType:
+		if c.Path != "" {
+			dictionary["Path"] = c.Path
+		} else {
+			dictionary["Port"] = c.Port
+		}
+	case config.CommandType:
+		dictionary["Command"] = strings.Join(append([]string{c.Command}, c.Args...), " ")
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows
+
+package command
+
+import "os/exec"
+
+// setupCommand is a no-op on Windows, only the command itself is killed.
+func setupCommand(_ *exec.Cmd) {}
@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package command implements a tailer running a command and collecting the
+// lines written on its standard output and error.
+package command
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/status"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// Stdout is the name of the standard output stream.
+	Stdout = "stdout"
+	// Stderr is the name of the standard error stream.
+	Stderr = "stderr"
+)
+
+const (
+	// a command running for longer than resetInterval is considered healthy,
+	// its backoff is reset when it exits.
+	resetInterval = time.Minute
+	// waitDelay is the time given to the command to close its outputs once
+	// it has been killed, e.g. when it started children which inherited them.
+	waitDelay = 5 * time.Second
+)
+
+// Tailer runs a command and forwards the lines of its outputs, restarting the
+// command with an exponential backoff whenever it exits.
+type Tailer struct {
+	source     *sources.LogSource
+	outputChan chan *message.Message
+	backoff    backoff.Policy
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *sources.LogSource, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		outputChan: outputChan,
+		backoff:    backoff.NewExpBackoffPolicy(2, 1, 60, 1, true),
+		done:       make(chan struct{}),
+	}
+}
+
+// Identifier returns a string that uniquely identifies a source.
+func Identifier(source *sources.LogSource) string {
+	return fmt.Sprintf("command:%s %s", source.Config.Command, strings.Join(source.Config.Args, " "))
+}
+
+// Start starts running the command.
+func (t *Tailer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	go t.run(ctx)
+}
+
+// Stop kills the command and waits for its outputs to be flushed.
+func (t *Tailer) Stop() {
+	t.cancel()
+	<-t.done
+}
+
+// run runs the command until the tailer is stopped.
+func (t *Tailer) run(ctx context.Context) {
+	defer close(t.done)
+	numErrors := 0
+	for {
+		start := time.Now()
+		err := t.runCommand(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(start) >= resetInterval {
+			numErrors = 0
+		}
+		numErrors = t.backoff.IncError(numErrors)
+		delay := t.backoff.GetBackoffDuration(numErrors)
+		if err != nil {
+			log.Warnf("Command %s exited: %v, restarting it in %s", Identifier(t.source), err, delay)
+			t.source.Status.Error(fmt.Errorf("command exited: %v", err))
+		} else {
+			log.Infof("Command %s exited, restarting it in %s", Identifier(t.source), delay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runCommand runs the command once and returns when it has exited and its
+// outputs have been flushed.
+func (t *Tailer) runCommand(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, t.source.Config.Command, t.source.Config.Args...)
+	cmd.WaitDelay = waitDelay
+	setupCommand(cmd)
+
+	stdout := t.newStream(Stdout)
+	stderr := t.newStream(Stderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	defer func() {
+		stdout.stop()
+		stderr.stop()
+	}()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	t.source.Status.Success()
+	return cmd.Wait()
+}
+
+// stream decodes the content written on one of the outputs of the command.
+type stream struct {
+	tailer  *Tailer
+	name    string
+	status  string
+	decoder *decoder.Decoder
+	wg      sync.WaitGroup
+
+	// mu guards closed, the exec package can still write after Wait returned
+	// when the outputs were not closed within waitDelay.
+	mu     sync.Mutex
+	closed bool
+	// partial is true when the last write didn't end with a newline
+	partial bool
+}
+
+func (t *Tailer) newStream(name string) *stream {
+	s := &stream{
+		tailer:  t,
+		name:    name,
+		status:  message.StatusInfo,
+		decoder: decoder.InitializeDecoder(sources.NewReplaceableSource(t.source), noop.New(), status.NewInfoRegistry()),
+	}
+	if name == Stderr {
+		s.status = message.StatusError
+	}
+	s.wg.Add(1)
+	go s.forwardMessages()
+	s.decoder.Start()
+	return s
+}
+
+// Write implements io.Writer, it is called by the exec package with the
+// content of the output.
+func (s *stream) Write(p []byte) (int, error) {
+	// the buffer is reused by the caller, and the decoder works asynchronously
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return len(p), nil
+	}
+	s.tailer.source.RecordBytes(int64(len(data)))
+	s.partial = len(data) > 0 && data[len(data)-1] != '\n'
+	s.decoder.InputChan <- decoder.NewInput(data)
+	return len(p), nil
+}
+
+// stop flushes the decoder and waits for the last lines to be forwarded.
+func (s *stream) stop() {
+	s.mu.Lock()
+	if s.partial {
+		// the framer keeps the content following the last newline until more
+		// data is written, terminate the last line as the command has exited
+		s.decoder.InputChan <- decoder.NewInput([]byte{'\n'})
+	}
+	s.closed = true
+	s.decoder.Stop()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// forwardMessages forwards the lines of the output to the pipeline, tagged
+// with the name of the output.
+func (s *stream) forwardMessages() {
+	defer s.wg.Done()
+	tags := []string{"stream:" + s.name}
+	for output := range s.decoder.OutputChan {
+		if len(output.GetContent()) == 0 {
+			continue
+		}
+		msg := message.NewMessageWithSource(output.GetContent(), s.status, s.tailer.source, output.IngestionTimestamp)
+		msg.Origin.SetTags(tags)
+		s.tailer.outputChan <- msg
+	}
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+)
+
+func newTestTailer(script string) (*Tailer, chan *message.Message) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.CommandType, Command: "sh", Args: []string{"-c", script}})
+	outputChan := make(chan *message.Message, 10)
+	tailer := NewTailer(source, outputChan)
+	tailer.backoff = backoff.NewConstantBackoffPolicy(10 * time.Millisecond)
+	return tailer, outputChan
+}
+
+func receive(t *testing.T, outputChan chan *message.Message) *message.Message {
+	select {
+	case msg := <-outputChan:
+		return msg
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "no message received")
+		return nil
+	}
+}
+
+func TestTailerForwardsOutputs(t *testing.T) {
+	tailer, outputChan := newTestTailer("echo hello; sleep 0.1; echo oops >&2; sleep 60")
+	tailer.Start()
+	defer tailer.Stop()
+
+	msg := receive(t, outputChan)
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Contains(t, msg.Origin.Tags(), "stream:stdout")
+
+	msg = receive(t, outputChan)
+	assert.Equal(t, "oops", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Contains(t, msg.Origin.Tags(), "stream:stderr")
+
+	assert.True(t, tailer.source.Status.IsSuccess())
+}
+
+func TestTailerRestartsCommand(t *testing.T) {
+	tailer, outputChan := newTestTailer("echo run; exit 1")
+	tailer.Start()
+	defer tailer.Stop()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "run", string(receive(t, outputChan).GetContent()))
+	}
+}
+
+func TestTailerFlushesOutputsWhenCommandExits(t *testing.T) {
+	// the last line doesn't end with a newline
+	tailer, outputChan := newTestTailer("printf 'first\\nlast'")
+	tailer.Start()
+	defer tailer.Stop()
+
+	assert.Equal(t, "first", string(receive(t, outputChan).GetContent()))
+	assert.Equal(t, "last", string(receive(t, outputChan).GetContent()))
+}
+
+func TestTailerStopKillsCommand(t *testing.T) {
+	tailer, _ := newTestTailer("sleep 60")
+	tailer.Start()
+
+	done := make(chan struct{})
+	go func() {
+		tailer.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "the tailer did not stop")
+	}
+}
+
+func TestTailerReportsStartErrors(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.CommandType, Command: "/does/not/exist"})
+	tailer := NewTailer(source, make(chan *message.Message))
+	tailer.backoff = backoff.NewConstantBackoffPolicy(10 * time.Millisecond)
+	tailer.Start()
+	defer tailer.Stop()
+
+	assert.Eventually(t, source.Status.IsError, 10*time.Second, 10*time.Millisecond)
+}
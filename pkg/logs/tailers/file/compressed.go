@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// archiveFingerprintSize is the number of bytes at the beginning of a compressed
+// file used to identify it in the registry.
+const archiveFingerprintSize = 4096
+
+// ArchiveIdentifier returns the registry identifier of a compressed file.
+//
+// Compressed files are identified by a fingerprint of their first bytes rather than by
+// their path, so that an archive which gets renamed by a log rotation (e.g. `app.log.1.gz`
+// to `app.log.2.gz`) is not collected twice.
+func ArchiveIdentifier(path string) (string, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, archiveFingerprintSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return fmt.Sprintf("compressed_file:%s", hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// newDecompressor returns a reader decompressing the given reader with the given compression.
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case config.GzipCompression:
+		return gzip.NewReader(r)
+	case config.ZstdCompression:
+		return zstd.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q", compression)
+}
+
+// setupCompressed sets up the tailer of a compressed file.
+//
+// Compressed files can't be seeked, the offsets are expressed in decompressed bytes and
+// the content before the offset is decompressed and discarded. Starting from the end of
+// the file means that its whole content is discarded.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(fullpath)
+	if err != nil {
+		return err
+	}
+	decompressor, err := newDecompressor(f, t.compression)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var skipped int64
+	switch whence {
+	case io.SeekStart:
+		skipped, err = io.CopyN(io.Discard, decompressor, offset)
+	case io.SeekEnd:
+		skipped, err = io.Copy(io.Discard, decompressor)
+	}
+	if err != nil && err != io.EOF {
+		decompressor.Close()
+		f.Close()
+		return err
+	}
+
+	t.osFile = f
+	t.decompressor = decompressor
+	t.lastReadOffset.Store(skipped)
+	t.decodedOffset.Store(skipped)
+
+	return nil
+}
+
+// readCompressed reads the decompressed content of the file, it returns io.EOF once
+// the whole file has been read.
+func (t *Tailer) readCompressed() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.decompressor.Read(inBuf)
+	if n > 0 {
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		t.lastReadOffset.Add(int64(n))
+	}
+	switch {
+	case err == io.EOF && n > 0:
+		// the next read returns io.EOF again
+		return n, nil
+	case err == io.EOF:
+		log.Infof("Compressed file %s has been fully read (%d bytes)", t.file.Path, t.lastReadOffset.Load())
+		t.archiveConsumed.Store(true)
+		return 0, io.EOF
+	case err != nil:
+		// the archive is either corrupted or still being written, the remaining
+		// content is read again by a new tailer on the next scan
+		t.file.Source.Status().Error(err)
+		return n, log.Errorf("Unexpected error occurred while reading compressed file %s: %v", t.file.Path, err)
+	}
+	return n, nil
+}
+
+// IsArchiveConsumed returns true if the tailer reads a compressed file and it has
+// been read until its end.
+func (t *Tailer) IsArchiveConsumed() bool {
+	return t.archiveConsumed.Load()
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/status"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func writeCompressedFile(t *testing.T, path string, compression string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.WriteCloser
+	switch compression {
+	case config.GzipCompression:
+		w = gzip.NewWriter(f)
+	case config.ZstdCompression:
+		w = zstd.NewWriter(f)
+	}
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func newCompressedTailer(path string, compression string, outputChan chan *message.Message) *Tailer {
+	source := sources.NewLogSource("", &config.LogsConfig{
+		Type:        config.FileType,
+		Path:        path,
+		Compression: compression,
+	})
+	info := status.NewInfoRegistry()
+	return NewTailer(&TailerOptions{
+		OutputChan:    outputChan,
+		File:          NewFile(path, source, false),
+		SleepDuration: 10 * time.Millisecond,
+		Decoder:       decoder.NewDecoderFromSource(sources.NewReplaceableSource(source), info),
+		Info:          info,
+	})
+}
+
+func TestFileCompression(t *testing.T) {
+	tests := []struct {
+		compression string
+		path        string
+		expected    string
+	}{
+		{"", "app.log.gz", ""},
+		{config.GzipCompression, "app.log", config.GzipCompression},
+		{config.AutoCompression, "app.log.1.gz", config.GzipCompression},
+		{config.AutoCompression, "app.log.zst", config.ZstdCompression},
+		{config.AutoCompression, "app.log.1", ""},
+	}
+	for _, test := range tests {
+		source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: test.path, Compression: test.compression})
+		assert.Equal(t, test.expected, NewFile(test.path, source, false).Compression(), test.path)
+	}
+}
+
+func TestTailCompressedFile(t *testing.T) {
+	for _, compression := range []string{config.GzipCompression, config.ZstdCompression} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.1")
+			writeCompressedFile(t, path, compression, "first line\nsecond line\n")
+
+			outputChan := make(chan *message.Message, 10)
+			tailer := newCompressedTailer(path, compression, outputChan)
+			assert.Contains(t, tailer.Identifier(), "compressed_file:")
+			require.NoError(t, tailer.StartFromBeginning())
+
+			msg := <-outputChan
+			assert.Equal(t, "first line", string(msg.GetContent()))
+			assert.Equal(t, "11", msg.Origin.Offset)
+			msg = <-outputChan
+			assert.Equal(t, "second line", string(msg.GetContent()))
+			assert.Equal(t, "23", msg.Origin.Offset)
+			assert.Equal(t, tailer.Identifier(), msg.Origin.Identifier)
+
+			assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+			assert.True(t, tailer.IsArchiveConsumed())
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailCompressedFileFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+	writeCompressedFile(t, path, config.GzipCompression, "first line\nsecond line\n")
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newCompressedTailer(path, config.AutoCompression, outputChan)
+	require.NoError(t, tailer.Start(11, io.SeekStart))
+
+	msg := <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+	assert.Equal(t, "23", msg.Origin.Offset)
+	tailer.Stop()
+}
+
+func TestArchiveIdentifierFollowsRenames(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log.1.gz")
+	writeCompressedFile(t, path, config.GzipCompression, "some content\n")
+
+	identifier, err := ArchiveIdentifier(path)
+	require.NoError(t, err)
+
+	rotatedPath := filepath.Join(dir, "app.log.2.gz")
+	require.NoError(t, os.Rename(path, rotatedPath))
+	rotatedIdentifier, err := ArchiveIdentifier(rotatedPath)
+	require.NoError(t, err)
+	assert.Equal(t, identifier, rotatedIdentifier)
+
+	writeCompressedFile(t, path, config.GzipCompression, "other content\n")
+	newIdentifier, err := ArchiveIdentifier(path)
+	require.NoError(t, err)
+	assert.NotEqual(t, identifier, newIdentifier)
+}
@@ -8,7 +8,9 @@ package file
 
 import (
 	"fmt"
+	"path/filepath"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 )
 
@@ -45,3 +47,24 @@ func (t *File) GetScanKey() string {
 	}
 	return t.Path
 }
+
+// Compression returns the compression format of the file, either `gzip` or `zstd`,
+// or an empty string if the file is not compressed.
+// With the `auto` compression, the format is detected from the file extension.
+func (t *File) Compression() string {
+	if t.Source == nil || t.Source.Config() == nil {
+		return ""
+	}
+	switch compression := t.Source.Config().Compression; compression {
+	case config.GzipCompression, config.ZstdCompression:
+		return compression
+	case config.AutoCompression:
+		switch filepath.Ext(t.Path) {
+		case ".gz":
+			return config.GzipCompression
+		case ".zst", ".zstd":
+			return config.ZstdCompression
+		}
+	}
+	return ""
+}
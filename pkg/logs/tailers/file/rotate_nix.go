@@ -41,7 +41,8 @@ func (t *Tailer) DidRotate() (bool, error) {
 	fileSize := fi1.Size()
 
 	recreated := !os.SameFile(fi1, fi2)
-	truncated := fileSize < lastReadOffset
+	// the offset of a compressed file is expressed in decompressed bytes
+	truncated := t.compression == "" && fileSize < lastReadOffset
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
@@ -37,7 +37,8 @@ func (t *Tailer) DidRotate() (bool, error) {
 	// polled before the offset.
 	sz := st.Size()
 
-	if sz < offset {
+	// the offset of a compressed file is expressed in decompressed bytes
+	if t.compression == "" && sz < offset {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", offset, sz)
 		return true, nil
 	}
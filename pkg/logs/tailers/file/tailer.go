@@ -60,6 +60,18 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// compression is the compression format of the file, empty if it is not compressed.
+	compression string
+
+	// decompressor reads the decompressed content of osFile for compressed files.
+	decompressor io.ReadCloser
+
+	// archiveIdentifier is the registry identifier of a compressed file.
+	archiveIdentifier string
+
+	// archiveConsumed is true when a compressed file has been read until its end.
+	archiveConsumed *atomic.Bool
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -160,8 +172,20 @@ func NewTailer(opts *TailerOptions) *Tailer {
 	movingSum := util.NewMovingSum(timeWindow, bucketSize, clock.New())
 	opts.Info.Register(movingSum)
 
+	compression := opts.File.Compression()
+	var archiveIdentifier string
+	if compression != "" {
+		var err error
+		if archiveIdentifier, err = ArchiveIdentifier(opts.File.Path); err != nil {
+			log.Warnf("Could not compute the identifier of compressed file %s, falling back on its path: %v", opts.File.Path, err)
+		}
+	}
+
 	t := &Tailer{
 		file:                   opts.File,
+		compression:            compression,
+		archiveIdentifier:      archiveIdentifier,
+		archiveConsumed:        atomic.NewBool(false),
 		outputChan:             opts.OutputChan,
 		decoder:                opts.Decoder,
 		tagProvider:            tagProvider,
@@ -219,12 +243,20 @@ func (t *Tailer) Identifier() string {
 	//
 	// This is the identifier used in the registry, so changing it will invalidate existing
 	// registry entries on upgrade.
+	if t.archiveIdentifier != "" {
+		return t.archiveIdentifier
+	}
 	return fmt.Sprintf("file:%s", t.file.Path)
 }
 
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.compression != "" {
+		err = t.setupCompressed(offset, whence)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
@@ -281,14 +313,22 @@ func (t *Tailer) StopAfterFileRotation() {
 // readForever lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
+	read := t.read
+	if t.decompressor != nil {
+		read = t.readCompressed
+	}
+
 	defer func() {
+		if t.decompressor != nil {
+			t.decompressor.Close()
+		}
 		t.osFile.Close()
 		t.decoder.Stop()
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
 	for {
-		n, err := t.read()
+		n, err := read()
 		if err != nil {
 			return
 		}
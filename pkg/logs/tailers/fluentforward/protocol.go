@@ -0,0 +1,434 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// eventTimeExtension is the msgpack extension type of the EventTime, which holds
+// the seconds and nanoseconds of the time as two big-endian uint32.
+const eventTimeExtension = 0
+
+// maxDecompressedSize is the maximum size of a CompressedPackedForward entry once decompressed.
+const maxDecompressedSize = 64 * 1024 * 1024
+
+// maxObjectDepth is the maximum nesting depth of the arrays and maps of an entry.
+const maxObjectDepth = 64
+
+var errDecompressedTooLarge = fmt.Errorf("decompressed entry larger than %d bytes", maxDecompressedSize)
+
+// entry is an entry of the Forward protocol, all its events share the same tag.
+type entry struct {
+	tag    string
+	events []event
+	// chunk is the id to acknowledge the entry with, when the client requested it
+	chunk string
+}
+
+// event is a record sent by the client with its time.
+type event struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// decodeEntry decodes the next entry, in any of the modes of the protocol:
+//
+//	Message:                 [tag, time, record, option?]
+//	Forward:                 [tag, [[time, record], ...], option?]
+//	PackedForward:           [tag, packed events, option?]
+//	CompressedPackedForward: [tag, gzipped packed events, {"compressed": "gzip", ...}]
+//
+// The entry is read as is first, it is rejected if it is larger than maxSize bytes.
+func decodeEntry(r *msgp.Reader, maxSize int) (*entry, error) {
+	raw := &limitedBuffer{limit: maxSize}
+	if err := copyObject(r, raw); err != nil {
+		return nil, err
+	}
+	return decodeRawEntry(msgp.NewReader(bytes.NewReader(raw.buf.Bytes())))
+}
+
+// decodeRawEntry decodes an entry whose objects have been checked by copyObject.
+func decodeRawEntry(r *msgp.Reader) (*entry, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if size < 2 || size > 4 {
+		return nil, fmt.Errorf("invalid entry with %d elements", size)
+	}
+	e := &entry{}
+	if e.tag, err = r.ReadString(); err != nil {
+		return nil, fmt.Errorf("invalid tag: %v", err)
+	}
+
+	typ, err := r.NextType()
+	if err != nil {
+		return nil, err
+	}
+	var packed []byte
+	remaining := size - 2
+	switch typ {
+	case msgp.ArrayType:
+		var count uint32
+		if count, err = r.ReadArrayHeader(); err != nil {
+			return nil, err
+		}
+		// the count was sent by the client, the events are not preallocated
+		for i := uint32(0); i < count; i++ {
+			ev, err := decodeEvent(r)
+			if err != nil {
+				return nil, err
+			}
+			e.events = append(e.events, ev)
+		}
+	case msgp.BinType:
+		if packed, err = r.ReadBytes(nil); err != nil {
+			return nil, err
+		}
+	case msgp.StrType:
+		if packed, err = r.ReadStringAsBytes(nil); err != nil {
+			return nil, err
+		}
+	default:
+		if size < 3 {
+			return nil, errors.New("invalid message entry without record")
+		}
+		ev, err := decodeEventFields(r)
+		if err != nil {
+			return nil, err
+		}
+		e.events = []event{ev}
+		remaining--
+	}
+
+	var compressed string
+	if remaining > 0 {
+		if e.chunk, compressed, err = decodeOption(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if packed != nil {
+		if compressed != "" {
+			if compressed != "gzip" {
+				return nil, fmt.Errorf("unsupported compression %q", compressed)
+			}
+			if packed, err = gunzip(packed); err != nil {
+				return nil, err
+			}
+		}
+		if e.events, err = decodePackedEvents(packed); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// decodeOption decodes the option map of an entry, and returns its chunk id and compression.
+func decodeOption(r *msgp.Reader) (string, string, error) {
+	if r.IsNil() {
+		return "", "", r.ReadNil()
+	}
+	option := make(map[string]interface{})
+	if err := r.ReadMapStrIntf(option); err != nil {
+		return "", "", fmt.Errorf("invalid option: %v", err)
+	}
+	chunk, _ := option["chunk"].(string)
+	compressed, _ := option["compressed"].(string)
+	return chunk, compressed, nil
+}
+
+// decodePackedEvents decodes a stream of events.
+func decodePackedEvents(packed []byte) ([]event, error) {
+	// the packed events may have been decompressed, they have not been checked yet
+	check := msgp.NewReader(bytes.NewReader(packed))
+	for {
+		if _, err := check.R.Peek(1); err == io.EOF {
+			break
+		}
+		if err := copyObject(check, io.Discard); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := bytes.NewReader(packed)
+	r := msgp.NewReader(buf)
+	var events []event
+	for buf.Len() > 0 || r.Buffered() > 0 {
+		ev, err := decodeEvent(r)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// decodeEvent decodes a `[time, record]` event.
+func decodeEvent(r *msgp.Reader) (event, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return event{}, err
+	}
+	if size != 2 {
+		return event{}, fmt.Errorf("invalid event with %d elements", size)
+	}
+	return decodeEventFields(r)
+}
+
+// decodeEventFields decodes the time and the record of an event.
+func decodeEventFields(r *msgp.Reader) (event, error) {
+	t, err := decodeTime(r)
+	if err != nil {
+		return event{}, err
+	}
+	record := make(map[string]interface{})
+	if err := r.ReadMapStrIntf(record); err != nil {
+		return event{}, fmt.Errorf("invalid record: %v", err)
+	}
+	normalize(record)
+	return event{time: t, record: record}, nil
+}
+
+// decodeTime decodes the time of an event, either an EventTime or a number of seconds.
+// Recent Fluent Bit versions send `[time, metadata]` arrays, the metadata is ignored.
+func decodeTime(r *msgp.Reader) (time.Time, error) {
+	typ, err := r.NextType()
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch typ {
+	case msgp.ArrayType:
+		size, err := r.ReadArrayHeader()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if size == 0 {
+			return time.Time{}, errors.New("invalid empty time")
+		}
+		t, err := decodeTime(r)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for i := uint32(1); i < size; i++ {
+			if err := r.Skip(); err != nil {
+				return time.Time{}, err
+			}
+		}
+		return t, nil
+	case msgp.IntType, msgp.UintType:
+		sec, err := r.ReadInt64()
+		return time.Unix(sec, 0).UTC(), err
+	case msgp.Float32Type, msgp.Float64Type:
+		sec, err := r.ReadFloat64()
+		return time.Unix(0, int64(sec*float64(time.Second))).UTC(), err
+	case msgp.ExtensionType:
+		ext := msgp.RawExtension{Type: eventTimeExtension}
+		if err := r.ReadExtension(&ext); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time: %v", err)
+		}
+		if len(ext.Data) != 8 {
+			return time.Time{}, fmt.Errorf("invalid event time of %d bytes", len(ext.Data))
+		}
+		sec := binary.BigEndian.Uint32(ext.Data[:4])
+		nsec := binary.BigEndian.Uint32(ext.Data[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid time of type %s", typ)
+	}
+}
+
+// normalize converts the binary values of a record to strings, as they are usually
+// sent by the clients for strings and would otherwise be rendered in base64.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = normalize(nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = normalize(nested)
+		}
+	case *msgp.RawExtension:
+		return nil
+	}
+	return value
+}
+
+// gunzip decompresses the given data, which can hold several gzip members.
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxDecompressedSize {
+		return nil, errDecompressedTooLarge
+	}
+	return decompressed, nil
+}
+
+// limitedBuffer is a buffer failing the writes exceeding its limit.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("entry larger than %d bytes", b.limit)
+	}
+	return b.buf.Write(p)
+}
+
+// copyObject copies the next msgpack object of r to w, without interpreting it.
+//
+// The msgp decoders allocate the lengths announced by the headers before reading the
+// data, and recurse without limit into nested objects: copying an object first checks
+// that its data is actually there, up to the size accepted by w, and that it is at most
+// maxObjectDepth levels deep.
+func copyObject(r *msgp.Reader, w io.Writer) error {
+	// pending holds the number of objects left to copy at each depth
+	pending := []uint64{1}
+	for len(pending) > 0 {
+		if pending[len(pending)-1] == 0 {
+			pending = pending[:len(pending)-1]
+			continue
+		}
+		pending[len(pending)-1]--
+
+		headerLen, dataLen, objects, err := peekHeader(r)
+		if err != nil {
+			return err
+		}
+		header, err := r.R.Next(headerLen)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if err := copyData(r, w, dataLen); err != nil {
+			return err
+		}
+		if objects > 0 {
+			if len(pending) > maxObjectDepth {
+				return fmt.Errorf("objects nested more than %d levels deep", maxObjectDepth)
+			}
+			pending = append(pending, objects)
+		}
+	}
+	return nil
+}
+
+// copyData copies the n bytes of data following a header.
+func copyData(r *msgp.Reader, w io.Writer, n uint64) error {
+	if n <= uint64(r.R.BufferSize()) {
+		data, err := r.R.Next(int(n))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	copied, err := io.CopyN(w, r.R, int64(n))
+	if err == nil && uint64(copied) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// peekHeader returns the length of the header of the next object, the length of
+// the data following it and the number of objects it contains.
+func peekHeader(r *msgp.Reader) (headerLen int, dataLen uint64, objects uint64, err error) {
+	lead, err := r.R.Peek(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	b := lead[0]
+	switch {
+	case b <= 0x7f || b >= 0xe0: // fixint
+		return 1, 0, 0, nil
+	case b <= 0x8f: // fixmap
+		return 1, 0, 2 * uint64(b&0x0f), nil
+	case b <= 0x9f: // fixarray
+		return 1, 0, uint64(b & 0x0f), nil
+	case b <= 0xbf: // fixstr
+		return 1, uint64(b & 0x1f), 0, nil
+	}
+
+	// the length of the header, the size of its length field and its kind
+	var size int
+	var kind byte
+	switch b {
+	case 0xc0, 0xc2, 0xc3: // nil, bool
+		return 1, 0, 0, nil
+	case 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf, 0xd0, 0xd1, 0xd2, 0xd3: // numbers
+		return 1, uint64(numberSizes[b-0xca]), 0, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext
+		return 2, uint64(1) << (b - 0xd4), 0, nil
+	case 0xc4, 0xd9: // bin8, str8
+		size, kind = 1, 'd'
+	case 0xc5, 0xda: // bin16, str16
+		size, kind = 2, 'd'
+	case 0xc6, 0xdb: // bin32, str32
+		size, kind = 4, 'd'
+	case 0xc7: // ext8
+		size, kind = 1, 'e'
+	case 0xc8: // ext16
+		size, kind = 2, 'e'
+	case 0xc9: // ext32
+		size, kind = 4, 'e'
+	case 0xdc: // array16
+		size, kind = 2, 'a'
+	case 0xdd: // array32
+		size, kind = 4, 'a'
+	case 0xde: // map16
+		size, kind = 2, 'm'
+	case 0xdf: // map32
+		size, kind = 4, 'm'
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid msgpack prefix 0x%x", b)
+	}
+
+	header, err := r.R.Peek(1 + size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var n uint64
+	for _, c := range header[1:] {
+		n = n<<8 | uint64(c)
+	}
+	switch kind {
+	case 'e':
+		// the length is followed by the extension type
+		return 2 + size, n, 0, nil
+	case 'a':
+		return 1 + size, 0, n, nil
+	case 'm':
+		return 1 + size, 0, 2 * n, nil
+	}
+	return 1 + size, n, 0, nil
+}
+
+// numberSizes are the sizes of the float, uint and int values, from 0xca to 0xd3.
+var numberSizes = [...]int{4, 8, 1, 2, 4, 8, 1, 2, 4, 8}
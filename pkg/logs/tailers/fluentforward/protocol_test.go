@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var testTime = time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC)
+
+const testMaxEntrySize = 1024 * 1024
+
+func appendEventTime(b []byte, t time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(t.Nanosecond()))
+	b, _ = msgp.AppendExtension(b, &msgp.RawExtension{Type: eventTimeExtension, Data: data})
+	return b
+}
+
+func appendEvent(b []byte, t time.Time, record map[string]interface{}) []byte {
+	b = msgp.AppendArrayHeader(b, 2)
+	b = appendEventTime(b, t)
+	b, _ = msgp.AppendMapStrIntf(b, record)
+	return b
+}
+
+func decode(t *testing.T, payload []byte) *entry {
+	e, err := decodeEntry(msgp.NewReader(bytes.NewReader(payload)), testMaxEntrySize)
+	require.NoError(t, err)
+	return e
+}
+
+func TestDecodeMessageMode(t *testing.T) {
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, "app.access")
+	b = msgp.AppendInt64(b, testTime.Unix())
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"message": "hello", "status": 200})
+
+	e := decode(t, b)
+	assert.Equal(t, "app.access", e.tag)
+	assert.Empty(t, e.chunk)
+	require.Len(t, e.events, 1)
+	assert.Equal(t, testTime.Truncate(time.Second), e.events[0].time)
+	assert.Equal(t, map[string]interface{}{"message": "hello", "status": int64(200)}, e.events[0].record)
+}
+
+func TestDecodeMessageModeWithOption(t *testing.T) {
+	b := msgp.AppendArrayHeader(nil, 4)
+	b = msgp.AppendString(b, "app")
+	b = appendEventTime(b, testTime)
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"log": []byte("binary string")})
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"chunk": "p8n9gmxTQVC8/nh2wlKKeQ=="})
+
+	e := decode(t, b)
+	assert.Equal(t, "p8n9gmxTQVC8/nh2wlKKeQ==", e.chunk)
+	require.Len(t, e.events, 1)
+	assert.Equal(t, testTime, e.events[0].time)
+	// binary values are converted to strings
+	assert.Equal(t, map[string]interface{}{"log": "binary string"}, e.events[0].record)
+}
+
+func TestDecodeForwardMode(t *testing.T) {
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, "app")
+	b = msgp.AppendArrayHeader(b, 2)
+	b = appendEvent(b, testTime, map[string]interface{}{"message": "first"})
+	b = appendEvent(b, testTime.Add(time.Second), map[string]interface{}{"message": "second"})
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"chunk": "abc", "size": 2})
+
+	e := decode(t, b)
+	assert.Equal(t, "abc", e.chunk)
+	require.Len(t, e.events, 2)
+	assert.Equal(t, "first", e.events[0].record["message"])
+	assert.Equal(t, "second", e.events[1].record["message"])
+	assert.Equal(t, testTime.Add(time.Second), e.events[1].time)
+}
+
+func TestDecodeForwardModeWithMetadata(t *testing.T) {
+	// Fluent Bit 2.1+ sends [[time, metadata], record] events
+	b := msgp.AppendArrayHeader(nil, 2)
+	b = msgp.AppendString(b, "app")
+	b = msgp.AppendArrayHeader(b, 1)
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendArrayHeader(b, 2)
+	b = appendEventTime(b, testTime)
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"otlp": "metadata"})
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"message": "hello"})
+
+	e := decode(t, b)
+	require.Len(t, e.events, 1)
+	assert.Equal(t, testTime, e.events[0].time)
+	assert.Equal(t, "hello", e.events[0].record["message"])
+}
+
+func TestDecodePackedForwardMode(t *testing.T) {
+	var packed []byte
+	packed = appendEvent(packed, testTime, map[string]interface{}{"message": "first"})
+	packed = appendEvent(packed, testTime, map[string]interface{}{"message": "second"})
+
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, "app")
+	b = msgp.AppendBytes(b, packed)
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"size": 2})
+
+	e := decode(t, b)
+	require.Len(t, e.events, 2)
+	assert.Equal(t, "first", e.events[0].record["message"])
+	assert.Equal(t, "second", e.events[1].record["message"])
+
+	// older clients send the events as a string
+	b = msgp.AppendArrayHeader(nil, 2)
+	b = msgp.AppendString(b, "app")
+	b = msgp.AppendStringFromBytes(b, packed)
+	assert.Len(t, decode(t, b).events, 2)
+}
+
+func TestDecodeCompressedPackedForwardMode(t *testing.T) {
+	// the events can be compressed in several gzip members
+	var compressed bytes.Buffer
+	for _, message := range []string{"first", "second"} {
+		writer := gzip.NewWriter(&compressed)
+		_, err := writer.Write(appendEvent(nil, testTime, map[string]interface{}{"message": message}))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+	}
+
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, "app")
+	b = msgp.AppendBytes(b, compressed.Bytes())
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"compressed": "gzip", "chunk": "xyz"})
+
+	e := decode(t, b)
+	assert.Equal(t, "xyz", e.chunk)
+	require.Len(t, e.events, 2)
+	assert.Equal(t, "first", e.events[0].record["message"])
+	assert.Equal(t, "second", e.events[1].record["message"])
+}
+
+func TestDecodeInvalidEntries(t *testing.T) {
+	invalid := map[string][]byte{
+		"not an array": msgp.AppendString(nil, "app"),
+		"too short":    msgp.AppendString(msgp.AppendArrayHeader(nil, 1), "app"),
+		"invalid tag":  msgp.AppendInt(msgp.AppendArrayHeader(nil, 2), 1),
+		"invalid time": msgp.AppendMapHeader(msgp.AppendString(msgp.AppendString(msgp.AppendArrayHeader(nil, 3), "app"), "now"), 0),
+		"no record":    msgp.AppendInt(msgp.AppendString(msgp.AppendArrayHeader(nil, 2), "app"), 1),
+		"unsupported compression": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 3)
+			b = msgp.AppendString(b, "app")
+			b = msgp.AppendBytes(b, []byte("data"))
+			b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"compressed": "zip"})
+			return b
+		}(),
+	}
+	for name, payload := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeEntry(msgp.NewReader(bytes.NewReader(payload)), testMaxEntrySize)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeRejectsOversizedLengths(t *testing.T) {
+	prefix := msgp.AppendString(msgp.AppendArrayHeader(nil, 2), "app")
+	gzipped := func(packed []byte) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(packed) //nolint:errcheck
+		w.Close()
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app")
+		b = msgp.AppendBytes(b, buf.Bytes())
+		b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"compressed": "gzip"})
+		return b
+	}
+	deep := append([]byte{}, prefix...)
+	for i := 0; i < 100000; i++ {
+		deep = msgp.AppendArrayHeader(deep, 1)
+	}
+
+	invalid := map[string][]byte{
+		// headers announcing much more data than sent
+		"array32 of events":              append(append([]byte{}, prefix...), 0xdd, 0xff, 0xff, 0xff, 0xff),
+		"bin32 packed":                   append(append([]byte{}, prefix...), 0xc6, 0xff, 0xff, 0xff, 0xff),
+		"str32 packed":                   append(append([]byte{}, prefix...), 0xdb, 0xff, 0xff, 0xff, 0xff),
+		"str32 in packed":                gzipped([]byte{0x92, 0x01, 0x81, 0xa1, 'k', 0xdb, 0xff, 0xff, 0xff, 0xff}),
+		"nested too deep":                deep,
+		"larger than the max entry size": msgp.AppendBytes(append([]byte{}, prefix...), make([]byte, testMaxEntrySize)),
+	}
+	for name, payload := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeEntry(msgp.NewReader(bytes.NewReader(payload)), testMaxEntrySize)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package fluentforward implements a tailer reading the events sent by the Fluentd
+// and Fluent Bit clients over a connection with the Forward protocol.
+package fluentforward
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Tailer reads the entries sent over a connection, and acknowledges them once
+// their events have been forwarded to the pipeline.
+type Tailer struct {
+	source       *sources.LogSource
+	conn         net.Conn
+	outputChan   chan *message.Message
+	idleTimeout  time.Duration
+	maxEntrySize int
+	done         chan struct{}
+}
+
+// NewTailer returns a new Tailer, the connection is closed when the client sends an
+// entry larger than maxEntrySize bytes.
+func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *message.Message, idleTimeout time.Duration, maxEntrySize int) *Tailer {
+	return &Tailer{
+		source:       source,
+		conn:         conn,
+		outputChan:   outputChan,
+		idleTimeout:  idleTimeout,
+		maxEntrySize: maxEntrySize,
+		done:         make(chan struct{}),
+	}
+}
+
+// Start starts reading the connection.
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop closes the connection and waits for the last events to be forwarded.
+func (t *Tailer) Stop() {
+	t.conn.Close()
+	<-t.done
+}
+
+// Wait waits until the connection is closed, by either side.
+func (t *Tailer) Wait() {
+	<-t.done
+}
+
+// readForever reads the entries until the connection is closed.
+func (t *Tailer) readForever() {
+	defer func() {
+		t.conn.Close()
+		close(t.done)
+	}()
+	reader := msgp.NewReader(&bytesCounter{reader: t.conn, source: t.source})
+	writer := msgp.NewWriter(t.conn)
+	for {
+		if t.idleTimeout > 0 {
+			t.conn.SetReadDeadline(time.Now().Add(t.idleTimeout)) //nolint:errcheck
+		}
+		entry, err := decodeEntry(reader, t.maxEntrySize)
+		if err != nil {
+			if !errors.Is(msgp.Cause(err), io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Warnf("Couldn't read Forward protocol entry from connection: %v", err)
+				t.source.Status.Error(err)
+			}
+			return
+		}
+
+		for _, ev := range entry.events {
+			t.outputChan <- t.newMessage(entry.tag, ev)
+		}
+
+		if entry.chunk != "" {
+			if err := writeAck(writer, entry.chunk); err != nil {
+				log.Warnf("Couldn't acknowledge Forward protocol entry: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// newMessage returns a structured message holding the record of the event. The
+// content of the message is read from the `message` or `log` key of the record,
+// the latter being used by the Fluent Bit inputs and the Docker logging driver.
+func (t *Tailer) newMessage(tag string, ev event) *message.Message {
+	record := ev.record
+	if _, ok := record["message"].(string); !ok {
+		if logContent, ok := record["log"].(string); ok {
+			record["message"] = logContent
+			delete(record, "log")
+		} else if _, exists := record["message"]; !exists {
+			record["message"] = ""
+		} else {
+			// the message must be a string, keep the original value under another key
+			record["fluent_message"] = record["message"]
+			record["message"] = ""
+		}
+	}
+
+	origin := message.NewOrigin(t.source)
+	tags := []string{"fluent_tag:" + tag}
+	if containerID, ok := record["container_id"].(string); ok && containerID != "" {
+		containerTags, err := tagger.Tag(containers.BuildTaggerEntityName(containerID), collectors.HighCardinality)
+		if err != nil {
+			log.Debugf("Cannot tag container %s: %v", containerID, err)
+		}
+		tags = append(tags, containerTags...)
+	}
+	origin.SetTags(tags)
+
+	msg := message.NewStructuredMessage(&message.BasicStructuredContent{Data: record}, origin, message.StatusInfo, time.Now().UnixNano())
+	msg.EventTimestamp = ev.time
+	return msg
+}
+
+// writeAck acknowledges an entry with its chunk id.
+func writeAck(writer *msgp.Writer, chunk string) error {
+	if err := writer.WriteMapHeader(1); err != nil {
+		return err
+	}
+	if err := writer.WriteString("ack"); err != nil {
+		return err
+	}
+	if err := writer.WriteString(chunk); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// bytesCounter records the bytes read from the connection.
+type bytesCounter struct {
+	reader io.Reader
+	source *sources.LogSource
+}
+
+func (c *bytesCounter) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.source.RecordBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package fluentforward
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestTailerForwardsEventsAndAcknowledgesEntries(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FluentForwardType, Port: 24224})
+	outputChan := make(chan *message.Message, 10)
+	server, client := net.Pipe()
+	tailer := NewTailer(source, server, outputChan, 0, 1024*1024)
+	tailer.Start()
+	defer tailer.Stop()
+
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, "docker.web")
+	b = msgp.AppendArrayHeader(b, 2)
+	b = appendEvent(b, testTime, map[string]interface{}{"log": "GET /", "container_name": "/web"})
+	b = appendEvent(b, testTime, map[string]interface{}{"message": "POST /"})
+	b, _ = msgp.AppendMapStrIntf(b, map[string]interface{}{"chunk": "chunk-1"})
+	go client.Write(b) //nolint:errcheck
+
+	msg := <-outputChan
+	assert.Equal(t, message.StateStructured, msg.State)
+	assert.Equal(t, "GET /", string(msg.GetContent()))
+	assert.Equal(t, map[string]interface{}{"message": "GET /", "container_name": "/web"}, msg.GetStructuredContent().(*message.BasicStructuredContent).Data)
+	assert.Equal(t, testTime, msg.EventTimestamp)
+	assert.Contains(t, msg.Origin.Tags(), "fluent_tag:docker.web")
+	assert.Equal(t, "POST /", string((<-outputChan).GetContent()))
+
+	// the entry is acknowledged once its events are forwarded
+	client.SetReadDeadline(time.Now().Add(10 * time.Second)) //nolint:errcheck
+	ack := make(map[string]interface{})
+	require.NoError(t, msgp.NewReader(client).ReadMapStrIntf(ack))
+	assert.Equal(t, map[string]interface{}{"ack": "chunk-1"}, ack)
+}
+
+func TestTailerStopsOnInvalidEntry(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FluentForwardType, Port: 24224})
+	server, client := net.Pipe()
+	tailer := NewTailer(source, server, make(chan *message.Message), 0, 1024*1024)
+	tailer.Start()
+
+	go client.Write(msgp.AppendString(nil, "not an entry")) //nolint:errcheck
+	tailer.Wait()
+	assert.True(t, source.Status.IsError())
+}
+
+func TestNewMessageWithoutMessage(t *testing.T) {
+	tailer := NewTailer(sources.NewLogSource("", &config.LogsConfig{}), nil, nil, 0, 1024*1024)
+
+	msg := tailer.newMessage("app", event{time: testTime, record: map[string]interface{}{"level": "info"}})
+	assert.Equal(t, "", string(msg.GetContent()))
+
+	msg = tailer.newMessage("app", event{time: testTime, record: map[string]interface{}{"message": map[string]interface{}{"nested": "value"}}})
+	assert.Equal(t, "", string(msg.GetContent()))
+	assert.Equal(t, map[string]interface{}{"nested": "value"}, msg.GetStructuredContent().(*message.BasicStructuredContent).Data["fluent_message"])
+}
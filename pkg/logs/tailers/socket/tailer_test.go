@@ -40,6 +40,31 @@ func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType}), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// octet-counted and non-transparent frames
+	w.Write([]byte("30 <11>1 - host app 12 - - failed<14>Oct 11 22:14:15 host app: done\n"))
+	msg = <-msgChan
+	assert.Equal(t, "failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, message.StateStructured, msg.State)
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"failed","syslog":{"facility":1,"severity":3,"version":1,"hostname":"host","appname":"app","procid":"12"}}`, string(rendered))
+
+	msg = <-msgChan
+	assert.Equal(t, "done", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	tailer.Stop()
+}
+
 func TestReadShouldFailWithError(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
@@ -0,0 +1,702 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ObfuscatedGraphQL holds the result of obfuscating a GraphQL query.
+type ObfuscatedGraphQL struct {
+	// Query is the obfuscated query, on a single line, with its literal values
+	// and the default values of its variables replaced by "?".
+	Query string
+
+	// Signature is a normalized form of the obfuscated query. It is the same for
+	// queries differing only by their literal values, aliases, formatting, or by
+	// the order of their fields, arguments, variables and fragments.
+	Signature string
+}
+
+// ObfuscateGraphQLString obfuscates the given GraphQL query. It returns an error if
+// the query is not a valid executable GraphQL document.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) (*ObfuscatedGraphQL, error) {
+	defs, err := parseGraphQL(query)
+	if err != nil {
+		return nil, err
+	}
+	queryPrinter := graphQLPrinter{removeAliases: o.opts.GraphQL.RemoveAliases}
+	signaturePrinter := graphQLPrinter{removeAliases: true, normalize: true}
+	return &ObfuscatedGraphQL{
+		Query:     queryPrinter.document(defs),
+		Signature: signaturePrinter.document(defs),
+	}, nil
+}
+
+// graphQLMaxDepth bounds the nesting of selection sets and values of the parsed queries.
+const graphQLMaxDepth = 256
+
+type graphQLDefinition struct {
+	operation     string // query, mutation, subscription or fragment, empty for the query shorthand
+	name          string
+	variables     []graphQLVariable
+	typeCondition string // fragments only
+	directives    []graphQLDirective
+	selections    []*graphQLSelection
+}
+
+type graphQLVariable struct {
+	name         string
+	typ          string
+	defaultValue *graphQLValue
+	directives   []graphQLDirective
+}
+
+type graphQLDirective struct {
+	name string
+	args []graphQLArgument
+}
+
+// graphQLArgument is an argument, or the field of an object value.
+type graphQLArgument struct {
+	name  string
+	value *graphQLValue
+}
+
+type graphQLSelection struct {
+	alias         string
+	name          string // the field name, or the fragment name of fragment spreads
+	spread        bool   // fragment spreads and inline fragments
+	typeCondition string // inline fragments only
+	args          []graphQLArgument
+	directives    []graphQLDirective
+	selections    []*graphQLSelection
+}
+
+type graphQLValueKind int
+
+const (
+	graphQLValueVariable graphQLValueKind = iota
+	graphQLValueLiteral                   // ints, floats, strings, booleans and obfuscated values
+	graphQLValueEnum                      // enums and null
+	graphQLValueList
+	graphQLValueObject
+)
+
+type graphQLValue struct {
+	kind   graphQLValueKind
+	text   string
+	list   []*graphQLValue
+	fields []graphQLArgument
+}
+
+// hasVariable returns whether the value is, or contains, a variable.
+func (v *graphQLValue) hasVariable() bool {
+	switch v.kind {
+	case graphQLValueVariable:
+		return true
+	case graphQLValueList:
+		for _, e := range v.list {
+			if e.hasVariable() {
+				return true
+			}
+		}
+	case graphQLValueObject:
+		for _, f := range v.fields {
+			if f.value.hasVariable() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type graphQLToken struct {
+	text string
+	typ  graphQLTokenType
+}
+
+// graphQLParser parses executable GraphQL documents, as specified in
+// https://spec.graphql.org/October2021/#sec-Executable-Definitions.
+type graphQLParser struct {
+	tokens []graphQLToken
+	pos    int
+	depth  int
+}
+
+var errGraphQLTooDeep = errors.New("maximum depth exceeded")
+
+func parseGraphQL(query string) ([]*graphQLDefinition, error) {
+	p := graphQLParser{}
+	t := newGraphQLTokenizer(query)
+	for {
+		tok, typ, err := t.scan()
+		if err != nil {
+			return nil, err
+		}
+		p.tokens = append(p.tokens, graphQLToken{text: tok, typ: typ})
+		if typ == graphQLTokenEOF {
+			break
+		}
+	}
+	var defs []*graphQLDefinition
+	for p.peek().typ != graphQLTokenEOF {
+		def, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	if len(defs) == 0 {
+		return nil, errors.New("empty document")
+	}
+	return defs, nil
+}
+
+func (p *graphQLParser) peek() graphQLToken {
+	return p.tokens[p.pos]
+}
+
+func (p *graphQLParser) next() graphQLToken {
+	tok := p.tokens[p.pos]
+	if tok.typ != graphQLTokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// peekPunctuator returns whether the next token is the given punctuator.
+func (p *graphQLParser) peekPunctuator(punct string) bool {
+	tok := p.peek()
+	return tok.typ == graphQLTokenPunctuator && tok.text == punct
+}
+
+// accept consumes the next token if it is the given punctuator.
+func (p *graphQLParser) accept(punct string) bool {
+	if p.peekPunctuator(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *graphQLParser) expect(punct string) error {
+	if !p.accept(punct) {
+		return p.unexpected(fmt.Sprintf("%q", punct))
+	}
+	return nil
+}
+
+func (p *graphQLParser) unexpected(expected string) error {
+	tok := p.peek()
+	if tok.typ == graphQLTokenEOF {
+		return fmt.Errorf("expected %s, got EOF", expected)
+	}
+	return fmt.Errorf("expected %s, got %s %q", expected, tok.typ, tok.text)
+}
+
+func (p *graphQLParser) name() (string, error) {
+	tok := p.peek()
+	if tok.typ != graphQLTokenName {
+		return "", p.unexpected("a name")
+	}
+	p.pos++
+	return tok.text, nil
+}
+
+func (p *graphQLParser) definition() (*graphQLDefinition, error) {
+	def := &graphQLDefinition{}
+	var err error
+	if !p.peekPunctuator("{") {
+		tok := p.peek()
+		switch tok.text {
+		case "query", "mutation", "subscription":
+		case "fragment":
+			return p.fragmentDefinition()
+		default:
+			return nil, p.unexpected("an operation or a fragment definition")
+		}
+		def.operation = p.next().text
+		if p.peek().typ == graphQLTokenName {
+			def.name = p.next().text
+		}
+		if def.variables, err = p.variableDefinitions(); err != nil {
+			return nil, err
+		}
+		if def.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+	}
+	if def.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (p *graphQLParser) fragmentDefinition() (*graphQLDefinition, error) {
+	def := &graphQLDefinition{operation: p.next().text}
+	var err error
+	if def.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if def.name == "on" {
+		return nil, errors.New(`fragment name can not be "on"`)
+	}
+	if p.peek().text != "on" {
+		return nil, p.unexpected(`"on"`)
+	}
+	p.next()
+	if def.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if def.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if def.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (p *graphQLParser) variableDefinitions() ([]graphQLVariable, error) {
+	if !p.accept("(") {
+		return nil, nil
+	}
+	var vars []graphQLVariable
+	for !p.accept(")") {
+		var v graphQLVariable
+		var err error
+		if err = p.expect("$"); err != nil {
+			return nil, err
+		}
+		if v.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if v.typ, err = p.typeReference(0); err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			if v.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if v.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+	}
+	if len(vars) == 0 {
+		return nil, errors.New("empty variable definitions")
+	}
+	return vars, nil
+}
+
+func (p *graphQLParser) typeReference(depth int) (string, error) {
+	if depth > graphQLMaxDepth {
+		return "", errGraphQLTooDeep
+	}
+	var typ string
+	if p.accept("[") {
+		elem, err := p.typeReference(depth + 1)
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + elem + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.accept("!") {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *graphQLParser) directives() ([]graphQLDirective, error) {
+	var dirs []graphQLDirective
+	for p.accept("@") {
+		var d graphQLDirective
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.arguments(); err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+func (p *graphQLParser) arguments() ([]graphQLArgument, error) {
+	if !p.accept("(") {
+		return nil, nil
+	}
+	var args []graphQLArgument
+	for !p.accept(")") {
+		var a graphQLArgument
+		var err error
+		if a.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if a.value, err = p.value(false); err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty arguments")
+	}
+	return args, nil
+}
+
+func (p *graphQLParser) selectionSet() ([]*graphQLSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > graphQLMaxDepth {
+		return nil, errGraphQLTooDeep
+	}
+	var sels []*graphQLSelection
+	for !p.accept("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, errors.New("empty selection set")
+	}
+	return sels, nil
+}
+
+func (p *graphQLParser) selection() (*graphQLSelection, error) {
+	sel := &graphQLSelection{}
+	var err error
+	if p.accept("...") {
+		sel.spread = true
+		tok := p.peek()
+		if tok.typ == graphQLTokenName && tok.text != "on" {
+			// fragment spread
+			sel.name = p.next().text
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		// inline fragment
+		if tok.text == "on" {
+			p.next()
+			if sel.typeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.selections, err = p.selectionSet()
+		return sel, err
+	}
+	if sel.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.accept(":") {
+		sel.alias = sel.name
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if sel.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunctuator("{") {
+		sel.selections, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+// value parses a value. Constant values, such as the default values of variables,
+// can not contain variables.
+func (p *graphQLParser) value(constant bool) (*graphQLValue, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > graphQLMaxDepth {
+		return nil, errGraphQLTooDeep
+	}
+	tok := p.peek()
+	if tok.typ == graphQLTokenEOF {
+		return nil, p.unexpected("a value")
+	}
+	p.pos++
+	switch tok.typ {
+	case graphQLTokenInt, graphQLTokenFloat, graphQLTokenString:
+		return &graphQLValue{kind: graphQLValueLiteral, text: tok.text}, nil
+	case graphQLTokenName:
+		if tok.text == "true" || tok.text == "false" {
+			return &graphQLValue{kind: graphQLValueLiteral, text: tok.text}, nil
+		}
+		return &graphQLValue{kind: graphQLValueEnum, text: tok.text}, nil
+	case graphQLTokenPunctuator:
+		switch tok.text {
+		case "?":
+			// already obfuscated queries can be obfuscated again
+			return &graphQLValue{kind: graphQLValueLiteral, text: tok.text}, nil
+		case "$":
+			if constant {
+				return nil, errors.New("unexpected variable in constant value")
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return &graphQLValue{kind: graphQLValueVariable, text: name}, nil
+		case "[":
+			v := &graphQLValue{kind: graphQLValueList}
+			for !p.accept("]") {
+				e, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.list = append(v.list, e)
+			}
+			return v, nil
+		case "{":
+			v := &graphQLValue{kind: graphQLValueObject}
+			for !p.accept("}") {
+				var f graphQLArgument
+				var err error
+				if f.name, err = p.name(); err != nil {
+					return nil, err
+				}
+				if err = p.expect(":"); err != nil {
+					return nil, err
+				}
+				if f.value, err = p.value(constant); err != nil {
+					return nil, err
+				}
+				v.fields = append(v.fields, f)
+			}
+			return v, nil
+		}
+	}
+	p.pos--
+	return nil, p.unexpected("a value")
+}
+
+// graphQLPrinter prints obfuscated GraphQL documents.
+type graphQLPrinter struct {
+	removeAliases bool
+	// normalize sorts the definitions, selections, variables and arguments, and
+	// names the query shorthand.
+	normalize bool
+	b         strings.Builder
+}
+
+func (p *graphQLPrinter) document(defs []*graphQLDefinition) string {
+	p.b.Reset()
+	if p.normalize {
+		// operations first, in their original order, then fragments sorted by name
+		defs = append([]*graphQLDefinition(nil), defs...)
+		sort.SliceStable(defs, func(i, j int) bool {
+			fi, fj := defs[i].operation == "fragment", defs[j].operation == "fragment"
+			if fi != fj {
+				return fj
+			}
+			return fi && defs[i].name < defs[j].name
+		})
+	}
+	for i, def := range defs {
+		if i > 0 {
+			p.b.WriteByte(' ')
+		}
+		p.definition(def)
+	}
+	return p.b.String()
+}
+
+func (p *graphQLPrinter) definition(def *graphQLDefinition) {
+	op := def.operation
+	if op == "" && p.normalize {
+		op = "query"
+	}
+	if op != "" {
+		p.b.WriteString(op)
+		if def.name != "" {
+			p.b.WriteByte(' ')
+			p.b.WriteString(def.name)
+		}
+		if def.typeCondition != "" {
+			p.b.WriteString(" on ")
+			p.b.WriteString(def.typeCondition)
+		}
+	}
+	if len(def.variables) > 0 {
+		vars := def.variables
+		if p.normalize {
+			vars = append([]graphQLVariable(nil), vars...)
+			sort.SliceStable(vars, func(i, j int) bool { return vars[i].name < vars[j].name })
+		}
+		p.b.WriteByte('(')
+		for i, v := range vars {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+			p.b.WriteByte('$')
+			p.b.WriteString(v.name)
+			p.b.WriteString(": ")
+			p.b.WriteString(v.typ)
+			if v.defaultValue != nil {
+				p.b.WriteString(" = ?")
+			}
+			p.directives(v.directives)
+		}
+		p.b.WriteByte(')')
+	}
+	p.directives(def.directives)
+	if op != "" {
+		p.b.WriteByte(' ')
+	}
+	p.selectionSet(def.selections)
+}
+
+func (p *graphQLPrinter) selectionSet(sels []*graphQLSelection) {
+	if !p.normalize {
+		p.b.WriteString("{ ")
+		for _, sel := range sels {
+			p.selection(sel)
+			p.b.WriteByte(' ')
+		}
+		p.b.WriteByte('}')
+		return
+	}
+	// sort the selections by their printed form
+	printed := make([]string, 0, len(sels))
+	for _, sel := range sels {
+		sub := graphQLPrinter{removeAliases: p.removeAliases, normalize: true}
+		sub.selection(sel)
+		printed = append(printed, sub.b.String())
+	}
+	sort.Strings(printed)
+	p.b.WriteString("{ ")
+	for _, s := range printed {
+		p.b.WriteString(s)
+		p.b.WriteByte(' ')
+	}
+	p.b.WriteByte('}')
+}
+
+func (p *graphQLPrinter) selection(sel *graphQLSelection) {
+	if sel.spread {
+		p.b.WriteString("...")
+		if sel.name != "" {
+			p.b.WriteString(sel.name)
+			p.directives(sel.directives)
+			return
+		}
+		if sel.typeCondition != "" {
+			p.b.WriteString(" on ")
+			p.b.WriteString(sel.typeCondition)
+		}
+		p.directives(sel.directives)
+		p.b.WriteByte(' ')
+		p.selectionSet(sel.selections)
+		return
+	}
+	if sel.alias != "" && !p.removeAliases {
+		p.b.WriteString(sel.alias)
+		p.b.WriteString(": ")
+	}
+	p.b.WriteString(sel.name)
+	p.arguments(sel.args)
+	p.directives(sel.directives)
+	if len(sel.selections) > 0 {
+		p.b.WriteByte(' ')
+		p.selectionSet(sel.selections)
+	}
+}
+
+func (p *graphQLPrinter) directives(dirs []graphQLDirective) {
+	for _, d := range dirs {
+		p.b.WriteString(" @")
+		p.b.WriteString(d.name)
+		p.arguments(d.args)
+	}
+}
+
+func (p *graphQLPrinter) arguments(args []graphQLArgument) {
+	if len(args) == 0 {
+		return
+	}
+	p.b.WriteByte('(')
+	p.fields(args)
+	p.b.WriteByte(')')
+}
+
+// fields prints arguments, or the fields of an object value.
+func (p *graphQLPrinter) fields(args []graphQLArgument) {
+	if p.normalize {
+		args = append([]graphQLArgument(nil), args...)
+		sort.SliceStable(args, func(i, j int) bool { return args[i].name < args[j].name })
+	}
+	for i, a := range args {
+		if i > 0 {
+			p.b.WriteString(", ")
+		}
+		p.b.WriteString(a.name)
+		p.b.WriteString(": ")
+		p.value(a.value)
+	}
+}
+
+// value prints an obfuscated value: literals are replaced by "?", as well as lists
+// which do not contain variables.
+func (p *graphQLPrinter) value(v *graphQLValue) {
+	switch v.kind {
+	case graphQLValueVariable:
+		p.b.WriteByte('$')
+		p.b.WriteString(v.text)
+	case graphQLValueEnum:
+		p.b.WriteString(v.text)
+	case graphQLValueList:
+		if !v.hasVariable() {
+			p.b.WriteByte('?')
+			return
+		}
+		p.b.WriteByte('[')
+		for i, e := range v.list {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+			p.value(e)
+		}
+		p.b.WriteByte(']')
+	case graphQLValueObject:
+		p.b.WriteByte('{')
+		p.fields(v.fields)
+		p.b.WriteByte('}')
+	default:
+		p.b.WriteByte('?')
+	}
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, query, signature string
+	}{
+		{
+			in:        `{ user(id: 4) { name } }`,
+			query:     `{ user(id: ?) { name } }`,
+			signature: `query { user(id: ?) { name } }`,
+		},
+		{
+			in: `
+				# fetch a user
+				query GetUser($id: ID! = "u-1", $first: Int = 10) @cached(ttl: 60) {
+					me: user(id: $id, token: """secret""") {
+						name
+						friends(first: $first, orderBy: {field: NAME, direction: ASC}, ids: [1, 2, 3]) { name }
+						... on Admin @include(if: true) { permissions }
+						...UserFields
+					}
+				}
+				fragment UserFields on User { email avatar(size: 128.5) }`,
+			query:     `query GetUser($id: ID! = ?, $first: Int = ?) @cached(ttl: ?) { me: user(id: $id, token: ?) { name friends(first: $first, orderBy: {field: NAME, direction: ASC}, ids: ?) { name } ... on Admin @include(if: ?) { permissions } ...UserFields } } fragment UserFields on User { email avatar(size: ?) }`,
+			signature: `query GetUser($first: Int = ?, $id: ID! = ?) @cached(ttl: ?) { user(id: $id, token: ?) { ... on Admin @include(if: ?) { permissions } ...UserFields friends(first: $first, ids: ?, orderBy: {direction: ASC, field: NAME}) { name } name } } fragment UserFields on User { avatar(size: ?) email }`,
+		},
+		{
+			in:        `mutation { addTags(input: {id: $id, tags: ["a", $tag], meta: {k: "v"}}) { id } }`,
+			query:     `mutation { addTags(input: {id: $id, tags: [?, $tag], meta: {k: ?}}) { id } }`,
+			signature: `mutation { addTags(input: {id: $id, meta: {k: ?}, tags: [?, $tag]}) { id } }`,
+		},
+		{
+			in:        `subscription OnEvent { event(filter: null, kind: -1e3, active: false) { id } }`,
+			query:     `subscription OnEvent { event(filter: null, kind: ?, active: ?) { id } }`,
+			signature: `subscription OnEvent { event(active: ?, filter: null, kind: ?) { id } }`,
+		},
+		{
+			in:        `fragment B on T { b } query Q { ...B ...A } fragment A on T { a }`,
+			query:     `fragment B on T { b } query Q { ...B ...A } fragment A on T { a }`,
+			signature: `query Q { ...A ...B } fragment A on T { a } fragment B on T { b }`,
+		},
+		{
+			in:        `query { ... @skip(if: $skip) { a } }`,
+			query:     `query { ... @skip(if: $skip) { a } }`,
+			signature: `query { ... @skip(if: $skip) { a } }`,
+		},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			oq, err := NewObfuscator(Config{}).ObfuscateGraphQLString(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.query, oq.Query)
+			assert.Equal(t, tt.signature, oq.Signature)
+		})
+	}
+}
+
+func TestObfuscateGraphQLRemoveAliases(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true, RemoveAliases: true}})
+	oq, err := o.ObfuscateGraphQLString(`{ a: user(id: 1) { n: name } b: user(id: 2) { name } }`)
+	require.NoError(t, err)
+	assert.Equal(t, `{ user(id: ?) { name } user(id: ?) { name } }`, oq.Query)
+}
+
+func TestObfuscateGraphQLSignatureStable(t *testing.T) {
+	o := NewObfuscator(Config{})
+	var signatures []string
+	for _, in := range []string{
+		`query Search($q: String) { search(text: $q, limit: 10) { id title } }`,
+		"query Search($q: String) {\n  s: search(limit: 25, text: $q) {\n    title\n    id\n  }\n}",
+		`query Search($q: String,) { search(limit: 5 text: $q) { title, id, } } # trailing comment`,
+	} {
+		oq, err := o.ObfuscateGraphQLString(in)
+		require.NoError(t, err)
+		signatures = append(signatures, oq.Signature)
+	}
+	assert.Equal(t, signatures[0], signatures[1])
+	assert.Equal(t, signatures[0], signatures[2])
+}
+
+func TestObfuscateGraphQLIdempotent(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, in := range []string{
+		`{ user(id: 4, admin: true) { name } }`,
+		`query GetUser($id: ID! = "u-1", $ids: [ID] = [1, 2]) @cached(ttl: 60) { me: user(id: $id) { friends(ids: [1, $id], orderBy: {field: NAME}) { name } ... @include(if: false) { email } } }`,
+		`mutation { addTags(input: {id: $id, tags: ["a", $tag], meta: {k: "v"}}) { id } }`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			oq, err := o.ObfuscateGraphQLString(in)
+			require.NoError(t, err)
+			again, err := o.ObfuscateGraphQLString(oq.Query)
+			require.NoError(t, err)
+			assert.Equal(t, oq.Query, again.Query)
+			assert.Equal(t, oq.Signature, again.Signature)
+			again, err = o.ObfuscateGraphQLString(oq.Signature)
+			require.NoError(t, err)
+			assert.Equal(t, oq.Signature, again.Signature)
+		})
+	}
+}
+
+func TestObfuscateGraphQLErrors(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, in := range []string{
+		``,
+		`# only a comment`,
+		`GetUser`,
+		`type User { id: ID }`,
+		`query`,
+		`{ }`,
+		`{ user( }`,
+		`{ user() }`,
+		`{ user(id: ) }`,
+		`{ user(id: 1 }`,
+		`{ user { name }`,
+		`query ($id: ID = $other) { a }`,
+		`query () { a }`,
+		`query ($id) { a }`,
+		`query ($id: [ID) { a }`,
+		`fragment on on T { a }`,
+		`fragment F T { a }`,
+		`{ a: }`,
+		`{ "a" }`,
+		strings.Repeat("{ a ", graphQLMaxDepth+1) + strings.Repeat("}", graphQLMaxDepth+1),
+		"{ a(b: " + strings.Repeat("[", graphQLMaxDepth+1) + strings.Repeat("]", graphQLMaxDepth+1) + ") }",
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := o.ObfuscateGraphQLString(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func BenchmarkObfuscateGraphQL(b *testing.B) {
+	o := NewObfuscator(Config{})
+	query := `query GetUser($id: ID!) { user(id: $id) { name friends(first: 10, after: "Y3Vyc29y") { edges { node { id name } } } ...UserFields } } fragment UserFields on User { email avatar(size: 128) }`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := o.ObfuscateGraphQLString(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
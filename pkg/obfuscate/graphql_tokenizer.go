@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// graphQLTokenType specifies the token type returned by the GraphQL tokenizer.
+type graphQLTokenType int
+
+const (
+	// graphQLTokenEOF marks the end of the query.
+	graphQLTokenEOF graphQLTokenType = iota
+
+	// graphQLTokenPunctuator is one of ! $ & ( ) ... : = @ [ ] { | }, or ?, the
+	// placeholder of the obfuscated values.
+	graphQLTokenPunctuator
+
+	// graphQLTokenName is a name, such as a field, a type or a keyword.
+	graphQLTokenName
+
+	// graphQLTokenInt is an integer value.
+	graphQLTokenInt
+
+	// graphQLTokenFloat is a floating point value.
+	graphQLTokenFloat
+
+	// graphQLTokenString is a string or a block string value.
+	graphQLTokenString
+)
+
+// String implements fmt.Stringer.
+func (t graphQLTokenType) String() string {
+	return map[graphQLTokenType]string{
+		graphQLTokenEOF:        "EOF",
+		graphQLTokenPunctuator: "punctuator",
+		graphQLTokenName:       "name",
+		graphQLTokenInt:        "int",
+		graphQLTokenFloat:      "float",
+		graphQLTokenString:     "string",
+	}[t]
+}
+
+// graphQLTokenizer tokenizes a GraphQL document, as specified in
+// https://spec.graphql.org/October2021/#sec-Language.Source-Text. Whitespaces,
+// commas and comments are ignored.
+type graphQLTokenizer struct {
+	data string
+	off  int
+}
+
+// newGraphQLTokenizer returns a new tokenizer for the given query.
+func newGraphQLTokenizer(query string) *graphQLTokenizer {
+	return &graphQLTokenizer{data: query}
+}
+
+// scan returns the next token and its type. String tokens are returned
+// with their quotes.
+func (t *graphQLTokenizer) scan() (tok string, typ graphQLTokenType, err error) {
+	t.skipIgnored()
+	if t.off >= len(t.data) {
+		return "", graphQLTokenEOF, nil
+	}
+	start := t.off
+	switch ch := t.data[t.off]; {
+	case strings.HasPrefix(t.data[t.off:], "..."):
+		t.off += 3
+		return "...", graphQLTokenPunctuator, nil
+	case strings.IndexByte("!$&():=?@[]{|}", ch) >= 0:
+		t.off++
+		return t.data[start:t.off], graphQLTokenPunctuator, nil
+	case isGraphQLNameStart(ch):
+		for t.off < len(t.data) && isGraphQLNameContinue(t.data[t.off]) {
+			t.off++
+		}
+		return t.data[start:t.off], graphQLTokenName, nil
+	case ch == '-' || isDigit(rune(ch)):
+		return t.scanNumber()
+	case ch == '"':
+		return t.scanString()
+	default:
+		return "", graphQLTokenEOF, fmt.Errorf("unexpected character %q at offset %d", ch, t.off)
+	}
+}
+
+// skipIgnored skips whitespaces, line terminators, commas, comments and the unicode BOM.
+func (t *graphQLTokenizer) skipIgnored() {
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case ' ', '\t', '\n', '\r', ',':
+			t.off++
+		case '#':
+			for t.off < len(t.data) && t.data[t.off] != '\n' && t.data[t.off] != '\r' {
+				t.off++
+			}
+		default:
+			if strings.HasPrefix(t.data[t.off:], "\uFEFF") {
+				t.off += len("\uFEFF")
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (t *graphQLTokenizer) scanDigits() int {
+	start := t.off
+	for t.off < len(t.data) && isDigit(rune(t.data[t.off])) {
+		t.off++
+	}
+	return t.off - start
+}
+
+func (t *graphQLTokenizer) scanNumber() (tok string, typ graphQLTokenType, err error) {
+	start := t.off
+	typ = graphQLTokenInt
+	if t.data[t.off] == '-' {
+		t.off++
+	}
+	if t.scanDigits() == 0 {
+		return "", graphQLTokenEOF, fmt.Errorf("invalid number at offset %d", start)
+	}
+	if t.off < len(t.data) && t.data[t.off] == '.' {
+		t.off++
+		typ = graphQLTokenFloat
+		if t.scanDigits() == 0 {
+			return "", graphQLTokenEOF, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if t.off < len(t.data) && (t.data[t.off] == 'e' || t.data[t.off] == 'E') {
+		t.off++
+		typ = graphQLTokenFloat
+		if t.off < len(t.data) && (t.data[t.off] == '+' || t.data[t.off] == '-') {
+			t.off++
+		}
+		if t.scanDigits() == 0 {
+			return "", graphQLTokenEOF, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if t.off < len(t.data) && (isGraphQLNameStart(t.data[t.off]) || t.data[t.off] == '.') {
+		return "", graphQLTokenEOF, fmt.Errorf("invalid number at offset %d", start)
+	}
+	return t.data[start:t.off], typ, nil
+}
+
+func (t *graphQLTokenizer) scanString() (tok string, typ graphQLTokenType, err error) {
+	start := t.off
+	if strings.HasPrefix(t.data[t.off:], `"""`) {
+		t.off += 3
+		for t.off < len(t.data) {
+			switch {
+			case strings.HasPrefix(t.data[t.off:], `\"""`):
+				t.off += 4
+			case strings.HasPrefix(t.data[t.off:], `"""`):
+				t.off += 3
+				return t.data[start:t.off], graphQLTokenString, nil
+			default:
+				t.off++
+			}
+		}
+		return "", graphQLTokenEOF, fmt.Errorf("unterminated block string at offset %d", start)
+	}
+	t.off++
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case '\\':
+			t.off += 2
+		case '"':
+			t.off++
+			return t.data[start:t.off], graphQLTokenString, nil
+		case '\n', '\r':
+			return "", graphQLTokenEOF, fmt.Errorf("unterminated string at offset %d", start)
+		default:
+			t.off++
+		}
+	}
+	return "", graphQLTokenEOF, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func isGraphQLNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isGraphQLNameContinue(ch byte) bool {
+	return isGraphQLNameStart(ch) || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLTokenizer(t *testing.T) {
+	type testResult struct {
+		tok string
+		typ graphQLTokenType
+	}
+	for _, tt := range []struct {
+		in  string
+		out []testResult
+	}{
+		{
+			in:  "",
+			out: nil,
+		},
+		{
+			in: "\uFEFF{ user(id: 4, name: \"a \\\"b\\\"\") { ...F } } # comment\n",
+			out: []testResult{
+				{"{", graphQLTokenPunctuator},
+				{"user", graphQLTokenName},
+				{"(", graphQLTokenPunctuator},
+				{"id", graphQLTokenName},
+				{":", graphQLTokenPunctuator},
+				{"4", graphQLTokenInt},
+				{"name", graphQLTokenName},
+				{":", graphQLTokenPunctuator},
+				{`"a \"b\""`, graphQLTokenString},
+				{")", graphQLTokenPunctuator},
+				{"{", graphQLTokenPunctuator},
+				{"...", graphQLTokenPunctuator},
+				{"F", graphQLTokenName},
+				{"}", graphQLTokenPunctuator},
+				{"}", graphQLTokenPunctuator},
+			},
+		},
+		{
+			in: "-1.5e+3 0 -0.25 3E2 $v_2 \"\"\"block \\\"\"\" \"quoted\" \"\"\"",
+			out: []testResult{
+				{"-1.5e+3", graphQLTokenFloat},
+				{"0", graphQLTokenInt},
+				{"-0.25", graphQLTokenFloat},
+				{"3E2", graphQLTokenFloat},
+				{"$", graphQLTokenPunctuator},
+				{"v_2", graphQLTokenName},
+				{"\"\"\"block \\\"\"\" \"quoted\" \"\"\"", graphQLTokenString},
+			},
+		},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			tokenizer := newGraphQLTokenizer(tt.in)
+			var out []testResult
+			for {
+				tok, typ, err := tokenizer.scan()
+				assert.NoError(t, err)
+				if err != nil || typ == graphQLTokenEOF {
+					break
+				}
+				out = append(out, testResult{tok, typ})
+			}
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestGraphQLTokenizerErrors(t *testing.T) {
+	for _, in := range []string{
+		`"unterminated`,
+		"\"multi\nline\"",
+		`"""unterminated block`,
+		`-a`,
+		`1.`,
+		`1e`,
+		`12abc`,
+		`1.5.2`,
+		`%`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			tokenizer := newGraphQLTokenizer(in)
+			for {
+				_, typ, err := tokenizer.scan()
+				if err != nil {
+					return
+				}
+				if typ == graphQLTokenEOF {
+					t.Fatal("expected an error")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// config holds the settings of the receiver
+type config struct {
+	addr            string
+	namespace       string
+	counterSuffixes []string
+	labelsAsTags    map[string]string
+	excludeLabels   []string
+	maxRequestSize  int
+	counterExpiry   int
+}
+
+func readConfig(conf model.Reader) (*config, error) {
+	port := conf.GetInt("prometheus_remote_write.port")
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid prometheus_remote_write.port: %d", port)
+	}
+	maxRequestSize := conf.GetInt("prometheus_remote_write.max_request_size")
+	if maxRequestSize <= 0 {
+		return nil, fmt.Errorf("invalid prometheus_remote_write.max_request_size: %d", maxRequestSize)
+	}
+	return &config{
+		addr:            net.JoinHostPort(pkgconfig.GetBindHostFromConfig(conf), strconv.Itoa(port)),
+		namespace:       conf.GetString("prometheus_remote_write.namespace"),
+		counterSuffixes: conf.GetStringSlice("prometheus_remote_write.counter_suffixes"),
+		labelsAsTags:    conf.GetStringMapString("prometheus_remote_write.labels_as_tags"),
+		excludeLabels:   conf.GetStringSlice("prometheus_remote_write.exclude_labels"),
+		maxRequestSize:  maxRequestSize,
+		counterExpiry:   conf.GetInt("prometheus_remote_write.counter_expiry_seconds"),
+	}, nil
+}
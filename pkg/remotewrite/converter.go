@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const metricNameLabel = "__name__"
+
+var (
+	// histogramSuffixes are the suffixes of the series of histogram and summary families
+	histogramSuffixes = []string{"_bucket", "_count", "_sum"}
+	// familySuffixes are the suffixes added to the family names in the series names
+	familySuffixes = []string{"_bucket", "_count", "_sum", "_total"}
+)
+
+// counterValue is the last value of a counter series
+type counterValue struct {
+	value     float64
+	timestamp int64
+	lastSeen  time.Time
+}
+
+// converter converts the remote write time series into metric samples. Prometheus
+// counters are cumulative: they are sent as counts holding the increase since the
+// previous sample of the series, the first sample of a series only being recorded.
+type converter struct {
+	namespace       string
+	hostname        string
+	counterSuffixes []string
+	labelsAsTags    map[string]string
+	excludeLabels   map[string]struct{}
+
+	mu sync.Mutex
+	// typesByFamily holds the types sent in the metadata, which are sent separately
+	// from the time series
+	typesByFamily map[string]metricType
+	counters      map[string]*counterValue
+}
+
+func newConverter(c *config, hostname string) *converter {
+	excludeLabels := make(map[string]struct{}, len(c.excludeLabels))
+	for _, l := range c.excludeLabels {
+		excludeLabels[l] = struct{}{}
+	}
+	return &converter{
+		namespace:       c.namespace,
+		hostname:        hostname,
+		counterSuffixes: c.counterSuffixes,
+		labelsAsTags:    c.labelsAsTags,
+		excludeLabels:   excludeLabels,
+		typesByFamily:   make(map[string]metricType),
+		counters:        make(map[string]*counterValue),
+	}
+}
+
+// convert calls submit with the samples of a write request, and returns the number of
+// samples dropped.
+func (c *converter) convert(req *writeRequest, now time.Time, submit func(metrics.MetricSample)) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range req.metadata {
+		c.typesByFamily[m.metricFamilyName] = m.mtype
+	}
+
+	dropped := 0
+	for _, ts := range req.timeseries {
+		dropped += ts.histograms
+		name, tags := c.nameAndTags(ts.labels)
+		if name == "" {
+			dropped += len(ts.samples)
+			continue
+		}
+		isCounter := c.isCounter(name)
+		if c.namespace != "" {
+			name = c.namespace + "." + name
+		}
+
+		var counter *counterValue
+		if isCounter {
+			key := seriesKey(ts.labels)
+			counter = c.counters[key]
+			if counter == nil {
+				counter = &counterValue{timestamp: math.MinInt64}
+				c.counters[key] = counter
+			}
+			counter.lastSeen = now
+		}
+
+		for _, s := range ts.samples {
+			// skip the staleness markers and other invalid values
+			if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+				dropped++
+				continue
+			}
+			sample := metrics.MetricSample{
+				Name:       name,
+				Value:      s.value,
+				Mtype:      metrics.GaugeType,
+				Tags:       tags,
+				Host:       c.hostname,
+				SampleRate: 1,
+				Timestamp:  float64(s.timestamp) / 1000,
+			}
+			if isCounter {
+				// out of order samples, for instance sent by HA pairs, are ignored
+				if s.timestamp <= counter.timestamp {
+					dropped++
+					continue
+				}
+				first := counter.timestamp == math.MinInt64
+				delta := s.value - counter.value
+				if s.value < counter.value {
+					// the counter was reset
+					delta = s.value
+				}
+				counter.value, counter.timestamp = s.value, s.timestamp
+				if first {
+					continue
+				}
+				sample.Value = delta
+				sample.Mtype = metrics.CountType
+			}
+			submit(sample)
+		}
+	}
+	return dropped
+}
+
+// nameAndTags returns the metric name of a time series and the tags built from its labels.
+func (c *converter) nameAndTags(labels []label) (string, []string) {
+	var name string
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l.name == metricNameLabel {
+			name = l.value
+			continue
+		}
+		if _, ok := c.excludeLabels[l.name]; ok || l.value == "" {
+			continue
+		}
+		key := l.name
+		if tagKey, ok := c.labelsAsTags[l.name]; ok {
+			key = tagKey
+		}
+		tags = append(tags, key+":"+l.value)
+	}
+	return name, tags
+}
+
+// isCounter returns true if the series of a metric are cumulative, either from the type
+// of their family in the metadata, or from their name suffix if the type is unknown.
+func (c *converter) isCounter(name string) bool {
+	switch c.familyType(name) {
+	case metricTypeCounter:
+		return true
+	case metricTypeHistogram, metricTypeSummary:
+		// the quantiles of the summaries are gauges
+		return hasSuffix(name, histogramSuffixes)
+	case metricTypeUnknown:
+		return hasSuffix(name, c.counterSuffixes)
+	default:
+		return false
+	}
+}
+
+// familyType returns the type of the family of a metric sent in the metadata, if any.
+func (c *converter) familyType(name string) metricType {
+	if mtype, ok := c.typesByFamily[name]; ok {
+		return mtype
+	}
+	for _, suffix := range familySuffixes {
+		if strings.HasSuffix(name, suffix) {
+			if mtype, ok := c.typesByFamily[strings.TrimSuffix(name, suffix)]; ok {
+				return mtype
+			}
+		}
+	}
+	return metricTypeUnknown
+}
+
+// expire forgets the counters not received since the given time.
+func (c *converter) expire(before time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, counter := range c.counters {
+		if counter.lastSeen.Before(before) {
+			delete(c.counters, key)
+		}
+	}
+	tlmCounters.Set(float64(len(c.counters)))
+}
+
+// seriesKey identifies a series by its labels, which are sorted by name in the write
+// requests.
+func seriesKey(labels []label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0xff)
+		b.WriteString(l.value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+func hasSuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func testConverter() *converter {
+	return newConverter(&config{
+		counterSuffixes: []string{"_total", "_count", "_sum", "_bucket"},
+		labelsAsTags:    map[string]string{"instance": "prometheus_instance"},
+		excludeLabels:   []string{"job"},
+	}, "myhost")
+}
+
+func series(name string, samples ...sample) timeSeries {
+	return timeSeries{
+		labels: []label{
+			{name: "__name__", value: name},
+			{name: "code", value: "200"},
+			{name: "instance", value: "localhost:9090"},
+			{name: "job", value: "prometheus"},
+		},
+		samples: samples,
+	}
+}
+
+func convert(c *converter, req *writeRequest) ([]metrics.MetricSample, int) {
+	var samples []metrics.MetricSample
+	dropped := c.convert(req, time.Now(), func(s metrics.MetricSample) {
+		samples = append(samples, s)
+	})
+	return samples, dropped
+}
+
+func TestConvertGauge(t *testing.T) {
+	c := testConverter()
+	c.namespace = "prom"
+
+	samples, dropped := convert(c, &writeRequest{timeseries: []timeSeries{
+		series("go_goroutines", sample{value: 42, timestamp: 1700000000000}, sample{value: math.NaN(), timestamp: 1700000015000}),
+	}})
+	assert.Equal(t, 1, dropped)
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.MetricSample{
+		Name:       "prom.go_goroutines",
+		Value:      42,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"code:200", "prometheus_instance:localhost:9090"},
+		Host:       "myhost",
+		SampleRate: 1,
+		Timestamp:  1700000000,
+	}, samples[0])
+}
+
+func TestConvertCounter(t *testing.T) {
+	c := testConverter()
+
+	// the first sample is only recorded
+	samples, _ := convert(c, &writeRequest{timeseries: []timeSeries{
+		series("http_requests_total", sample{value: 10, timestamp: 1000}, sample{value: 15, timestamp: 2000}),
+	}})
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.CountType, samples[0].Mtype)
+	assert.Equal(t, 5.0, samples[0].Value)
+
+	// out of order samples are ignored, resets are handled
+	samples, dropped := convert(c, &writeRequest{timeseries: []timeSeries{
+		series("http_requests_total", sample{value: 12, timestamp: 1500}, sample{value: 3, timestamp: 3000}, sample{value: 7, timestamp: 4000}),
+	}})
+	assert.Equal(t, 1, dropped)
+	require.Len(t, samples, 2)
+	assert.Equal(t, 3.0, samples[0].Value)
+	assert.Equal(t, 4.0, samples[1].Value)
+
+	// the counters are tracked per series
+	other := series("http_requests_total", sample{value: 100, timestamp: 5000})
+	other.labels[1].value = "500"
+	samples, _ = convert(c, &writeRequest{timeseries: []timeSeries{other}})
+	assert.Empty(t, samples)
+}
+
+func TestConvertWithMetadata(t *testing.T) {
+	c := testConverter()
+	convert(c, &writeRequest{metadata: []metricMetadata{
+		{mtype: metricTypeGauge, metricFamilyName: "queue_size_total"},
+		{mtype: metricTypeCounter, metricFamilyName: "processed"},
+		{mtype: metricTypeSummary, metricFamilyName: "rpc_duration_seconds"},
+	}})
+
+	assert.False(t, c.isCounter("queue_size_total"))
+	assert.True(t, c.isCounter("processed"))
+	assert.True(t, c.isCounter("processed_total"))
+	assert.True(t, c.isCounter("rpc_duration_seconds_count"))
+	assert.False(t, c.isCounter("rpc_duration_seconds"))
+	// without metadata, the type is inferred from the suffix
+	assert.True(t, c.isCounter("other_total"))
+	assert.False(t, c.isCounter("other"))
+}
+
+func TestConvertDropsSeriesWithoutName(t *testing.T) {
+	c := testConverter()
+	samples, dropped := convert(c, &writeRequest{timeseries: []timeSeries{
+		{labels: []label{{name: "job", value: "prometheus"}}, samples: []sample{{value: 1}}, histograms: 2},
+	}})
+	assert.Empty(t, samples)
+	assert.Equal(t, 3, dropped)
+}
+
+func TestExpireCounters(t *testing.T) {
+	c := testConverter()
+	convert(c, &writeRequest{timeseries: []timeSeries{series("http_requests_total", sample{value: 10, timestamp: 1000})}})
+	require.Len(t, c.counters, 1)
+
+	c.expire(time.Now().Add(-time.Minute))
+	assert.Len(t, c.counters, 1)
+	c.expire(time.Now().Add(time.Minute))
+	assert.Empty(t, c.counters)
+}
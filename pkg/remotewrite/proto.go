@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages below are the subset of the Prometheus remote write protocol
+// (prometheus/prompb/types.proto and remote.proto) used by the receiver. They are
+// decoded by hand to avoid depending on the Prometheus module.
+
+// metricType is the type of a metric family, as sent in the metadata
+type metricType int32
+
+const (
+	metricTypeUnknown metricType = iota
+	metricTypeCounter
+	metricTypeGauge
+	metricTypeHistogram
+	metricTypeGaugeHistogram
+	metricTypeSummary
+	metricTypeInfo
+	metricTypeStateset
+)
+
+type writeRequest struct {
+	timeseries []timeSeries
+	metadata   []metricMetadata
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+	// histograms is the number of native histogram samples, which aren't supported
+	histograms int
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value float64
+	// timestamp is in milliseconds
+	timestamp int64
+}
+
+type metricMetadata struct {
+	mtype            metricType
+	metricFamilyName string
+}
+
+func (r *writeRequest) unmarshal(b []byte) error {
+	return forEachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var ts timeSeries
+			if err := ts.unmarshal(v); err != nil {
+				return 0, fmt.Errorf("invalid time series: %w", err)
+			}
+			r.timeseries = append(r.timeseries, ts)
+			return n, nil
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var m metricMetadata
+			if err := m.unmarshal(v); err != nil {
+				return 0, fmt.Errorf("invalid metadata: %w", err)
+			}
+			r.metadata = append(r.metadata, m)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+}
+
+func (ts *timeSeries) unmarshal(b []byte) error {
+	return forEachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var l label
+			if err := l.unmarshal(v); err != nil {
+				return 0, err
+			}
+			ts.labels = append(ts.labels, l)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var s sample
+			if err := s.unmarshal(v); err != nil {
+				return 0, err
+			}
+			ts.samples = append(ts.samples, s)
+			return n, nil
+		case num == 4 && typ == protowire.BytesType:
+			ts.histograms++
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+}
+
+func (l *label) unmarshal(b []byte) error {
+	return forEachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if (num == 1 || num == 2) && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			if num == 1 {
+				l.name = string(v)
+			} else {
+				l.value = string(v)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+}
+
+func (s *sample) unmarshal(b []byte) error {
+	return forEachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			s.value = math.Float64frombits(v)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			s.timestamp = int64(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+}
+
+func (m *metricMetadata) unmarshal(b []byte) error {
+	return forEachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.mtype = metricType(v)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.metricFamilyName = string(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+}
+
+// forEachField calls consume with the number, type and encoded value of each field of
+// a message. consume returns the length of the value, negative if it's invalid.
+func forEachField(b []byte, consume func(protowire.Number, protowire.Type, []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := consume(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite implements a server receiving the metrics sent with the
+// Prometheus remote write protocol, and submitting them to the aggregator.
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// WritePath is the path of the remote write endpoint
+const WritePath = "/api/v1/write"
+
+const expirationInterval = time.Minute
+
+// Demultiplexer is the part of the aggregator demultiplexer used by the server
+type Demultiplexer interface {
+	AggregateSamples(shard aggregator.TimeSamplerID, samples metrics.MetricSampleBatch)
+	GetMetricSamplePool() *metrics.MetricSamplePool
+}
+
+// Server receives the remote write requests
+type Server struct {
+	config    *config
+	converter *converter
+	demux     Demultiplexer
+	server    *http.Server
+	listener  net.Listener
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+var serverInstance *Server
+
+// IsEnabled returns whether the remote write receiver is enabled in the Agent configuration.
+func IsEnabled(conf model.Reader) bool {
+	return conf.GetBool("prometheus_remote_write.enabled")
+}
+
+// StartServer starts the global remote write server.
+func StartServer(hostname string, demux Demultiplexer, conf model.Reader) error {
+	server, err := NewServer(hostname, demux, conf)
+	if err != nil {
+		return err
+	}
+	if err := server.Start(); err != nil {
+		return err
+	}
+	serverInstance = server
+	return nil
+}
+
+// StopServer stops the global remote write server, if it is running.
+func StopServer() {
+	if serverInstance != nil {
+		serverInstance.Stop()
+		serverInstance = nil
+	}
+}
+
+// NewServer returns a server submitting the samples it receives to the demultiplexer,
+// with the given hostname.
+func NewServer(hostname string, demux Demultiplexer, conf model.Reader) (*Server, error) {
+	config, err := readConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		config:    config,
+		converter: newConverter(config, hostname),
+		demux:     demux,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WritePath, s.handleWrite)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// Start starts listening for remote write requests.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.addr)
+	if err != nil {
+		return fmt.Errorf("can't listen on %s: %w", s.config.addr, err)
+	}
+	s.listener = listener
+	log.Infof("Listening for Prometheus remote write requests on %s", listener.Addr())
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Prometheus remote write server stopped: %v", err)
+		}
+	}()
+	go s.expireCounters()
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Warnf("Error while stopping the Prometheus remote write server: %v", err)
+	}
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Server) expireCounters() {
+	defer close(s.done)
+	ticker := time.NewTicker(expirationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.converter.expire(now.Add(-time.Duration(s.config.counterExpiry) * time.Second))
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Server) handleWrite(w http.ResponseWriter, r *http.Request) {
+	status, err := s.write(r)
+	tlmRequests.Inc(strconv.Itoa(status))
+	if err != nil {
+		log.Debugf("Invalid Prometheus remote write request from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// write submits the samples of a request, and returns the HTTP status of the response.
+func (s *Server) write(r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method)
+	}
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %s", encoding)
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, int64(s.config.maxRequestSize)+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(compressed) > s.config.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, errors.New("request too large")
+	}
+	if size, err := snappy.DecodedLen(compressed); err != nil {
+		return http.StatusBadRequest, err
+	} else if size > s.config.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, errors.New("request too large")
+	}
+	payload, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	var req writeRequest
+	if err := req.unmarshal(payload); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	pool := s.demux.GetMetricSamplePool()
+	batch := pool.GetBatch()
+	n, submitted := 0, 0
+	dropped := s.converter.convert(&req, time.Now(), func(sample metrics.MetricSample) {
+		batch[n] = sample
+		n++
+		submitted++
+		if n == len(batch) {
+			s.demux.AggregateSamples(0, batch[:n])
+			batch = pool.GetBatch()
+			n = 0
+		}
+	})
+	if n > 0 {
+		s.demux.AggregateSamples(0, batch[:n])
+	} else {
+		pool.PutBatch(batch)
+	}
+	tlmSamples.Add(float64(submitted), "ok")
+	tlmSamples.Add(float64(dropped), "dropped")
+	return http.StatusNoContent, nil
+}
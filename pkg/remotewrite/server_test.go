@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type fakeDemultiplexer struct {
+	pool    *metrics.MetricSamplePool
+	samples []metrics.MetricSample
+}
+
+func (d *fakeDemultiplexer) AggregateSamples(_ aggregator.TimeSamplerID, samples metrics.MetricSampleBatch) {
+	d.samples = append(d.samples, samples...)
+	d.pool.PutBatch(samples)
+}
+
+func (d *fakeDemultiplexer) GetMetricSamplePool() *metrics.MetricSamplePool {
+	return d.pool
+}
+
+// marshal encodes a write request with the Prometheus protobuf schema.
+func (r *writeRequest) marshal() []byte {
+	var b []byte
+	for _, ts := range r.timeseries {
+		var tsb []byte
+		for _, l := range ts.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			tsb = protowire.AppendTag(tsb, 1, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, lb)
+		}
+		for _, s := range ts.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(s.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(s.timestamp))
+			tsb = protowire.AppendTag(tsb, 2, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, sb)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, tsb)
+	}
+	for _, m := range r.metadata {
+		var mb []byte
+		mb = protowire.AppendTag(mb, 1, protowire.VarintType)
+		mb = protowire.AppendVarint(mb, uint64(m.mtype))
+		mb = protowire.AppendTag(mb, 2, protowire.BytesType)
+		mb = protowire.AppendString(mb, m.metricFamilyName)
+		// help, which is ignored
+		mb = protowire.AppendTag(mb, 4, protowire.BytesType)
+		mb = protowire.AppendString(mb, "some help")
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, mb)
+	}
+	return b
+}
+
+func TestUnmarshalWriteRequest(t *testing.T) {
+	req := &writeRequest{
+		timeseries: []timeSeries{series("up", sample{value: 1, timestamp: 1700000000000}, sample{value: -2.5, timestamp: 1700000015000})},
+		metadata:   []metricMetadata{{mtype: metricTypeGauge, metricFamilyName: "up"}},
+	}
+	var decoded writeRequest
+	require.NoError(t, decoded.unmarshal(req.marshal()))
+	assert.Equal(t, *req, decoded)
+
+	assert.Error(t, decoded.unmarshal([]byte{0x0a, 0x10, 0x01}))
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeDemultiplexer) {
+	demux := &fakeDemultiplexer{pool: metrics.NewMetricSamplePool(2, false)}
+	s, err := NewServer("myhost", demux, pkgconfig.Mock(t))
+	require.NoError(t, err)
+	s.config.addr = "127.0.0.1:0"
+	require.NoError(t, s.Start())
+	t.Cleanup(s.Stop)
+	return s, demux
+}
+
+func TestNewServerInvalidConfig(t *testing.T) {
+	cfg := pkgconfig.Mock(t)
+	cfg.SetWithoutSource("prometheus_remote_write.port", 0)
+	_, err := NewServer("myhost", nil, cfg)
+	assert.Error(t, err)
+}
+
+func TestServerWrite(t *testing.T) {
+	s, demux := newTestServer(t)
+	req := &writeRequest{timeseries: []timeSeries{
+		series("up", sample{value: 1, timestamp: 1000}),
+		series("go_goroutines", sample{value: 10, timestamp: 1000}, sample{value: 12, timestamp: 2000}),
+	}}
+
+	resp, err := http.Post("http://"+s.Addr()+WritePath, "application/x-protobuf", bytes.NewReader(snappy.Encode(nil, req.marshal())))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Len(t, demux.samples, 3)
+	assert.Equal(t, "up", demux.samples[0].Name)
+	assert.Equal(t, "myhost", demux.samples[0].Host)
+	assert.Equal(t, 12.0, demux.samples[2].Value)
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	s, demux := newTestServer(t)
+	url := "http://" + s.Addr() + WritePath
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// not compressed
+	req := &writeRequest{timeseries: []timeSeries{series("up", sample{value: 1, timestamp: 1000})}}
+	resp, err = http.Post(url, "application/x-protobuf", bytes.NewReader(req.marshal()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	s.config.maxRequestSize = 16
+	resp, err = http.Post(url, "application/x-protobuf", bytes.NewReader(snappy.Encode(nil, req.marshal())))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	assert.Empty(t, demux.samples)
+}
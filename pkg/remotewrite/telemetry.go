@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmRequests = telemetry.NewCounter("prometheus_remote_write", "requests",
+		[]string{"status"}, "Count of the remote write requests received, by HTTP status")
+	tlmSamples = telemetry.NewCounter("prometheus_remote_write", "samples",
+		[]string{"state"}, "Count of the remote write samples received")
+	tlmCounters = telemetry.NewGauge("prometheus_remote_write", "counters",
+		nil, "Number of counter series whose last value is tracked")
+)
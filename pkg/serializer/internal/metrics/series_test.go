@@ -373,7 +373,7 @@ func makeSeries(numItems, numPoints int) *IterableSeries {
 func TestMarshalSplitCompress(t *testing.T) {
 	series := makeSeries(10000, 50)
 
-	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 	require.NoError(t, err)
 	// check that we got multiple payloads, so splitting occurred
 	require.Greater(t, len(payloads), 1)
@@ -400,7 +400,7 @@ func TestMarshalSplitCompressPointsLimit(t *testing.T) {
 	// ten series, each with 50 points, so two should fit in each payload
 	series := makeSeries(10, 50)
 
-	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 	require.NoError(t, err)
 	require.Equal(t, 5, len(payloads))
 }
@@ -412,7 +412,7 @@ func TestMarshalSplitCompressPointsLimitTooBig(t *testing.T) {
 	mockConfig.SetWithoutSource("serializer_max_series_points_per_payload", 1)
 
 	series := makeSeries(1, 2)
-	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 	require.NoError(t, err)
 	require.Len(t, payloads, 0)
 }
@@ -445,7 +445,7 @@ func TestPayloadsSeries(t *testing.T) {
 	}
 
 	originalLength := len(testSeries)
-	builder := stream.NewJSONPayloadBuilder(true)
+	builder := stream.NewJSONPayloadBuilder(true, stream.NewZlibCompression())
 	iterableSeries := CreateIterableSeries(CreateSerieSource(testSeries))
 	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
 	require.Nil(t, err)
@@ -488,7 +488,7 @@ func BenchmarkPayloadsSeries(b *testing.B) {
 	}
 
 	var r transaction.BytesPayloads
-	builder := stream.NewJSONPayloadBuilder(true)
+	builder := stream.NewJSONPayloadBuilder(true, stream.NewZlibCompression())
 	for n := 0; n < b.N; n++ {
 		// always record the result of Payloads to prevent
 		// the compiler eliminating the function call.
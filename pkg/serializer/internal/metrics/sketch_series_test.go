@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 
@@ -94,7 +95,7 @@ func TestSketchSeriesMarshalSplitCompressEmpty(t *testing.T) {
 
 	sl := SketchSeriesList{SketchesSource: metrics.NewSketchesSourceTest()}
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 
 	assert.Nil(t, err)
 
@@ -128,7 +129,7 @@ func TestSketchSeriesMarshalSplitCompressItemTooBigIsDropped(t *testing.T) {
 	})
 
 	serializer := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := serializer.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 
 	assert.Nil(t, err)
 
@@ -157,7 +158,7 @@ func TestSketchSeriesMarshalSplitCompress(t *testing.T) {
 
 	sl.Reset()
 	serializer2 := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer2.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := serializer2.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 	require.NoError(t, err)
 
 	firstPayload := payloads[0]
@@ -204,7 +205,7 @@ func TestSketchSeriesMarshalSplitCompressSplit(t *testing.T) {
 	}
 
 	serializer := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer.MarshalSplitCompress(marshaler.NewBufferContext())
+	payloads, err := serializer.MarshalSplitCompress(marshaler.NewBufferContext(), stream.NewZlibCompression())
 	assert.Nil(t, err)
 
 	recoveredSketches := []gogen.SketchPayload{}
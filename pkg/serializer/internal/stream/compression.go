@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stream
+
+import (
+	"bytes"
+	"io"
+)
+
+const (
+	// ZlibKind is the name of the zlib compression in `serializer_compressor_kind`
+	ZlibKind = "zlib"
+	// ZstdKind is the name of the zstd compression in `serializer_compressor_kind`
+	ZstdKind = "zstd"
+)
+
+// StreamCompressor compresses the data written to it into the output buffer it was
+// created with.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+type StreamCompressor interface {
+	io.WriteCloser
+	// Flush compresses the pending data, so that the length of the output buffer
+	// accounts for all the data written so far.
+	Flush() error
+}
+
+// Compression is a compression algorithm used by the Compressor to build payloads
+type Compression interface {
+	// NewStreamCompressor returns a StreamCompressor writing to output
+	NewStreamCompressor(output *bytes.Buffer) (StreamCompressor, error)
+	// CompressBound returns the worst case size of the compression of sourceLen bytes
+	CompressBound(sourceLen int) int
+	// ContentEncoding returns the HTTP Content-Encoding of the compressed payloads
+	ContentEncoding() string
+}
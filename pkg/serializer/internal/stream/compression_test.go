@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build zlib && cgo && test
+
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+)
+
+func TestNewCompression(t *testing.T) {
+	c, err := NewCompression(ZlibKind, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "deflate", c.ContentEncoding())
+
+	c, err = NewCompression(ZstdKind, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", c.ContentEncoding())
+
+	_, err = NewCompression(ZstdKind, 0)
+	assert.Error(t, err)
+	_, err = NewCompression(ZstdKind, 23)
+	assert.Error(t, err)
+	_, err = NewCompression("gzip", 1)
+	assert.Error(t, err)
+}
+
+func TestNewCompressionFromConfig(t *testing.T) {
+	defer config.Datadog.SetWithoutSource("serializer_compressor_kind", nil)
+	defer config.Datadog.SetWithoutSource("serializer_zstd_compressor_level", nil)
+
+	assert.Equal(t, "deflate", NewCompressionFromConfig().ContentEncoding())
+
+	config.Datadog.SetWithoutSource("serializer_compressor_kind", ZstdKind)
+	assert.Equal(t, "zstd", NewCompressionFromConfig().ContentEncoding())
+
+	// invalid levels fall back to zlib
+	config.Datadog.SetWithoutSource("serializer_zstd_compressor_level", 40)
+	assert.Equal(t, "deflate", NewCompressionFromConfig().ContentEncoding())
+}
+
+func TestZstdCompressor(t *testing.T) {
+	compression, err := NewZstdCompression(1)
+	require.NoError(t, err)
+
+	c, err := NewCompressor(
+		&bytes.Buffer{}, &bytes.Buffer{},
+		1024, 2048,
+		[]byte("{["), []byte("]}"), []byte(","), compression)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, c.AddItem([]byte("A")))
+	}
+	p, err := c.Close()
+	require.NoError(t, err)
+
+	decompressed, err := zstd.Decompress(nil, p)
+	require.NoError(t, err)
+	assert.Equal(t, "{[A,A,A,A,A]}", string(decompressed))
+}
+
+func TestZstdPayloadsRespectMaxSize(t *testing.T) {
+	compression, err := NewZstdCompression(5)
+	require.NoError(t, err)
+
+	maxPayloadSize := 512
+	config.Datadog.SetDefault("serializer_max_payload_size", maxPayloadSize)
+	defer resetDefaults()
+
+	items := make([]string, 0, 500)
+	for i := 0; i < cap(items); i++ {
+		items = append(items, fmt.Sprintf(`"item-%d-%s"`, i, strings.Repeat("x", i%17)))
+	}
+	m := &marshaler.DummyMarshaller{
+		Items:  items,
+		Header: "[",
+		Footer: "]",
+	}
+
+	builder := NewJSONPayloadBuilder(true, compression)
+	payloads, err := BuildJSONPayload(builder, m)
+	require.NoError(t, err)
+	require.Greater(t, len(payloads), 1)
+
+	var got []string
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload.GetContent()), maxPayloadSize)
+		decompressed, err := zstd.Decompress(nil, payload.GetContent())
+		require.NoError(t, err)
+		s := string(decompressed)
+		require.True(t, strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"), s)
+		got = append(got, strings.Split(s[1:len(s)-1], ",")...)
+	}
+	assert.Equal(t, items, got)
+}
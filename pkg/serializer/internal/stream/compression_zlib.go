@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build zlib
+
+package stream
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// NewCompression returns the compression of the given kind, the level being only used
+// by zstd.
+func NewCompression(kind string, zstdLevel int) (Compression, error) {
+	switch kind {
+	case ZlibKind:
+		return NewZlibCompression(), nil
+	case ZstdKind:
+		return NewZstdCompression(zstdLevel)
+	default:
+		return nil, fmt.Errorf("unknown compression kind %q", kind)
+	}
+}
+
+// NewCompressionFromConfig returns the compression set in `serializer_compressor_kind`,
+// falling back to zlib when it can't be used.
+func NewCompressionFromConfig() Compression {
+	kind := config.Datadog.GetString("serializer_compressor_kind")
+	c, err := NewCompression(kind, config.Datadog.GetInt("serializer_zstd_compressor_level"))
+	if err != nil {
+		log.Warnf("Invalid serializer_compressor_kind, falling back to %s: %v", ZlibKind, err)
+		return NewZlibCompression()
+	}
+	return c
+}
+
+type zlibCompression struct{}
+
+// NewZlibCompression returns the zlib compression
+func NewZlibCompression() Compression {
+	return zlibCompression{}
+}
+
+// NewStreamCompressor returns a zlib writer
+func (zlibCompression) NewStreamCompressor(output *bytes.Buffer) (StreamCompressor, error) {
+	return zlib.NewWriter(output), nil
+}
+
+// CompressBound returns the worst case size of the zlib compression of sourceLen bytes
+func (zlibCompression) CompressBound(sourceLen int) int {
+	return compression.CompressBound(sourceLen)
+}
+
+// ContentEncoding returns the HTTP Content-Encoding of the zlib payloads
+func (zlibCompression) ContentEncoding() string {
+	return "deflate"
+}
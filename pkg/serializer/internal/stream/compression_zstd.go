@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build zlib && cgo
+
+package stream
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/DataDog/zstd"
+)
+
+// zstdMaxLevel is the highest compression level supported by zstd
+const zstdMaxLevel = 22
+
+type zstdCompression struct {
+	level int
+}
+
+// NewZstdCompression returns the zstd compression with the given level, from 1 (fastest)
+// to 22 (smallest payloads).
+func NewZstdCompression(level int) (Compression, error) {
+	if level < zstd.BestSpeed || level > zstdMaxLevel {
+		return nil, fmt.Errorf("invalid zstd compression level %d, it should be between %d and %d", level, zstd.BestSpeed, zstdMaxLevel)
+	}
+	return zstdCompression{level: level}, nil
+}
+
+// NewStreamCompressor returns a zstd writer
+func (c zstdCompression) NewStreamCompressor(output *bytes.Buffer) (StreamCompressor, error) {
+	return zstd.NewWriterLevel(output, c.level), nil
+}
+
+// CompressBound returns the worst case size of the zstd compression of sourceLen bytes
+func (zstdCompression) CompressBound(sourceLen int) int {
+	return zstd.CompressBound(sourceLen)
+}
+
+// ContentEncoding returns the HTTP Content-Encoding of the zstd payloads
+func (zstdCompression) ContentEncoding() string {
+	return ZstdKind
+}
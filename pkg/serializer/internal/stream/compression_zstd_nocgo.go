@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build zlib && !cgo
+
+package stream
+
+import "fmt"
+
+// NewZstdCompression is not available without cgo
+func NewZstdCompression(level int) (Compression, error) {
+	return nil, fmt.Errorf("zstd compression is not available in this build")
+}
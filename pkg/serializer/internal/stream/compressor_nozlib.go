@@ -30,7 +30,27 @@ var (
 type Compressor struct{}
 
 // NewCompressor not implemented
-func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte) (*Compressor, error) {
+func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte, compression Compression) (*Compressor, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// NewCompression not implemented
+func NewCompression(kind string, zstdLevel int) (Compression, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// NewCompressionFromConfig not implemented
+func NewCompressionFromConfig() Compression {
+	return nil
+}
+
+// NewZlibCompression not implemented
+func NewZlibCompression() Compression {
+	return nil
+}
+
+// NewZstdCompression not implemented
+func NewZstdCompression(level int) (Compression, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
@@ -58,7 +58,7 @@ func TestCompressorSimple(t *testing.T) {
 	c, err := NewCompressor(
 		&bytes.Buffer{}, &bytes.Buffer{},
 		maxPayloadSize, maxUncompressedSize,
-		[]byte("{["), []byte("]}"), []byte(","))
+		[]byte("{["), []byte("]}"), []byte(","), NewZlibCompression())
 	require.NoError(t, err)
 
 	for i := 0; i < 5; i++ {
@@ -78,7 +78,7 @@ func TestCompressorAddItemErrCodeWithEmptyCompressor(t *testing.T) {
 		c, err := NewCompressor(
 			&bytes.Buffer{}, &bytes.Buffer{},
 			maxPayloadSize, maxUncompressedSize,
-			[]byte("{["), []byte("]}"), []byte(","))
+			[]byte("{["), []byte("]}"), []byte(","), NewZlibCompression())
 		require.NoError(t, err)
 
 		payload := strings.Repeat("A", dataLen)
@@ -113,7 +113,7 @@ func TestOnePayloadSimple(t *testing.T) {
 		Footer: "]}",
 	}
 
-	builder := NewJSONPayloadBuilder(true)
+	builder := NewJSONPayloadBuilder(true, NewZlibCompression())
 	payloads, err := BuildJSONPayload(builder, m)
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
@@ -130,7 +130,7 @@ func TestMaxCompressedSizePayload(t *testing.T) {
 	config.Datadog.SetDefault("serializer_max_payload_size", 22)
 	defer resetDefaults()
 
-	builder := NewJSONPayloadBuilder(true)
+	builder := NewJSONPayloadBuilder(true, NewZlibCompression())
 	payloads, err := BuildJSONPayload(builder, m)
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
@@ -147,7 +147,7 @@ func TestTwoPayload(t *testing.T) {
 	config.Datadog.SetDefault("serializer_max_payload_size", 22)
 	defer resetDefaults()
 
-	builder := NewJSONPayloadBuilder(true)
+	builder := NewJSONPayloadBuilder(true, NewZlibCompression())
 	payloads, err := BuildJSONPayload(builder, m)
 	require.NoError(t, err)
 	require.Len(t, payloads, 2)
@@ -164,8 +164,8 @@ func TestLockedCompressorProducesSamePayloads(t *testing.T) {
 	}
 	defer resetDefaults()
 
-	builderLocked := NewJSONPayloadBuilder(true)
-	builderUnLocked := NewJSONPayloadBuilder(false)
+	builderLocked := NewJSONPayloadBuilder(true, NewZlibCompression())
+	builderUnLocked := NewJSONPayloadBuilder(false, NewZlibCompression())
 	payloads1, err := BuildJSONPayload(builderLocked, m)
 	require.NoError(t, err)
 	payloads2, err := BuildJSONPayload(builderUnLocked, m)
@@ -178,7 +178,7 @@ func TestBuildWithOnErrItemTooBigPolicyMetadata(t *testing.T) {
 	config.Datadog.SetWithoutSource("serializer_max_uncompressed_payload_size", 40)
 	defer config.Datadog.SetWithoutSource("serializer_max_uncompressed_payload_size", nil)
 	marshaler := &IterableStreamJSONMarshalerMock{index: 0, maxIndex: 100}
-	builder := NewJSONPayloadBuilder(false)
+	builder := NewJSONPayloadBuilder(false, NewZlibCompression())
 	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(
 		marshaler,
 		DropItemOnErrItemTooBig)
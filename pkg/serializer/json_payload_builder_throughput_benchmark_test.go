@@ -21,7 +21,7 @@ func benchmarkJSONPayloadBuilderThroughput(points int, items int, tags int, runs
 	initialSize := len(json)
 	metricsCount := len(series)
 
-	payloadBuilder := stream.NewJSONPayloadBuilder(true)
+	payloadBuilder := stream.NewJSONPayloadBuilder(true, stream.NewZlibCompression())
 	var totalTime time.Duration
 
 	for i := 0; i < runs; i++ {
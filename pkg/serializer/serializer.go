@@ -89,6 +89,13 @@ func initExtraHeaders() {
 	}
 }
 
+// withContentEncoding returns a copy of the headers with the given Content-Encoding
+func withContentEncoding(headers http.Header, contentEncoding string) http.Header {
+	h := headers.Clone()
+	h.Set("Content-Encoding", contentEncoding)
+	return h
+}
+
 // MetricSerializer represents the interface of method needed by the aggregator to serialize its data
 type MetricSerializer interface {
 	SendEvents(e event.Events) error
@@ -114,6 +121,12 @@ type Serializer struct {
 
 	seriesJSONPayloadBuilder *stream.JSONPayloadBuilder
 
+	// streamCompression compresses the payloads built in stream, their headers
+	// hold its Content-Encoding.
+	streamCompression          stream.Compression
+	jsonStreamExtraHeaders     http.Header
+	protobufStreamExtraHeaders http.Header
+
 	// Those variables allow users to blacklist any kind of payload
 	// from being sent by the agent. This was introduced for
 	// environment where, for example, events or serviceChecks
@@ -133,11 +146,15 @@ type Serializer struct {
 
 // NewSerializer returns a new Serializer initialized
 func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestratorForwarder.Component) *Serializer {
+	streamCompression := stream.NewCompressionFromConfig()
 	s := &Serializer{
 		clock:                         clock.New(),
 		Forwarder:                     forwarder,
 		orchestratorForwarder:         orchestratorForwarder,
-		seriesJSONPayloadBuilder:      stream.NewJSONPayloadBuilder(config.Datadog.GetBool("enable_json_stream_shared_compressor_buffers")),
+		seriesJSONPayloadBuilder:      stream.NewJSONPayloadBuilder(config.Datadog.GetBool("enable_json_stream_shared_compressor_buffers"), streamCompression),
+		streamCompression:             streamCompression,
+		jsonStreamExtraHeaders:        jsonExtraHeadersWithCompression,
+		protobufStreamExtraHeaders:    protobufExtraHeadersWithCompression,
 		enableEvents:                  config.Datadog.GetBool("enable_payloads.events"),
 		enableSeries:                  config.Datadog.GetBool("enable_payloads.series"),
 		enableServiceChecks:           config.Datadog.GetBool("enable_payloads.service_checks"),
@@ -149,6 +166,11 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestr
 		enableSketchProtobufStream:    stream.Available && config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
 	}
 
+	if streamCompression != nil {
+		s.jsonStreamExtraHeaders = withContentEncoding(jsonExtraHeaders, streamCompression.ContentEncoding())
+		s.protobufStreamExtraHeaders = withContentEncoding(protobufExtraHeaders, streamCompression.ContentEncoding())
+	}
+
 	if !s.enableEvents {
 		log.Warn("event payloads are disabled: all events will be dropped")
 	}
@@ -218,12 +240,12 @@ func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler
 func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (transaction.BytesPayloads, http.Header, error) {
 	adapter := marshaler.NewIterableStreamJSONMarshalerAdapter(payload)
 	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(adapter, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+	return payloads, s.jsonStreamExtraHeaders, err
 }
 
 func (s Serializer) serializeIterableStreamablePayload(payload marshaler.IterableStreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (transaction.BytesPayloads, http.Header, error) {
 	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+	return payloads, s.jsonStreamExtraHeaders, err
 }
 
 // As events are gathered by SourceType, the serialization logic is more complex than for the other serializations.
@@ -334,8 +356,8 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 	} else if useV1API && !s.enableJSONStream {
 		seriesBytesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, true)
 	} else {
-		seriesBytesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.NewBufferContext())
-		extraHeaders = protobufExtraHeadersWithCompression
+		seriesBytesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(), s.streamCompression)
+		extraHeaders = s.protobufStreamExtraHeaders
 	}
 
 	if err != nil {
@@ -361,12 +383,12 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 	}
 	sketchesSerializer := metricsserializer.SketchSeriesList{SketchesSource: sketches}
 	if s.enableSketchProtobufStream {
-		payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext())
+		payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(), s.streamCompression)
 		if err != nil {
 			return fmt.Errorf("dropping sketch payload: %v", err)
 		}
 
-		return s.Forwarder.SubmitSketchSeries(payloads, protobufExtraHeadersWithCompression)
+		return s.Forwarder.SubmitSketchSeries(payloads, s.protobufStreamExtraHeaders)
 	} else {
 		//nolint:revive // TODO(AML) Fix revive linter
 		compress := true
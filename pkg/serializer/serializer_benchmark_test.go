@@ -40,7 +40,7 @@ var results transaction.BytesPayloads
 func benchmarkJSONStream(b *testing.B, passes int, sharedBuffers bool, numberOfEvents int) {
 	events := buildEvents(numberOfEvents)
 	marshaler := events.CreateSingleMarshaler()
-	payloadBuilder := stream.NewJSONPayloadBuilder(sharedBuffers)
+	payloadBuilder := stream.NewJSONPayloadBuilder(sharedBuffers, stream.NewZlibCompression())
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
@@ -27,6 +27,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
@@ -355,6 +356,31 @@ func TestSendSketch(t *testing.T) {
 	f.AssertExpectations(t)
 }
 
+func TestSendWithZstdStreamCompression(t *testing.T) {
+	if !stream.Available {
+		t.Skip("the stream serialization is not available")
+	}
+	config.Datadog.SetWithoutSource("serializer_compressor_kind", "zstd")
+	defer config.Datadog.SetWithoutSource("serializer_compressor_kind", nil)
+
+	protobufHeaders := protobufExtraHeaders.Clone()
+	protobufHeaders.Set("Content-Encoding", "zstd")
+	jsonHeaders := jsonExtraHeaders.Clone()
+	jsonHeaders.Set("Content-Encoding", "zstd")
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSeries", mock.Anything, protobufHeaders).Return(nil).Times(1)
+	f.On("SubmitSketchSeries", mock.Anything, protobufHeaders).Return(nil).Times(1)
+	f.On("SubmitV1CheckRuns", mock.Anything, jsonHeaders).Return(nil).Times(1)
+	config.Datadog.SetWithoutSource("use_v2_api.series", true)
+
+	s := NewSerializer(f, nil)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{}})))
+	require.NoError(t, s.SendSketch(metrics.NewSketchesSourceTestWithSketch()))
+	require.NoError(t, s.SendServiceChecks(servicecheck.ServiceChecks{&servicecheck.ServiceCheck{}}))
+	f.AssertExpectations(t)
+}
+
 func TestSendMetadata(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	f.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
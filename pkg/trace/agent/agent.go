@@ -58,10 +58,12 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanFilter            *filters.SpanFilter
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	TailSampler           *TailSampler // nil unless tail sampling is enabled
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -109,6 +111,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanFilter:            filters.NewSpanFilter(conf.SpanFilters),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(conf),
@@ -126,6 +129,10 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector)
+	if conf.TailSampling.Enabled {
+		log.Infof("Tail sampling enabled with a decision window of %s", conf.TailSampling.DecisionWait)
+		agnt.TailSampler = NewTailSampler(conf.TailSampling, agnt.TraceWriter.In)
+	}
 	return agnt
 }
 
@@ -146,6 +153,10 @@ func (a *Agent) Run() {
 		starter.Start()
 	}
 
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
+
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
 
@@ -202,6 +213,10 @@ func (a *Agent) loop() {
 	if err := a.Receiver.Stop(); err != nil {
 		log.Error(err)
 	}
+	if a.TailSampler != nil {
+		// Make a decision over the buffered traces before stopping the TraceWriter
+		a.TailSampler.Stop()
+	}
 	for _, stopper := range []interface{ Stop() }{
 		a.Concentrator,
 		a.ClientStatsAggregator,
@@ -259,6 +274,7 @@ func (a *Agent) Process(p *api.Payload) {
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
+	var payloadMetadata *pb.TracerPayload // used by the TailSampler
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
 
@@ -301,6 +317,11 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if a.filterSpans(config.SpanFilterBeforeStats, ts, chunk) {
+			p.RemoveChunk(i)
+			continue
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -334,6 +355,21 @@ func (a *Agent) Process(p *api.Payload) {
 		if !p.ClientComputedStats {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
+		if a.filterSpans(config.SpanFilterAfterStats, ts, pt.TraceChunk) {
+			p.RemoveChunk(i)
+			continue
+		}
+
+		if a.TailSampler != nil {
+			// The sampling decision is made once the whole trace has been buffered. The head
+			// based samplers and the APM events extraction are skipped for these traces.
+			if payloadMetadata == nil {
+				payloadMetadata = tracerPayloadMetadata(p.TracerPayload)
+			}
+			a.TailSampler.Add(now, payloadMetadata, pt.TraceChunk)
+			p.RemoveChunk(i)
+			continue
+		}
 
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
@@ -606,6 +642,22 @@ func traceContainsError(trace pb.Trace) bool {
 	return false
 }
 
+// filterSpans applies the span filters of the given stage to the chunk, removing the
+// dropped spans from it. It returns true if the whole chunk has to be dropped.
+func (a *Agent) filterSpans(stage string, ts *info.TagStats, chunk *pb.TraceChunk) bool {
+	spans, dropTrace := a.SpanFilter.Filter(stage, chunk.Spans)
+	if dropTrace {
+		ts.TracesFiltered.Inc()
+		ts.SpansFiltered.Add(int64(len(chunk.Spans)))
+		return true
+	}
+	if n := len(chunk.Spans) - len(spans); n > 0 {
+		ts.SpansFiltered.Add(int64(n))
+		chunk.Spans = spans
+	}
+	return false
+}
+
 func filteredByTags(root *pb.Span, require, reject []*config.Tag, requireRegex, rejectRegex []*config.TagRegex) bool {
 	for _, tag := range reject {
 		if v, ok := root.Meta[tag.K]; ok && (tag.V == "" || v == tag.V) {
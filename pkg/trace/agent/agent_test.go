@@ -255,6 +255,67 @@ func TestProcess(t *testing.T) {
 		assert.Equal("unnamed_operation", span.Name)
 	})
 
+	t.Run("SpanFilter", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanFilters = []*config.SpanFilterRule{
+			{Name: "cache", Expr: `name == "cache.get"`, Action: config.SpanFilterDropSpan, Stage: config.SpanFilterBeforeStats},
+			{Name: "health", Expr: `resource =~ "^GET /health"`, Action: config.SpanFilterDropTrace, Stage: config.SpanFilterAfterStats},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(traceID, spanID, parentID uint64, name, resource string) *pb.Span {
+			return &pb.Span{
+				TraceID:  traceID,
+				SpanID:   spanID,
+				ParentID: parentID,
+				Service:  "web",
+				Name:     name,
+				Resource: resource,
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+		}
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunks([]*pb.TraceChunk{
+				testutil.TraceChunkWithSpansAndPriority([]*pb.Span{
+					newSpan(1, 1, 0, "http.request", "GET /users"),
+					newSpan(1, 2, 1, "cache.get", "GET"),
+					newSpan(1, 3, 2, "render", "users"),
+				}, 2),
+				testutil.TraceChunkWithSpansAndPriority([]*pb.Span{
+					newSpan(2, 1, 0, "http.request", "GET /health"),
+				}, 2),
+			}),
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered.Load())
+		assert.EqualValues(2, want.SpansFiltered.Load())
+
+		require.Len(t, agnt.Concentrator.In, 1)
+		statsInput := <-agnt.Concentrator.In
+		require.Len(t, statsInput.Traces, 2)
+		assert.Len(statsInput.Traces[0].TraceChunk.Spans, 2)
+		assert.Len(statsInput.Traces[1].TraceChunk.Spans, 1)
+
+		select {
+		case ss := <-agnt.TraceWriter.In:
+			require.Len(t, ss.TracerPayload.Chunks, 1)
+			spans := ss.TracerPayload.Chunks[0].Spans
+			require.Len(t, spans, 2)
+			assert.Equal(uint64(3), spans[1].SpanID)
+			assert.Equal(uint64(1), spans[1].ParentID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout: Expected one valid trace, but none were received.")
+		}
+	})
+
 	t.Run("Stats/Priority", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -22,10 +22,13 @@ const (
 	tagElasticBody      = "elasticsearch.body"
 	tagSQLQuery         = "sql.query"
 	tagHTTPURL          = "http.url"
+	tagGraphQLSource    = "graphql.source"
+	tagGraphQLSignature = "graphql.signature"
 )
 
 const (
-	textNonParsable = "Non-parsable SQL query"
+	textNonParsable        = "Non-parsable SQL query"
+	textNonParsableGraphQL = "Non-parsable GraphQL query"
 )
 
 func (a *Agent) obfuscateSpan(span *pb.Span) {
@@ -90,6 +93,26 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagElasticBody] = o.ObfuscateElasticSearchString(span.Meta[tagElasticBody])
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		// the resource is only replaced when it is a query, it can also be an operation name
+		if oq, err := o.ObfuscateGraphQLString(span.Resource); err == nil {
+			span.Resource = oq.Query
+		}
+		if span.Meta == nil || span.Meta[tagGraphQLSource] == "" {
+			return
+		}
+		oq, err := o.ObfuscateGraphQLString(span.Meta[tagGraphQLSource])
+		if err != nil {
+			// discard the query as it could contain sensitive values.
+			log.Debugf("Error parsing GraphQL query: %v. Query: %q", err, span.Meta[tagGraphQLSource])
+			span.Meta[tagGraphQLSource] = textNonParsableGraphQL
+			return
+		}
+		span.Meta[tagGraphQLSource] = oq.Query
+		span.Meta[tagGraphQLSignature] = oq.Signature
 	}
 }
 
@@ -106,6 +129,13 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		if oq, err := o.ObfuscateGraphQLString(b.Resource); err == nil {
+			b.Resource = oq.Query
+		}
 	}
 }
 
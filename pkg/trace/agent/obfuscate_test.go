@@ -246,6 +246,78 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&config.ObfuscationConfig{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query GetUser { user(id: 4) { name } }`,
+		`query GetUser { user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/remove_aliases", testConfig(
+		"graphql",
+		"graphql.source",
+		`{ me: user(id: 4) { name } }`,
+		`{ user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{
+			Enabled:       true,
+			RemoveAliases: true,
+		}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`{ user(id: 4) { name } }`,
+		`{ user(id: 4) { name } }`,
+		&config.ObfuscationConfig{},
+	))
+}
+
+func TestGraphQLObfuscation(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation = &config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}}
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+	t.Run("span", func(t *testing.T) {
+		query := `query GetUser { u: user(id: 4) { name id } }`
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: query,
+			Meta:     map[string]string{"graphql.source": query},
+		}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, `query GetUser { u: user(id: ?) { name id } }`, span.Resource)
+		assert.Equal(t, `query GetUser { u: user(id: ?) { name id } }`, span.Meta["graphql.source"])
+		assert.Equal(t, `query GetUser { user(id: ?) { id name } }`, span.Meta["graphql.signature"])
+	})
+
+	t.Run("operation", func(t *testing.T) {
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: "GetUser",
+			Meta:     map[string]string{"graphql.source": `{ user(id: "4" }`},
+		}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "GetUser", span.Resource)
+		assert.Equal(t, textNonParsableGraphQL, span.Meta["graphql.source"])
+		assert.NotContains(t, span.Meta, "graphql.signature")
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		for in, out := range map[string]string{
+			`{ user(id: 4) { name } }`: `{ user(id: ?) { name } }`,
+			"GetUser":                  "GetUser",
+		} {
+			b := &pb.ClientGroupedStats{Type: "graphql", Resource: in}
+			agnt.obfuscateStatsGroup(b)
+			assert.Equal(t, out, b.Resource)
+		}
+	})
 }
 
 func SQLSpan(query string) *pb.Span {
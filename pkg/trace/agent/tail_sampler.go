@@ -0,0 +1,376 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	// tagTailSamplingPolicy is set on the chunks of the traces kept by the tail sampler,
+	// with the name of the policy which kept them.
+	tagTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+	// userKeepPolicy is the policy reported for traces kept by the tracers.
+	userKeepPolicy = "user_keep"
+
+	// tailSamplerTickInterval is the interval at which the decision window of the
+	// buffered traces is checked.
+	tailSamplerTickInterval = time.Second
+)
+
+// TailSampler buffers the processed chunks by trace ID for a decision window and
+// then keeps or drops complete traces, depending on whether they match one of
+// its policies. This allows sampling traces which are made of chunks coming from
+// several tracers based on their overall properties, such as their duration.
+//
+// The buffer is bounded in memory: once it is full, the decisions are made early
+// over the oldest traces. The decisions are remembered for a while, and applied to
+// the chunks received once the decision over their trace is made.
+type TailSampler struct {
+	conf config.TailSamplingConfig
+	out  chan<- *writer.SampledChunks
+
+	mu     sync.Mutex
+	traces map[uint64]*bufferedTrace
+	order  []uint64 // trace IDs, by arrival of their first chunk
+	size   int64
+
+	decisions     map[uint64]*tailDecision
+	decisionOrder []*tailDecision // by time of the decision
+
+	kept    *atomic.Int64
+	dropped *atomic.Int64
+	evicted *atomic.Int64
+	late    *atomic.Int64
+
+	exit    chan struct{}
+	stopped chan struct{}
+}
+
+// bufferedTrace holds the chunks received for a trace.
+type bufferedTrace struct {
+	traceID   uint64
+	firstSeen time.Time
+	chunks    []bufferedChunk
+	size      int64
+}
+
+// tailDecision is a decision made over a trace, applied to its late chunks until it expires.
+type tailDecision struct {
+	traceID uint64
+	policy  string
+	keep    bool
+	expires time.Time
+}
+
+type bufferedChunk struct {
+	// payload holds the metadata of the tracer payload the chunk came from.
+	payload *pb.TracerPayload
+	chunk   *pb.TraceChunk
+}
+
+// NewTailSampler returns a TailSampler writing the kept traces to out.
+func NewTailSampler(conf config.TailSamplingConfig, out chan<- *writer.SampledChunks) *TailSampler {
+	return &TailSampler{
+		conf:      conf,
+		out:       out,
+		traces:    make(map[uint64]*bufferedTrace),
+		decisions: make(map[uint64]*tailDecision),
+		kept:      atomic.NewInt64(0),
+		dropped:   atomic.NewInt64(0),
+		evicted:   atomic.NewInt64(0),
+		late:      atomic.NewInt64(0),
+		exit:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+// Start starts making decisions over the buffered traces.
+func (s *TailSampler) Start() {
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(tailSamplerTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.flush(now)
+				s.report()
+			case <-s.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the TailSampler, making a decision over all the buffered traces.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	<-s.stopped
+
+	s.mu.Lock()
+	traces := make([]*bufferedTrace, 0, len(s.order))
+	for len(s.order) > 0 {
+		traces = append(traces, s.pop())
+	}
+	s.mu.Unlock()
+	now := time.Now()
+	for _, t := range traces {
+		s.decide(now, t)
+	}
+	s.report()
+}
+
+// Add buffers a processed chunk until a decision is made over its trace. The chunk
+// is kept or dropped right away if a decision was already made over its trace.
+// payload holds the metadata of the tracer payload the chunk came from.
+func (s *TailSampler) Add(now time.Time, payload *pb.TracerPayload, chunk *pb.TraceChunk) {
+	traceID := chunk.Spans[0].TraceID
+	size := int64(chunk.Msgsize())
+
+	s.mu.Lock()
+	if d, ok := s.decisions[traceID]; ok && now.Before(d.expires) {
+		s.mu.Unlock()
+		s.late.Inc()
+		if d.keep {
+			s.write(d.policy, []bufferedChunk{{payload: payload, chunk: chunk}})
+		}
+		return
+	}
+	t, ok := s.traces[traceID]
+	if !ok {
+		t = &bufferedTrace{traceID: traceID, firstSeen: now}
+		s.traces[traceID] = t
+		s.order = append(s.order, traceID)
+	}
+	t.chunks = append(t.chunks, bufferedChunk{payload: payload, chunk: chunk})
+	t.size += size
+	s.size += size
+
+	var evicted []*bufferedTrace
+	for s.size > s.conf.MaxMemoryBytes && len(s.order) > 0 {
+		evicted = append(evicted, s.pop())
+	}
+	s.mu.Unlock()
+
+	s.evicted.Add(int64(len(evicted)))
+	for _, t := range evicted {
+		s.decide(now, t)
+	}
+}
+
+// pop removes the oldest trace from the buffer. The lock must be held by the caller.
+func (s *TailSampler) pop() *bufferedTrace {
+	traceID := s.order[0]
+	s.order = s.order[1:]
+	t := s.traces[traceID]
+	delete(s.traces, traceID)
+	s.size -= t.size
+	return t
+}
+
+// flush makes a decision over the traces whose decision window is over, and forgets
+// the expired decisions.
+func (s *TailSampler) flush(now time.Time) {
+	s.mu.Lock()
+	var ready []*bufferedTrace
+	for len(s.order) > 0 && now.Sub(s.traces[s.order[0]].firstSeen) >= s.conf.DecisionWait {
+		ready = append(ready, s.pop())
+	}
+	for len(s.decisionOrder) > 0 && !now.Before(s.decisionOrder[0].expires) {
+		s.forgetOldestDecision()
+	}
+	s.mu.Unlock()
+
+	for _, t := range ready {
+		s.decide(now, t)
+	}
+}
+
+// decide writes the trace if it matches a policy, and drops it otherwise. The
+// decision is remembered for the chunks of the trace received later.
+func (s *TailSampler) decide(now time.Time, t *bufferedTrace) {
+	policy, keep := s.match(t)
+	s.remember(&tailDecision{traceID: t.traceID, policy: policy, keep: keep, expires: now.Add(s.conf.DecisionCacheTTL)})
+	if !keep {
+		s.dropped.Inc()
+		return
+	}
+	s.kept.Inc()
+	s.write(policy, t.chunks)
+}
+
+// remember records a decision, forgetting the oldest ones once the cache is full.
+func (s *TailSampler) remember(d *tailDecision) {
+	if s.conf.DecisionCacheSize <= 0 || s.conf.DecisionCacheTTL <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decisions[d.traceID] = d
+	s.decisionOrder = append(s.decisionOrder, d)
+	for len(s.decisionOrder) > s.conf.DecisionCacheSize {
+		s.forgetOldestDecision()
+	}
+}
+
+// forgetOldestDecision removes the oldest decision from the cache. The lock must be
+// held by the caller.
+func (s *TailSampler) forgetOldestDecision() {
+	d := s.decisionOrder[0]
+	s.decisionOrder[0] = nil
+	s.decisionOrder = s.decisionOrder[1:]
+	// a more recent decision may have been made over the same trace
+	if s.decisions[d.traceID] == d {
+		delete(s.decisions, d.traceID)
+	}
+}
+
+// write writes the chunks of a kept trace, tagged with the policy which kept it.
+func (s *TailSampler) write(policy string, chunks []bufferedChunk) {
+	var out []*writer.SampledChunks
+	byPayload := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	for _, c := range chunks {
+		if isUserDrop(c.chunk) {
+			// the late chunks dropped by the tracers are dropped even if their trace was kept
+			continue
+		}
+		c.chunk.DroppedTrace = false
+		if c.chunk.Priority < int32(sampler.PriorityAutoKeep) {
+			c.chunk.Priority = int32(sampler.PriorityAutoKeep)
+		}
+		if c.chunk.Tags == nil {
+			c.chunk.Tags = make(map[string]string)
+		}
+		c.chunk.Tags[tagTailSamplingPolicy] = policy
+
+		sc, ok := byPayload[c.payload]
+		if !ok {
+			sc = &writer.SampledChunks{TracerPayload: tracerPayloadMetadata(c.payload)}
+			byPayload[c.payload] = sc
+			out = append(out, sc)
+		}
+		sc.TracerPayload.Chunks = append(sc.TracerPayload.Chunks, c.chunk)
+		sc.Size += c.chunk.Msgsize()
+		sc.SpanCount += int64(len(c.chunk.Spans))
+	}
+	for _, sc := range out {
+		s.out <- sc
+	}
+}
+
+// match returns the name of the first policy matching the trace. The traces dropped
+// by the tracers (user drop) never match.
+func (s *TailSampler) match(t *bufferedTrace) (policy string, ok bool) {
+	for _, c := range t.chunks {
+		if isUserDrop(c.chunk) {
+			return "", false
+		}
+	}
+	var spans pb.Trace
+	for _, c := range t.chunks {
+		if c.chunk.Priority >= int32(sampler.PriorityUserKeep) {
+			return userKeepPolicy, true
+		}
+		spans = append(spans, c.chunk.Spans...)
+	}
+
+	for _, p := range s.conf.Policies {
+		if matchTailSamplingPolicy(p, spans) {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// isUserDrop reports whether the chunk was dropped by the tracer.
+func isUserDrop(chunk *pb.TraceChunk) bool {
+	priority, ok := sampler.GetSamplingPriority(chunk)
+	return ok && priority < 0
+}
+
+func matchTailSamplingPolicy(p *config.TailSamplingPolicy, spans pb.Trace) bool {
+	switch p.Type {
+	case config.TailSamplingLatency:
+		return traceDuration(spans) >= time.Duration(p.ThresholdMs*float64(time.Millisecond))
+	case config.TailSamplingError:
+		return traceContainsError(spans)
+	case config.TailSamplingAttribute:
+		for _, span := range spans {
+			if v, ok := span.Meta[p.Key]; ok && (p.Re == nil || p.Re.MatchString(v)) {
+				return true
+			}
+			if v, ok := span.Metrics[p.Key]; ok && (p.Re == nil || p.Re.MatchString(strconv.FormatFloat(v, 'f', -1, 64))) {
+				return true
+			}
+		}
+		return false
+	case config.TailSamplingRate:
+		root := traceutil.GetRoot(spans)
+		if p.Service != "" && root.Service != p.Service {
+			return false
+		}
+		return sampler.SampleByRate(root.TraceID, p.Rate)
+	default:
+		log.Debugf("Unknown tail sampling policy type %q", p.Type)
+		return false
+	}
+}
+
+// traceDuration returns the duration between the start of the first span and the
+// end of the last one.
+func traceDuration(spans pb.Trace) time.Duration {
+	start, end := spans[0].Start, spans[0].Start+spans[0].Duration
+	for _, span := range spans[1:] {
+		if span.Start < start {
+			start = span.Start
+		}
+		if span.Start+span.Duration > end {
+			end = span.Start + span.Duration
+		}
+	}
+	return time.Duration(end - start)
+}
+
+// tracerPayloadMetadata returns a copy of the tracer payload without its chunks.
+func tracerPayloadMetadata(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.ContainerID,
+		LanguageName:    p.LanguageName,
+		LanguageVersion: p.LanguageVersion,
+		TracerVersion:   p.TracerVersion,
+		RuntimeID:       p.RuntimeID,
+		Tags:            p.Tags,
+		Env:             p.Env,
+		Hostname:        p.Hostname,
+		AppVersion:      p.AppVersion,
+	}
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	size := s.size
+	s.mu.Unlock()
+
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffer_bytes", float64(size), nil, 1)
+	metrics.Count("datadog.trace_agent.tail_sampler.kept", s.kept.Swap(0), nil, 1)
+	metrics.Count("datadog.trace_agent.tail_sampler.dropped", s.dropped.Swap(0), nil, 1)
+	metrics.Count("datadog.trace_agent.tail_sampler.evicted", s.evicted.Swap(0), nil, 1)
+	metrics.Count("datadog.trace_agent.tail_sampler.late_chunks", s.late.Swap(0), nil, 1)
+}
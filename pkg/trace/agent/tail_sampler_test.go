@@ -0,0 +1,272 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+func tailSamplingChunk(traceID uint64, spans ...*pb.Span) *pb.TraceChunk {
+	for _, span := range spans {
+		span.TraceID = traceID
+	}
+	return &pb.TraceChunk{Priority: int32(sampler.PriorityAutoDrop), Spans: spans}
+}
+
+func TestTailSamplingPolicies(t *testing.T) {
+	now := time.Now().UnixNano()
+	root := &pb.Span{SpanID: 1, Service: "web", Start: now, Duration: int64(time.Second)}
+	child := &pb.Span{SpanID: 2, ParentID: 1, Service: "db", Start: now + int64(9*time.Second), Duration: int64(2 * time.Second), Meta: map[string]string{"http.status_code": "503"}, Metrics: map[string]float64{"retries": 3}}
+	failed := &pb.Span{SpanID: 3, ParentID: 1, Service: "db", Start: now, Duration: 10, Error: 1}
+	slowTrace := pb.Trace{root, child}
+	errorTrace := pb.Trace{root, failed}
+
+	for _, tt := range []struct {
+		name   string
+		policy *config.TailSamplingPolicy
+		spans  pb.Trace
+		match  bool
+	}{
+		{"latency", &config.TailSamplingPolicy{Type: config.TailSamplingLatency, ThresholdMs: 10_000}, slowTrace, true},
+		{"latency-fast", &config.TailSamplingPolicy{Type: config.TailSamplingLatency, ThresholdMs: 10_000}, errorTrace, false},
+		{"error", &config.TailSamplingPolicy{Type: config.TailSamplingError}, errorTrace, true},
+		{"no-error", &config.TailSamplingPolicy{Type: config.TailSamplingError}, slowTrace, false},
+		{"attribute-presence", &config.TailSamplingPolicy{Type: config.TailSamplingAttribute, Key: "http.status_code"}, slowTrace, true},
+		{"attribute-pattern", &config.TailSamplingPolicy{Type: config.TailSamplingAttribute, Key: "http.status_code", Re: regexp.MustCompile("^5")}, slowTrace, true},
+		{"attribute-no-match", &config.TailSamplingPolicy{Type: config.TailSamplingAttribute, Key: "http.status_code", Re: regexp.MustCompile("^4")}, slowTrace, false},
+		{"attribute-metric", &config.TailSamplingPolicy{Type: config.TailSamplingAttribute, Key: "retries", Re: regexp.MustCompile("^3$")}, slowTrace, true},
+		{"attribute-missing", &config.TailSamplingPolicy{Type: config.TailSamplingAttribute, Key: "http.status_code"}, errorTrace, false},
+		{"rate-all", &config.TailSamplingPolicy{Type: config.TailSamplingRate, Rate: 1}, slowTrace, true},
+		{"rate-none", &config.TailSamplingPolicy{Type: config.TailSamplingRate, Rate: 0}, slowTrace, false},
+		{"rate-service", &config.TailSamplingPolicy{Type: config.TailSamplingRate, Service: "web", Rate: 1}, slowTrace, true},
+		{"rate-other-service", &config.TailSamplingPolicy{Type: config.TailSamplingRate, Service: "db", Rate: 1}, slowTrace, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, matchTailSamplingPolicy(tt.policy, tt.spans))
+		})
+	}
+}
+
+func TestTailSamplerDecisionWindow(t *testing.T) {
+	out := make(chan *writer.SampledChunks, 10)
+	s := NewTailSampler(config.TailSamplingConfig{
+		DecisionWait:   10 * time.Second,
+		MaxMemoryBytes: 1 << 20,
+		Policies:       []*config.TailSamplingPolicy{{Name: "slow", Type: config.TailSamplingLatency, ThresholdMs: 1000}},
+	}, out)
+
+	now := time.Now()
+	start := now.UnixNano()
+	tracer1 := &pb.TracerPayload{LanguageName: "go", Env: "prod"}
+	tracer2 := &pb.TracerPayload{LanguageName: "python", Env: "prod"}
+
+	// a slow trace made of two fast chunks coming from different tracers
+	s.Add(now, tracer1, tailSamplingChunk(1, &pb.Span{SpanID: 1, Start: start, Duration: int64(100 * time.Millisecond)}))
+	s.Add(now.Add(time.Second), tracer2, tailSamplingChunk(1, &pb.Span{SpanID: 2, ParentID: 1, Start: start + int64(2*time.Second), Duration: int64(100 * time.Millisecond)}))
+	// a fast trace
+	s.Add(now.Add(time.Second), tracer1, tailSamplingChunk(2, &pb.Span{SpanID: 3, Start: start, Duration: int64(100 * time.Millisecond)}))
+
+	s.flush(now.Add(9 * time.Second))
+	assert.Len(t, out, 0)
+
+	s.flush(now.Add(11 * time.Second))
+	require.Len(t, out, 2)
+	byLanguage := map[string]*writer.SampledChunks{}
+	for i := 0; i < 2; i++ {
+		sc := <-out
+		byLanguage[sc.TracerPayload.LanguageName] = sc
+	}
+	for _, lang := range []string{"go", "python"} {
+		sc := byLanguage[lang]
+		require.NotNil(t, sc, lang)
+		assert.Equal(t, "prod", sc.TracerPayload.Env)
+		require.Len(t, sc.TracerPayload.Chunks, 1)
+		chunk := sc.TracerPayload.Chunks[0]
+		assert.Equal(t, uint64(1), chunk.Spans[0].TraceID)
+		assert.Equal(t, int32(sampler.PriorityAutoKeep), chunk.Priority)
+		assert.False(t, chunk.DroppedTrace)
+		assert.Equal(t, "slow", chunk.Tags[tagTailSamplingPolicy])
+		assert.EqualValues(t, 1, sc.SpanCount)
+		assert.Greater(t, sc.Size, 0)
+	}
+
+	// the fast trace was dropped
+	s.flush(now.Add(time.Minute))
+	assert.Len(t, out, 0)
+	assert.Len(t, s.traces, 0)
+	assert.EqualValues(t, 0, s.size)
+}
+
+func TestTailSamplerUserKeep(t *testing.T) {
+	out := make(chan *writer.SampledChunks, 10)
+	s := NewTailSampler(config.TailSamplingConfig{DecisionWait: time.Second, MaxMemoryBytes: 1 << 20}, out)
+
+	now := time.Now()
+	chunk := tailSamplingChunk(1, &pb.Span{SpanID: 1})
+	chunk.Priority = int32(sampler.PriorityUserKeep)
+	s.Add(now, &pb.TracerPayload{}, chunk)
+	s.flush(now.Add(time.Second))
+
+	require.Len(t, out, 1)
+	sc := <-out
+	assert.Equal(t, int32(sampler.PriorityUserKeep), sc.TracerPayload.Chunks[0].Priority)
+	assert.Equal(t, userKeepPolicy, sc.TracerPayload.Chunks[0].Tags[tagTailSamplingPolicy])
+}
+
+func TestTailSamplerUserDrop(t *testing.T) {
+	out := make(chan *writer.SampledChunks, 10)
+	s := NewTailSampler(config.TailSamplingConfig{
+		DecisionWait:      time.Second,
+		MaxMemoryBytes:    1 << 20,
+		DecisionCacheTTL:  time.Minute,
+		DecisionCacheSize: 10,
+		Policies:          []*config.TailSamplingPolicy{{Name: "all", Type: config.TailSamplingRate, Rate: 1}},
+	}, out)
+
+	// a trace dropped by the tracer doesn't match any policy
+	now := time.Now()
+	dropped := tailSamplingChunk(1, &pb.Span{SpanID: 1, Error: 1})
+	dropped.Priority = int32(sampler.PriorityUserDrop)
+	s.Add(now, &pb.TracerPayload{}, dropped)
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(1, &pb.Span{SpanID: 2, ParentID: 1}))
+	s.flush(now.Add(time.Second))
+	assert.Len(t, out, 0)
+	assert.EqualValues(t, 1, s.dropped.Load())
+
+	// the late chunks dropped by the tracer aren't written with their kept trace
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(2, &pb.Span{SpanID: 1}))
+	s.flush(now.Add(time.Second))
+	require.Len(t, out, 1)
+	<-out
+	late := tailSamplingChunk(2, &pb.Span{SpanID: 2, ParentID: 1})
+	late.Priority = int32(sampler.PriorityUserDrop)
+	s.Add(now.Add(2*time.Second), &pb.TracerPayload{}, late)
+	assert.Len(t, out, 0)
+}
+
+func TestTailSamplerMaxMemory(t *testing.T) {
+	out := make(chan *writer.SampledChunks, 10)
+	chunkSize := int64(tailSamplingChunk(1, &pb.Span{SpanID: 1, Error: 1}).Msgsize())
+	s := NewTailSampler(config.TailSamplingConfig{
+		DecisionWait:   time.Hour,
+		MaxMemoryBytes: 2 * chunkSize,
+		Policies:       []*config.TailSamplingPolicy{{Name: "errors", Type: config.TailSamplingError}},
+	}, out)
+
+	now := time.Now()
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(1, &pb.Span{SpanID: 1, Error: 1}))
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(2, &pb.Span{SpanID: 1, Error: 1}))
+	assert.Len(t, out, 0)
+
+	// the oldest trace is decided early to make room for the new one
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(3, &pb.Span{SpanID: 1, Error: 1}))
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(1), (<-out).TracerPayload.Chunks[0].Spans[0].TraceID)
+	assert.EqualValues(t, 1, s.evicted.Load())
+	assert.Equal(t, []uint64{2, 3}, s.order)
+	assert.Equal(t, 2*chunkSize, s.size)
+
+	// stopping makes a decision over all the buffered traces
+	s.Start()
+	s.Stop()
+	assert.Len(t, out, 2)
+	assert.Len(t, s.traces, 0)
+}
+
+func TestTailSamplerLateChunks(t *testing.T) {
+	out := make(chan *writer.SampledChunks, 10)
+	s := NewTailSampler(config.TailSamplingConfig{
+		DecisionWait:      10 * time.Second,
+		MaxMemoryBytes:    1 << 20,
+		DecisionCacheTTL:  time.Minute,
+		DecisionCacheSize: 2,
+		Policies:          []*config.TailSamplingPolicy{{Name: "errors", Type: config.TailSamplingError}},
+	}, out)
+
+	now := time.Now()
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(1, &pb.Span{SpanID: 1, Error: 1}))
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(2, &pb.Span{SpanID: 1}))
+	s.flush(now.Add(10 * time.Second))
+	require.Len(t, out, 1)
+	<-out
+
+	// the chunks received after the decision are kept, or dropped, with their trace
+	s.Add(now.Add(20*time.Second), &pb.TracerPayload{LanguageName: "go"}, tailSamplingChunk(1, &pb.Span{SpanID: 2, ParentID: 1}))
+	s.Add(now.Add(20*time.Second), &pb.TracerPayload{}, tailSamplingChunk(2, &pb.Span{SpanID: 2, ParentID: 1, Error: 1}))
+	require.Len(t, out, 1)
+	sc := <-out
+	assert.Equal(t, "go", sc.TracerPayload.LanguageName)
+	assert.Equal(t, uint64(2), sc.TracerPayload.Chunks[0].Spans[0].SpanID)
+	assert.Equal(t, "errors", sc.TracerPayload.Chunks[0].Tags[tagTailSamplingPolicy])
+	assert.Len(t, s.traces, 0)
+	assert.EqualValues(t, 2, s.late.Load())
+
+	// the decisions expire
+	s.flush(now.Add(80 * time.Second))
+	assert.Len(t, s.decisions, 0)
+	s.Add(now.Add(80*time.Second), &pb.TracerPayload{}, tailSamplingChunk(2, &pb.Span{SpanID: 3, ParentID: 1}))
+	assert.Len(t, s.traces, 1)
+
+	// the number of decisions remembered is bounded
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(3, &pb.Span{SpanID: 1}))
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(4, &pb.Span{SpanID: 1}))
+	s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(5, &pb.Span{SpanID: 1}))
+	s.flush(now.Add(time.Hour))
+	assert.Len(t, s.decisions, 2)
+	assert.Len(t, s.decisionOrder, 2)
+}
+
+func TestProcessWithTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Name: "errors", Type: config.TailSamplingError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	agnt.TailSampler = NewTailSampler(cfg.TailSampling, agnt.TraceWriter.In)
+
+	now := time.Now()
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Name: "request", Resource: "GET /", Start: now.UnixNano(), Duration: 10}
+	chunk := testutil.TraceChunkWithSpan(root)
+	chunk.Priority = int32(sampler.PriorityAutoKeep)
+	agnt.Process(&api.Payload{
+		TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+		Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+	})
+	child := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "worker", Name: "job", Resource: "process", Start: now.UnixNano(), Duration: 10, Error: 1}
+	agnt.Process(&api.Payload{
+		TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(child)),
+		Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+	})
+
+	// nothing is written before the decision, but stats are computed for every chunk
+	assert.Len(t, agnt.TraceWriter.In, 0)
+	assert.Len(t, agnt.Concentrator.In, 2)
+
+	agnt.TailSampler.flush(time.Now().Add(cfg.TailSampling.DecisionWait))
+	require.Len(t, agnt.TraceWriter.In, 2)
+	for i := 0; i < 2; i++ {
+		sc := <-agnt.TraceWriter.In
+		require.Len(t, sc.TracerPayload.Chunks, 1)
+		assert.Equal(t, "errors", sc.TracerPayload.Chunks[0].Tags[tagTailSamplingPolicy])
+	}
+}
@@ -392,6 +392,24 @@ func decodeTracerPayload(v Version, req *http.Request, ts *info.TagStats, cIDPro
 		var tracerPayload pb.TracerPayload
 		_, err = tracerPayload.UnmarshalMsg(buf.Bytes())
 		return &tracerPayload, true, err
+	case vZipkinV2, vJaegerThrift:
+		var chunks []*pb.TraceChunk
+		if v == vZipkinV2 {
+			chunks, err = decodeZipkinRequest(req)
+		} else {
+			chunks, err = decodeJaegerRequest(req)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		runMetaHook(chunks)
+		return &pb.TracerPayload{
+			LanguageName:    ts.Lang,
+			LanguageVersion: ts.LangVersion,
+			ContainerID:     cIDProvider.GetContainerID(req.Context(), req.Header),
+			Chunks:          chunks,
+			TracerVersion:   ts.TracerVersion,
+		}, true, nil
 	default:
 		var traces pb.Traces
 		if ranHook, err = decodeRequest(req, &traces); err != nil {
@@ -412,7 +430,7 @@ func decodeTracerPayload(v Version, req *http.Request, ts *info.TagStats, cIDPro
 // was successful.
 func (r *HTTPReceiver) replyOK(req *http.Request, v Version, w http.ResponseWriter) (n uint64, ok bool) {
 	switch v {
-	case v01, v02, v03:
+	case v01, v02, v03, vZipkinV2, vJaegerThrift:
 		return httpOK(w)
 	default:
 		ratesVersion := req.Header.Get(header.RatesPayloadVersion)
@@ -12,6 +12,7 @@ import (
 	"net/http"
 
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
 )
 
 // makeInfoHandler returns a new handler for handling the discovery endpoint.
@@ -69,6 +70,7 @@ func (r *HTTPReceiver) makeInfoHandler() (hash string, handler http.HandlerFunc)
 		ClientDropP0s    bool          `json:"client_drop_p0s"`
 		SpanMetaStructs  bool          `json:"span_meta_structs"`
 		LongRunningSpans bool          `json:"long_running_spans"`
+		PeerTags         []string      `json:"peer_tags"`
 		Config           reducedConfig `json:"config"`
 	}{
 		Version:          r.conf.AgentVersion,
@@ -78,6 +80,7 @@ func (r *HTTPReceiver) makeInfoHandler() (hash string, handler http.HandlerFunc)
 		ClientDropP0s:    true,
 		SpanMetaStructs:  true,
 		LongRunningSpans: true,
+		PeerTags:         stats.TracerPeerTags(r.conf),
 		Config: reducedConfig{
 			DefaultEnv:             r.conf.DefaultEnv,
 			TargetTPS:              r.conf.TargetTPS,
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
@@ -305,6 +306,7 @@ func TestInfoHandler(t *testing.T) {
 		"client_drop_p0s":    nil,
 		"span_meta_structs":  nil,
 		"long_running_spans": nil,
+		"peer_tags":          nil,
 		"config": map[string]interface{}{
 			"default_env":               nil,
 			"target_tps":                nil,
@@ -345,3 +347,33 @@ func TestInfoHandler(t *testing.T) {
 	}
 	assert.NoError(t, ensureKeys(expectedKeys, m, ""))
 }
+
+func infoPeerTags(t *testing.T, conf *config.AgentConfig) interface{} {
+	rcv := newTestReceiverFromConfig(conf)
+	_, h := rcv.makeInfoHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/info", nil))
+	var m map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&m))
+	return m["peer_tags"]
+}
+
+func TestInfoHandlerPeerTags(t *testing.T) {
+	conf := config.New()
+	assert.Nil(t, infoPeerTags(t, conf))
+
+	// the configured peer tags are advertised along the default ones
+	conf.PeerTagsAggregation = true
+	conf.PeerTags = []string{"zz.custom.peer"}
+	peerTags := infoPeerTags(t, conf).([]interface{})
+	assert.Contains(t, peerTags, "peer.service")
+	assert.Contains(t, peerTags, "zz.custom.peer")
+
+	// the custom tags are advertised once their feature is enabled, since the tracers
+	// send them along the peer tags
+	conf = config.New()
+	conf.StatsCustomTags = []string{"tenant", "region"}
+	assert.Nil(t, infoPeerTags(t, conf))
+	conf.Features = map[string]struct{}{"enable_stats_custom_tags": {}}
+	assert.Equal(t, []interface{}{"region", "tenant"}, infoPeerTags(t, conf))
+}
@@ -0,0 +1,569 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// The types of the jaeger.thrift structures are described in
+// https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift
+
+const (
+	jaegerTagString = 0
+	jaegerTagDouble = 1
+	jaegerTagBool   = 2
+	jaegerTagLong   = 3
+	jaegerTagBinary = 4
+
+	jaegerRefChildOf = 0
+
+	jaegerFlagSampled = 1
+	jaegerFlagDebug   = 2
+)
+
+type jaegerBatch struct {
+	process jaegerProcess
+	spans   []*jaegerSpan
+}
+
+type jaegerProcess struct {
+	serviceName string
+	tags        []jaegerTag
+}
+
+type jaegerSpan struct {
+	traceIDLow    int64
+	traceIDHigh   int64
+	spanID        int64
+	parentSpanID  int64
+	operationName string
+	references    []jaegerSpanRef
+	flags         int32
+	startTime     int64 // epoch microseconds
+	duration      int64 // microseconds
+	tags          []jaegerTag
+	logs          []jaegerLog
+}
+
+type jaegerSpanRef struct {
+	refType     int32
+	traceIDLow  int64
+	traceIDHigh int64
+	spanID      int64
+}
+
+type jaegerTag struct {
+	key     string
+	vType   int32
+	vStr    string
+	vDouble float64
+	vBool   bool
+	vLong   int64
+	vBinary []byte
+}
+
+type jaegerLog struct {
+	timestamp int64 // epoch microseconds
+	fields    []jaegerTag
+}
+
+// decodeJaegerRequest decodes the Jaeger batch of the request, encoded with the
+// Thrift binary protocol, into trace chunks.
+func decodeJaegerRequest(req *http.Request) ([]*pb.TraceChunk, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := io.Copy(buf, req.Body); err != nil {
+		return nil, err
+	}
+	var batch jaegerBatch
+	r := &thriftReader{b: buf.Bytes()}
+	if err := r.readStruct(batch.readField); err != nil {
+		return nil, err
+	}
+
+	var chunks traceChunkBuilder
+	for _, js := range batch.spans {
+		priority := sampler.PriorityAutoKeep
+		switch {
+		case js.flags&jaegerFlagDebug != 0:
+			priority = sampler.PriorityUserKeep
+		case js.flags&jaegerFlagSampled == 0:
+			priority = sampler.PriorityAutoDrop
+		}
+		chunks.add(convertJaegerSpan(&batch.process, js), priority)
+	}
+	return chunks.chunks, nil
+}
+
+// convertJaegerSpan converts the Jaeger span js, reported by process, to a Datadog span.
+func convertJaegerSpan(process *jaegerProcess, js *jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		Service:  process.serviceName,
+		TraceID:  uint64(js.traceIDLow),
+		SpanID:   uint64(js.spanID),
+		ParentID: uint64(js.parentSpanID),
+		Start:    js.startTime * 1000,
+		Duration: js.duration * 1000,
+		Meta:     make(map[string]string, len(process.tags)+len(js.tags)),
+		Metrics:  map[string]float64{},
+	}
+	if span.ParentID == 0 {
+		// newer clients only report the parent in the references
+		for _, ref := range js.references {
+			if ref.traceIDLow == js.traceIDLow && ref.traceIDHigh == js.traceIDHigh {
+				span.ParentID = uint64(ref.spanID)
+				if ref.refType == jaegerRefChildOf {
+					break
+				}
+			}
+		}
+	}
+	if js.traceIDHigh != 0 {
+		span.Meta[tagTraceIDHigh] = fmt.Sprintf("%016x", uint64(js.traceIDHigh))
+	}
+	for _, tag := range process.tags {
+		setJaegerTag(span, tag)
+	}
+	for _, tag := range js.tags {
+		setJaegerTag(span, tag)
+	}
+	kind := jaegerSpanKind(span.Meta["span.kind"])
+	setMetaOTLP(span, "span.kind", spanKindName(kind))
+	if len(js.logs) > 0 {
+		events := make([]spanEvent, 0, len(js.logs))
+		for _, l := range js.logs {
+			e := spanEvent{TimeUnixNano: uint64(l.timestamp) * 1000, Attributes: make(map[string]string, len(l.fields))}
+			for _, f := range l.fields {
+				e.Attributes[f.key] = jaegerTagValue(f)
+			}
+			if name, ok := e.Attributes["event"]; ok {
+				e.Name = name
+				delete(e.Attributes, "event")
+			}
+			if e.Name == "error" && span.Meta["error.msg"] == "" {
+				// error logs, as described in the OpenTracing semantic conventions
+				if _, msg := getFirstFromMap(e.Attributes, "message", "error.object"); msg != "" {
+					span.Meta["error.msg"] = msg
+				}
+				if v := e.Attributes["error.kind"]; v != "" {
+					span.Meta["error.type"] = v
+				}
+				if v := e.Attributes["stack"]; v != "" {
+					span.Meta["error.stack"] = v
+				}
+			}
+			events = append(events, e)
+		}
+		setMetaOTLP(span, "events", marshalSpanEvents(events))
+	}
+	finishSpan(span, kind, "jaeger", js.operationName)
+	return span
+}
+
+// setJaegerTag sets the Jaeger tag on the span, as a metric for numeric values and
+// as a tag otherwise.
+func setJaegerTag(span *pb.Span, tag jaegerTag) {
+	switch tag.vType {
+	case jaegerTagDouble:
+		setMetricOTLP(span, tag.key, tag.vDouble)
+	case jaegerTagLong:
+		setMetricOTLP(span, tag.key, float64(tag.vLong))
+	default:
+		setMetaOTLP(span, tag.key, jaegerTagValue(tag))
+	}
+}
+
+// jaegerTagValue returns the value of the tag as a string.
+func jaegerTagValue(tag jaegerTag) string {
+	switch tag.vType {
+	case jaegerTagDouble:
+		return strconv.FormatFloat(tag.vDouble, 'f', -1, 64)
+	case jaegerTagBool:
+		return strconv.FormatBool(tag.vBool)
+	case jaegerTagLong:
+		return strconv.FormatInt(tag.vLong, 10)
+	case jaegerTagBinary:
+		return base64.StdEncoding.EncodeToString(tag.vBinary)
+	default:
+		return tag.vStr
+	}
+}
+
+// jaegerSpanKind returns the span kind corresponding to the value of the
+// OpenTracing "span.kind" tag.
+func jaegerSpanKind(kind string) ptrace.SpanKind {
+	switch kind {
+	case "client":
+		return ptrace.SpanKindClient
+	case "server":
+		return ptrace.SpanKindServer
+	case "producer":
+		return ptrace.SpanKindProducer
+	case "consumer":
+		return ptrace.SpanKindConsumer
+	default:
+		return ptrace.SpanKindInternal
+	}
+}
+
+func (b *jaegerBatch) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftStruct:
+		return r.readStruct(b.process.readField)
+	case id == 2 && typ == thriftList:
+		return r.readList(thriftStruct, func() error {
+			s := &jaegerSpan{}
+			if err := r.readStruct(s.readField); err != nil {
+				return err
+			}
+			b.spans = append(b.spans, s)
+			return nil
+		})
+	default:
+		return r.skip(typ)
+	}
+}
+
+func (p *jaegerProcess) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftString:
+		p.serviceName, err = r.readString()
+	case id == 2 && typ == thriftList:
+		p.tags, err = readJaegerTags(r)
+	default:
+		err = r.skip(typ)
+	}
+	return err
+}
+
+func (s *jaegerSpan) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftI64:
+		s.traceIDLow, err = r.readI64()
+	case id == 2 && typ == thriftI64:
+		s.traceIDHigh, err = r.readI64()
+	case id == 3 && typ == thriftI64:
+		s.spanID, err = r.readI64()
+	case id == 4 && typ == thriftI64:
+		s.parentSpanID, err = r.readI64()
+	case id == 5 && typ == thriftString:
+		s.operationName, err = r.readString()
+	case id == 6 && typ == thriftList:
+		err = r.readList(thriftStruct, func() error {
+			var ref jaegerSpanRef
+			if err := r.readStruct(ref.readField); err != nil {
+				return err
+			}
+			s.references = append(s.references, ref)
+			return nil
+		})
+	case id == 7 && typ == thriftI32:
+		s.flags, err = r.readI32()
+	case id == 8 && typ == thriftI64:
+		s.startTime, err = r.readI64()
+	case id == 9 && typ == thriftI64:
+		s.duration, err = r.readI64()
+	case id == 10 && typ == thriftList:
+		s.tags, err = readJaegerTags(r)
+	case id == 11 && typ == thriftList:
+		err = r.readList(thriftStruct, func() error {
+			var l jaegerLog
+			if err := r.readStruct(l.readField); err != nil {
+				return err
+			}
+			s.logs = append(s.logs, l)
+			return nil
+		})
+	default:
+		err = r.skip(typ)
+	}
+	return err
+}
+
+func (ref *jaegerSpanRef) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftI32:
+		ref.refType, err = r.readI32()
+	case id == 2 && typ == thriftI64:
+		ref.traceIDLow, err = r.readI64()
+	case id == 3 && typ == thriftI64:
+		ref.traceIDHigh, err = r.readI64()
+	case id == 4 && typ == thriftI64:
+		ref.spanID, err = r.readI64()
+	default:
+		err = r.skip(typ)
+	}
+	return err
+}
+
+func (l *jaegerLog) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftI64:
+		l.timestamp, err = r.readI64()
+	case id == 2 && typ == thriftList:
+		l.fields, err = readJaegerTags(r)
+	default:
+		err = r.skip(typ)
+	}
+	return err
+}
+
+func readJaegerTags(r *thriftReader) ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var tag jaegerTag
+		if err := r.readStruct(tag.readField); err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	return tags, err
+}
+
+func (t *jaegerTag) readField(r *thriftReader, id int16, typ byte) (err error) {
+	switch {
+	case id == 1 && typ == thriftString:
+		t.key, err = r.readString()
+	case id == 2 && typ == thriftI32:
+		t.vType, err = r.readI32()
+	case id == 3 && typ == thriftString:
+		t.vStr, err = r.readString()
+	case id == 4 && typ == thriftDouble:
+		t.vDouble, err = r.readDouble()
+	case id == 5 && typ == thriftBool:
+		var b byte
+		b, err = r.readByte()
+		t.vBool = b != 0
+	case id == 6 && typ == thriftI64:
+		t.vLong, err = r.readI64()
+	case id == 7 && typ == thriftString:
+		t.vBinary, err = r.readBinary()
+	default:
+		err = r.skip(typ)
+	}
+	return err
+}
+
+// Thrift types, as encoded by the binary protocol.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+
+	// thriftMaxDepth bounds the nesting of the skipped values.
+	thriftMaxDepth = 64
+)
+
+var errThriftShortBuffer = errors.New("thrift: unexpected end of payload")
+
+// thriftReader reads values encoded with the Thrift binary protocol.
+type thriftReader struct {
+	b     []byte
+	depth int
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errThriftShortBuffer
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.next(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (r *thriftReader) readString() (string, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.next(int(n))
+	return string(b), err
+}
+
+// readStruct reads a struct, calling fn with the ID and the type of each of its
+// fields. fn must read or skip the value of the field.
+func (r *thriftReader) readStruct(fn func(r *thriftReader, id int16, typ byte) error) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := fn(r, id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList reads a list of elements of type typ, calling fn to read each of them.
+// Lists of other types are skipped.
+func (r *thriftReader) readList(typ byte, fn func() error) error {
+	elemType, n, err := r.readListHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if elemType != typ {
+			err = r.skip(elemType)
+		} else {
+			err = fn()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *thriftReader) readListHeader() (elemType byte, n int, err error) {
+	if elemType, err = r.readByte(); err != nil {
+		return 0, 0, err
+	}
+	size, err := r.readI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	// every element takes at least one byte
+	if size < 0 || int(size) > len(r.b) {
+		return 0, 0, errThriftShortBuffer
+	}
+	return elemType, int(size), nil
+}
+
+// skip skips a value of type typ.
+func (r *thriftReader) skip(typ byte) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > thriftMaxDepth {
+		return fmt.Errorf("thrift: maximum depth of %d exceeded", thriftMaxDepth)
+	}
+
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		var n int32
+		if n, err = r.readI32(); err == nil {
+			_, err = r.next(int(n))
+		}
+	case thriftStruct:
+		err = r.readStruct(func(r *thriftReader, _ int16, typ byte) error { return r.skip(typ) })
+	case thriftMap:
+		var keyType, valueType byte
+		if keyType, err = r.readByte(); err != nil {
+			return err
+		}
+		var n int
+		if valueType, n, err = r.readListHeader(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			if err = r.skip(keyType); err == nil {
+				err = r.skip(valueType)
+			}
+		}
+	case thriftSet, thriftList:
+		var elemType byte
+		var n int
+		if elemType, n, err = r.readListHeader(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skip(elemType)
+		}
+	default:
+		err = fmt.Errorf("thrift: unknown type %d", typ)
+	}
+	return err
+}
@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// thriftWriter writes values with the Thrift binary protocol.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(thriftString, id)
+	binary.Write(w, binary.BigEndian, int32(len(v))) //nolint:errcheck
+	w.WriteString(v)
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, n int) {
+	w.field(thriftList, id)
+	w.WriteByte(elemType)
+	binary.Write(w, binary.BigEndian, int32(n)) //nolint:errcheck
+}
+
+func (w *thriftWriter) tag(key string, vType int32, value interface{}) {
+	w.str(1, key)
+	w.i32(2, vType)
+	switch v := value.(type) {
+	case string:
+		w.str(3, v)
+	case float64:
+		w.field(thriftDouble, 4)
+		binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+	case bool:
+		w.field(thriftBool, 5)
+		if v {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case int64:
+		w.i64(6, v)
+	case []byte:
+		w.str(7, string(v))
+	}
+	w.stop()
+}
+
+// jaegerTestBatch returns a batch made of a server span with a child, and of a
+// client span of another trace.
+func jaegerTestBatch() []byte {
+	var w thriftWriter
+	// process
+	w.field(thriftStruct, 1)
+	w.str(1, "driver")
+	w.list(2, thriftStruct, 2)
+	w.tag("hostname", jaegerTagString, "node-1")
+	w.tag("deployment.environment", jaegerTagString, "Staging")
+	w.stop()
+
+	w.list(2, thriftStruct, 3)
+
+	// server span
+	w.i64(1, 0x6b4b8d3c2a1e0f9d)
+	w.i64(2, 0x5af7183fb1d4cf5f)
+	w.i64(3, 10)
+	w.i64(4, 0)
+	w.str(5, "HTTP GET /driver")
+	w.i32(7, jaegerFlagSampled)
+	w.i64(8, 1556604172355737)
+	w.i64(9, 1431)
+	w.list(10, thriftStruct, 5)
+	w.tag("span.kind", jaegerTagString, "server")
+	w.tag("http.status_code", jaegerTagLong, int64(500))
+	w.tag("error", jaegerTagBool, true)
+	w.tag("load", jaegerTagDouble, 0.5)
+	w.tag("payload", jaegerTagBinary, []byte("raw"))
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1556604172355800)
+	w.list(2, thriftStruct, 3)
+	w.tag("event", jaegerTagString, "error")
+	w.tag("message", jaegerTagString, "no driver available")
+	w.tag("error.kind", jaegerTagString, "NotFound")
+	w.stop()
+	w.field(thriftI16, 99) // unknown fields are skipped
+	w.WriteString("\x00\x01")
+	w.stop()
+
+	// child span, with its parent in the references only
+	w.i64(1, 0x6b4b8d3c2a1e0f9d)
+	w.i64(2, 0x5af7183fb1d4cf5f)
+	w.i64(3, 11)
+	w.i64(4, 0)
+	w.str(5, "FindDriverIDs")
+	w.list(6, thriftStruct, 1)
+	w.i32(1, jaegerRefChildOf)
+	w.i64(2, 0x6b4b8d3c2a1e0f9d)
+	w.i64(3, 0x5af7183fb1d4cf5f)
+	w.i64(4, 10)
+	w.stop()
+	w.i32(7, jaegerFlagSampled)
+	w.i64(8, 1556604172355800)
+	w.i64(9, 100)
+	w.list(10, thriftStruct, 2)
+	w.tag("span.kind", jaegerTagString, "client")
+	w.tag("db.system", jaegerTagString, "redis")
+	w.stop()
+
+	// debug span of another trace
+	w.i64(1, 1)
+	w.i64(2, 0)
+	w.i64(3, 2)
+	w.i64(4, 0)
+	w.str(5, "publish")
+	w.i32(7, jaegerFlagSampled|jaegerFlagDebug)
+	w.i64(8, 1556604172355800)
+	w.i64(9, 100)
+	w.list(10, thriftStruct, 1)
+	w.tag("span.kind", jaegerTagString, "producer")
+	w.stop()
+
+	w.i64(3, 42) // seqNo
+	w.stop()
+	return w.Bytes()
+}
+
+func TestDecodeJaegerThrift(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(jaegerTestBatch()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+
+	chunks, err := decodeJaegerRequest(req)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, int32(sampler.PriorityAutoKeep), chunks[0].Priority)
+	require.Len(t, chunks[0].Spans, 2)
+	server, child := chunks[0].Spans[0], chunks[0].Spans[1]
+	assert.Equal(t, uint64(0x6b4b8d3c2a1e0f9d), server.TraceID)
+	assert.Equal(t, "5af7183fb1d4cf5f", server.Meta[tagTraceIDHigh])
+	assert.Equal(t, uint64(10), server.SpanID)
+	assert.Equal(t, uint64(0), server.ParentID)
+	assert.Equal(t, int64(1556604172355737000), server.Start)
+	assert.Equal(t, int64(1431000), server.Duration)
+	assert.Equal(t, "driver", server.Service)
+	assert.Equal(t, "jaeger.server", server.Name)
+	assert.Equal(t, "HTTP GET /driver", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.Equal(t, "server", server.Meta["span.kind"])
+	assert.Equal(t, "node-1", server.Meta["hostname"])
+	assert.Equal(t, "staging", server.Meta["env"])
+	assert.Equal(t, float64(500), server.Metrics["http.status_code"])
+	assert.Equal(t, 0.5, server.Metrics["load"])
+	assert.Equal(t, "cmF3", server.Meta["payload"])
+	assert.Equal(t, int32(1), server.Error)
+	assert.NotContains(t, server.Meta, "error")
+	assert.Equal(t, "no driver available", server.Meta["error.msg"])
+	assert.Equal(t, "NotFound", server.Meta["error.type"])
+	assert.JSONEq(t, `[{"time_unix_nano":1556604172355800000,"name":"error","attributes":{"message":"no driver available","error.kind":"NotFound"}}]`, server.Meta["events"])
+
+	assert.Equal(t, server.SpanID, child.ParentID)
+	assert.Equal(t, "jaeger.client", child.Name)
+	assert.Equal(t, "FindDriverIDs", child.Resource)
+	assert.Equal(t, "cache", child.Type)
+	assert.Equal(t, int32(0), child.Error)
+
+	assert.Equal(t, int32(sampler.PriorityUserKeep), chunks[1].Priority)
+	require.Len(t, chunks[1].Spans, 1)
+	assert.Equal(t, uint64(1), chunks[1].Spans[0].TraceID)
+	assert.NotContains(t, chunks[1].Spans[0].Meta, tagTraceIDHigh)
+	assert.Equal(t, "jaeger.producer", chunks[1].Spans[0].Name)
+	assert.Equal(t, "custom", chunks[1].Spans[0].Type)
+}
+
+func TestDecodeJaegerThriftInvalid(t *testing.T) {
+	batch := jaegerTestBatch()
+	for name, payload := range map[string][]byte{
+		"truncated":    batch[:len(batch)/2],
+		"empty":        {},
+		"unknown-type": {42, 0, 1},
+		"huge-list":    {thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+		"huge-string":  {thriftString, 0, 9, 0x7f, 0xff, 0xff, 0xff},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(payload))
+			require.NoError(t, err)
+			_, err = decodeJaegerRequest(req)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("depth", func(t *testing.T) {
+		var w thriftWriter
+		for i := 0; i < thriftMaxDepth+1; i++ {
+			w.field(thriftStruct, 9)
+		}
+		req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(w.Bytes()))
+		require.NoError(t, err)
+		_, err = decodeJaegerRequest(req)
+		assert.ErrorContains(t, err, "maximum depth")
+	})
+}
+
+func TestReceiverJaegerEndpoint(t *testing.T) {
+	conf := newTestReceiverConfig()
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.handleWithVersion(vJaegerThrift, r.handleTraces))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/x-thrift", bytes.NewReader(jaegerTestBatch()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		require.Len(t, p.TracerPayload.Chunks, 2)
+		assert.Equal(t, string(vJaegerThrift), p.Source.EndpointVersion)
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+}
@@ -118,4 +118,24 @@ const (
 	// Response: Service sampling rates (see description in v04).
 	//
 	V07 Version = "v0.7"
+
+	// vZipkinV2 API
+	//
+	// Request: Zipkin v2 spans (https://zipkin.io/zipkin-api/#/default/post_spans).
+	// 	Content-Type: application/json or application/x-protobuf
+	// 	Payload: A list of spans (ListOfSpans in zipkin.proto when encoded with protobuf).
+	//
+	// Response: OK.
+	//
+	vZipkinV2 Version = "zipkin_v2"
+
+	// vJaegerThrift API
+	//
+	// Request: Jaeger spans, as sent to the Jaeger collector over HTTP.
+	// 	Content-Type: application/x-thrift or application/vnd.apache.thrift.binary
+	// 	Payload: A Batch (jaeger.thrift) encoded with the Thrift binary protocol.
+	//
+	// Response: OK.
+	//
+	vJaegerThrift Version = "jaeger_thrift"
 )
@@ -0,0 +1,458 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+const (
+	// zipkinNoServiceName is the service of the Zipkin spans without a local endpoint service name.
+	zipkinNoServiceName = "ZipkinNoServiceName"
+
+	// tagTraceIDHigh holds the hex encoded upper 64 bits of 128-bit trace IDs, the
+	// lower 64 bits being the span's TraceID.
+	tagTraceIDHigh = "_dd.p.tid"
+)
+
+// zipkinSpan is a Zipkin v2 span, as described in https://zipkin.io/zipkin-api/zipkin2-api.yaml.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind"`
+	Name           string             `json:"name"`
+	Timestamp      uint64             `json:"timestamp"` // epoch microseconds
+	Duration       uint64             `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+	Debug          bool               `json:"debug"`
+	Shared         bool               `json:"shared"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int32  `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"` // epoch microseconds
+	Value     string `json:"value"`
+}
+
+// decodeZipkinRequest decodes the Zipkin v2 spans of the request, encoded in
+// JSON or protobuf, into trace chunks.
+func decodeZipkinRequest(req *http.Request) ([]*pb.TraceChunk, error) {
+	var spans []*zipkinSpan
+	switch getMediaType(req) {
+	case "application/x-protobuf", "application/protobuf":
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if _, err := io.Copy(buf, req.Body); err != nil {
+			return nil, err
+		}
+		var err error
+		if spans, err = decodeZipkinProto(buf.Bytes()); err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.NewDecoder(req.Body).Decode(&spans); err != nil {
+			return nil, err
+		}
+	}
+
+	converted := make([]*pb.Span, len(spans))
+	// sharedSpans holds the service of the shared spans, by the ID they share with the client span
+	sharedSpans := make(map[sharedSpanKey]string)
+	for i, zs := range spans {
+		if zs == nil {
+			continue
+		}
+		span, err := convertZipkinSpan(zs)
+		if err != nil {
+			return nil, err
+		}
+		if zs.Shared {
+			sharedSpans[sharedSpanKey{span.TraceID, span.ParentID}] = span.Service
+		}
+		converted[i] = span
+	}
+
+	var chunks traceChunkBuilder
+	for i, zs := range spans {
+		span := converted[i]
+		if span == nil {
+			continue
+		}
+		// the children of a shared span reported along with it by the same service point
+		// to the shared ID, they are re-parented to the ID given to the server span
+		if service, ok := sharedSpans[sharedSpanKey{span.TraceID, span.ParentID}]; ok && !zs.Shared && span.Service == service {
+			span.ParentID = sharedSpanID(span.ParentID)
+		}
+		priority := sampler.PriorityAutoKeep
+		if zs.Debug {
+			priority = sampler.PriorityUserKeep
+		}
+		chunks.add(span, priority)
+	}
+	return chunks.chunks, nil
+}
+
+// sharedSpanKey identifies the ID shared by a client span and a server span within a trace.
+type sharedSpanKey struct {
+	traceID uint64
+	spanID  uint64
+}
+
+// sharedSpanID returns the ID given to a shared span, from the ID it shares with the client span.
+func sharedSpanID(id uint64) uint64 {
+	return id * knuthFactor
+}
+
+// convertZipkinSpan converts the Zipkin span zs to a Datadog span.
+func convertZipkinSpan(zs *zipkinSpan) (*pb.Span, error) {
+	traceID, traceIDHigh, err := parseTraceIDHex(zs.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid traceId %q: %v", zs.TraceID, err)
+	}
+	spanID, err := strconv.ParseUint(zs.ID, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q: %v", zs.ID, err)
+	}
+	var parentID uint64
+	if zs.ParentID != "" {
+		if parentID, err = strconv.ParseUint(zs.ParentID, 16, 64); err != nil {
+			return nil, fmt.Errorf("invalid parentId %q: %v", zs.ParentID, err)
+		}
+	}
+	span := &pb.Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    int64(zs.Timestamp) * 1000,
+		Duration: int64(zs.Duration) * 1000,
+		Meta:     make(map[string]string, len(zs.Tags)+2),
+		Metrics:  map[string]float64{},
+	}
+	if zs.Shared {
+		// a shared span is the server side of an RPC, which reuses the ID of the client
+		// span: it gets an ID of its own, as a child of the client span.
+		span.ParentID = span.SpanID
+		span.SpanID = sharedSpanID(span.SpanID)
+	}
+	if traceIDHigh != 0 {
+		span.Meta[tagTraceIDHigh] = fmt.Sprintf("%016x", traceIDHigh)
+	}
+	kind := zipkinSpanKind(zs.Kind)
+	setMetaOTLP(span, "span.kind", spanKindName(kind))
+	if ep := zs.LocalEndpoint; ep != nil {
+		span.Service = ep.ServiceName
+	}
+	if ep := zs.RemoteEndpoint; ep != nil {
+		if ep.ServiceName != "" {
+			setMetaOTLP(span, "peer.service", ep.ServiceName)
+		}
+		if ep.IPv4 != "" {
+			setMetaOTLP(span, "peer.ipv4", ep.IPv4)
+		}
+		if ep.IPv6 != "" {
+			setMetaOTLP(span, "peer.ipv6", ep.IPv6)
+		}
+		if ep.Port != 0 {
+			setMetricOTLP(span, "peer.port", float64(ep.Port))
+		}
+	}
+	if len(zs.Annotations) > 0 {
+		events := make([]spanEvent, 0, len(zs.Annotations))
+		for _, a := range zs.Annotations {
+			events = append(events, spanEvent{TimeUnixNano: a.Timestamp * 1000, Name: a.Value})
+		}
+		setMetaOTLP(span, "events", marshalSpanEvents(events))
+	}
+	for k, v := range zs.Tags {
+		setMetaOTLP(span, k, v)
+	}
+	finishSpan(span, kind, "zipkin", zs.Name)
+	if span.Service == "" {
+		span.Service = zipkinNoServiceName
+	}
+	return span, nil
+}
+
+// zipkinSpanKind returns the span kind corresponding to the Zipkin kind. Zipkin
+// spans without a kind are local spans.
+func zipkinSpanKind(kind string) ptrace.SpanKind {
+	switch strings.ToUpper(kind) {
+	case "CLIENT":
+		return ptrace.SpanKindClient
+	case "SERVER":
+		return ptrace.SpanKindServer
+	case "PRODUCER":
+		return ptrace.SpanKindProducer
+	case "CONSUMER":
+		return ptrace.SpanKindConsumer
+	default:
+		return ptrace.SpanKindInternal
+	}
+}
+
+// zipkinProtoSpanKinds maps the Kind enum of zipkin.proto to the kinds of the JSON format.
+var zipkinProtoSpanKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+// decodeZipkinProto decodes a ListOfSpans message of zipkin.proto
+// (https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto).
+func decodeZipkinProto(b []byte) ([]*zipkinSpan, error) {
+	var spans []*zipkinSpan
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		span := &zipkinSpan{}
+		if err := rangeProtoFields(protoBytes(v), span.decodeProtoField); err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, err
+}
+
+func (s *zipkinSpan) decodeProtoField(num protowire.Number, typ protowire.Type, v []byte) error {
+	switch {
+	case num == 1 && typ == protowire.BytesType:
+		s.TraceID = hex.EncodeToString(protoBytes(v))
+	case num == 2 && typ == protowire.BytesType:
+		s.ParentID = hex.EncodeToString(protoBytes(v))
+	case num == 3 && typ == protowire.BytesType:
+		s.ID = hex.EncodeToString(protoBytes(v))
+	case num == 4 && typ == protowire.VarintType:
+		s.Kind = zipkinProtoSpanKinds[protoVarint(v)]
+	case num == 5 && typ == protowire.BytesType:
+		s.Name = string(protoBytes(v))
+	case num == 6 && typ == protowire.Fixed64Type:
+		s.Timestamp, _ = protowire.ConsumeFixed64(v)
+	case num == 7 && typ == protowire.VarintType:
+		s.Duration = protoVarint(v)
+	case (num == 8 || num == 9) && typ == protowire.BytesType:
+		ep := &zipkinEndpoint{}
+		if err := rangeProtoFields(protoBytes(v), ep.decodeProtoField); err != nil {
+			return err
+		}
+		if num == 8 {
+			s.LocalEndpoint = ep
+		} else {
+			s.RemoteEndpoint = ep
+		}
+	case num == 10 && typ == protowire.BytesType:
+		var a zipkinAnnotation
+		err := rangeProtoFields(protoBytes(v), func(num protowire.Number, typ protowire.Type, v []byte) error {
+			switch {
+			case num == 1 && typ == protowire.Fixed64Type:
+				a.Timestamp, _ = protowire.ConsumeFixed64(v)
+			case num == 2 && typ == protowire.BytesType:
+				a.Value = string(protoBytes(v))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		s.Annotations = append(s.Annotations, a)
+	case num == 11 && typ == protowire.BytesType:
+		// map entries are messages with the key in field 1 and the value in field 2
+		var key, value string
+		err := rangeProtoFields(protoBytes(v), func(num protowire.Number, typ protowire.Type, v []byte) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			switch num {
+			case 1:
+				key = string(protoBytes(v))
+			case 2:
+				value = string(protoBytes(v))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if s.Tags == nil {
+			s.Tags = make(map[string]string)
+		}
+		s.Tags[key] = value
+	case num == 12 && typ == protowire.VarintType:
+		s.Debug = protoVarint(v) != 0
+	case num == 13 && typ == protowire.VarintType:
+		s.Shared = protoVarint(v) != 0
+	}
+	return nil
+}
+
+func (ep *zipkinEndpoint) decodeProtoField(num protowire.Number, typ protowire.Type, v []byte) error {
+	switch {
+	case num == 1 && typ == protowire.BytesType:
+		ep.ServiceName = string(protoBytes(v))
+	case num == 2 && typ == protowire.BytesType:
+		if ip := protoBytes(v); len(ip) == net.IPv4len {
+			ep.IPv4 = net.IP(ip).String()
+		}
+	case num == 3 && typ == protowire.BytesType:
+		if ip := protoBytes(v); len(ip) == net.IPv6len {
+			ep.IPv6 = net.IP(ip).String()
+		}
+	case num == 4 && typ == protowire.VarintType:
+		ep.Port = int32(protoVarint(v))
+	}
+	return nil
+}
+
+// rangeProtoFields calls fn with the number, the wire type and the encoded value
+// of each field of the protobuf message b.
+func rangeProtoFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, typ, b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// protoBytes returns the content of the length-delimited value v, validated by rangeProtoFields.
+func protoBytes(v []byte) []byte {
+	b, _ := protowire.ConsumeBytes(v)
+	return b
+}
+
+// protoVarint returns the varint value v, validated by rangeProtoFields.
+func protoVarint(v []byte) uint64 {
+	n, _ := protowire.ConsumeVarint(v)
+	return n
+}
+
+// parseTraceIDHex parses a 64 or 128-bit hex encoded trace ID, returning its lower
+// and upper 64 bits.
+func parseTraceIDHex(s string) (low, high uint64, err error) {
+	if len(s) > 32 {
+		return 0, 0, fmt.Errorf("trace ID longer than 128 bits")
+	}
+	if len(s) > 16 {
+		if high, err = strconv.ParseUint(s[:len(s)-16], 16, 64); err != nil {
+			return 0, 0, err
+		}
+		s = s[len(s)-16:]
+	}
+	low, err = strconv.ParseUint(s, 16, 64)
+	return low, high, err
+}
+
+// finishSpan completes a span converted from the Zipkin or Jaeger format, once its
+// tags are set: it applies the error tag, and sets the name, the resource and the
+// type of the span when no tag overrode them. The name is made of the prefix and
+// of the span kind, the operation name becoming the resource of the span.
+func finishSpan(span *pb.Span, kind ptrace.SpanKind, prefix, operationName string) {
+	if v, ok := span.Meta["error"]; ok {
+		delete(span.Meta, "error")
+		if v != "false" {
+			span.Error = 1
+			if _, ok := span.Meta["error.msg"]; !ok && v != "" && v != "true" {
+				span.Meta["error.msg"] = v
+			}
+		}
+	}
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta[string(semconv.AttributeDeploymentEnvironment)]; env != "" {
+			setMetaOTLP(span, "env", traceutil.NormalizeTag(env))
+		}
+	}
+	if span.Name == "" {
+		span.Name = prefix + "." + spanKindName(kind)
+	}
+	if span.Resource == "" {
+		if r := resourceFromTags(span.Meta); r != "" {
+			span.Resource = r
+		} else {
+			span.Resource = operationName
+		}
+	}
+	if span.Type == "" {
+		span.Type = spanKind2Type(kind, span)
+	}
+}
+
+// spanEvent is a span event, encoded in the "events" tag as in marshalEvents.
+type spanEvent struct {
+	TimeUnixNano uint64            `json:"time_unix_nano,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+func marshalSpanEvents(events []spanEvent) string {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// traceChunkBuilder groups spans into trace chunks by trace ID.
+type traceChunkBuilder struct {
+	byID   map[uint64]*pb.TraceChunk
+	chunks []*pb.TraceChunk
+}
+
+// add adds the span to the chunk of its trace. The chunk gets the highest of the
+// priorities of its spans, a sampling priority set on a span taking precedence.
+func (b *traceChunkBuilder) add(span *pb.Span, priority sampler.SamplingPriority) {
+	if p, ok := span.Metrics["_sampling_priority_v1"]; ok {
+		priority = sampler.SamplingPriority(p)
+	}
+	chunk, ok := b.byID[span.TraceID]
+	if !ok {
+		if b.byID == nil {
+			b.byID = make(map[uint64]*pb.TraceChunk)
+		}
+		chunk = &pb.TraceChunk{Priority: int32(priority)}
+		b.byID[span.TraceID] = chunk
+		b.chunks = append(b.chunks, chunk)
+	} else if int32(priority) > chunk.Priority {
+		chunk.Priority = int32(priority)
+	}
+	chunk.Spans = append(chunk.Spans, span)
+}
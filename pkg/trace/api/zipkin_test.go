@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const zipkinJSONPayload = `[
+  {
+    "traceId": "5af7183fb1d4cf5f6b4b8d3c2a1e0f9d",
+    "id": "352bff9a74ca9ad2",
+    "kind": "SERVER",
+    "name": "get /api",
+    "timestamp": 1556604172355737,
+    "duration": 1431,
+    "localEndpoint": {"serviceName": "backend", "ipv4": "192.168.99.1", "port": 3306},
+    "remoteEndpoint": {"serviceName": "frontend", "ipv4": "172.19.0.2", "port": 58648},
+    "annotations": [{"timestamp": 1556604172355800, "value": "wr"}],
+    "tags": {"http.method": "GET", "http.route": "/api", "error": "connection reset", "deployment.environment": "Prod"}
+  },
+  {
+    "traceId": "5af7183fb1d4cf5f6b4b8d3c2a1e0f9d",
+    "parentId": "352bff9a74ca9ad2",
+    "id": "5a5d7c1e8f9b0a11",
+    "name": "compute",
+    "timestamp": 1556604172355900,
+    "duration": 200,
+    "localEndpoint": {"serviceName": "backend"}
+  },
+  {
+    "traceId": "0000000000000001",
+    "id": "0000000000000002",
+    "kind": "CLIENT",
+    "name": "select",
+    "debug": true,
+    "tags": {"db.system": "mysql"}
+  }
+]`
+
+func TestDecodeZipkinJSON(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(zipkinJSONPayload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	chunks, err := decodeZipkinRequest(req)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, int32(sampler.PriorityAutoKeep), chunks[0].Priority)
+	require.Len(t, chunks[0].Spans, 2)
+	server, local := chunks[0].Spans[0], chunks[0].Spans[1]
+	assert.Equal(t, uint64(0x6b4b8d3c2a1e0f9d), server.TraceID)
+	assert.Equal(t, "5af7183fb1d4cf5f", server.Meta[tagTraceIDHigh])
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), server.SpanID)
+	assert.Equal(t, uint64(0), server.ParentID)
+	assert.Equal(t, int64(1556604172355737000), server.Start)
+	assert.Equal(t, int64(1431000), server.Duration)
+	assert.Equal(t, "backend", server.Service)
+	assert.Equal(t, "zipkin.server", server.Name)
+	assert.Equal(t, "GET /api", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.Equal(t, "server", server.Meta["span.kind"])
+	assert.Equal(t, "frontend", server.Meta["peer.service"])
+	assert.Equal(t, "172.19.0.2", server.Meta["peer.ipv4"])
+	assert.Equal(t, float64(58648), server.Metrics["peer.port"])
+	assert.Equal(t, "prod", server.Meta["env"])
+	assert.Equal(t, int32(1), server.Error)
+	assert.Equal(t, "connection reset", server.Meta["error.msg"])
+	assert.NotContains(t, server.Meta, "error")
+	assert.Equal(t, `[{"time_unix_nano":1556604172355800000,"name":"wr"}]`, server.Meta["events"])
+
+	assert.Equal(t, server.TraceID, local.TraceID)
+	assert.Equal(t, server.SpanID, local.ParentID)
+	assert.Equal(t, "zipkin.internal", local.Name)
+	assert.Equal(t, "compute", local.Resource)
+	assert.Equal(t, "custom", local.Type)
+	assert.Equal(t, int32(0), local.Error)
+
+	assert.Equal(t, int32(sampler.PriorityUserKeep), chunks[1].Priority)
+	require.Len(t, chunks[1].Spans, 1)
+	client := chunks[1].Spans[0]
+	assert.Equal(t, uint64(1), client.TraceID)
+	assert.NotContains(t, client.Meta, tagTraceIDHigh)
+	assert.Equal(t, zipkinNoServiceName, client.Service)
+	assert.Equal(t, "db", client.Type)
+	assert.Equal(t, "select", client.Resource)
+}
+
+func TestDecodeZipkinSharedSpan(t *testing.T) {
+	span, err := convertZipkinSpan(&zipkinSpan{TraceID: "1", ID: "2", ParentID: "1", Kind: "SERVER", Shared: true})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), span.ParentID)
+	assert.NotEqual(t, uint64(2), span.SpanID)
+}
+
+func TestDecodeZipkinSharedSpanChildren(t *testing.T) {
+	payload := `[
+  {"traceId": "1", "id": "2", "parentId": "1", "kind": "CLIENT", "name": "call", "localEndpoint": {"serviceName": "frontend"}},
+  {"traceId": "1", "id": "2", "parentId": "1", "kind": "SERVER", "name": "handle", "shared": true, "localEndpoint": {"serviceName": "backend"}},
+  {"traceId": "1", "id": "3", "parentId": "2", "name": "compute", "localEndpoint": {"serviceName": "backend"}},
+  {"traceId": "1", "id": "4", "parentId": "2", "name": "retry", "localEndpoint": {"serviceName": "frontend"}}
+]`
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	chunks, err := decodeZipkinRequest(req)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	require.Len(t, chunks[0].Spans, 4)
+	client, server, serverChild, clientChild := chunks[0].Spans[0], chunks[0].Spans[1], chunks[0].Spans[2], chunks[0].Spans[3]
+
+	assert.Equal(t, uint64(2), client.SpanID)
+	assert.Equal(t, client.SpanID, server.ParentID)
+	assert.NotEqual(t, client.SpanID, server.SpanID)
+	// the children of the server span are re-parented to its new ID
+	assert.Equal(t, server.SpanID, serverChild.ParentID)
+	// the children of the client span keep their parent
+	assert.Equal(t, client.SpanID, clientChild.ParentID)
+}
+
+func TestDecodeZipkinInvalid(t *testing.T) {
+	for name, payload := range map[string]string{
+		"json":      `[{"traceId": `,
+		"trace-id":  `[{"traceId": "xyz", "id": "1"}]`,
+		"long-id":   `[{"traceId": "5af7183fb1d4cf5f6b4b8d3c2a1e0f9d5af7", "id": "1"}]`,
+		"span-id":   `[{"traceId": "1", "id": ""}]`,
+		"parent-id": `[{"traceId": "1", "id": "1", "parentId": "-"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(payload))
+			require.NoError(t, err)
+			_, err = decodeZipkinRequest(req)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendProtoVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func TestDecodeZipkinProto(t *testing.T) {
+	var local, remote, annotation, tag, span []byte
+	local = appendProtoBytes(local, 1, []byte("backend"))
+	local = appendProtoBytes(local, 2, []byte{192, 168, 99, 1})
+	remote = appendProtoBytes(remote, 1, []byte("frontend"))
+	remote = appendProtoBytes(remote, 3, []byte{0xfe, 0x80, 15: 1})
+	remote = appendProtoVarint(remote, 4, 58648)
+	annotation = appendProtoFixed64(annotation, 1, 1556604172355800)
+	annotation = appendProtoBytes(annotation, 2, []byte("wr"))
+	tag = appendProtoBytes(tag, 1, []byte("http.method"))
+	tag = appendProtoBytes(tag, 2, []byte("GET"))
+
+	span = appendProtoBytes(span, 1, []byte{0x5a, 0xf7, 0x18, 0x3f, 0xb1, 0xd4, 0xcf, 0x5f, 0x6b, 0x4b, 0x8d, 0x3c, 0x2a, 0x1e, 0x0f, 0x9d})
+	span = appendProtoBytes(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 1})
+	span = appendProtoBytes(span, 3, []byte{0x35, 0x2b, 0xff, 0x9a, 0x74, 0xca, 0x9a, 0xd2})
+	span = appendProtoVarint(span, 4, 1) // CLIENT
+	span = appendProtoBytes(span, 5, []byte("get"))
+	span = appendProtoFixed64(span, 6, 1556604172355737)
+	span = appendProtoVarint(span, 7, 1431)
+	span = appendProtoBytes(span, 8, local)
+	span = appendProtoBytes(span, 9, remote)
+	span = appendProtoBytes(span, 10, annotation)
+	span = appendProtoBytes(span, 11, tag)
+	span = appendProtoVarint(span, 12, 1)
+	span = appendProtoVarint(span, 99, 1) // unknown fields are ignored
+	payload := appendProtoBytes(nil, 1, span)
+
+	spans, err := decodeZipkinProto(payload)
+	require.NoError(t, err)
+	assert.Equal(t, []*zipkinSpan{{
+		TraceID:        "5af7183fb1d4cf5f6b4b8d3c2a1e0f9d",
+		ParentID:       "0000000000000001",
+		ID:             "352bff9a74ca9ad2",
+		Kind:           "CLIENT",
+		Name:           "get",
+		Timestamp:      1556604172355737,
+		Duration:       1431,
+		LocalEndpoint:  &zipkinEndpoint{ServiceName: "backend", IPv4: "192.168.99.1"},
+		RemoteEndpoint: &zipkinEndpoint{ServiceName: "frontend", IPv6: "fe80::1", Port: 58648},
+		Annotations:    []zipkinAnnotation{{Timestamp: 1556604172355800, Value: "wr"}},
+		Tags:           map[string]string{"http.method": "GET"},
+		Debug:          true,
+	}}, spans)
+
+	_, err = decodeZipkinProto(payload[:len(payload)-3])
+	assert.Error(t, err)
+}
+
+func TestReceiverZipkinEndpoint(t *testing.T) {
+	conf := newTestReceiverConfig()
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.handleWithVersion(vZipkinV2, r.handleTraces))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(zipkinJSONPayload))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		require.Len(t, p.TracerPayload.Chunks, 2)
+		assert.Equal(t, string(vZipkinV2), p.Source.EndpointVersion)
+		assert.EqualValues(t, 2, p.Source.TracesReceived.Load())
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+
+	resp, err = http.Post(server.URL, "application/json", bytes.NewBufferString(`[{"traceId": "xyz"}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestTraceChunkBuilder(t *testing.T) {
+	var b traceChunkBuilder
+	b.add(&pb.Span{TraceID: 1, SpanID: 1}, sampler.PriorityAutoDrop)
+	b.add(&pb.Span{TraceID: 2, SpanID: 2}, sampler.PriorityAutoKeep)
+	b.add(&pb.Span{TraceID: 1, SpanID: 3}, sampler.PriorityAutoKeep)
+	b.add(&pb.Span{TraceID: 2, SpanID: 4, Metrics: map[string]float64{"_sampling_priority_v1": 2}}, sampler.PriorityAutoKeep)
+
+	require.Len(t, b.chunks, 2)
+	assert.Equal(t, int32(sampler.PriorityAutoKeep), b.chunks[0].Priority)
+	assert.Len(t, b.chunks[0].Spans, 2)
+	assert.Equal(t, int32(sampler.PriorityUserKeep), b.chunks[1].Priority)
+	assert.Len(t, b.chunks[1].Spans, 2)
+}
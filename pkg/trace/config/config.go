@@ -110,6 +110,10 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the "graphql.source" tag
+	// and the resource of spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards CreditCardsConfig `mapstructure:"credit_cards"`
 }
@@ -131,6 +135,7 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		HTTP:                 o.HTTP,
 		Redis:                o.Redis,
 		Memcached:            o.Memcached,
+		GraphQL:              o.GraphQL,
 		Logger:               new(debugLogger),
 	}
 }
@@ -197,6 +202,107 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// Tail sampling policy types.
+const (
+	// TailSamplingLatency keeps traces lasting longer than a threshold.
+	TailSamplingLatency = "latency"
+	// TailSamplingError keeps traces containing an error.
+	TailSamplingError = "error"
+	// TailSamplingAttribute keeps traces with a span having a matching tag.
+	TailSamplingAttribute = "attribute"
+	// TailSamplingRate keeps a ratio of the traces of a service.
+	TailSamplingRate = "rate"
+)
+
+// TailSamplingConfig specifies the configuration of the tail-based sampling,
+// which makes sampling decisions over complete traces instead of trace chunks.
+type TailSamplingConfig struct {
+	// Enabled enables the tail-based sampling, replacing the head-based samplers.
+	Enabled bool
+
+	// DecisionWait is the time spans are buffered, from the first span of a trace,
+	// before making a decision over the trace.
+	DecisionWait time.Duration
+
+	// MaxMemoryBytes bounds the size of the buffered spans. Once it is exceeded,
+	// decisions are made early over the oldest traces.
+	MaxMemoryBytes int64
+
+	// DecisionCacheTTL is the time the decision made over a trace is remembered, in
+	// order to apply it to the chunks of the trace received after the decision.
+	DecisionCacheTTL time.Duration
+
+	// DecisionCacheSize bounds the number of decisions remembered. No decision is
+	// remembered if it is 0.
+	DecisionCacheSize int
+
+	// Policies are the rules a trace has to match one of in order to be kept.
+	Policies []*TailSamplingPolicy
+}
+
+// TailSamplingPolicy specifies a rule keeping the traces matching it.
+type TailSamplingPolicy struct {
+	// Name identifies the policy, it is reported on the chunks of the traces it keeps.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "latency", "error", "attribute" or "rate".
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the duration above which a trace is kept by a latency policy.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Key is the tag, or metric, looked up on the spans by an attribute policy.
+	Key string `mapstructure:"key"`
+
+	// Pattern is the regexp the value of Key has to match for an attribute policy.
+	// Any value matches if it is empty.
+	Pattern string `mapstructure:"pattern"`
+
+	// Re holds the compiled Pattern and is only used internally.
+	Re *regexp.Regexp `mapstructure:"-"`
+
+	// Service restricts a rate policy to the traces whose root span has this service.
+	// All the services are matched if it is empty.
+	Service string `mapstructure:"service"`
+
+	// Rate is the ratio of traces kept by a rate policy, between 0 and 1.
+	Rate float64 `mapstructure:"rate"`
+}
+
+// Span filter actions.
+const (
+	// SpanFilterDropSpan drops the matching spans, their children being re-parented.
+	SpanFilterDropSpan = "drop_span"
+	// SpanFilterDropTrace drops the traces containing a matching span.
+	SpanFilterDropTrace = "drop_trace"
+)
+
+// Span filter stages.
+const (
+	// SpanFilterBeforeStats applies a filter before computing the stats, which do not
+	// include the dropped spans.
+	SpanFilterBeforeStats = "before_stats"
+	// SpanFilterAfterStats applies a filter after computing the stats, which include
+	// the dropped spans.
+	SpanFilterAfterStats = "after_stats"
+)
+
+// SpanFilterRule specifies spans, or traces, to drop.
+type SpanFilterRule struct {
+	// Name identifies the filter in logs.
+	Name string `mapstructure:"name"`
+
+	// Expr is the expression over the span fields the spans have to match, e.g.
+	// `service == "web" && resource =~ "^GET /health"`.
+	Expr string `mapstructure:"expr"`
+
+	// Action is one of "drop_span" or "drop_trace".
+	Action string `mapstructure:"action"`
+
+	// Stage is one of "before_stats" or "after_stats".
+	Stage string `mapstructure:"stage"`
+}
+
 // FargateOrchestratorName is a Fargate orchestrator name.
 type FargateOrchestratorName string
 
@@ -297,6 +403,14 @@ type AgentConfig struct {
 	ComputeStatsBySpanKind bool          // enables/disables the computing of stats based on a span's `span.kind` field
 	PeerTags               []string      // additional tags to use for peer entity stats aggregation
 
+	// StatsCustomTags lists span tags used as additional stats dimensions by the Concentrator
+	// and the ClientStatsAggregator. They are sent along the peer tags of the stats, and are
+	// only used when the "enable_stats_custom_tags" feature is enabled.
+	StatsCustomTags []string
+	// StatsCustomTagsMaxCardinality is the maximum number of distinct values of each custom
+	// tag in a stats bucket. Further values are aggregated together under an overflow value.
+	StatsCustomTagsMaxCardinality int
+
 	// Sampler configuration
 	ExtraSampleRate float64
 	TargetTPS       float64
@@ -310,6 +424,9 @@ type AgentConfig struct {
 	RareSamplerCooldownPeriod time.Duration
 	RareSamplerCardinality    int
 
+	// TailSampling holds the configuration of the tail-based sampling
+	TailSampling TailSamplingConfig
+
 	// Receiver
 	ReceiverHost    string
 	ReceiverPort    int
@@ -322,6 +439,9 @@ type AgentConfig struct {
 	MaxConnections  int   // specifies the maximum number of concurrent incoming connections allowed.
 	DecoderTimeout  int   // specifies the maximum time in milliseconds that the decoders will wait for a turn to accept a payload before returning 429
 
+	ZipkinReceiverEnabled bool // enables the Zipkin v2 endpoint (/api/v2/spans) on the receiver
+	JaegerReceiverEnabled bool // enables the Jaeger Thrift over HTTP endpoint (/api/traces) on the receiver
+
 	WindowsPipeName        string
 	PipeBufferSize         int
 	PipeSecurityDescriptor string
@@ -395,6 +515,9 @@ type AgentConfig struct {
 	// RejectTagsRegex specifies a list of regexp for tags which must be absent on the root span in order for a trace to be accepted.
 	RejectTagsRegex []*TagRegex
 
+	// SpanFilters specifies filters dropping the spans, or the traces, matching an expression.
+	SpanFilters []*SpanFilterRule
+
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
@@ -470,7 +593,8 @@ func New() *AgentConfig {
 		Site:                "datadoghq.com",
 		MaxCatalogEntries:   5000,
 
-		BucketInterval: time.Duration(10) * time.Second,
+		BucketInterval:                time.Duration(10) * time.Second,
+		StatsCustomTagsMaxCardinality: 100,
 
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
@@ -483,6 +607,13 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait:      30 * time.Second,
+			MaxMemoryBytes:    100 * 1024 * 1024, // 100MB
+			DecisionCacheTTL:  5 * time.Minute,
+			DecisionCacheSize: 100_000,
+		},
+
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
 		MaxRequestBytes:        25 * 1024 * 1024, // 25MB
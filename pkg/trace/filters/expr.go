@@ -0,0 +1,401 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// A span expression is a boolean expression over the fields of a span, such as:
+//
+//	service == "web" && (resource =~ "^GET /health" || metrics.http.status_code >= 500)
+//
+// The supported fields are name, service, resource, type, error, duration (in
+// nanoseconds), meta.<key> and metrics.<key>. A field can be compared to a string
+// or to a number with ==, !=, <, <=, > and >=, matched against a regular expression
+// with =~ and !~, or used alone to check that the span has it. Expressions are
+// combined with !, && and ||.
+//
+// Comparisons with a missing field are false, except for != and !~.
+
+// exprNode is a node of a parsed span expression.
+type exprNode interface {
+	eval(s *pb.Span) bool
+}
+
+type orNode struct{ left, right exprNode }
+
+func (n *orNode) eval(s *pb.Span) bool { return n.left.eval(s) || n.right.eval(s) }
+
+type andNode struct{ left, right exprNode }
+
+func (n *andNode) eval(s *pb.Span) bool { return n.left.eval(s) && n.right.eval(s) }
+
+type notNode struct{ node exprNode }
+
+func (n *notNode) eval(s *pb.Span) bool { return !n.node.eval(s) }
+
+// existsNode matches the spans having the field.
+type existsNode struct{ field spanField }
+
+func (n *existsNode) eval(s *pb.Span) bool {
+	_, ok := n.field.value(s)
+	return ok
+}
+
+// compareNode compares a field with a literal, with one of ==, =~, <, <=, > or >=.
+type compareNode struct {
+	field spanField
+	op    string
+	lit   exprLiteral
+	re    *regexp.Regexp
+}
+
+func (n *compareNode) eval(s *pb.Span) bool {
+	v, ok := n.field.value(s)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		if n.lit.isNum {
+			num, ok := v.number()
+			return ok && num == n.lit.num
+		}
+		return v.str == n.lit.text
+	case "=~":
+		return n.re.MatchString(v.str)
+	}
+	num, ok := v.number()
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return num < n.lit.num
+	case "<=":
+		return num <= n.lit.num
+	case ">":
+		return num > n.lit.num
+	case ">=":
+		return num >= n.lit.num
+	}
+	return false
+}
+
+type exprLiteral struct {
+	text  string
+	num   float64
+	isNum bool
+}
+
+// spanField is a field of a span referenced by an expression.
+type spanField struct {
+	name string // name, service, resource, type, error, duration, meta or metrics
+	key  string // key of the meta and metrics fields
+}
+
+// fieldValue is the value of a field of a span.
+type fieldValue struct {
+	str   string
+	num   float64
+	isNum bool
+}
+
+// number returns the numeric value of the field, parsing string values.
+func (v fieldValue) number() (float64, bool) {
+	if v.isNum {
+		return v.num, true
+	}
+	num, err := strconv.ParseFloat(v.str, 64)
+	return num, err == nil
+}
+
+func numericValue(num float64) fieldValue {
+	return fieldValue{str: strconv.FormatFloat(num, 'f', -1, 64), num: num, isNum: true}
+}
+
+// value returns the value of the field on the span, and whether the span has it.
+func (f spanField) value(s *pb.Span) (fieldValue, bool) {
+	switch f.name {
+	case "name":
+		return fieldValue{str: s.Name}, true
+	case "service":
+		return fieldValue{str: s.Service}, true
+	case "resource":
+		return fieldValue{str: s.Resource}, true
+	case "type":
+		return fieldValue{str: s.Type}, true
+	case "error":
+		return numericValue(float64(s.Error)), true
+	case "duration":
+		return numericValue(float64(s.Duration)), true
+	case "meta":
+		v, ok := s.Meta[f.key]
+		return fieldValue{str: v}, ok
+	case "metrics":
+		v, ok := s.Metrics[f.key]
+		return numericValue(v), ok
+	}
+	return fieldValue{}, false
+}
+
+func newSpanField(ident string) (spanField, error) {
+	switch ident {
+	case "name", "service", "resource", "type", "error", "duration":
+		return spanField{name: ident}, nil
+	}
+	if name, key, ok := strings.Cut(ident, "."); ok && key != "" && (name == "meta" || name == "metrics") {
+		return spanField{name: name, key: key}, nil
+	}
+	return spanField{}, fmt.Errorf("unknown field %q, expected one of name, service, resource, type, error, duration, meta.<key> or metrics.<key>", ident)
+}
+
+type exprTokenKind int
+
+const (
+	tokEOF exprTokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+// exprOperators lists the operators, two characters ones first.
+var exprOperators = []string{"==", "!=", "=~", "!~", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentChar(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || strings.ContainsRune(".-/@:", r)
+}
+
+// lexExpr splits a span expression into tokens.
+func lexExpr(in string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(in)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: tokString, text: sb.String(), pos: i})
+			i = j + 1
+		case unicode.IsDigit(r) || ((r == '-' || r == '.') && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || strings.ContainsRune(".eE+-", runes[j])) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: string(runes[i:j]), pos: i})
+			i = j
+		case isIdentStart(r):
+			j := i + 1
+			for j < len(runes) && isIdentChar(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: string(runes[i:j]), pos: i})
+			i = j
+		default:
+			var op string
+			for _, o := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, exprToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// exprParser is a recursive descent parser of span expressions.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// parseSpanExpr parses a span expression.
+func parseSpanExpr(in string) (exprNode, error) {
+	tokens, err := lexExpr(in)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *exprParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node}, nil
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			t := p.peek()
+			return nil, fmt.Errorf("expected \")\" at position %d", t.pos)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, fmt.Errorf("expected a field at position %d", t.pos)
+	}
+	field, err := newSpanField(t.text)
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	if op.kind != tokOp {
+		return &existsNode{field}, nil
+	}
+	switch op.text {
+	case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		return &existsNode{field}, nil
+	}
+	lit, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	n := &compareNode{field: field, op: op.text, lit: lit}
+	switch op.text {
+	case "!=":
+		n.op = "=="
+		return &notNode{n}, nil
+	case "=~", "!~":
+		if lit.isNum {
+			return nil, fmt.Errorf("operator %q at position %d expects a string", op.text, op.pos)
+		}
+		if n.re, err = regexp.Compile(lit.text); err != nil {
+			return nil, err
+		}
+		if op.text == "!~" {
+			n.op = "=~"
+			return &notNode{n}, nil
+		}
+	case "<", "<=", ">", ">=":
+		if !lit.isNum {
+			return nil, fmt.Errorf("operator %q at position %d expects a number", op.text, op.pos)
+		}
+	}
+	return n, nil
+}
+
+func (p *exprParser) parseLiteral() (exprLiteral, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return exprLiteral{text: t.text}, nil
+	case tokNumber:
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return exprLiteral{}, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return exprLiteral{text: t.text, num: num, isNum: true}, nil
+	}
+	return exprLiteral{}, fmt.Errorf("expected a string or a number at position %d", t.pos)
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanExpr(t *testing.T) {
+	span := &pb.Span{
+		Service:  "web",
+		Name:     "http.request",
+		Resource: "GET /health",
+		Type:     "web",
+		Duration: 1500,
+		Meta: map[string]string{
+			"http.method":      "GET",
+			"http.status_code": "503",
+			"peer-host":        "db:5432",
+		},
+		Metrics: map[string]float64{
+			"_sampling_priority_v1": 1,
+			"db.rows":               12.5,
+		},
+	}
+	for _, tt := range []struct {
+		expr  string
+		match bool
+	}{
+		{`service == "web"`, true},
+		{`service == 'api'`, false},
+		{`service != "api"`, true},
+		{`name == "http.request" && resource =~ "^GET /health"`, true},
+		{`name == "http.request" && resource !~ "^GET /health"`, false},
+		{`type == "db" || resource =~ "health"`, true},
+		{`!(type == "db" || resource =~ "health")`, false},
+		{`type == "db" || service == "web" && name == "other"`, false},
+		{`(type == "db" || service == "web") && name == "http.request"`, true},
+		{`duration > 1000`, true},
+		{`duration <= 1000`, false},
+		{`error == 0`, true},
+		{`meta.http.status_code >= 500`, true},
+		{`meta.http.status_code == 503`, true},
+		{`meta.http.status_code == "503"`, true},
+		{`meta.http.method < 1`, false},
+		{`meta.peer-host == "db:5432"`, true},
+		{`metrics.db.rows > 10 && metrics.db.rows < 12.6`, true},
+		{`metrics.db.rows == 12.5`, true},
+		{`metrics.db.rows == "12.5"`, true},
+		{`metrics._sampling_priority_v1 >= -1`, true},
+		{`meta.http.method`, true},
+		{`!meta.missing`, true},
+		{`meta.missing == ""`, false},
+		{`meta.missing != "x"`, true},
+		{`meta.missing !~ "x"`, true},
+		{`metrics.missing < 1`, false},
+		{`resource == "GET \"quoted\""`, false},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseSpanExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, expr.eval(span))
+		})
+	}
+}
+
+func TestSpanExprInvalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`service ==`,
+		`service == web`,
+		`host == "a"`,
+		`meta. == "a"`,
+		`service == "web`,
+		`service > "a"`,
+		`resource =~ 12`,
+		`resource =~ "("`,
+		`(service == "web"`,
+		`service == "web")`,
+		`service == "web" &&`,
+		`service == "web" # comment`,
+		`service "web"`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseSpanExpr(expr)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// SpanFilter drops the spans, or the whole traces, matching the expressions of
+// its rules. Rules are applied at the stage they are configured for: before or
+// after computing the stats.
+type SpanFilter struct {
+	stages map[string][]*spanFilterRule
+}
+
+type spanFilterRule struct {
+	name      string
+	expr      exprNode
+	dropTrace bool
+}
+
+// NewSpanFilter creates a new SpanFilter from the given rules. Rules with an
+// invalid expression are ignored.
+func NewSpanFilter(rules []*config.SpanFilterRule) *SpanFilter {
+	f := &SpanFilter{stages: make(map[string][]*spanFilterRule)}
+	for _, r := range rules {
+		expr, err := parseSpanExpr(r.Expr)
+		if err != nil {
+			log.Errorf("Invalid span filter %q: %s", r.Name, err)
+			continue
+		}
+		stage := r.Stage
+		if stage == "" {
+			stage = config.SpanFilterBeforeStats
+		}
+		f.stages[stage] = append(f.stages[stage], &spanFilterRule{
+			name:      r.Name,
+			expr:      expr,
+			dropTrace: r.Action == config.SpanFilterDropTrace,
+		})
+	}
+	return f
+}
+
+// Filter applies the rules of the given stage to the trace. It returns the spans
+// to keep, and whether the whole trace has to be dropped. The children of dropped
+// spans are re-parented to their closest kept ancestor. The root span is never
+// dropped on its own, as it carries the trace-level metadata.
+func (f *SpanFilter) Filter(stage string, trace pb.Trace) (kept pb.Trace, dropTrace bool) {
+	if f == nil || len(f.stages[stage]) == 0 {
+		return trace, false
+	}
+	var (
+		root    *pb.Span
+		dropped []bool
+		parents map[uint64]uint64 // parent IDs of the dropped spans
+	)
+	for i, s := range trace {
+		for _, r := range f.stages[stage] {
+			if !r.expr.eval(s) {
+				continue
+			}
+			if r.dropTrace {
+				log.Debugf("Trace dropped by span filter %q. span: %v", r.name, s)
+				return nil, true
+			}
+			if root == nil {
+				root = traceutil.GetRoot(trace)
+			}
+			if s == root {
+				continue
+			}
+			if dropped == nil {
+				dropped = make([]bool, len(trace))
+				parents = make(map[uint64]uint64)
+			}
+			dropped[i] = true
+			parents[s.SpanID] = s.ParentID
+		}
+	}
+	if dropped == nil {
+		return trace, false
+	}
+	kept = make(pb.Trace, 0, len(trace)-len(parents))
+	for i, s := range trace {
+		if dropped[i] {
+			continue
+		}
+		// bound the number of hops, in case of cycles in malformed traces
+		for n := 0; n < len(parents); n++ {
+			parent, ok := parents[s.ParentID]
+			if !ok {
+				break
+			}
+			s.ParentID = parent
+		}
+		kept = append(kept, s)
+	}
+	return kept, false
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTrace() pb.Trace {
+	// root(1) -> cache.get(2) -> cache.get(3) -> render(4)
+	//         -> healthcheck(5)
+	return pb.Trace{
+		{SpanID: 1, Name: "http.request", Resource: "GET /users"},
+		{SpanID: 2, ParentID: 1, Name: "cache.get"},
+		{SpanID: 3, ParentID: 2, Name: "cache.get"},
+		{SpanID: 4, ParentID: 3, Name: "render"},
+		{SpanID: 5, ParentID: 1, Name: "healthcheck", Metrics: map[string]float64{"http.status_code": 200}},
+	}
+}
+
+func spanIDs(trace pb.Trace) map[uint64]uint64 {
+	ids := make(map[uint64]uint64, len(trace))
+	for _, s := range trace {
+		ids[s.SpanID] = s.ParentID
+	}
+	return ids
+}
+
+func TestSpanFilter(t *testing.T) {
+	f := NewSpanFilter([]*config.SpanFilterRule{
+		{Name: "cache", Expr: `name == "cache.get"`, Action: config.SpanFilterDropSpan},
+		{Name: "invalid", Expr: `name ==`, Action: config.SpanFilterDropTrace},
+		{Name: "health", Expr: `name == "healthcheck" && metrics.http.status_code < 400`, Action: config.SpanFilterDropSpan, Stage: config.SpanFilterAfterStats},
+		{Name: "admin", Expr: `resource =~ "^GET /admin"`, Action: config.SpanFilterDropTrace, Stage: config.SpanFilterAfterStats},
+	})
+
+	t.Run("before_stats", func(t *testing.T) {
+		kept, dropTrace := f.Filter(config.SpanFilterBeforeStats, testTrace())
+		assert.False(t, dropTrace)
+		assert.Equal(t, map[uint64]uint64{1: 0, 4: 1, 5: 1}, spanIDs(kept))
+	})
+
+	t.Run("after_stats", func(t *testing.T) {
+		kept, dropTrace := f.Filter(config.SpanFilterAfterStats, testTrace())
+		assert.False(t, dropTrace)
+		assert.Equal(t, map[uint64]uint64{1: 0, 2: 1, 3: 2, 4: 3}, spanIDs(kept))
+
+		trace := testTrace()
+		trace[0].Resource = "GET /admin/users"
+		kept, dropTrace = f.Filter(config.SpanFilterAfterStats, trace)
+		assert.True(t, dropTrace)
+		assert.Nil(t, kept)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		f := NewSpanFilter([]*config.SpanFilterRule{{Expr: `name != "render"`}})
+		kept, dropTrace := f.Filter(config.SpanFilterBeforeStats, testTrace())
+		assert.False(t, dropTrace)
+		assert.Equal(t, map[uint64]uint64{1: 0, 4: 1}, spanIDs(kept))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		trace := pb.Trace{
+			{SpanID: 1, ParentID: 2, Name: "a"},
+			{SpanID: 2, ParentID: 1, Name: "cache.get"},
+			{SpanID: 3, ParentID: 2, Name: "b"},
+		}
+		kept, _ := f.Filter(config.SpanFilterBeforeStats, trace)
+		assert.Len(t, kept, 2)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		var f *SpanFilter
+		trace := testTrace()
+		kept, dropTrace := f.Filter(config.SpanFilterBeforeStats, trace)
+		assert.False(t, dropTrace)
+		assert.Equal(t, trace, kept)
+	})
+}
@@ -46,6 +46,9 @@ type ClientStatsAggregator struct {
 	agentVersion        string
 	peerTagsAggregation bool // flag to enable aggregation over peer tags
 
+	customTagKeys         []string // keys of the span tags used as additional stats dimensions
+	customTagsCardinality int      // maximum number of distinct values of each custom tag in a bucket
+
 	exit chan struct{}
 	done chan struct{}
 }
@@ -53,17 +56,19 @@ type ClientStatsAggregator struct {
 // NewClientStatsAggregator initializes a new aggregator ready to be started
 func NewClientStatsAggregator(conf *config.AgentConfig, out chan *pb.StatsPayload) *ClientStatsAggregator {
 	c := &ClientStatsAggregator{
-		flushTicker:         time.NewTicker(time.Second),
-		In:                  make(chan *pb.ClientStatsPayload, 10),
-		buckets:             make(map[int64]*bucket, 20),
-		out:                 out,
-		agentEnv:            conf.DefaultEnv,
-		agentHostname:       conf.Hostname,
-		agentVersion:        conf.AgentVersion,
-		peerTagsAggregation: conf.PeerServiceAggregation || conf.PeerTagsAggregation,
-		oldestTs:            alignAggTs(time.Now().Add(bucketDuration - oldestBucketStart)),
-		exit:                make(chan struct{}),
-		done:                make(chan struct{}),
+		flushTicker:           time.NewTicker(time.Second),
+		In:                    make(chan *pb.ClientStatsPayload, 10),
+		buckets:               make(map[int64]*bucket, 20),
+		out:                   out,
+		agentEnv:              conf.DefaultEnv,
+		agentHostname:         conf.Hostname,
+		agentVersion:          conf.AgentVersion,
+		peerTagsAggregation:   conf.PeerServiceAggregation || conf.PeerTagsAggregation,
+		customTagKeys:         prepareCustomTags(conf),
+		customTagsCardinality: conf.StatsCustomTagsMaxCardinality,
+		oldestTs:              alignAggTs(time.Now().Add(bucketDuration - oldestBucketStart)),
+		exit:                  make(chan struct{}),
+		done:                  make(chan struct{}),
 	}
 	return c
 }
@@ -134,7 +139,7 @@ func (a *ClientStatsAggregator) add(now time.Time, p *pb.ClientStatsPayload) {
 		}
 		b, ok := a.buckets[ts.Unix()]
 		if !ok {
-			b = &bucket{ts: ts}
+			b = &bucket{ts: ts, customTags: newCustomTagsLimiter(a.customTagKeys, a.customTagsCardinality)}
 			a.buckets[ts.Unix()] = b
 		}
 		p.Stats = []*pb.ClientStatsBucket{clientBucket}
@@ -175,9 +180,12 @@ type bucket struct {
 	n int
 	// agg contains the aggregated Hits/Errors/Duration counts
 	agg map[PayloadAggregationKey]map[BucketsAggregationKey]*aggregatedCounts
+	// customTags limits the values of the custom tags dimensions, nil if there are none
+	customTags *customTagsLimiter
 }
 
 func (b *bucket) add(p *pb.ClientStatsPayload, enablePeerSvcAgg bool) []*pb.ClientStatsPayload {
+	b.customTags.limitPayload(p)
 	b.n++
 	if b.n == 1 {
 		b.first = &pb.ClientStatsPayload{
@@ -225,14 +233,16 @@ func (b *bucket) aggregateCounts(p *pb.ClientStatsPayload, enablePeerTagsAgg boo
 			if sb == nil {
 				continue
 			}
-			aggKey := newBucketAggregationKey(sb, enablePeerTagsAgg)
+			peerTags, customTags := b.customTags.splitGroupTags(sb.PeerTags)
+			if !enablePeerTagsAgg {
+				peerTags = nil
+			}
+			aggKey := newBucketAggregationKey(sb, peerTags, customTags)
 			agg, ok := payloadAgg[aggKey]
 			if !ok {
 				agg = &aggregatedCounts{}
 				payloadAgg[aggKey] = agg
-				if enablePeerTagsAgg {
-					agg.peerTags = sb.PeerTags
-				}
+				agg.peerTags = append(peerTags, customTags...)
 			}
 			agg.hits += sb.Hits
 			agg.errors += sb.Errors
@@ -288,20 +298,21 @@ func newPayloadAggregationKey(env, hostname, version, cid string) PayloadAggrega
 	return PayloadAggregationKey{Env: env, Hostname: hostname, Version: version, ContainerID: cid}
 }
 
-func newBucketAggregationKey(b *pb.ClientGroupedStats, enablePeerTagsAgg bool) BucketsAggregationKey {
-	k := BucketsAggregationKey{
-		Service:    b.Service,
-		Name:       b.Name,
-		SpanKind:   b.SpanKind,
-		Resource:   b.Resource,
-		Type:       b.Type,
-		Synthetics: b.Synthetics,
-		StatusCode: b.HTTPStatusCode,
-	}
-	if enablePeerTagsAgg {
-		k.PeerTagsHash = peerTagsHash(b.GetPeerTags())
+// newBucketAggregationKey returns the aggregation key of grouped stats, with the
+// peer tags and the custom tags, carried together in the peer tags of the grouped
+// stats, hashed separately.
+func newBucketAggregationKey(b *pb.ClientGroupedStats, peerTags, customTags []string) BucketsAggregationKey {
+	return BucketsAggregationKey{
+		Service:        b.Service,
+		Name:           b.Name,
+		SpanKind:       b.SpanKind,
+		Resource:       b.Resource,
+		Type:           b.Type,
+		Synthetics:     b.Synthetics,
+		StatusCode:     b.HTTPStatusCode,
+		PeerTagsHash:   peerTagsHash(peerTags),
+		CustomTagsHash: peerTagsHash(customTags),
 	}
-	return k
 }
 
 func trimCounts(p *pb.ClientStatsPayload) *pb.ClientStatsPayload {
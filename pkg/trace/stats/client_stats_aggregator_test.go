@@ -6,11 +6,13 @@
 package stats
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	fuzz "github.com/google/gofuzz"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/runtime/protoiface"
 
 	proto "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
@@ -444,14 +446,25 @@ func TestNewBucketAggregationKeyPeerTags(t *testing.T) {
 	peerTagsHash := uint64(3430395298086625290)
 	t.Run("disabled", func(t *testing.T) {
 		assert := assert.New(t)
-		r := newBucketAggregationKey(&proto.ClientGroupedStats{Service: "a", PeerTags: []string{"peer.service:remote-service"}}, false)
+		r := newBucketAggregationKey(&proto.ClientGroupedStats{Service: "a", PeerTags: []string{"peer.service:remote-service"}}, nil, nil)
 		assert.Equal(BucketsAggregationKey{Service: "a"}, r)
 	})
 	t.Run("enabled", func(t *testing.T) {
 		assert := assert.New(t)
-		r := newBucketAggregationKey(&proto.ClientGroupedStats{Service: "a", PeerTags: []string{"peer.service:remote-service"}}, true)
+		r := newBucketAggregationKey(&proto.ClientGroupedStats{Service: "a", PeerTags: []string{"peer.service:remote-service"}}, []string{"peer.service:remote-service"}, nil)
 		assert.Equal(BucketsAggregationKey{Service: "a", PeerTagsHash: peerTagsHash}, r)
 	})
+	t.Run("custom tags", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &proto.ClientGroupedStats{Service: "a", PeerTags: []string{"peer.service:remote-service", "tenant:a"}}
+		l := newCustomTagsLimiter([]string{"tenant"}, 0)
+		peerTags, customTags := l.splitGroupTags(g.PeerTags)
+		r := newBucketAggregationKey(g, peerTags, customTags)
+		// the custom tags, carried in the peer tags, aren't part of the peer tags hash
+		assert.Equal(peerTagsHash, r.PeerTagsHash)
+		assert.NotZero(r.CustomTagsHash)
+		assert.NotEqual(r.PeerTagsHash, r.CustomTagsHash)
+	})
 }
 
 func deepCopy(p *proto.ClientStatsPayload) *proto.ClientStatsPayload {
@@ -579,3 +592,98 @@ func TestNewClientStatsAggregatorPeerAggregation(t *testing.T) {
 		assert.True(a.peerTagsAggregation)
 	})
 }
+
+func TestCountAggregationCustomTags(t *testing.T) {
+	for _, enablePeerTagsAgg := range []bool{false, true} {
+		t.Run(fmt.Sprintf("peer_tags_aggregation:%v", enablePeerTagsAgg), func(t *testing.T) {
+			assert := assert.New(t)
+			a := newTestAggregator()
+			a.peerTagsAggregation = enablePeerTagsAgg
+			a.customTagKeys = []string{"tenant"}
+			a.customTagsCardinality = 1
+			testTime := time.Unix(time.Now().Unix(), 0)
+
+			k := BucketsAggregationKey{Service: "s", Name: "test.op"}
+			c1 := payloadWithCounts(testTime, k, 11, 7, 100)
+			c2 := payloadWithCounts(testTime, k, 27, 2, 300)
+			c3 := payloadWithCounts(testTime, k, 5, 10, 3)
+			c1.Stats[0].Stats[0].PeerTags = []string{"peer.service:db", "tenant:a"}
+			c2.Stats[0].Stats[0].PeerTags = []string{"peer.service:db", "tenant:b"}
+			c3.Stats[0].Stats[0].PeerTags = []string{"peer.service:db", "tenant:c"}
+
+			a.add(testTime, deepCopy(c1))
+			a.add(testTime, deepCopy(c2))
+			a.add(testTime, deepCopy(c3))
+			assert.Len(a.out, 2)
+			for i := 0; i < 2; i++ {
+				for _, p := range (<-a.out).Stats {
+					for _, g := range p.Stats[0].Stats {
+						assert.Contains([]string{"tenant:a", "tenant:" + customTagOverflowValue}, g.PeerTags[1])
+					}
+				}
+			}
+			a.flushOnTime(testTime.Add(oldestBucketStart + time.Nanosecond))
+			aggCounts := <-a.out
+			assertAggCountsPayload(t, aggCounts)
+
+			peerTags := func(tenant string) []string {
+				if enablePeerTagsAgg {
+					return []string{"peer.service:db", "tenant:" + tenant}
+				}
+				return []string{"tenant:" + tenant}
+			}
+			assert.ElementsMatch([]*proto.ClientGroupedStats{
+				{Service: "s", Name: "test.op", PeerTags: peerTags("a"), Hits: 11, Errors: 7, Duration: 100},
+				{Service: "s", Name: "test.op", PeerTags: peerTags(customTagOverflowValue), Hits: 32, Errors: 12, Duration: 303},
+			}, aggCounts.Stats[0].Stats[0].Stats)
+		})
+	}
+}
+
+func TestClientStatsCustomTagsFromTracer(t *testing.T) {
+	conf := &config.AgentConfig{
+		DefaultEnv:                    "agentEnv",
+		Hostname:                      "agentHostname",
+		PeerTagsAggregation:           true,
+		StatsCustomTags:               []string{"tenant"},
+		StatsCustomTagsMaxCardinality: 100,
+		Features:                      map[string]struct{}{customTagsFeature: {}},
+	}
+	a := NewClientStatsAggregator(conf, make(chan *proto.StatsPayload, 100))
+	a.Start()
+	a.flushTicker.Stop()
+	defer a.Stop()
+	testTime := time.Unix(time.Now().Unix(), 0)
+
+	// tracerPayload builds the stats payload a tracer sends for a span, using the
+	// peer tags advertised by the Agent
+	tracerPayload := func(meta map[string]string) *proto.ClientStatsPayload {
+		p := payloadWithCounts(testTime, BucketsAggregationKey{Service: "s", Name: "client.request", SpanKind: "client"}, 1, 0, 10)
+		for _, k := range TracerPeerTags(conf) {
+			if v, ok := meta[k]; ok {
+				p.Stats[0].Stats[0].PeerTags = append(p.Stats[0].Stats[0].PeerTags, k+":"+v)
+			}
+		}
+		// the payload goes through the msgpack encoding used by the tracers
+		b, err := p.MarshalMsg(nil)
+		require.NoError(t, err)
+		decoded := &proto.ClientStatsPayload{}
+		_, err = decoded.UnmarshalMsg(b)
+		require.NoError(t, err)
+		return decoded
+	}
+	a.add(testTime, tracerPayload(map[string]string{"peer.service": "db", "tenant": "a", "other": "x"}))
+	a.add(testTime, tracerPayload(map[string]string{"peer.service": "db", "tenant": "a"}))
+	a.add(testTime, tracerPayload(map[string]string{"peer.service": "db", "tenant": "b"}))
+	for len(a.out) > 0 {
+		<-a.out
+	}
+
+	a.flushOnTime(testTime.Add(oldestBucketStart + time.Nanosecond))
+	aggCounts := <-a.out
+	assertAggCountsPayload(t, aggCounts)
+	assert.ElementsMatch(t, []*proto.ClientGroupedStats{
+		{Service: "s", Name: "client.request", SpanKind: "client", PeerTags: []string{"peer.service:db", "tenant:a"}, Hits: 2, Duration: 20},
+		{Service: "s", Name: "client.request", SpanKind: "client", PeerTags: []string{"peer.service:db", "tenant:b"}, Hits: 1, Duration: 10},
+	}, aggCounts.Stats[0].Stats[0].Stats)
+}
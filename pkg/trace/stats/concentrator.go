@@ -50,6 +50,8 @@ type Concentrator struct {
 	peerTagsAggregation    bool     // flag to enable aggregation of peer tags
 	computeStatsBySpanKind bool     // flag to enable computation of stats through checking the span.kind field
 	peerTagKeys            []string // keys for supplementary tags that describe peer.service entities
+	customTagKeys          []string // keys of the span tags used as additional stats dimensions
+	customTagsCardinality  int      // maximum number of distinct values of each custom tag in a bucket
 }
 
 var defaultPeerTags = []string{
@@ -100,6 +102,18 @@ func preparePeerTags(tags ...string) []string {
 	return deduped
 }
 
+// TracerPeerTags returns the keys of the peer tags the tracers computing stats should
+// aggregate on: the peer tags, when peer tags aggregation is enabled, and the custom
+// tags, which are sent along the peer tags.
+func TracerPeerTags(conf *config.AgentConfig) []string {
+	var keys []string
+	if conf.PeerServiceAggregation || conf.PeerTagsAggregation {
+		keys = append(keys, defaultPeerTags...)
+		keys = append(keys, conf.PeerTags...)
+	}
+	return preparePeerTags(append(keys, prepareCustomTags(conf)...)...)
+}
+
 // NewConcentrator initializes a new concentrator ready to be started
 func NewConcentrator(conf *config.AgentConfig, out chan *pb.StatsPayload, now time.Time) *Concentrator {
 	bsize := conf.BucketInterval.Nanoseconds()
@@ -119,11 +133,13 @@ func NewConcentrator(conf *config.AgentConfig, out chan *pb.StatsPayload, now ti
 		agentVersion:           conf.AgentVersion,
 		peerTagsAggregation:    conf.PeerServiceAggregation || conf.PeerTagsAggregation,
 		computeStatsBySpanKind: conf.ComputeStatsBySpanKind,
+		customTagsCardinality:  conf.StatsCustomTagsMaxCardinality,
 	}
 	// NOTE: maintain backwards-compatibility with old peer service flag that will eventually be deprecated.
 	if conf.PeerServiceAggregation || conf.PeerTagsAggregation {
 		c.peerTagKeys = preparePeerTags(append(defaultPeerTags, conf.PeerTags...)...)
 	}
+	c.customTagKeys = prepareCustomTags(conf)
 	return &c
 }
 
@@ -250,6 +266,7 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 		b, ok := c.buckets[btime]
 		if !ok {
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
+			b.customTags = newCustomTagsLimiter(c.customTagKeys, c.customTagsCardinality)
 			c.buckets[btime] = b
 		}
 		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey, c.peerTagsAggregation, c.peerTagKeys)
@@ -924,3 +924,39 @@ func TestPreparePeerTags(t *testing.T) {
 		assert.Equal(t, tc.output, preparePeerTags(tc.input...))
 	}
 }
+
+func TestCustomTags(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	var spans []*pb.Span
+	for i, tenant := range []string{"a", "b", "a", "c"} {
+		spans = append(spans, &pb.Span{
+			SpanID:   uint64(i + 1),
+			Service:  "myservice",
+			Name:     "http.server.request",
+			Resource: "GET /users",
+			Duration: 100,
+			Meta:     map[string]string{"span.kind": "server", "tenant": tenant, "db.system": "postgres"},
+			Metrics:  map[string]float64{"_dd.measured": 1.0, "shard": 2},
+		})
+	}
+	traceutil.ComputeTopLevel(spans)
+	testTrace := toProcessedTrace(spans, "none", "")
+	c := NewTestConcentrator(now)
+	c.customTagKeys = []string{"shard", "tenant"}
+	c.customTagsCardinality = 2
+	c.addNow(testTrace, "")
+	stats := c.flushNow(now.UnixNano()+int64(c.bufferLen)*testBucketInterval, false)
+
+	hits := make(map[string]uint64)
+	for _, st := range stats.Stats[0].Stats[0].Stats {
+		assert.Len(st.PeerTags, 2)
+		assert.Equal("shard:2", st.PeerTags[0])
+		hits[st.PeerTags[1]] += st.Hits
+	}
+	assert.Equal(map[string]uint64{
+		"tenant:a":                         2,
+		"tenant:b":                         1,
+		"tenant:" + customTagOverflowValue: 1,
+	}, hits)
+}
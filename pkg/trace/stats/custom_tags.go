@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"strconv"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// customTagsFeature is the feature flag enabling `apm_config.stats_custom_tags`.
+const customTagsFeature = "enable_stats_custom_tags"
+
+// customTagOverflowValue replaces the values of a custom tag once its maximum
+// cardinality is reached in a bucket.
+const customTagOverflowValue = "__overflow__"
+
+// customTagsLimiter computes the custom tags dimensions of the stats of a bucket,
+// configured with `apm_config.stats_custom_tags`. It bounds the number of distinct
+// values of each tag: once a tag has maxCardinality values, its other values are
+// replaced by customTagOverflowValue.
+//
+// The stats payloads have no field dedicated to the custom tags: they are sent
+// along the peer tags of the grouped stats, where the backend validates them like
+// peer tags, which is why the setting is gated by the customTagsFeature flag. The
+// custom tags and the peer tags are still hashed separately in the aggregation keys.
+type customTagsLimiter struct {
+	keys           []string
+	maxCardinality int
+	values         map[string]map[string]struct{}
+}
+
+// newCustomTagsLimiter returns a limiter for the given tag keys, or nil if keys is empty.
+func newCustomTagsLimiter(keys []string, maxCardinality int) *customTagsLimiter {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &customTagsLimiter{
+		keys:           keys,
+		maxCardinality: maxCardinality,
+		values:         make(map[string]map[string]struct{}, len(keys)),
+	}
+}
+
+// prepareCustomTags dedupes and sorts the custom tag keys of the configuration,
+// dropping the ones which are already aggregated as peer tags. No keys are returned
+// unless the customTagsFeature flag is enabled.
+func prepareCustomTags(conf *config.AgentConfig) []string {
+	if len(conf.StatsCustomTags) == 0 {
+		return nil
+	}
+	if !conf.HasFeature(customTagsFeature) {
+		log.Warnf("apm_config.stats_custom_tags is ignored, the %q feature must be enabled in apm_config.features to use it", customTagsFeature)
+		return nil
+	}
+	peerTags := make(map[string]struct{})
+	if conf.PeerServiceAggregation || conf.PeerTagsAggregation {
+		for _, k := range append(defaultPeerTags, conf.PeerTags...) {
+			peerTags[k] = struct{}{}
+		}
+	}
+	var custom []string
+	for _, k := range preparePeerTags(conf.StatsCustomTags...) {
+		if _, ok := peerTags[k]; !ok && k != "" {
+			custom = append(custom, k)
+		}
+	}
+	return custom
+}
+
+// limit returns value, or customTagOverflowValue when the key already has the
+// maximum number of distinct values. A maximum cardinality of 0 means unlimited.
+func (l *customTagsLimiter) limit(key, value string) string {
+	values, ok := l.values[key]
+	if !ok {
+		values = make(map[string]struct{})
+		l.values[key] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if l.maxCardinality > 0 && len(values) >= l.maxCardinality {
+		return customTagOverflowValue
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+// spanTags returns the custom tags of the span, as "key:value" pairs. Numeric
+// values are read from the span metrics.
+func (l *customTagsLimiter) spanTags(s *pb.Span) []string {
+	if l == nil {
+		return nil
+	}
+	var tags []string
+	for _, k := range l.keys {
+		v, ok := s.Meta[k]
+		if !ok || v == "" {
+			m, ok := s.Metrics[k]
+			if !ok {
+				continue
+			}
+			v = strconv.FormatFloat(m, 'f', -1, 64)
+		}
+		tags = append(tags, k+":"+l.limit(k, v))
+	}
+	return tags
+}
+
+// limitPayload limits in place the values of the custom tags of the grouped
+// stats of a payload computed by a tracer.
+func (l *customTagsLimiter) limitPayload(p *pb.ClientStatsPayload) {
+	if l == nil {
+		return
+	}
+	for _, s := range p.Stats {
+		for _, g := range s.Stats {
+			if g == nil {
+				continue
+			}
+			for i, t := range g.PeerTags {
+				if k, v, ok := strings.Cut(t, ":"); ok && l.isCustom(k) {
+					g.PeerTags[i] = k + ":" + l.limit(k, v)
+				}
+			}
+		}
+	}
+}
+
+// splitGroupTags separates the custom tags from the other peer tags of grouped stats.
+func (l *customTagsLimiter) splitGroupTags(tags []string) (peerTags, customTags []string) {
+	if l == nil {
+		return tags, nil
+	}
+	for _, t := range tags {
+		if k, _, ok := strings.Cut(t, ":"); ok && l.isCustom(k) {
+			customTags = append(customTags, t)
+		} else {
+			peerTags = append(peerTags, t)
+		}
+	}
+	return peerTags, customTags
+}
+
+func (l *customTagsLimiter) isCustom(key string) bool {
+	for _, k := range l.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestPrepareCustomTags(t *testing.T) {
+	conf := &config.AgentConfig{StatsCustomTags: []string{"tenant", "db.system", "region", "tenant", ""}}
+	assert.Nil(t, prepareCustomTags(conf), "the custom tags are gated by a feature flag")
+
+	conf.Features = map[string]struct{}{customTagsFeature: {}}
+	assert.Equal(t, []string{"db.system", "region", "tenant"}, prepareCustomTags(conf))
+
+	conf.PeerTagsAggregation = true
+	conf.PeerTags = []string{"region"}
+	assert.Equal(t, []string{"tenant"}, prepareCustomTags(conf))
+
+	assert.Nil(t, prepareCustomTags(&config.AgentConfig{}))
+}
+
+func TestCustomTagsLimiter(t *testing.T) {
+	assert.Nil(t, newCustomTagsLimiter(nil, 10))
+
+	l := newCustomTagsLimiter([]string{"http.route", "shard", "tenant"}, 2)
+	for _, tt := range []struct {
+		in  *pb.Span
+		out []string
+	}{
+		{&pb.Span{}, nil},
+		{
+			&pb.Span{
+				Meta:    map[string]string{"tenant": "a", "http.route": "/users/:id"},
+				Metrics: map[string]float64{"shard": 3},
+			},
+			[]string{"http.route:/users/:id", "shard:3", "tenant:a"},
+		},
+		{&pb.Span{Meta: map[string]string{"tenant": "b"}}, []string{"tenant:b"}},
+		{&pb.Span{Meta: map[string]string{"tenant": "c"}}, []string{"tenant:" + customTagOverflowValue}},
+		{&pb.Span{Meta: map[string]string{"tenant": "a"}}, []string{"tenant:a"}},
+		{&pb.Span{Meta: map[string]string{"tenant": ""}, Metrics: map[string]float64{"tenant": 1.5}}, []string{"tenant:" + customTagOverflowValue}},
+	} {
+		assert.Equal(t, tt.out, l.spanTags(tt.in))
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		l := newCustomTagsLimiter([]string{"tenant"}, 0)
+		for _, v := range []string{"a", "b", "c", "d"} {
+			assert.Equal(t, v, l.limit("tenant", v))
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var l *customTagsLimiter
+		assert.Nil(t, l.spanTags(&pb.Span{Meta: map[string]string{"tenant": "a"}}))
+		peerTags, customTags := l.splitGroupTags([]string{"tenant:a"})
+		assert.Equal(t, []string{"tenant:a"}, peerTags)
+		assert.Nil(t, customTags)
+	})
+}
+
+func TestCustomTagsLimiterPayload(t *testing.T) {
+	l := newCustomTagsLimiter([]string{"tenant"}, 1)
+	p := &pb.ClientStatsPayload{
+		Stats: []*pb.ClientStatsBucket{{
+			Stats: []*pb.ClientGroupedStats{
+				{PeerTags: []string{"peer.service:db", "tenant:a"}},
+				nil,
+				{PeerTags: []string{"tenant:b", "peer.service:db"}},
+			},
+		}},
+	}
+	l.limitPayload(p)
+	assert.Equal(t, []string{"peer.service:db", "tenant:a"}, p.Stats[0].Stats[0].PeerTags)
+	assert.Equal(t, []string{"tenant:" + customTagOverflowValue, "peer.service:db"}, p.Stats[0].Stats[2].PeerTags)
+
+	peerTags, customTags := l.splitGroupTags(p.Stats[0].Stats[2].PeerTags)
+	assert.Equal(t, []string{"peer.service:db"}, peerTags)
+	assert.Equal(t, []string{"tenant:" + customTagOverflowValue}, customTags)
+}
@@ -105,6 +105,9 @@ type RawBucket struct {
 
 	// this should really remain private as it's subject to refactoring
 	data map[Aggregation]*groupedStats
+
+	// customTags computes the custom tags dimensions, nil if there are none.
+	customTags *customTagsLimiter
 }
 
 // NewRawBucket opens a new calculation bucket for time ts and initializes it properly
@@ -153,6 +156,11 @@ func (sb *RawBucket) HandleSpan(s *pb.Span, weight float64, isTop bool, origin s
 		panic("env should never be empty")
 	}
 	aggr, peerTags := NewAggregationFromSpan(s, origin, aggKey, enablePeerTagsAgg, peerTagKeys)
+	if customTags := sb.customTags.spanTags(s); len(customTags) > 0 {
+		// the custom tags are hashed apart from the peer tags, but sent along them
+		aggr.CustomTagsHash = peerTagsHash(customTags)
+		peerTags = append(peerTags, customTags...)
+	}
 	sb.add(s, weight, isTop, aggr, peerTags)
 }
 